@@ -0,0 +1,159 @@
+package limitron
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Defaults of AdaptiveConfig.
+const (
+	DefaultAdaptiveQuantile = 0.99
+	DefaultAdaptiveWindow   = 100
+)
+
+// AdaptiveConfig configures an AdaptiveLimiter.
+type AdaptiveConfig struct {
+	// Target is the latency the estimated quantile should stay below.
+	Target time.Duration
+	// Min and Max bound the admitted requests per Interval. The limiter starts at Max.
+	Min, Max uint16
+	// Interval is the period of the admitted rate.
+	Interval time.Duration
+	// Quantile is the latency quantile compared to Target; defaults to DefaultAdaptiveQuantile (p99).
+	Quantile float64
+	// Window is the number of observed latencies between limit adjustments;
+	// defaults to DefaultAdaptiveWindow.
+	Window int
+}
+
+// AdaptiveLimiter is a rate limiter whose rate adapts to latency feedback, in the spirit
+// of Netflix's concurrency-limits: callers report the latency of admitted requests with
+// ObserveLatency, and the limiter lowers the admitted rate while the estimated latency
+// quantile (p99 by default) exceeds the target, and raises it back while it does not.
+//
+// After every window of observations, the limit is decreased in proportion to
+// the overshoot of the target (at most halved), or increased by a hundredth of
+// the range between Min and Max (at least 1).
+//
+// TakeN uses the packed states of RateLimiter: a lower limit caps the tokens of
+// existing states at the new burst, and a higher one refills up to it at the new rate.
+//
+// The zero value is not usable; create instances with NewAdaptiveLimiter.
+// All methods are safe for concurrent use.
+type AdaptiveLimiter struct {
+	cfg     AdaptiveConfig
+	opts    []Option
+	limiter atomic.Pointer[RateLimiter]
+
+	mu       sync.Mutex
+	estimate float64 // estimated latency quantile, in nanoseconds
+	observed int
+}
+
+// NewAdaptiveLimiter returns an AdaptiveLimiter for `cfg`, starting at cfg.Max requests
+// per cfg.Interval. Options are applied to every limiter built as the limit changes.
+//
+// Example:
+//
+//	al, err := NewAdaptiveLimiter(AdaptiveConfig{
+//	    Target: 250 * time.Millisecond, Min: 10, Max: 1000, Interval: time.Second,
+//	})
+//	state := al.New()
+//	if _, ok := al.Take1(state); ok {
+//	    start := time.Now()
+//	    callBackend()
+//	    al.ObserveLatency(time.Since(start))
+//	}
+func NewAdaptiveLimiter(cfg AdaptiveConfig, opts ...Option) (*AdaptiveLimiter, error) {
+	if cfg.Target <= 0 {
+		return nil, fmt.Errorf("limitron: adaptive latency target must be positive")
+	}
+	if cfg.Min == 0 || cfg.Min > cfg.Max {
+		return nil, fmt.Errorf("limitron: adaptive limits must satisfy 0 < min <= max, got %d and %d", cfg.Min, cfg.Max)
+	}
+	if cfg.Interval < time.Millisecond {
+		return nil, fmt.Errorf("limitron: adaptive interval %s is shorter than 1ms", cfg.Interval)
+	}
+	if cfg.Quantile <= 0 || cfg.Quantile >= 1 {
+		cfg.Quantile = DefaultAdaptiveQuantile
+	}
+	if cfg.Window <= 0 {
+		cfg.Window = DefaultAdaptiveWindow
+	}
+	a := &AdaptiveLimiter{cfg: cfg, opts: opts}
+	a.setLimit(cfg.Max)
+	return a, nil
+}
+
+// New creates a brand-new, zero-use limiter state at the current limit.
+func (a *AdaptiveLimiter) New() *uint64 {
+	return a.limiter.Load().New()
+}
+
+// TakeN attempts to consume `requests` tokens from `*rl` at the current limit. See RateLimiter.TakeN.
+func (a *AdaptiveLimiter) TakeN(rl *uint64, requests uint16) (int64, bool) {
+	return a.limiter.Load().TakeN(rl, requests)
+}
+
+// Take1 attempts to consume 1 token from `*rl` at the current limit. See RateLimiter.TakeN.
+func (a *AdaptiveLimiter) Take1(rl *uint64) (int64, bool) {
+	return a.TakeN(rl, 1)
+}
+
+// Limit returns the current number of admitted requests per interval.
+func (a *AdaptiveLimiter) Limit() uint16 {
+	return a.limiter.Load().maxreq
+}
+
+// Estimate returns the current estimate of the latency quantile.
+func (a *AdaptiveLimiter) Estimate() time.Duration {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return time.Duration(a.estimate)
+}
+
+// ObserveLatency reports the latency of an admitted request.
+func (a *AdaptiveLimiter) ObserveLatency(d time.Duration) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.estimate = a.updatedEstimate(float64(max(d, 0)))
+	a.observed++
+	if a.observed < a.cfg.Window {
+		return
+	}
+	a.observed = 0
+
+	limit := float64(a.limiter.Load().maxreq)
+	if target := float64(a.cfg.Target); a.estimate > target {
+		limit *= max(target/a.estimate, 0.5)
+	} else {
+		limit += max(float64(a.cfg.Max-a.cfg.Min)/100, 1)
+	}
+	a.setLimit(uint16(min(max(limit, float64(a.cfg.Min)), float64(a.cfg.Max))))
+}
+
+// updatedEstimate returns the quantile estimate updated with the latency `x` in nanoseconds,
+// by steps proportional to the estimate: up by a share of the quantile for latencies
+// above it, down by a share of 1-quantile for those below, so that it settles where
+// a share of 1-quantile of the latencies is above it.
+// Must be called with a.mu held.
+func (a *AdaptiveLimiter) updatedEstimate(x float64) float64 {
+	const step = 0.05
+	switch {
+	case a.estimate == 0:
+		return x
+	case x > a.estimate:
+		return min(a.estimate*(1+step*a.cfg.Quantile), x)
+	default:
+		return max(a.estimate*(1-step*(1-a.cfg.Quantile)), x)
+	}
+}
+
+// setLimit replaces the limiter with one admitting `limit` requests per interval.
+func (a *AdaptiveLimiter) setLimit(limit uint16) {
+	l := BuildRateLimiter(limit, a.cfg.Interval, a.opts...)
+	a.limiter.Store(&l)
+}
@@ -0,0 +1,70 @@
+package limitron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewAdaptiveLimiter_Validates(t *testing.T) {
+	bad := []AdaptiveConfig{
+		{Min: 1, Max: 10, Interval: time.Second},
+		{Target: time.Second, Min: 0, Max: 10, Interval: time.Second},
+		{Target: time.Second, Min: 20, Max: 10, Interval: time.Second},
+		{Target: time.Second, Min: 1, Max: 10},
+	}
+	for i, cfg := range bad {
+		if _, err := NewAdaptiveLimiter(cfg); err == nil {
+			t.Errorf("config %d: no error", i)
+		}
+	}
+}
+
+func TestAdaptiveLimiter_DecreasesOnHighLatencyAndRecovers(t *testing.T) {
+	a, err := NewAdaptiveLimiter(AdaptiveConfig{
+		Target: 100 * time.Millisecond, Min: 10, Max: 1000, Interval: time.Second, Window: 10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Limit() != 1000 {
+		t.Fatalf("initial Limit() = %d, want 1000", a.Limit())
+	}
+
+	for i := 0; i < 100; i++ {
+		a.ObserveLatency(400 * time.Millisecond)
+	}
+	low := a.Limit()
+	if low > 100 {
+		t.Fatalf("Limit() = %d after sustained latency of 4x the target", low)
+	}
+	if a.Estimate() < 100*time.Millisecond {
+		t.Fatalf("Estimate() = %s, want above the target", a.Estimate())
+	}
+
+	// latencies well below the target: the estimate decays and the limit grows again
+	for i := 0; i < 5000; i++ {
+		a.ObserveLatency(10 * time.Millisecond)
+	}
+	if a.Limit() <= low {
+		t.Fatalf("Limit() = %d after low latencies, want above %d", a.Limit(), low)
+	}
+}
+
+func TestAdaptiveLimiter_NeverBelowMin(t *testing.T) {
+	a, _ := NewAdaptiveLimiter(AdaptiveConfig{
+		Target: time.Millisecond, Min: 5, Max: 50, Interval: time.Second, Window: 1,
+	})
+	for i := 0; i < 100; i++ {
+		a.ObserveLatency(time.Second)
+	}
+	if a.Limit() != 5 {
+		t.Fatalf("Limit() = %d, want the minimum 5", a.Limit())
+	}
+	state := a.New()
+	if _, ok := a.TakeN(state, 5); !ok {
+		t.Fatal("5 tokens denied at a limit of 5")
+	}
+	if _, ok := a.Take1(state); ok {
+		t.Fatal("sixth token allowed at a limit of 5")
+	}
+}
@@ -0,0 +1,131 @@
+package limitron
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// StateArena preallocates the packed states of a RateLimiter for a fixed number of
+// identities, such as worker or shard IDs, addressed by index. Taking tokens is a
+// bounds check and the usual CAS loop: no map lookup, no lock and no pointer per
+// identity, so a large arena is a single allocation the garbage collector never scans.
+//
+// Indices are either used directly (0 to Cap()-1) or handed out with Alloc and
+// returned with Release; the two styles should not be mixed in the same arena.
+//
+// The zero value is not usable; create instances with NewStateArena.
+// All methods are safe for concurrent use.
+type StateArena struct {
+	limiter RateLimiter
+	states  []uint64
+
+	// mu guards index allocation, which is not on the hot path.
+	mu sync.Mutex
+	// next is the lowest index never handed out; free holds released indices.
+	next int
+	free []int
+	// allocated is a bitset of the indices handed out and not released,
+	// created by the first Alloc.
+	allocated []uint64
+}
+
+// NewStateArena returns a StateArena of `n` states applying `limiter`, all starting
+// like a new key (see WithStartEmpty).
+//
+// Example:
+//
+//	workers := NewStateArena(BuildRateLimiterRps(100), numWorkers)
+//	if _, ok := workers.Take1(workerID); !ok {
+//	    // rate limited
+//	}
+func NewStateArena(limiter RateLimiter, n int) *StateArena {
+	a := &StateArena{limiter: limiter, states: make([]uint64, n)}
+	initial := limiter.initialState()
+	for i := range a.states {
+		a.states[i] = initial
+	}
+	return a
+}
+
+// Limiter returns the RateLimiter applied to every state.
+func (a *StateArena) Limiter() RateLimiter {
+	return a.limiter
+}
+
+// Cap returns the number of states of the arena.
+func (a *StateArena) Cap() int {
+	return len(a.states)
+}
+
+// TakeN attempts to consume `requests` tokens from state `idx`. See RateLimiter.TakeN.
+// It panics if `idx` is out of range.
+func (a *StateArena) TakeN(idx int, requests uint16) (int64, bool) {
+	return a.limiter.TakeN(&a.states[idx], requests)
+}
+
+// Take1 is TakeN for 1 token.
+func (a *StateArena) Take1(idx int) (int64, bool) {
+	return a.limiter.TakeN(&a.states[idx], 1)
+}
+
+// State returns packed state `idx`, for use with the methods of Limiter() that take a state.
+// It panics if `idx` is out of range.
+func (a *StateArena) State(idx int) *uint64 {
+	return &a.states[idx]
+}
+
+// Reset restores state `idx` to the state of a new identity.
+func (a *StateArena) Reset(idx int) {
+	atomic.StoreUint64(&a.states[idx], a.limiter.initialState())
+}
+
+// Alloc hands out an unused index with a fresh state, preferring released indices.
+// It returns false when all indices are in use.
+//
+// Example:
+//
+//	idx, ok := arena.Alloc()
+//	if !ok {
+//	    return errTooManyWorkers
+//	}
+//	defer arena.Release(idx)
+func (a *StateArena) Alloc() (int, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var idx int
+	if n := len(a.free); n > 0 {
+		idx = a.free[n-1]
+		a.free = a.free[:n-1]
+	} else if a.next < len(a.states) {
+		idx = a.next
+		a.next++
+	} else {
+		return -1, false
+	}
+	if a.allocated == nil {
+		a.allocated = make([]uint64, (len(a.states)+63)/64)
+	}
+	a.allocated[idx/64] |= 1 << (idx % 64)
+	a.Reset(idx)
+	return idx, true
+}
+
+// Release returns index `idx` handed out by Alloc, to be reused by a later Alloc.
+// It panics if `idx` is not handed out, e.g., when released twice.
+func (a *StateArena) Release(idx int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if idx < 0 || idx >= a.next || a.allocated[idx/64]&(1<<(idx%64)) == 0 {
+		panic(fmt.Sprintf("limitron: StateArena.Release of index %d, which was not allocated", idx))
+	}
+	a.allocated[idx/64] &^= 1 << (idx % 64)
+	a.free = append(a.free, idx)
+}
+
+// Allocated returns the number of indices handed out by Alloc and not released.
+func (a *StateArena) Allocated() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.next - len(a.free)
+}
@@ -0,0 +1,79 @@
+package limitron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStateArena(t *testing.T) {
+	a := NewStateArena(BuildRateLimiter(2, time.Hour), 4)
+	if a.Cap() != 4 {
+		t.Fatalf("Cap() = %d, want 4", a.Cap())
+	}
+	for idx := 0; idx < a.Cap(); idx++ {
+		if _, ok := a.TakeN(idx, 2); !ok {
+			t.Fatalf("index %d denied", idx)
+		}
+		if _, ok := a.Take1(idx); ok {
+			t.Fatalf("index %d allowed more than the burst", idx)
+		}
+	}
+
+	a.Reset(2)
+	if got := a.Limiter().Available(a.State(2)); got != 2 {
+		t.Fatalf("Available after Reset = %d, want 2", got)
+	}
+}
+
+func TestStateArena_Alloc(t *testing.T) {
+	a := NewStateArena(BuildRateLimiter(2, time.Hour), 2)
+	i, _ := a.Alloc()
+	j, _ := a.Alloc()
+	if i != 0 || j != 1 {
+		t.Fatalf("Alloc handed out %d and %d", i, j)
+	}
+	if _, ok := a.Alloc(); ok {
+		t.Fatalf("Alloc succeeded on a full arena")
+	}
+
+	a.TakeN(j, 2)
+	a.Release(j)
+	if a.Allocated() != 1 {
+		t.Fatalf("Allocated() = %d, want 1", a.Allocated())
+	}
+	// a released index comes back with a fresh state
+	k, ok := a.Alloc()
+	if !ok || k != j {
+		t.Fatalf("Alloc = %d, %v; want %d", k, ok, j)
+	}
+	if _, ok := a.TakeN(k, 2); !ok {
+		t.Fatalf("reused index kept its previous state")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("Release of an index never allocated did not panic")
+		}
+	}()
+	NewStateArena(BuildRateLimiter(2, time.Hour), 2).Release(0)
+}
+
+func TestStateArena_DoubleRelease(t *testing.T) {
+	a := NewStateArena(BuildRateLimiter(2, time.Hour), 2)
+	i, _ := a.Alloc()
+	a.Release(i)
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("double Release did not panic")
+		}
+		// the index was freed once, so only one Alloc gets it
+		if i, _ := a.Alloc(); i != 0 {
+			t.Fatalf("Alloc = %d, want the released index 0", i)
+		}
+		if j, _ := a.Alloc(); j != 1 {
+			t.Fatalf("Alloc = %d, want 1", j)
+		}
+	}()
+	a.Release(i)
+}
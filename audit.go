@@ -0,0 +1,67 @@
+package limitron
+
+import "time"
+
+// AuditEvent is a single decision of a KeyedLimiter, as reported to WithAudit.
+type AuditEvent[K comparable] struct {
+	// Time is the time of the decision, read from the limiter's clock.
+	Time time.Time
+	// Key is the limited key.
+	Key K
+	// Cost is the number of tokens requested.
+	Cost uint16
+	// Allowed is the decision returned to the caller.
+	Allowed bool
+	// WaitMillis is the wait suggested to a denied request. In Shadow mode,
+	// it is set for allowed requests that would have been denied.
+	WaitMillis int64
+	// Mode is the enforcement mode of the key's namespace (see SetNamespaceMode).
+	Mode EnforcementMode
+}
+
+// WithAudit makes a KeyedLimiter report every TakeN decision to fn, in the order
+// the decisions are made on each goroutine, to persist an audit trail of throttling
+// actions. Peek calls are not reported.
+//
+// fn is called synchronously from TakeN and should be fast; hand events off
+// to a buffered writer, or use WithAuditChannel.
+func WithAudit[K comparable](fn func(AuditEvent[K])) KeyedOption[K] {
+	return func(kl *KeyedLimiter[K]) {
+		kl.audit = fn
+	}
+}
+
+// WithAuditChannel makes a KeyedLimiter send every TakeN decision to ch.
+//
+// Sends block, so that no event is lost: a consumer that falls behind a full
+// buffer slows down the limited requests. Size the buffer for bursts accordingly.
+//
+// Example:
+//
+//	events := make(chan AuditEvent[string], 4096)
+//	kl := NewKeyedLimiter[string](limiter, WithAuditChannel(events))
+//	go func() {
+//	    for ev := range events {
+//	        auditLog.Append(ev)
+//	    }
+//	}()
+func WithAuditChannel[K comparable](ch chan<- AuditEvent[K]) KeyedOption[K] {
+	return WithAudit(func(ev AuditEvent[K]) {
+		ch <- ev
+	})
+}
+
+// record reports a TakeN decision to the audit callback, if any.
+func (kl *KeyedLimiter[K]) record(key K, cost uint16, mode EnforcementMode, waitMillis int64, allowed bool) {
+	if kl.audit == nil {
+		return
+	}
+	kl.audit(AuditEvent[K]{
+		Time:       kl.limiter.now(),
+		Key:        key,
+		Cost:       cost,
+		Allowed:    allowed,
+		WaitMillis: waitMillis,
+		Mode:       mode,
+	})
+}
@@ -0,0 +1,43 @@
+package limitron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyedLimiter_AuditChannel(t *testing.T) {
+	events := make(chan AuditEvent[string], 10)
+	kl := NewKeyedLimiter[string](BuildRateLimiter(2, time.Hour), WithAuditChannel(events))
+
+	kl.TakeN("acct-1", 2)
+	kl.Take1("acct-1")
+	kl.Peek("acct-1", 1)
+	close(events)
+
+	var got []AuditEvent[string]
+	for ev := range events {
+		got = append(got, ev)
+	}
+	if len(got) != 2 {
+		t.Fatalf("%d events, want 2 (Peek is not audited)", len(got))
+	}
+	if ev := got[0]; ev.Key != "acct-1" || ev.Cost != 2 || !ev.Allowed || ev.WaitMillis != 0 || ev.Time.IsZero() {
+		t.Fatalf("first event = %+v", ev)
+	}
+	if ev := got[1]; ev.Allowed || ev.WaitMillis <= 0 || ev.Mode != Enforce {
+		t.Fatalf("second event = %+v, want an enforced denial", ev)
+	}
+}
+
+func TestKeyedLimiter_AuditShadowMode(t *testing.T) {
+	var got []AuditEvent[string]
+	kl := NewKeyedLimiter[string](BuildRateLimiter(1, time.Hour),
+		WithAudit(func(ev AuditEvent[string]) { got = append(got, ev) }))
+	kl.SetNamespaceMode("", Shadow)
+
+	kl.Take1("k")
+	kl.Take1("k")
+	if len(got) != 2 || !got[1].Allowed || got[1].WaitMillis <= 0 || got[1].Mode != Shadow {
+		t.Fatalf("events = %+v, want a would-be denial in shadow mode", got)
+	}
+}
@@ -0,0 +1,60 @@
+package limitron
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// Available returns the number of tokens available in the limiter state `*rl` right now,
+// including the refill since its last use, without modifying the state.
+//
+// Example:
+//
+//	gauge.Set(float64(limiter.Available(state)))
+func (s RateLimiter) Available(rl *uint64) uint16 {
+	newreq, _ := s.calcNewRequestsAt(atomic.LoadUint64(rl), s.nowTicks())
+	return newreq
+}
+
+// TimeToAvailable returns the time until `requests` tokens will have been available to
+// a caller consuming the limiter state `*rl` as tokens accrue, without modifying the state.
+// Unlike the wait of TakeN, it is meaningful for requests above the burst: it is the time
+// to drain them over several intervals, e.g., in burst-sized TakeN calls, if nothing
+// else consumes the state meanwhile.
+//
+// Example:
+//
+//	// 10,000 rows against 1,000 per minute: about 9 minutes after the first 1,000
+//	eta := limiter.TimeToAvailable(state, 10_000)
+func (s RateLimiter) TimeToAvailable(rl *uint64, requests uint32) time.Duration {
+	now := s.nowTicks()
+	newreq, ts := s.calcNewRequestsAt(atomic.LoadUint64(rl), now)
+	if requests <= uint32(newreq) {
+		return 0
+	}
+	ticks := math.Ceil(float64(requests-uint32(newreq)) / s.rrpt())
+	if ts < now {
+		// the refill already accrued towards the next token
+		ticks = max(ticks-float64(now-ts), 0)
+	} else {
+		// debt or reservations are repaid first
+		ticks += float64(ts - now)
+	}
+	return s.tickDuration(uint64(ticks))
+}
+
+// TimeToFull returns the time until the limiter state `*rl` is refilled to the full
+// burst if nothing else is consumed, including the repayment of any debt or booked
+// reservation (see WithDebt and ReserveN), without modifying the state.
+func (s RateLimiter) TimeToFull(rl *uint64) time.Duration {
+	req, ts := unpackUint16Uint48(atomic.LoadUint64(rl))
+	if req >= s.maxreq {
+		return 0
+	}
+	full := ts + uint64(math.Ceil(float64(s.maxreq-req)/s.rrpt()))
+	if now := s.nowTicks(); full > now {
+		return s.tickDuration(full - now)
+	}
+	return 0
+}
@@ -0,0 +1,63 @@
+package limitron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_AvailableAndTimeToFull(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	s := BuildRateLimiter(10, time.Second, WithClock(clock)) // 1 token per 100ms
+	rl := s.New()
+
+	if s.Available(rl) != 10 || s.TimeToFull(rl) != 0 {
+		t.Fatalf("new state: Available %d, TimeToFull %s", s.Available(rl), s.TimeToFull(rl))
+	}
+	s.TakeN(rl, 7)
+	before := *rl
+	clock.t = clock.t.Add(250 * time.Millisecond)
+
+	if got := s.Available(rl); got != 5 {
+		t.Fatalf("Available() = %d, want 5", got)
+	}
+	if got := s.TimeToFull(rl); got != 450*time.Millisecond {
+		t.Fatalf("TimeToFull() = %s, want 450ms", got)
+	}
+	if *rl != before {
+		t.Fatal("queries modified the state")
+	}
+}
+
+func TestRateLimiter_TimeToFullWithBooking(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	s := BuildRateLimiter(10, time.Second, WithClock(clock))
+	rl := s.New()
+	s.ReserveN(rl, 10)
+	s.ReserveN(rl, 2) // booked 200ms ahead
+
+	if s.Available(rl) != 0 {
+		t.Fatalf("Available() = %d, want 0", s.Available(rl))
+	}
+	if got := s.TimeToFull(rl); got != 1200*time.Millisecond {
+		t.Fatalf("TimeToFull() = %s, want 1.2s", got)
+	}
+}
+
+func TestRateLimiter_TimeToAvailable(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	s := BuildRateLimiter(1000, time.Minute, WithClock(clock))
+	rl := s.New()
+
+	if got := s.TimeToAvailable(rl, 500); got != 0 {
+		t.Fatalf("TimeToAvailable(500) = %s, want 0", got)
+	}
+	if got := s.TimeToAvailable(rl, 10_000); got != 9*time.Minute {
+		t.Fatalf("TimeToAvailable(10000) = %s, want 9m", got)
+	}
+
+	s.TakeN(rl, 1000)
+	clock.t = clock.t.Add(30 * time.Second)
+	if got := s.TimeToAvailable(rl, 2000); got != 90*time.Second {
+		t.Fatalf("TimeToAvailable(2000) after 30s = %s, want 1m30s", got)
+	}
+}
@@ -0,0 +1,177 @@
+// Package awslimit rate-limits calls of AWS service operations (e.g., DynamoDB
+// writes, SES sends) on the client side with limitron, per operation, so that
+// calls are paced below the service quotas instead of failing with throttling
+// exceptions.
+//
+// It works at the HTTP layer, so it plugs into aws-sdk-go-v2 (and other AWS
+// clients) without adding the SDK as a dependency of limitron:
+//
+//	cfg, err := config.LoadDefaultConfig(ctx, config.WithHTTPClient(&http.Client{
+//	    Transport: awslimit.NewTransport(nil, map[string]limitron.RateLimiter{
+//	        "dynamodb:PutItem":        limitron.BuildRateLimiterRps(500),
+//	        "dynamodb:BatchWriteItem": limitron.BuildRateLimiterRps(50),
+//	        "email:SendEmail":         limitron.BuildRateLimiterRps(14),
+//	    }),
+//	}))
+//
+// Operation limits are keyed "<service>:<operation>", as identified by Operation.
+// Calls of operations without a limit pass unlimited.
+package awslimit
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/iryndin/limitron"
+)
+
+// maxFormBody caps the request body read to find the Action of query-protocol calls.
+const maxFormBody = 1 << 20
+
+// OperationFunc identifies the AWS operation of a request as "<service>:<operation>".
+// It returns false if the request's operation is unknown.
+type OperationFunc func(r *http.Request) (string, bool)
+
+// Option configures a Transport.
+type Option func(*Transport)
+
+// WithOperationFunc sets how operations are identified, e.g., to map the paths of
+// REST protocol services (such as SES v2) to operations. The default is Operation.
+func WithOperationFunc(fn OperationFunc) Option {
+	return func(t *Transport) {
+		t.operation = fn
+	}
+}
+
+// Transport is an http.RoundTripper pacing AWS operation calls.
+// Calls over an operation's limit wait for their turn, until their context is done.
+//
+// The zero value is not usable; create instances with NewTransport.
+// All methods are safe for concurrent use.
+type Transport struct {
+	base      http.RoundTripper
+	operation OperationFunc
+	limits    map[string]*opLimit
+}
+
+// opLimit is the limiter and state of an operation.
+type opLimit struct {
+	limiter limitron.RateLimiter
+	state   *uint64
+}
+
+// NewTransport returns a Transport sending requests through `base` (http.DefaultTransport
+// if nil) and limiting the operations of `limits`, keyed "<service>:<operation>".
+func NewTransport(base http.RoundTripper, limits map[string]limitron.RateLimiter, opts ...Option) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &Transport{base: base, operation: Operation, limits: make(map[string]*opLimit, len(limits))}
+	for op, limiter := range limits {
+		t.limits[op] = &opLimit{limiter: limiter, state: limiter.New()}
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// RoundTrip implements http.RoundTripper. It waits for the limit of the request's
+// operation, and returns the context error if the request's context is done first,
+// or a *limitron.LimitedError if the wait cannot end before the context's deadline.
+//
+// `r` is not modified: a form-encoded body without GetBody is buffered into a clone
+// of `r`, which is sent instead.
+func (t *Transport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = withGetBody(r)
+	if op, ok := t.operation(r); ok {
+		if l, ok := t.limits[op]; ok {
+			if err := l.limiter.WaitN(r.Context(), l.state, 1); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return t.base.RoundTrip(r)
+}
+
+// Operation identifies the operation of a request to an AWS endpoint by protocol:
+//   - JSON protocols (e.g., DynamoDB, Kinesis, SQS): the X-Amz-Target header,
+//     "DynamoDB_20120810.PutItem" giving "dynamodb:PutItem"
+//   - query protocols (e.g., SES v1, SNS): the Action form parameter of the URL or body;
+//     the body is read through GetBody, so requests without it are identified by URL only
+//
+// The service is the first label of the endpoint host, e.g., "dynamodb" for
+// dynamodb.us-east-1.amazonaws.com. REST protocols identify operations by method
+// and path, which Operation does not map; use WithOperationFunc for those.
+func Operation(r *http.Request) (string, bool) {
+	service, _, _ := strings.Cut(r.URL.Hostname(), ".")
+	if service == "" {
+		return "", false
+	}
+	if target := r.Header.Get("X-Amz-Target"); target != "" {
+		if i := strings.LastIndexByte(target, '.'); i >= 0 && i < len(target)-1 {
+			return service + ":" + target[i+1:], true
+		}
+	}
+	if action := r.URL.Query().Get("Action"); action != "" {
+		return service + ":" + action, true
+	}
+	if action := formAction(r); action != "" {
+		return service + ":" + action, true
+	}
+	return "", false
+}
+
+// formAction returns the Action parameter of a form-encoded request body,
+// read from a copy of the body returned by GetBody.
+func formAction(r *http.Request) string {
+	if r.GetBody == nil || !isForm(r) {
+		return ""
+	}
+	rc, err := r.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer rc.Close()
+	body, err := io.ReadAll(io.LimitReader(rc, maxFormBody+1))
+	if err != nil || len(body) > maxFormBody {
+		return ""
+	}
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return ""
+	}
+	return values.Get("Action")
+}
+
+// withGetBody returns `r`, or a clone of it with a GetBody if `r` is a form-encoded
+// request without one, so that formAction can read the body without consuming it.
+// Bodies over maxFormBody are not buffered; their clone has no GetBody.
+func withGetBody(r *http.Request) *http.Request {
+	if r.GetBody != nil || r.Body == nil || r.Body == http.NoBody || !isForm(r) {
+		return r
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxFormBody+1))
+	clone := r.Clone(r.Context())
+	if err != nil || len(body) > maxFormBody {
+		clone.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+		return clone
+	}
+	r.Body.Close()
+	clone.Body = io.NopCloser(bytes.NewReader(body))
+	clone.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return clone
+}
+
+// isForm reports whether `r` has a form-encoded body.
+func isForm(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded")
+}
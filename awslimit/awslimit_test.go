@@ -0,0 +1,169 @@
+package awslimit
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iryndin/limitron"
+)
+
+// echoTransport answers every request with 200, echoing the request body.
+type echoTransport struct{ sent int }
+
+func (e *echoTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	e.sent++
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(string(body))), Request: r}, nil
+}
+
+func jsonRequest(target string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "https://dynamodb.us-east-1.amazonaws.com/", strings.NewReader("{}"))
+	r.Header.Set("X-Amz-Target", target)
+	return r
+}
+
+func formRequest(body string) *http.Request {
+	r, _ := http.NewRequest(http.MethodPost, "https://email.us-east-1.amazonaws.com/", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	return r
+}
+
+func TestOperation(t *testing.T) {
+	tests := []struct {
+		req  *http.Request
+		want string
+		ok   bool
+	}{
+		{jsonRequest("DynamoDB_20120810.PutItem"), "dynamodb:PutItem", true},
+		{jsonRequest("DynamoDB_20120810."), "", false},
+		{formRequest("Action=SendEmail&Version=2010-12-01"), "email:SendEmail", true},
+		{httptest.NewRequest(http.MethodGet, "https://sns.eu-west-1.amazonaws.com/?Action=Publish", nil), "sns:Publish", true},
+		{httptest.NewRequest(http.MethodGet, "https://s3.amazonaws.com/bucket/key", nil), "", false},
+	}
+	for _, tt := range tests {
+		got, ok := Operation(tt.req)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Operation(%s %s) = %q, %v, want %q, %v", tt.req.Method, tt.req.URL, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestOperation_LeavesFormBody(t *testing.T) {
+	const body = "Action=SendEmail&Source=a%40example.com"
+	r := formRequest(body)
+	if op, _ := Operation(r); op != "email:SendEmail" {
+		t.Fatalf("Operation() = %q", op)
+	}
+	got, err := io.ReadAll(r.Body)
+	if err != nil || string(got) != body {
+		t.Fatalf("body after Operation = %q, %v, want %q", got, err, body)
+	}
+}
+
+func TestTransport_LimitsPerOperation(t *testing.T) {
+	base := &echoTransport{}
+	tr := NewTransport(base, map[string]limitron.RateLimiter{
+		"dynamodb:PutItem": limitron.BuildRateLimiter(2, time.Hour),
+	})
+
+	call := func(r *http.Request) error {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+		_, err := tr.RoundTrip(r.WithContext(ctx))
+		return err
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := call(jsonRequest("DynamoDB_20120810.PutItem")); err != nil {
+			t.Fatalf("PutItem %d: %v", i, err)
+		}
+	}
+	var limited *limitron.LimitedError
+	if err := call(jsonRequest("DynamoDB_20120810.PutItem")); !errors.As(err, &limited) && !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("third PutItem: err = %v, want it limited", err)
+	}
+	// operations without a limit pass
+	for i := 0; i < 5; i++ {
+		if err := call(jsonRequest("DynamoDB_20120810.GetItem")); err != nil {
+			t.Fatalf("GetItem %d: %v", i, err)
+		}
+	}
+	if base.sent != 7 {
+		t.Fatalf("sent %d requests, want 7", base.sent)
+	}
+}
+
+func TestTransport_SendsFullFormBody(t *testing.T) {
+	const body = "Action=SendEmail&Source=a%40example.com"
+	tr := NewTransport(&echoTransport{}, map[string]limitron.RateLimiter{
+		"email:SendEmail": limitron.BuildRateLimiterRps(10),
+	})
+	resp, err := tr.RoundTrip(formRequest(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _ := io.ReadAll(resp.Body)
+	if string(got) != body {
+		t.Fatalf("sent body %q, want %q", got, body)
+	}
+}
+
+func TestTransport_DoesNotModifyRequest(t *testing.T) {
+	const body = "Action=SendEmail&Source=a%40example.com"
+	tr := NewTransport(&echoTransport{}, map[string]limitron.RateLimiter{
+		"email:SendEmail": limitron.BuildRateLimiter(1, time.Hour),
+	})
+	// a request without GetBody, whose body can only be read once
+	r := httptest.NewRequest(http.MethodPost, "https://email.us-east-1.amazonaws.com/", strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	reqBody := r.Body
+
+	resp, err := tr.RoundTrip(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := io.ReadAll(resp.Body); string(got) != body {
+		t.Fatalf("sent body %q, want %q", got, body)
+	}
+	if r.Body != reqBody || r.GetBody != nil {
+		t.Fatal("RoundTrip modified the request")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := tr.RoundTrip(formRequest(body).WithContext(ctx)); err == nil {
+		t.Fatal("second SendEmail should be limited")
+	}
+}
+
+func TestWithOperationFunc(t *testing.T) {
+	base := &echoTransport{}
+	tr := NewTransport(base, map[string]limitron.RateLimiter{
+		"email:SendEmail": limitron.BuildRateLimiter(1, time.Hour),
+	}, WithOperationFunc(func(r *http.Request) (string, bool) {
+		return "email:SendEmail", r.URL.Path == "/v2/email/outbound-emails"
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	send := func() error {
+		r := httptest.NewRequest(http.MethodPost, "https://email.us-east-1.amazonaws.com/v2/email/outbound-emails", nil)
+		_, err := tr.RoundTrip(r.WithContext(ctx))
+		return err
+	}
+	if err := send(); err != nil {
+		t.Fatal(err)
+	}
+	if err := send(); err == nil {
+		t.Fatal("second SendEmail passed a limit of 1 per hour")
+	}
+}
@@ -0,0 +1,123 @@
+package limitron
+
+import (
+	"math"
+	"math/bits"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// banList is the negative cache of banned keys of a KeyedLimiter.
+//
+// It is an immutable snapshot replaced on every change (bans are rare compared to
+// lookups): a small Bloom filter answers "not banned" for almost all keys without
+// locks or map accesses, and an exact map of expiry times confirms its positives.
+type banList[K comparable] struct {
+	mu   sync.Mutex // serializes writers
+	snap atomic.Pointer[banSnapshot[K]]
+}
+
+// banSnapshot is an immutable state of a banList.
+type banSnapshot[K comparable] struct {
+	// filter is a Bloom filter of the banned keys with two probes; its length is a power of two.
+	filter []uint64
+	// until maps banned keys to the end of their ban in Unix milliseconds; 0 means forever.
+	until map[K]uint64
+}
+
+// Ban denies all requests of `key` for duration `d`, or until Unban if `d` is
+// not positive, regardless of the enforcement mode of its namespace.
+//
+// Banned keys are rejected before their state is looked up, so high-volume
+// attacks from them neither touch nor grow the limiter state. Banning again
+// replaces the previous ban.
+//
+// Example:
+//
+//	kl.Ban(attackerIP, time.Hour)
+func (kl *KeyedLimiter[K]) Ban(key K, d time.Duration) {
+	var until uint64
+	if d > 0 {
+		until = kl.limiter.nowMillis() + uint64(d.Milliseconds())
+	}
+	kl.bans.update(kl, func(m map[K]uint64) { m[key] = until })
+}
+
+// Unban lifts the ban of `key` and reports whether it was banned.
+func (kl *KeyedLimiter[K]) Unban(key K) bool {
+	_, ok := kl.banned(key)
+	if ok {
+		kl.bans.update(kl, func(m map[K]uint64) { delete(m, key) })
+	}
+	return ok
+}
+
+// Banned reports whether `key` is currently banned.
+func (kl *KeyedLimiter[K]) Banned(key K) bool {
+	_, ok := kl.banned(key)
+	return ok
+}
+
+// banned reports whether `key` is banned, and the wait in millis until the ban ends
+// (math.MaxInt64 for bans without an end).
+func (kl *KeyedLimiter[K]) banned(key K) (int64, bool) {
+	snap := kl.bans.snap.Load()
+	if snap == nil {
+		return 0, false
+	}
+	h := hashKey(kl.seed, key)
+	mask := uint64(len(snap.filter)*64 - 1)
+	for _, probe := range [2]uint64{h & mask, (h >> 32) & mask} {
+		if snap.filter[probe/64]&(1<<(probe%64)) == 0 {
+			return 0, false
+		}
+	}
+
+	until, ok := snap.until[key]
+	if !ok {
+		return 0, false
+	}
+	if until == 0 {
+		return math.MaxInt64, true
+	}
+	now := kl.limiter.nowMillis()
+	if now >= until {
+		return 0, false
+	}
+	return int64(until - now), true
+}
+
+// update applies `fn` to a copy of the banned keys, dropping expired bans,
+// and publishes a new snapshot.
+func (b *banList[K]) update(kl *KeyedLimiter[K], fn func(map[K]uint64)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := kl.limiter.nowMillis()
+	until := make(map[K]uint64)
+	if old := b.snap.Load(); old != nil {
+		for k, u := range old.until {
+			if u == 0 || u > now {
+				until[k] = u
+			}
+		}
+	}
+	fn(until)
+	if len(until) == 0 {
+		b.snap.Store(nil)
+		return
+	}
+
+	// about 16 bits per key keep false positives well below 1%
+	words := 1 << max(bits.Len(uint(len(until)*16/64)), 1)
+	snap := &banSnapshot[K]{filter: make([]uint64, words), until: until}
+	mask := uint64(words*64 - 1)
+	for k := range until {
+		h := hashKey(kl.seed, k)
+		for _, probe := range [2]uint64{h & mask, (h >> 32) & mask} {
+			snap.filter[probe/64] |= 1 << (probe % 64)
+		}
+	}
+	b.snap.Store(snap)
+}
@@ -0,0 +1,71 @@
+package limitron
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestKeyedLimiter_Ban(t *testing.T) {
+	kl := NewKeyedLimiter[string](BuildRateLimiterRps(10))
+	kl.Ban("evil", 0)
+
+	waitMillis, ok := kl.Take1("evil")
+	if ok || waitMillis != math.MaxInt64 {
+		t.Fatalf("banned Take1 = %d, %v; want MaxInt64, false", waitMillis, ok)
+	}
+	if _, ok := kl.Peek("evil", 1); ok {
+		t.Fatalf("banned Peek allowed")
+	}
+	if kl.Len() != 0 {
+		t.Fatalf("banned key created a state")
+	}
+	if _, ok := kl.Take1("good"); !ok {
+		t.Fatalf("unbanned key denied")
+	}
+
+	// bans apply in every enforcement mode
+	kl.SetNamespaceMode("", Off)
+	if _, ok := kl.Take1("evil"); ok {
+		t.Fatalf("banned key allowed in Off mode")
+	}
+	kl.SetNamespaceMode("", Enforce)
+
+	if !kl.Unban("evil") || kl.Banned("evil") {
+		t.Fatalf("Unban failed")
+	}
+	if kl.Unban("evil") {
+		t.Fatalf("second Unban reported a ban")
+	}
+	if _, ok := kl.Take1("evil"); !ok {
+		t.Fatalf("unbanned key denied")
+	}
+}
+
+func TestKeyedLimiter_BanExpires(t *testing.T) {
+	kl := NewKeyedLimiter[string](BuildRateLimiterRps(10))
+	kl.Ban("k", 30*time.Millisecond)
+	waitMillis, ok := kl.Take1("k")
+	if ok || waitMillis <= 0 || waitMillis > 30 {
+		t.Fatalf("Take1 = %d, %v; want denied for at most 30ms", waitMillis, ok)
+	}
+	time.Sleep(40 * time.Millisecond)
+	if _, ok := kl.Take1("k"); !ok {
+		t.Fatalf("key still banned after expiry")
+	}
+}
+
+func TestKeyedLimiter_BanManyKeys(t *testing.T) {
+	kl := NewKeyedLimiter[int](BuildRateLimiterRps(10))
+	for i := 0; i < 1000; i += 2 {
+		kl.Ban(i, time.Hour)
+	}
+	for i := 0; i < 1000; i++ {
+		if got, want := kl.Banned(i), i%2 == 0; got != want {
+			t.Fatalf("Banned(%d) = %v, want %v", i, got, want)
+		}
+	}
+	if snap := kl.bans.snap.Load(); len(snap.filter)*64 < 16*len(snap.until) {
+		t.Fatalf("filter of %d bits for %d keys", len(snap.filter)*64, len(snap.until))
+	}
+}
@@ -0,0 +1,104 @@
+package limitron
+
+import "sort"
+
+// AllowBatch attempts to consume `requests` tokens from the state of each of `keys`,
+// as TakeNResult would, and returns the decisions in the order of `keys`.
+//
+// It is meant for batch ingestion paths validating thousands of items per call:
+// the clock is read once and the refill of all keys is evaluated at that time,
+// and keys are grouped by shard, so that each shard is locked once per batch
+// rather than once per key. Duplicate keys consume tokens once per occurrence,
+// in order.
+//
+// Example:
+//
+//	decisions := kl.AllowBatch(tenantIDs, 1)
+//	for i, d := range decisions {
+//	    if !d.Allowed {
+//	        rejected = append(rejected, items[i])
+//	    }
+//	}
+func (kl *KeyedLimiter[K]) AllowBatch(keys []K, requests uint16) []Decision {
+	t := kl.limiter.now()
+	now := unixMillis(t)
+
+	// resolve the entries shard by shard
+	order := make([]batchKey, len(keys))
+	for i, key := range keys {
+		order[i] = batchKey{i, hashKey(kl.seed, key) & (keyedShards - 1)}
+	}
+	sort.SliceStable(order, func(a, b int) bool { return order[a].shard < order[b].shard })
+
+	entries := make([]*keyedEntry, len(keys))
+	for lo := 0; lo < len(order); {
+		hi := lo
+		for hi < len(order) && order[hi].shard == order[lo].shard {
+			hi++
+		}
+		kl.resolveShard(&kl.shards[order[lo].shard], keys, order[lo:hi], entries, now)
+		lo = hi
+	}
+
+	decisions := make([]Decision, len(keys))
+	for i, key := range keys {
+		waitMillis, allowed := kl.take(key, entries[i], requests, kl.limiter.ticksOf(t))
+		e := entries[i]
+		if e == nil {
+			e = kl.lookup(key)
+		}
+		decisions[i] = kl.entryDecision(key, e, waitMillis, allowed)
+	}
+	return decisions
+}
+
+// batchKey is the position of a key in a batch and its shard.
+type batchKey struct {
+	i     int
+	shard uint64
+}
+
+// resolveShard fills `entries` with the entries of the keys at positions `idx` of `keys`,
+// which all belong to shard `sh`, creating missing entries under a single write lock.
+// Keys that are banned or not enforced get no entry, so that they do not grow the state.
+// With reputations, missing entries are left to be created by take, which consults
+// the provider outside of any lock.
+func (kl *KeyedLimiter[K]) resolveShard(sh *keyedShard[K], keys []K, idx []batchKey, entries []*keyedEntry, now uint64) {
+	missing := 0
+	sh.mu.RLock()
+	for _, k := range idx {
+		if e, ok := sh.entries[keys[k.i]]; ok {
+			kl.touch(sh, e)
+			entries[k.i] = e
+		} else {
+			missing++
+		}
+	}
+	sh.mu.RUnlock()
+	if missing == 0 || kl.reputation != nil {
+		return
+	}
+
+	var evicted []K
+	sh.mu.Lock()
+	for _, k := range idx {
+		key := keys[k.i]
+		if entries[k.i] != nil || kl.mode(key) == Off || kl.Banned(key) {
+			continue
+		}
+		e, ok := sh.entries[key]
+		if ok {
+			kl.touch(sh, e)
+		} else {
+			e = &keyedEntry{state: kl.limiterOf(key).initialState(), created: now}
+			if ek, ok := kl.insert(sh, key, e); ok {
+				evicted = append(evicted, ek)
+			}
+		}
+		entries[k.i] = e
+	}
+	sh.mu.Unlock()
+	for _, ek := range evicted {
+		kl.notifyEvicted(ek, true)
+	}
+}
@@ -0,0 +1,60 @@
+package limitron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyedLimiter_AllowBatch(t *testing.T) {
+	kl := NewKeyedLimiter[string](BuildRateLimiter(2, time.Hour))
+	kl.Take1("b")
+	kl.Ban("banned", time.Hour)
+
+	keys := []string{"a", "b", "a", "b", "a", "banned"}
+	want := []bool{true, true, true, false, false, false}
+	decisions := kl.AllowBatch(keys, 1)
+	for i, d := range decisions {
+		if d.Allowed != want[i] {
+			t.Errorf("decision %d (%s) allowed = %v, want %v", i, keys[i], d.Allowed, want[i])
+		}
+	}
+	if d := decisions[2]; d.Remaining != 0 || d.Limit != 2 {
+		t.Errorf("decision of the 2nd a = %+v, want 0 of 2 remaining", d)
+	}
+	if kl.Len() != 2 {
+		t.Errorf("Len() = %d, want 2 (no state for the banned key)", kl.Len())
+	}
+}
+
+func TestKeyedLimiter_AllowBatchManyKeys(t *testing.T) {
+	kl := NewKeyedLimiter[int](BuildRateLimiter(1, time.Hour))
+	keys := make([]int, 5000)
+	for i := range keys {
+		keys[i] = i % 2500
+	}
+	for i, d := range kl.AllowBatch(keys, 1) {
+		if want := i < 2500; d.Allowed != want {
+			t.Fatalf("decision %d allowed = %v, want %v", i, d.Allowed, want)
+		}
+	}
+	if kl.Len() != 2500 {
+		t.Fatalf("Len() = %d, want 2500", kl.Len())
+	}
+}
+
+func TestKeyedLimiter_AllowBatchWithReputation(t *testing.T) {
+	kl := NewKeyedLimiter[string](BuildRateLimiter(2, time.Hour),
+		WithReputation[string](ReputationFunc[string](func(key string) float64 {
+			if key == "bad" {
+				return 0
+			}
+			return 1
+		}), 0))
+	decisions := kl.AllowBatch([]string{"good", "bad"}, 1)
+	if !decisions[0].Allowed || decisions[1].Allowed {
+		t.Fatalf("decisions = %+v, want good allowed and bad denied", decisions)
+	}
+	if decisions[0].Remaining != 1 {
+		t.Fatalf("remaining of good = %d, want 1", decisions[0].Remaining)
+	}
+}
@@ -0,0 +1,130 @@
+package limitron
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// Versions of the binary encodings of limiter configurations and states.
+const (
+	configBinaryVersion = 2
+	stateBinaryVersion  = 1
+)
+
+// Flags of the binary encoding of a limiter configuration.
+const (
+	configFlagSpaced = 1 << iota
+	configFlagMicros
+	configFlagStartEmpty
+)
+
+// configBinarySize is the size of the binary encoding of a limiter configuration.
+// Version 1 stored the shedding threshold as a token count of 2 bytes instead of a share.
+const (
+	configBinarySize   = 1 + 2 + 8 + 4 + 8 + 2 + 8 + 1
+	configBinarySizeV1 = 1 + 2 + 8 + 4 + 8 + 2 + 2 + 1
+)
+
+// stateBinarySize is the size of the binary encoding of a limiter state.
+const stateBinarySize = 1 + 2 + 8
+
+// MarshalBinary encodes the configuration of the limiter: burst, refill rate, retries and
+// the options set by WithPunitive, WithDebt, WithMinSpacing, WithShedding, WithMicroseconds
+// and WithStartEmpty. The clock and metrics are not encoded.
+//
+// Example:
+//
+//	data, _ := limiter.MarshalBinary()
+//	var restored RateLimiter
+//	err := restored.UnmarshalBinary(data)
+func (s RateLimiter) MarshalBinary() ([]byte, error) {
+	var flags byte
+	if s.spaced {
+		flags |= configFlagSpaced
+	}
+	if s.micros {
+		flags |= configFlagMicros
+	}
+	if s.startEmpty {
+		flags |= configFlagStartEmpty
+	}
+	b := make([]byte, 0, configBinarySize)
+	b = append(b, configBinaryVersion)
+	b = binary.LittleEndian.AppendUint16(b, s.maxreq)
+	b = binary.LittleEndian.AppendUint64(b, math.Float64bits(s.rrpm))
+	b = binary.LittleEndian.AppendUint32(b, uint32(min(max(s.retries, 0), math.MaxInt32)))
+	b = binary.LittleEndian.AppendUint64(b, s.penalty)
+	b = binary.LittleEndian.AppendUint16(b, s.debt)
+	b = binary.LittleEndian.AppendUint64(b, math.Float64bits(s.shedding))
+	return append(b, flags), nil
+}
+
+// UnmarshalBinary decodes a configuration encoded by MarshalBinary into the limiter,
+// keeping its clock and metrics. Encodings of earlier versions are accepted.
+func (s *RateLimiter) UnmarshalBinary(data []byte) error {
+	v1 := len(data) == configBinarySizeV1 && data[0] == 1
+	if !v1 && (len(data) != configBinarySize || data[0] != configBinaryVersion) {
+		return fmt.Errorf("limitron: invalid RateLimiter encoding of %d bytes", len(data))
+	}
+	rrpm := math.Float64frombits(binary.LittleEndian.Uint64(data[3:]))
+	if !(rrpm > 0) || math.IsInf(rrpm, 0) {
+		return fmt.Errorf("limitron: invalid refill rate %v in RateLimiter encoding", rrpm)
+	}
+	flags := data[len(data)-1]
+	s.maxreq = binary.LittleEndian.Uint16(data[1:])
+	s.rrpm = rrpm
+	s.retries = int(binary.LittleEndian.Uint32(data[11:]))
+	s.penalty = binary.LittleEndian.Uint64(data[15:])
+	s.debt = binary.LittleEndian.Uint16(data[23:])
+	if v1 {
+		s.shedding = 0
+		if below := binary.LittleEndian.Uint16(data[25:]); below > 0 && s.maxreq > 0 {
+			s.shedding = min(float64(below)/float64(s.maxreq), 1)
+		}
+	} else {
+		s.shedding = math.Float64frombits(binary.LittleEndian.Uint64(data[25:]))
+	}
+	s.spaced = flags&configFlagSpaced != 0
+	s.micros = flags&configFlagMicros != 0
+	s.startEmpty = flags&configFlagStartEmpty != 0
+	return nil
+}
+
+// MarshalState encodes the limiter state `*rl`, with its refill clock as a wall-clock time,
+// so that the state restored by UnmarshalState after a process restart or a rolling deploy
+// refills for the time that passed meanwhile, instead of coming back with a fresh burst.
+//
+// Example:
+//
+//	data, _ := limiter.MarshalState(state) // before shutdown
+//	err := limiter.UnmarshalState(data, state) // after restart
+func (s RateLimiter) MarshalState(rl *uint64) ([]byte, error) {
+	tokens, ts := unpackUint16Uint48(atomic.LoadUint64(rl))
+	var at int64 // a state never updated has no time
+	if ts != 0 {
+		at = s.timeOf(ts).UnixMicro()
+	}
+	b := make([]byte, 0, stateBinarySize)
+	b = append(b, stateBinaryVersion)
+	b = binary.LittleEndian.AppendUint16(b, tokens)
+	return binary.LittleEndian.AppendUint64(b, uint64(at)), nil
+}
+
+// UnmarshalState decodes a state encoded by MarshalState into `*rl`. The state may have
+// been encoded by a limiter of another resolution (see WithMicroseconds); its tokens are
+// capped at the burst of this limiter.
+func (s RateLimiter) UnmarshalState(data []byte, rl *uint64) error {
+	if len(data) != stateBinarySize || data[0] != stateBinaryVersion {
+		return fmt.Errorf("limitron: invalid limiter state encoding of %d bytes", len(data))
+	}
+	tokens := min(binary.LittleEndian.Uint16(data[1:]), s.maxreq)
+	var ts uint64
+	if at := int64(binary.LittleEndian.Uint64(data[3:])); at != 0 {
+		ts = s.ticksOf(time.UnixMicro(at))
+	}
+	atomic.StoreUint64(rl, packUint16AndUint48(tokens, ts))
+	return nil
+}
@@ -0,0 +1,80 @@
+package limitron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_BinaryRoundTrip(t *testing.T) {
+	limiter := BuildRateLimiter(100, time.Minute, WithBurst(20), WithRetries(7),
+		WithPunitive(250*time.Millisecond), WithDebt(5), WithShedding(0.5), WithMicroseconds(), WithStartEmpty())
+	data, err := limiter.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := &testClock{}
+	restored := BuildRateLimiterRps(1, WithClock(clock))
+	if err := restored.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if restored.clock != clock {
+		t.Fatal("UnmarshalBinary replaced the clock")
+	}
+	restored.clock = nil
+	if restored != limiter {
+		t.Fatalf("restored %+v, want %+v", restored, limiter)
+	}
+
+	for _, bad := range [][]byte{nil, data[:len(data)-1], append([]byte{9}, data[1:]...)} {
+		if err := new(RateLimiter).UnmarshalBinary(bad); err == nil {
+			t.Errorf("UnmarshalBinary(%v): no error", bad)
+		}
+	}
+}
+
+func TestRateLimiter_UnmarshalBinaryV1(t *testing.T) {
+	// version 1 stored the shedding threshold as a token count
+	limiter := BuildRateLimiter(100, time.Minute, WithShedding(0.5))
+	data, _ := limiter.MarshalBinary()
+	v1 := append([]byte{1}, data[1:25]...)
+	v1 = append(v1, 50, 0, data[len(data)-1])
+
+	var restored RateLimiter
+	if err := restored.UnmarshalBinary(v1); err != nil {
+		t.Fatal(err)
+	}
+	if restored != limiter {
+		t.Fatalf("restored %+v, want %+v", restored, limiter)
+	}
+}
+
+func TestRateLimiter_StateSurvivesRestart(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	limiter := BuildRateLimiter(10, time.Second, WithClock(clock))
+	state := limiter.New()
+	limiter.TakeN(state, 10)
+	data, err := limiter.MarshalState(state)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// restarted 300ms later, at microsecond resolution
+	clock.t = clock.t.Add(300 * time.Millisecond)
+	restarted := BuildRateLimiter(10, time.Second, WithClock(clock), WithMicroseconds())
+	restored := restarted.New()
+	if err := restarted.UnmarshalState(data, restored); err != nil {
+		t.Fatal(err)
+	}
+	if got := restarted.Available(restored); got != 3 {
+		t.Fatalf("Available after restart = %d, want 3", got)
+	}
+
+	fresh := limiter.New()
+	data, _ = limiter.MarshalState(fresh)
+	if err := limiter.UnmarshalState(data, restored); err != nil || *restored != *fresh {
+		t.Fatalf("restored fresh state = %x, %v, want %x", *restored, err, *fresh)
+	}
+	if err := limiter.UnmarshalState(data[:5], restored); err == nil {
+		t.Fatal("UnmarshalState of a truncated encoding: no error")
+	}
+}
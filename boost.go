@@ -0,0 +1,54 @@
+package limitron
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Boost temporarily scales the limits (burst and rate) of `key` by `factor`,
+// e.g., to let a customer through a data migration, reverting automatically
+// after `d`. A new Boost of the same key replaces the previous one; a factor of 1
+// or a non-positive duration removes it.
+//
+// The factor is kept with a precision of 0.001 and clamped to [0.001, 65.535].
+// Boosts combine with the other per-key policies: grace periods, reputation
+// and penalties scale the boosted limits.
+//
+// Example:
+//
+//	kl.Boost("customer-42", 3, 48*time.Hour) // triple limits during the migration
+func (kl *KeyedLimiter[K]) Boost(key K, factor float64, d time.Duration) {
+	e := kl.entry(key)
+	if factor == 1 || d <= 0 {
+		atomic.StoreUint64(&e.boost, 0)
+		return
+	}
+	expires := kl.limiter.nowMillis() + uint64(d.Milliseconds())
+	atomic.StoreUint64(&e.boost, packUint16AndUint48(max(scoreToMillis(factor), 1), expires))
+}
+
+// Boosted returns the boost factor of `key` and when it expires.
+// Returns false if the key has no active boost.
+func (kl *KeyedLimiter[K]) Boosted(key K) (factor float64, expires time.Time, ok bool) {
+	sh := kl.shard(key)
+	sh.mu.RLock()
+	e, found := sh.entries[key]
+	sh.mu.RUnlock()
+	if !found {
+		return 0, time.Time{}, false
+	}
+	millis, until := unpackUint16Uint48(atomic.LoadUint64(&e.boost))
+	if millis == 0 || kl.limiter.nowMillis() >= until {
+		return 0, time.Time{}, false
+	}
+	return float64(millis) / 1000, time.UnixMilli(int64(until)), true
+}
+
+// boosted scales `limiter` by the active boost of entry `e`, if any.
+func (kl *KeyedLimiter[K]) boosted(e *keyedEntry, limiter RateLimiter) RateLimiter {
+	millis, until := unpackUint16Uint48(atomic.LoadUint64(&e.boost))
+	if millis == 0 || limiter.maxreq == 0 || kl.limiter.nowMillis() >= until {
+		return limiter
+	}
+	return limiter.scaled(float64(millis) / 1000)
+}
@@ -0,0 +1,58 @@
+package limitron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyedLimiter_Boost(t *testing.T) {
+	kl := NewKeyedLimiter[string](BuildRateLimiter(2, time.Minute))
+	kl.Boost("vip", 3, time.Minute)
+	if _, ok := kl.Peek("vip", 3); !ok {
+		t.Fatalf("Peek ignores the boost")
+	}
+
+	for i := 0; i < 6; i++ {
+		if _, ok := kl.Take1("vip"); !ok {
+			t.Fatalf("boosted take %d denied", i)
+		}
+	}
+	if _, ok := kl.Take1("vip"); ok {
+		t.Fatalf("take beyond the boosted burst allowed")
+	}
+	if _, ok := kl.TakeN("other", 3); ok {
+		t.Fatalf("unboosted key allowed 3 tokens")
+	}
+
+	factor, expires, ok := kl.Boosted("vip")
+	if !ok || factor != 3 || time.Until(expires) < 59*time.Second {
+		t.Fatalf("Boosted = %v, %v, %v; want 3 for a minute", factor, expires, ok)
+	}
+	if d := kl.Take1Result("other"); d.Limit != 2 {
+		t.Errorf("unboosted limit = %d, want 2", d.Limit)
+	}
+
+	kl.Boost("vip", 1, time.Minute)
+	if _, _, ok := kl.Boosted("vip"); ok {
+		t.Errorf("boost not removed by factor 1")
+	}
+}
+
+func TestKeyedLimiter_BoostExpires(t *testing.T) {
+	kl := NewKeyedLimiter[string](BuildRateLimiter(2, time.Minute))
+	kl.Boost("k", 3, 30*time.Millisecond)
+	if d := kl.TakeNResult("k", 3); !d.Allowed || d.Limit != 6 {
+		t.Fatalf("boosted decision = %+v, want allowed with limit 6", d)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if _, _, ok := kl.Boosted("k"); ok {
+		t.Fatalf("boost still active after expiry")
+	}
+	if _, ok := kl.TakeN("k", 3); ok {
+		t.Fatalf("3 tokens allowed after the boost expired")
+	}
+	if d := kl.Take1Result("k"); !d.Allowed || d.Limit != 2 || d.Remaining != 1 {
+		t.Fatalf("decision after expiry = %+v, want allowed with 1 of 2 remaining", d)
+	}
+}
@@ -0,0 +1,53 @@
+package limitron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFreezeClock_StopsAndResumes(t *testing.T) {
+	c := NewFreezeClock()
+	if c.Frozen() {
+		t.Fatal("new clock should be running")
+	}
+
+	c.Freeze()
+	frozen := c.Now()
+	time.Sleep(30 * time.Millisecond)
+	if !c.Now().Equal(frozen) {
+		t.Fatal("frozen clock should not advance")
+	}
+
+	c.Thaw()
+	if c.Frozen() {
+		t.Fatal("thawed clock should be running")
+	}
+	if d := c.Now().Sub(frozen); d < 0 || d > 10*time.Millisecond {
+		t.Fatalf("thawed clock resumed %s after the freeze point, want about 0", d)
+	}
+	if behind := time.Since(c.Now()); behind < 30*time.Millisecond {
+		t.Fatalf("clock is %s behind system time, want at least the frozen period", behind)
+	}
+}
+
+func TestFreezeClock_NoRefillWhileFrozen(t *testing.T) {
+	c := NewFreezeClock()
+	s := BuildRateLimiterRps(20, WithClock(c)) // 1 token per 50ms
+	rl := s.New()
+	s.TakeN(rl, 20)
+
+	c.Freeze()
+	time.Sleep(120 * time.Millisecond)
+	if _, ok := s.Take1(rl); ok {
+		t.Fatal("state should not refill while the clock is frozen")
+	}
+
+	c.Thaw()
+	if _, ok := s.Take1(rl); ok {
+		t.Fatal("frozen period should not count as refill time after thaw")
+	}
+	time.Sleep(70 * time.Millisecond)
+	if _, ok := s.Take1(rl); !ok {
+		t.Fatal("state should refill after thaw")
+	}
+}
@@ -0,0 +1,49 @@
+// Command limitron-openapi generates a httplimit route-limit table from the
+// x-ratelimit extensions of a JSON OpenAPI document, keeping documented and
+// enforced limits in sync.
+//
+// Usage:
+//
+//	//go:generate go run github.com/iryndin/limitron/cmd/limitron-openapi -spec openapi.json -pkg api -o routes_gen.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/iryndin/limitron/httplimit"
+)
+
+func main() {
+	spec := flag.String("spec", "openapi.json", "path of the JSON OpenAPI document")
+	pkg := flag.String("pkg", "main", "package name of the generated file")
+	varName := flag.String("var", "RateLimitRoutes", "variable name of the generated route table")
+	out := flag.String("o", "", "output file (default: stdout)")
+	flag.Parse()
+
+	if err := run(*spec, *pkg, *varName, *out); err != nil {
+		fmt.Fprintln(os.Stderr, "limitron-openapi:", err)
+		os.Exit(1)
+	}
+}
+
+func run(spec, pkg, varName, out string) error {
+	doc, err := os.ReadFile(spec)
+	if err != nil {
+		return err
+	}
+	routes, err := httplimit.RoutesFromOpenAPI(doc)
+	if err != nil {
+		return err
+	}
+	src, err := httplimit.GenerateRoutesGo(routes, pkg, varName)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return os.WriteFile(out, src, 0o644)
+}
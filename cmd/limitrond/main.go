@@ -0,0 +1,89 @@
+// Command limitrond runs the limitrond daemon, sharing limitron-enforced limits
+// with non-Go services and scripts over HTTP. See package limitrond for the API.
+//
+// Usage:
+//
+//	limitrond -config limitrond.json
+//
+// The gRPC API is served on grpc_listen if configured, over TLS.
+//
+// Sending SIGHUP reloads the limits and schedules from the configuration file; limits
+// whose configuration did not change keep their state. Listen addresses are not reloaded.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/iryndin/limitron/limitrond"
+)
+
+func main() {
+	configPath := flag.String("config", "limitrond.json", "path of the JSON configuration file")
+	flag.Parse()
+
+	if err := run(*configPath); err != nil {
+		fmt.Fprintln(os.Stderr, "limitrond:", err)
+		os.Exit(1)
+	}
+}
+
+func run(configPath string) error {
+	cfg, err := limitrond.LoadConfig(configPath)
+	if err != nil {
+		return err
+	}
+	srv, err := limitrond.NewServer(cfg)
+	if err != nil {
+		return err
+	}
+
+	go srv.RunSchedules(context.Background())
+
+	errc := make(chan error, 3)
+	go func() {
+		log.Printf("limitrond: serving %d limits on %s", len(cfg.Limits), cfg.Listen)
+		errc <- http.ListenAndServe(cfg.Listen, srv.Handler())
+	}()
+	if cfg.GRPCListen != "" {
+		go func() {
+			log.Printf("limitrond: gRPC API on %s", cfg.GRPCListen)
+			errc <- http.ListenAndServeTLS(cfg.GRPCListen, cfg.TLSCert, cfg.TLSKey, srv.GRPCHandler())
+		}()
+	}
+	if cfg.AdminListen != "-" {
+		go func() {
+			log.Printf("limitrond: admin API on %s", cfg.AdminListen)
+			errc <- http.ListenAndServe(cfg.AdminListen, srv.AdminHandler())
+		}()
+	}
+
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for {
+		select {
+		case err := <-errc:
+			if errors.Is(err, http.ErrServerClosed) {
+				return nil
+			}
+			return err
+		case <-hup:
+			next, err := limitrond.LoadConfig(configPath)
+			if err == nil {
+				err = srv.Reload(next)
+			}
+			if err != nil {
+				log.Printf("limitrond: reload failed, keeping the current limits: %v", err)
+				continue
+			}
+			log.Printf("limitrond: reloaded %d limits", len(next.Limits))
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package limitron
+
+import "sync/atomic"
+
+// CompositeLimiter enforces several limits on the same requests at once, such as
+// "10 per second AND 100 per minute".
+//
+// Each limit has its own RateLimiter; the per-identity state is a small slice holding
+// one packed uint64 state per limit, created with New(). TakeN consumes from all
+// limits or from none: if any limit denies, the limits already consumed are rolled
+// back, so a denial never leaks tokens. See VectorLimiter for limits with
+// different costs per request.
+type CompositeLimiter struct {
+	limits []RateLimiter
+}
+
+// NewCompositeLimiter returns a CompositeLimiter enforcing all of the limiters.
+// All limiters must use the same clock.
+//
+// Example:
+//
+//	c := NewCompositeLimiter(
+//	    BuildRateLimiterRps(10),              // 10 per second
+//	    BuildRateLimiter(100, time.Minute),   // and 100 per minute
+//	)
+//	st := c.New()
+//	if wait, ok := c.Take1(st); !ok {
+//	    // retry after `wait` millis
+//	}
+func NewCompositeLimiter(limits ...RateLimiter) CompositeLimiter {
+	return CompositeLimiter{limits: append([]RateLimiter(nil), limits...)}
+}
+
+// New creates brand-new, zero-use states, one per limit.
+func (c CompositeLimiter) New() []uint64 {
+	states := make([]uint64, len(c.limits))
+	for i, l := range c.limits {
+		states[i] = *l.New()
+	}
+	return states
+}
+
+// Take1 attempts to consume 1 request from every limit. See TakeN.
+func (c CompositeLimiter) Take1(states []uint64) (int64, bool) {
+	return c.TakeN(states, 1)
+}
+
+// TakeN attempts to consume `requests` tokens from every limit, atomically with respect
+// to denials: either all limits are consumed, or none is. All limits are evaluated
+// at the same instant.
+//
+// Returns (0, true) on success. Otherwise it returns false and the wait in millis
+// until all limits would allow the requests, i.e., the longest wait of any limit,
+// so that a retry after the wait is not denied by another limit.
+// Panics if the number of states differs from the number of limits.
+func (c CompositeLimiter) TakeN(states []uint64, requests uint16) (int64, bool) {
+	if len(states) != len(c.limits) {
+		panic("limitron: CompositeLimiter.TakeN: states must match the number of limits")
+	}
+	return takeAll(requests, len(c.limits), func(i int) (RateLimiter, *uint64) {
+		return c.limits[i], &states[i]
+	})
+}
+
+// LimitState is a limiter state along with the limiter that owns it. See TakeAll.
+type LimitState struct {
+	Limiter RateLimiter
+	State   *uint64
+}
+
+// TakeAll attempts to consume `requests` tokens from every state, each with its own limiter,
+// atomically with respect to denials: if any state denies, the states already consumed are
+// rolled back. All states are evaluated at the same instant, by the clock of the first limiter.
+// Returns like CompositeLimiter.TakeN.
+//
+// Example:
+//
+//	wait, ok := TakeAll(1,
+//	    LimitState{perUser, userState},
+//	    LimitState{perEndpoint, endpointState},
+//	    LimitState{global, globalState},
+//	)
+func TakeAll(requests uint16, states ...LimitState) (int64, bool) {
+	return takeAll(requests, len(states), func(i int) (RateLimiter, *uint64) {
+		return states[i].Limiter, states[i].State
+	})
+}
+
+// TakeAll attempts to consume `requests` tokens from every one of `states`, rolling back
+// the states already consumed if any denies. See the TakeAll function.
+func (s RateLimiter) TakeAll(requests uint16, states ...*uint64) (int64, bool) {
+	return takeAll(requests, len(states), func(i int) (RateLimiter, *uint64) {
+		return s, states[i]
+	})
+}
+
+// takeAll implements TakeAll for `n` states, where `at` returns the i-th state and its limiter.
+// The result is reported to the metrics of every limiter.
+func takeAll(requests uint16, n int, at func(i int) (RateLimiter, *uint64)) (int64, bool) {
+	if n == 0 {
+		return 0, true
+	}
+
+	first, _ := at(0)
+	now := first.nowTicks()
+	waitMillis, ok := int64(0), true
+	for i := 0; i < n && ok; i++ {
+		l, rl := at(i)
+		if waitMillis, ok = l.takeNAt(rl, requests, now); ok {
+			continue
+		}
+		for j := 0; j < i; j++ {
+			lj, rlj := at(j)
+			lj.ReturnN(rlj, requests)
+		}
+		// the states after the denying one were not evaluated
+		for j := i + 1; j < n; j++ {
+			lj, rlj := at(j)
+			waitMillis = max(waitMillis, lj.waitFor(atomic.LoadUint64(rlj), requests, now))
+		}
+	}
+	for i := 0; i < n; i++ {
+		l, _ := at(i)
+		l.observe(waitMillis, ok)
+	}
+	return waitMillis, ok
+}
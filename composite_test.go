@@ -0,0 +1,93 @@
+package limitron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompositeLimiter_AllOrNone(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	perSecond := BuildRateLimiter(10, time.Second, WithClock(clock))
+	perMinute := BuildRateLimiter(15, time.Minute, WithClock(clock))
+	c := NewCompositeLimiter(perSecond, perMinute)
+	st := c.New()
+
+	if _, ok := c.TakeN(st, 10); !ok {
+		t.Fatal("10 requests denied")
+	}
+	// denied by the per-second limit: the per-minute one must not be charged
+	if _, ok := c.Take1(st); ok {
+		t.Fatal("11th request allowed in the same second")
+	}
+	clock.t = clock.t.Add(time.Second)
+	if _, ok := c.TakeN(st, 5); !ok {
+		t.Fatal("5 requests denied a second later, with 5 left per minute")
+	}
+
+	// denied by the per-minute limit: the per-second one is rolled back
+	clock.t = clock.t.Add(time.Second)
+	if _, ok := c.Take1(st); ok {
+		t.Fatal("16th request allowed within the minute")
+	}
+	if got, _ := perSecond.calcNewRequestsAt(st[0], uint64(clock.t.UnixMilli())); got != 10 {
+		t.Fatalf("per-second tokens after a rolled-back denial = %d, want 10", got)
+	}
+}
+
+func TestCompositeLimiter_WaitSatisfiesAllLimits(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	c := NewCompositeLimiter(
+		BuildRateLimiter(2, time.Second, WithClock(clock)),
+		BuildRateLimiter(2, 10*time.Second, WithClock(clock)),
+	)
+	st := c.New()
+	c.TakeN(st, 2)
+
+	// the per-second limit denies first, but the 10-second one needs longer
+	wait, ok := c.Take1(st)
+	if ok || wait < 5000 {
+		t.Fatalf("Take1 = %d, %v, want a wait of about 5000ms", wait, ok)
+	}
+	clock.t = clock.t.Add(time.Duration(wait) * time.Millisecond)
+	if _, ok := c.Take1(st); !ok {
+		t.Fatal("request denied after the suggested wait")
+	}
+}
+
+func TestTakeAll_RollsBackOnDenial(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	perUser := BuildRateLimiter(5, time.Second, WithClock(clock))
+	global := BuildRateLimiter(3, time.Second, WithClock(clock))
+	user, all := perUser.New(), global.New()
+
+	for i := 0; i < 3; i++ {
+		if _, ok := TakeAll(1, LimitState{perUser, user}, LimitState{global, all}); !ok {
+			t.Fatalf("take %d denied", i)
+		}
+	}
+	waitMillis, ok := TakeAll(1, LimitState{perUser, user}, LimitState{global, all})
+	if ok || waitMillis < 300 {
+		t.Fatalf("TakeAll over the global limit = %d, %v, want about 333ms, false", waitMillis, ok)
+	}
+	// the per-user token was rolled back: 2 are left
+	if _, ok := perUser.TakeN(user, 2); !ok {
+		t.Fatal("per-user tokens leaked by the denied take")
+	}
+}
+
+func TestRateLimiter_TakeAll(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	limiter := BuildRateLimiter(10, time.Second, WithClock(clock))
+	a, b := limiter.New(), limiter.New()
+	limiter.TakeN(b, 8)
+
+	if _, ok := limiter.TakeAll(3, a, b); ok {
+		t.Fatal("TakeAll allowed with 2 tokens left in b")
+	}
+	if _, ok := limiter.TakeN(a, 10); !ok {
+		t.Fatal("tokens of a leaked by the denied take")
+	}
+	if _, ok := limiter.TakeAll(0); !ok {
+		t.Fatal("TakeAll without states denied")
+	}
+}
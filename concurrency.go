@@ -0,0 +1,70 @@
+package limitron
+
+import "sync/atomic"
+
+// ConcurrencyLimiter caps the number of operations in flight per key, such as
+// the simultaneous requests of a tenant, next to a limit of their rate.
+//
+// Like RateLimiter, it keeps the entire per-key state, the in-flight count, in a single
+// uint64 updated lock-free, so that per-key concurrency limits need no semaphore
+// or other allocation per key.
+//
+// The zero value allows nothing; create instances with BuildConcurrencyLimiter.
+type ConcurrencyLimiter struct {
+	limit uint64
+}
+
+// BuildConcurrencyLimiter returns a ConcurrencyLimiter allowing up to `limit` operations
+// in flight per state.
+//
+// Example:
+//
+//	inflight := BuildConcurrencyLimiter(10)
+//	state := inflight.New()
+//	if !inflight.Acquire(state) {
+//	    return errTooManyRequests
+//	}
+//	defer inflight.Release(state)
+func BuildConcurrencyLimiter(limit uint32) ConcurrencyLimiter {
+	return ConcurrencyLimiter{limit: uint64(limit)}
+}
+
+// New creates a brand-new limiter state with no operations in flight.
+func (c ConcurrencyLimiter) New() *uint64 {
+	var rl uint64
+	return &rl
+}
+
+// Acquire attempts to start an operation: it returns true and atomically increments
+// the in-flight count of `*rl` if it is below the limit, or false otherwise.
+// Every successful Acquire must be paired with a Release.
+func (c ConcurrencyLimiter) Acquire(rl *uint64) bool {
+	for {
+		n := atomic.LoadUint64(rl)
+		if n >= c.limit {
+			return false
+		}
+		if atomic.CompareAndSwapUint64(rl, n, n+1) {
+			return true
+		}
+	}
+}
+
+// Release ends an operation started by a successful Acquire.
+// It panics if no operation is in flight.
+func (c ConcurrencyLimiter) Release(rl *uint64) {
+	for {
+		n := atomic.LoadUint64(rl)
+		if n == 0 {
+			panic("limitron: ConcurrencyLimiter.Release without Acquire")
+		}
+		if atomic.CompareAndSwapUint64(rl, n, n-1) {
+			return
+		}
+	}
+}
+
+// InFlight returns the number of operations in flight in `*rl`.
+func (c ConcurrencyLimiter) InFlight(rl *uint64) uint64 {
+	return atomic.LoadUint64(rl)
+}
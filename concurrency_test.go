@@ -0,0 +1,72 @@
+package limitron
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestConcurrencyLimiter_AcquireRelease(t *testing.T) {
+	c := BuildConcurrencyLimiter(2)
+	state := c.New()
+
+	if !c.Acquire(state) || !c.Acquire(state) {
+		t.Fatal("Acquire within the limit failed")
+	}
+	if c.Acquire(state) {
+		t.Fatal("third Acquire succeeded at a limit of 2")
+	}
+	c.Release(state)
+	if c.InFlight(state) != 1 {
+		t.Fatalf("InFlight() = %d, want 1", c.InFlight(state))
+	}
+	if !c.Acquire(state) {
+		t.Fatal("Acquire after Release failed")
+	}
+}
+
+func TestConcurrencyLimiter_ReleaseWithoutAcquirePanics(t *testing.T) {
+	c := BuildConcurrencyLimiter(1)
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Release without Acquire did not panic")
+		}
+	}()
+	c.Release(c.New())
+}
+
+func TestConcurrencyLimiter_Concurrent(t *testing.T) {
+	c := BuildConcurrencyLimiter(4)
+	state := c.New()
+
+	var current, peak atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				if !c.Acquire(state) {
+					continue
+				}
+				n := current.Add(1)
+				for {
+					p := peak.Load()
+					if n <= p || peak.CompareAndSwap(p, n) {
+						break
+					}
+				}
+				current.Add(-1)
+				c.Release(state)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if p := peak.Load(); p > 4 {
+		t.Fatalf("peak in flight = %d, want at most 4", p)
+	}
+	if c.InFlight(state) != 0 {
+		t.Fatalf("InFlight() = %d after all releases", c.InFlight(state))
+	}
+}
@@ -0,0 +1,269 @@
+package limitron
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ConsistencyMode selects how a StoreLimiter trades accuracy for latency.
+type ConsistencyMode uint8
+
+const (
+	// Strict checks and updates the store synchronously on every call.
+	// Limits are exact across instances, at the cost of a store round trip per call.
+	Strict ConsistencyMode = iota
+	// Eventual decides every call against a local copy of the key's state and
+	// reconciles it with the store asynchronously: consumed tokens are written
+	// to the store in the background, and the local copy then adopts the store's
+	// state, including tokens consumed by other instances. Calls never wait for
+	// the store, but instances may together admit more than the limit until
+	// they reconcile.
+	Eventual
+)
+
+// String returns the name of the mode.
+func (m ConsistencyMode) String() string {
+	switch m {
+	case Strict:
+		return "strict"
+	case Eventual:
+		return "eventual"
+	default:
+		return "unknown"
+	}
+}
+
+// WithConsistency sets the consistency mode of a StoreLimiter. The default is Strict.
+//
+// Example:
+//
+//	// exact limits for payments, low latency for search
+//	payments := NewStoreLimiter(store, BuildRateLimiter(10, time.Minute))
+//	search := NewStoreLimiter(store, BuildRateLimiterRps(50), WithConsistency(Eventual))
+func WithConsistency(mode ConsistencyMode) StoreOption {
+	return func(sl *StoreLimiter) {
+		if mode != Eventual {
+			sl.eventual = nil
+		} else if sl.eventual == nil {
+			sl.eventual = &eventualStates{sl: sl}
+		}
+	}
+}
+
+// WithWriteBehind makes a StoreLimiter use Eventual consistency with batched writes:
+// instead of writing each key's consumption to the store right away, consumption is
+// accumulated per key and written every `interval`, or as soon as `maxKeys` keys have
+// consumption pending. A hot key then costs one store update per interval regardless
+// of its request rate, cutting store load by orders of magnitude.
+//
+// Call StoreLimiter.Close to write the remaining consumption when done.
+// A non-positive `maxKeys` means no size threshold.
+func WithWriteBehind(interval time.Duration, maxKeys int) StoreOption {
+	return func(sl *StoreLimiter) {
+		if sl.eventual == nil {
+			sl.eventual = &eventualStates{sl: sl}
+		}
+		es := sl.eventual
+		es.interval = interval
+		es.maxKeys = maxKeys
+		es.dirty = make(map[string]*eventualEntry)
+		es.kick = make(chan struct{}, 1)
+		es.done = make(chan struct{})
+	}
+}
+
+// eventualStates holds the local copies of key states of an Eventual StoreLimiter.
+type eventualStates struct {
+	sl      *StoreLimiter
+	entries sync.Map // string -> *eventualEntry
+
+	// write-behind batching (see WithWriteBehind); interval is 0 when disabled
+	interval time.Duration
+	maxKeys  int
+	mu       sync.Mutex
+	dirty    map[string]*eventualEntry
+	started  bool
+	closed   bool
+	kick     chan struct{}
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// eventualEntry is the local copy of a key state with the consumption not yet
+// written to the store.
+type eventualEntry struct {
+	state   uint64
+	pending atomic.Uint32
+	// syncing is set while a reconciliation of the entry is scheduled or running.
+	syncing atomic.Bool
+}
+
+// take decides a call against the local state of `key`, scheduling a reconciliation
+// of the consumed tokens.
+func (es *eventualStates) take(key string, requests uint16) (int64, bool) {
+	sl := es.sl
+	v, ok := es.entries.Load(key)
+	if !ok {
+		v, _ = es.entries.LoadOrStore(key, &eventualEntry{state: sl.limiter.initialState()})
+	}
+	e := v.(*eventualEntry)
+
+	waitMillis, allowed := sl.limiter.TakeN(&e.state, requests)
+	if allowed {
+		e.pending.Add(uint32(requests))
+		if e.syncing.CompareAndSwap(false, true) {
+			es.schedule(key, e)
+		}
+	}
+	return waitMillis, allowed
+}
+
+// schedule arranges for the reconciliation of entry `e`, whose syncing flag the caller set:
+// right away on its own goroutine, or with the next write-behind batch.
+func (es *eventualStates) schedule(key string, e *eventualEntry) {
+	if es.interval <= 0 {
+		go es.reconcile(key, e)
+		return
+	}
+
+	es.mu.Lock()
+	if es.closed {
+		es.mu.Unlock()
+		go es.reconcile(key, e)
+		return
+	}
+	es.dirty[key] = e
+	full := es.maxKeys > 0 && len(es.dirty) >= es.maxKeys
+	if !es.started {
+		es.started = true
+		es.wg.Add(1)
+		go es.writeBehind()
+	}
+	es.mu.Unlock()
+
+	if full {
+		select {
+		case es.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// writeBehind writes the batch of dirty entries every interval, or when kicked,
+// until the limiter is closed.
+func (es *eventualStates) writeBehind() {
+	defer es.wg.Done()
+	ticker := time.NewTicker(es.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-es.done:
+			es.flushDirty()
+			return
+		case <-ticker.C:
+		case <-es.kick:
+		}
+		es.flushDirty()
+	}
+}
+
+// flushDirty reconciles all dirty entries.
+func (es *eventualStates) flushDirty() {
+	es.mu.Lock()
+	batch := es.dirty
+	es.dirty = make(map[string]*eventualEntry, len(batch))
+	es.mu.Unlock()
+
+	for key, e := range batch {
+		es.reconcile(key, e)
+	}
+}
+
+// close stops write-behind batching after writing the pending consumption.
+func (es *eventualStates) close() {
+	es.mu.Lock()
+	if es.closed || es.interval <= 0 {
+		es.mu.Unlock()
+		return
+	}
+	es.closed = true
+	started := es.started
+	es.mu.Unlock()
+
+	if started {
+		close(es.done)
+		es.wg.Wait()
+	}
+}
+
+// reconcile writes the pending consumption of entry `e` to the store and adopts
+// the store's state locally. Consumption recorded meanwhile is scheduled again.
+func (es *eventualStates) reconcile(key string, e *eventualEntry) {
+	failed := false
+	if delta := e.pending.Swap(0); delta > 0 {
+		if err := es.flush(key, e, delta); err != nil {
+			// keep the consumption for the next attempt
+			e.pending.Add(delta)
+			failed = true
+		}
+	}
+	e.syncing.Store(false)
+
+	// Consumption recorded after the swap must not be left unscheduled. After a failure,
+	// only batches retry on their own; otherwise the next admission retries.
+	if (!failed || es.batching()) && e.pending.Load() > 0 && e.syncing.CompareAndSwap(false, true) {
+		es.schedule(key, e)
+	}
+}
+
+// batching reports whether write-behind batching is active.
+func (es *eventualStates) batching() bool {
+	if es.interval <= 0 {
+		return false
+	}
+	es.mu.Lock()
+	defer es.mu.Unlock()
+	return !es.closed
+}
+
+// flush drains `delta` tokens from the store state of `key` and copies the result,
+// less any consumption recorded meanwhile, into the local state.
+func (es *eventualStates) flush(key string, e *eventualEntry, delta uint32) error {
+	ctx := context.Background()
+	sl := es.sl
+	s := sl.limiter
+	for {
+		old, err := sl.store.Get(ctx, key)
+		if err != nil {
+			return err
+		}
+		rlval := old
+		if rlval == 0 {
+			rlval = s.initialState()
+		}
+		req, ts := s.calcNewRequests(rlval)
+		req = uint16(max(int64(req)-int64(delta), 0))
+		next := packUint16AndUint48(req, ts)
+
+		swapped, err := sl.store.CompareAndSet(ctx, key, old, next)
+		if err != nil {
+			return err
+		}
+		if !swapped {
+			continue
+		}
+		if err := sl.expire(ctx, key, next, s.nowTicks()); err != nil {
+			return err
+		}
+
+		// adopt the store's view, which includes other instances' consumption
+		local := next
+		if p := e.pending.Load(); p > 0 {
+			local = packUint16AndUint48(uint16(max(int64(req)-int64(p), 0)), ts)
+		}
+		atomic.StoreUint64(&e.state, local)
+		return nil
+	}
+}
@@ -0,0 +1,104 @@
+package limitron
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStoreLimiter_EventualReconciles(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	limiter := BuildRateLimiter(10, time.Hour)
+	eventual := NewStoreLimiter(store, limiter, WithConsistency(Eventual))
+	strict := NewStoreLimiter(store, limiter)
+
+	for i := 0; i < 5; i++ {
+		if _, ok, err := eventual.Take1(ctx, "k"); err != nil || !ok {
+			t.Fatalf("eventual Take1 %d => ok=%v err=%v", i, ok, err)
+		}
+	}
+	time.Sleep(20 * time.Millisecond) // let the reconciliation land
+
+	// the strict limiter sees the eventual consumption in the store
+	if _, ok, _ := strict.TakeN(ctx, "k", 5); !ok {
+		t.Fatal("strict TakeN of the remaining 5 should succeed")
+	}
+	if _, ok, _ := strict.Take1(ctx, "k"); ok {
+		t.Fatal("store should be exhausted")
+	}
+
+	// the eventual limiter still decides on its stale copy, then adopts the store's state
+	if _, ok, _ := eventual.Take1(ctx, "k"); !ok {
+		t.Fatal("eventual Take1 should be admitted from the local copy")
+	}
+	time.Sleep(20 * time.Millisecond)
+	if _, ok, _ := eventual.Take1(ctx, "k"); ok {
+		t.Fatal("after reconciling, the eventual limiter should see the exhausted store")
+	}
+}
+
+func TestConsistencyMode_String(t *testing.T) {
+	if Strict.String() != "strict" || Eventual.String() != "eventual" {
+		t.Fatalf("String = %q, %q", Strict, Eventual)
+	}
+}
+
+// countingStore counts the CompareAndSet calls of a Store.
+type countingStore struct {
+	Store
+	cas atomic.Int64
+}
+
+func (c *countingStore) CompareAndSet(ctx context.Context, key string, old, new uint64) (bool, error) {
+	c.cas.Add(1)
+	return c.Store.CompareAndSet(ctx, key, old, new)
+}
+
+func TestStoreLimiter_WriteBehindBatches(t *testing.T) {
+	ctx := context.Background()
+	store := &countingStore{Store: NewMemoryStore()}
+	limiter := BuildRateLimiter(1000, time.Hour)
+	sl := NewStoreLimiter(store, limiter, WithWriteBehind(30*time.Millisecond, 0))
+
+	for i := 0; i < 200; i++ {
+		if _, ok, _ := sl.Take1(ctx, "hot"); !ok {
+			t.Fatalf("Take1 %d should be admitted", i)
+		}
+	}
+	if got := store.cas.Load(); got != 0 {
+		t.Fatalf("%d store writes before the interval, want none", got)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := store.cas.Load(); got < 1 || got > 2 {
+		t.Fatalf("%d store writes for 200 admissions, want a single batched one", got)
+	}
+
+	sl.Take1(ctx, "hot")
+	if err := sl.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	strict := NewStoreLimiter(store, limiter)
+	if _, ok, _ := strict.TakeN(ctx, "hot", 799); !ok {
+		t.Fatal("store should hold 799 tokens after Close wrote the remaining consumption")
+	}
+	if _, ok, _ := strict.Take1(ctx, "hot"); ok {
+		t.Fatal("store should be exhausted")
+	}
+}
+
+func TestStoreLimiter_WriteBehindSizeThreshold(t *testing.T) {
+	ctx := context.Background()
+	store := &countingStore{Store: NewMemoryStore()}
+	sl := NewStoreLimiter(store, BuildRateLimiter(10, time.Hour), WithWriteBehind(time.Hour, 3))
+	defer sl.Close()
+
+	for _, key := range []string{"a", "b", "c"} {
+		sl.Take1(ctx, key)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if got := store.cas.Load(); got != 3 {
+		t.Fatalf("%d store writes, want the 3 keys flushed on reaching the threshold", got)
+	}
+}
@@ -0,0 +1,68 @@
+package limitron
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// TakeCost attempts to atomically consume a fractional or weighted `cost` from the
+// limiter state `*rl`, e.g., 0.25 for a cached response and 3.5 for a heavy query.
+//
+// The whole tokens of the cost are taken from the state's token count; the fraction
+// is taken from the refill accrued towards the next token, kept in the state's refill
+// clock, so that fractional costs add up exactly (to the nearest millisecond of refill) instead of
+// being rounded to whole tokens.
+//
+// It has the same contract as TakeN: it returns 0, true if the cost was consumed,
+// or the number of millis to wait before it would be, and false.
+//
+// Edge cases:
+//   - If `cost <= 0` or NaN: always returns (0, true) - noop
+//   - If `cost > maxreq`: returns (math.MaxInt64, false) immediately
+//
+// Example:
+//
+//	limiter := BuildRateLimiterRps(100)
+//	state := limiter.New()
+//	limiter.TakeCost(state, 0.25) // a cached response
+//	limiter.TakeCost(state, 3.5)  // a heavy query
+func (s RateLimiter) TakeCost(rl *uint64, cost float64) (int64, bool) {
+	return s.observe(s.takeCostAt(rl, cost, s.nowTicks()))
+}
+
+// costEpsilon is the tolerance of TakeCost for float rounding, in tokens.
+const costEpsilon = 1e-9
+
+// takeCostAt is TakeCost without metrics, evaluated at time `now` in state clock ticks.
+func (s RateLimiter) takeCostAt(rl *uint64, cost float64, now uint64) (int64, bool) {
+	if !(cost > 0) {
+		return 0, true
+	} else if cost > float64(s.maxreq) {
+		return math.MaxInt64, false
+	}
+
+	for i := 0; i < s.retries; i++ {
+		rlval := atomic.LoadUint64(rl)
+		req, lastTs := unpackUint16Uint48(rlval)
+
+		// the tokens available, including the fraction refilled towards the next one
+		available, base := float64(min(req, s.maxreq)), lastTs
+		if now >= lastTs {
+			available = min(available+s.rrpt()*float64(now-lastTs), float64(s.maxreq))
+			base = now
+		}
+		// tolerate float rounding of the refill, so that fractions add up to whole tokens
+		if available+costEpsilon < cost {
+			return s.tickMillis(1 + int64((cost-available)/s.rrpt())), false
+		}
+
+		// keep the fraction left as refill accrued before `base`, to the nearest tick
+		left := max(available-cost, 0)
+		whole := math.Floor(left)
+		credit := min(uint64(math.Round((left-whole)/s.rrpt())), base)
+		if atomic.CompareAndSwapUint64(rl, rlval, packUint16AndUint48(uint16(whole), base-credit)) {
+			return 0, true
+		}
+	}
+	return 1, false
+}
@@ -0,0 +1,59 @@
+package limitron
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_TakeCostFractions(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	s := BuildRateLimiter(2, time.Hour, WithClock(clock))
+	rl := s.New()
+
+	// 8 quarters consume the 2 tokens exactly
+	for i := 0; i < 8; i++ {
+		if _, ok := s.TakeCost(rl, 0.25); !ok {
+			t.Fatalf("quarter %d denied", i)
+		}
+	}
+	if _, ok := s.TakeCost(rl, 0.25); ok {
+		t.Fatal("ninth quarter allowed with 2 tokens")
+	}
+}
+
+func TestRateLimiter_TakeCostMixesWithTakeN(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	s := BuildRateLimiter(10, 10*time.Second, WithClock(clock)) // 1 token per second
+	rl := s.New()
+
+	if _, ok := s.TakeCost(rl, 3.5); !ok {
+		t.Fatal("cost 3.5 denied with 10 tokens")
+	}
+	// 6.5 left: 6 whole tokens now, the 7th after half a second
+	if _, ok := s.TakeN(rl, 7); ok {
+		t.Fatal("7 tokens allowed with 6.5 left")
+	}
+	clock.t = clock.t.Add(500 * time.Millisecond)
+	if _, ok := s.TakeN(rl, 7); !ok {
+		t.Fatal("7 tokens denied after the half token refilled")
+	}
+
+	wait, ok := s.TakeCost(rl, 1.5)
+	if ok || wait != 1501 {
+		t.Fatalf("TakeCost(1.5) with no tokens = %d, %v, want 1501, false", wait, ok)
+	}
+}
+
+func TestRateLimiter_TakeCostEdgeCases(t *testing.T) {
+	s := BuildRateLimiter(2, time.Second)
+	rl := s.New()
+	for _, cost := range []float64{0, -1, math.NaN()} {
+		if wait, ok := s.TakeCost(rl, cost); !ok || wait != 0 {
+			t.Fatalf("TakeCost(%v) = %d, %v", cost, wait, ok)
+		}
+	}
+	if wait, ok := s.TakeCost(rl, 2.01); ok || wait != math.MaxInt64 {
+		t.Fatalf("TakeCost over the burst = %d, %v", wait, ok)
+	}
+}
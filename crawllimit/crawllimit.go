@@ -0,0 +1,269 @@
+// Package crawllimit provides crawler politeness for limitron: a minimum delay
+// between fetches from the same domain (honoring robots.txt Crawl-delay through
+// a pluggable provider), a cap on concurrent fetches per domain, and a global rate.
+package crawllimit
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iryndin/limitron"
+)
+
+// Defaults of a Config.
+const (
+	DefaultDelay        = time.Second
+	DefaultMaxPerDomain = 2
+	DefaultDelayTTL     = time.Hour
+)
+
+// ErrNoHost is returned by WaitToFetch for URLs without a host.
+var ErrNoHost = errors.New("crawllimit: URL has no host")
+
+// DelayProvider supplies the crawl delay a domain asks for, such as the
+// Crawl-delay of its robots.txt (see RobotsDelay). It returns false if the domain
+// specifies none. Implementations must be safe for concurrent use.
+type DelayProvider interface {
+	CrawlDelay(ctx context.Context, host string) (time.Duration, bool)
+}
+
+// DelayFunc adapts an ordinary function to the DelayProvider interface.
+type DelayFunc func(ctx context.Context, host string) (time.Duration, bool)
+
+// CrawlDelay calls f(ctx, host).
+func (f DelayFunc) CrawlDelay(ctx context.Context, host string) (time.Duration, bool) {
+	return f(ctx, host)
+}
+
+// Config configures a Politeness.
+type Config struct {
+	// Delay is the minimum time between the starts of two fetches from a domain
+	// that specifies no crawl delay. Defaults to DefaultDelay.
+	Delay time.Duration
+	// MaxDelay caps crawl delays asked for by domains; 0 means no cap.
+	MaxDelay time.Duration
+	// MaxPerDomain caps the concurrent fetches from a domain. Defaults to DefaultMaxPerDomain.
+	MaxPerDomain int
+	// Global limits the total rate of fetches; a zero RateLimiter means no global limit.
+	Global limitron.RateLimiter
+	// Delays supplies per-domain crawl delays; nil uses Delay for all domains.
+	Delays DelayProvider
+	// DelayTTL is how long a domain's crawl delay is cached. Defaults to DefaultDelayTTL.
+	DelayTTL time.Duration
+}
+
+// Politeness paces the fetches of a crawler.
+//
+// The zero value is not usable; create instances with New.
+// All methods are safe for concurrent use.
+type Politeness struct {
+	cfg         Config
+	globalState *uint64
+
+	mu        sync.Mutex
+	domains   map[string]*domain
+	lastSweep time.Time
+}
+
+// domain is the politeness state of a single domain.
+type domain struct {
+	// slots holds a token per fetch in progress.
+	slots chan struct{}
+
+	mu sync.Mutex
+	// next is the earliest start time of the next fetch.
+	next time.Time
+	// delay is the crawl delay, valid until delayExpires.
+	delay        time.Duration
+	delayExpires time.Time
+	// waiting counts the callers of WaitToFetch holding or waiting for the domain.
+	waiting int
+}
+
+// New returns a Politeness enforcing `cfg`.
+//
+// Example:
+//
+//	p := crawllimit.New(crawllimit.Config{
+//	    Global: limitron.BuildRateLimiterRps(50),
+//	    Delays: crawllimit.RobotsDelay(http.DefaultClient, "MyBot"),
+//	})
+//	release, err := p.WaitToFetch(ctx, pageURL)
+//	if err != nil {
+//	    return err
+//	}
+//	defer release()
+//	resp, err := http.Get(pageURL)
+func New(cfg Config) *Politeness {
+	if cfg.Delay <= 0 {
+		cfg.Delay = DefaultDelay
+	}
+	if cfg.MaxPerDomain <= 0 {
+		cfg.MaxPerDomain = DefaultMaxPerDomain
+	}
+	if cfg.DelayTTL <= 0 {
+		cfg.DelayTTL = DefaultDelayTTL
+	}
+	p := &Politeness{cfg: cfg, domains: make(map[string]*domain), lastSweep: time.Now()}
+	if cfg.Global != (limitron.RateLimiter{}) {
+		p.globalState = cfg.Global.New()
+	}
+	return p
+}
+
+// WaitToFetch blocks until `rawURL` may be fetched: a fetch slot of its domain is
+// free, the domain's crawl delay has passed since the previous fetch started,
+// and the global rate admits it. Call the returned release function once the
+// fetch is done, to free the slot.
+//
+// Returns the context error if ctx is done first, or ErrNoHost for URLs without a host.
+func (p *Politeness) WaitToFetch(ctx context.Context, rawURL string) (release func(), err error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	host := strings.ToLower(u.Hostname())
+	if host == "" {
+		return nil, ErrNoHost
+	}
+
+	d := p.domain(host)
+	defer func() {
+		if err != nil {
+			p.done(d)
+		}
+	}()
+
+	// concurrency cap
+	select {
+	case d.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	defer func() {
+		if err != nil {
+			<-d.slots
+		}
+	}()
+
+	// per-domain delay: reserve the next start time, then wait for it
+	delay := p.delayOf(ctx, host, d)
+	d.mu.Lock()
+	start := time.Now()
+	if d.next.After(start) {
+		start = d.next
+	}
+	d.next = start.Add(delay)
+	d.mu.Unlock()
+	if err := sleepUntil(ctx, start); err != nil {
+		return nil, err
+	}
+
+	// global rate
+	if p.globalState != nil {
+		if err := p.cfg.Global.WaitN(ctx, p.globalState, 1); err != nil {
+			return nil, err
+		}
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			<-d.slots
+			p.done(d)
+		})
+	}, nil
+}
+
+// Domains returns the number of domains with a politeness state.
+func (p *Politeness) Domains() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.domains)
+}
+
+// domain returns the state of `host`, creating it if needed, and registers a caller.
+func (p *Politeness) domain(host string) *domain {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.sweepLocked()
+	d, ok := p.domains[host]
+	if !ok {
+		d = &domain{slots: make(chan struct{}, p.cfg.MaxPerDomain)}
+		p.domains[host] = d
+	}
+	d.mu.Lock()
+	d.waiting++
+	d.mu.Unlock()
+	return d
+}
+
+// done unregisters a caller of `d`.
+func (p *Politeness) done(d *domain) {
+	d.mu.Lock()
+	d.waiting--
+	d.mu.Unlock()
+}
+
+// sweepLocked removes, at most once a minute, the domains nobody waits for
+// whose delay has passed. Must be called with p.mu held.
+func (p *Politeness) sweepLocked() {
+	now := time.Now()
+	if now.Sub(p.lastSweep) < time.Minute {
+		return
+	}
+	p.lastSweep = now
+	for host, d := range p.domains {
+		d.mu.Lock()
+		idle := d.waiting == 0 && now.After(d.next)
+		d.mu.Unlock()
+		if idle {
+			delete(p.domains, host)
+		}
+	}
+}
+
+// delayOf returns the crawl delay of `host`, consulting the provider when the cached value expired.
+func (p *Politeness) delayOf(ctx context.Context, host string, d *domain) time.Duration {
+	d.mu.Lock()
+	if time.Now().Before(d.delayExpires) {
+		defer d.mu.Unlock()
+		return d.delay
+	}
+	d.mu.Unlock()
+
+	delay := p.cfg.Delay
+	if p.cfg.Delays != nil {
+		if asked, ok := p.cfg.Delays.CrawlDelay(ctx, host); ok {
+			delay = max(asked, 0)
+		}
+	}
+	if p.cfg.MaxDelay > 0 {
+		delay = min(delay, p.cfg.MaxDelay)
+	}
+
+	d.mu.Lock()
+	d.delay, d.delayExpires = delay, time.Now().Add(p.cfg.DelayTTL)
+	d.mu.Unlock()
+	return delay
+}
+
+// sleepUntil blocks until `t` or until ctx is done.
+func sleepUntil(ctx context.Context, t time.Time) error {
+	wait := time.Until(t)
+	if wait <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
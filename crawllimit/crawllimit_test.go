@@ -0,0 +1,102 @@
+package crawllimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/iryndin/limitron"
+)
+
+func TestPoliteness_DelayPerDomain(t *testing.T) {
+	p := New(Config{
+		Delay: 30 * time.Millisecond,
+		Delays: DelayFunc(func(ctx context.Context, host string) (time.Duration, bool) {
+			return 0, host == "fast.example"
+		}),
+	})
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		release, err := p.WaitToFetch(ctx, "https://Slow.example/page")
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	if elapsed := time.Since(start); elapsed < 55*time.Millisecond {
+		t.Fatalf("3 fetches 30ms apart took %v", elapsed)
+	}
+
+	start = time.Now()
+	for i := 0; i < 3; i++ {
+		release, err := p.WaitToFetch(ctx, "https://fast.example/page")
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	if elapsed := time.Since(start); elapsed > 20*time.Millisecond {
+		t.Fatalf("fetches without crawl delay took %v", elapsed)
+	}
+	if p.Domains() != 2 {
+		t.Fatalf("Domains() = %d, want 2", p.Domains())
+	}
+}
+
+func TestPoliteness_ConcurrencyCap(t *testing.T) {
+	p := New(Config{Delay: time.Nanosecond, MaxPerDomain: 2})
+	var inFlight, peak atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := p.WaitToFetch(context.Background(), "https://a.example/")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			n := inFlight.Add(1)
+			for {
+				old := peak.Load()
+				if n <= old || peak.CompareAndSwap(old, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			inFlight.Add(-1)
+			release()
+		}()
+	}
+	wg.Wait()
+	if peak.Load() != 2 {
+		t.Fatalf("peak concurrency = %d, want 2", peak.Load())
+	}
+}
+
+func TestPoliteness_GlobalRateAndCancel(t *testing.T) {
+	p := New(Config{Delay: time.Nanosecond, Global: limitron.BuildRateLimiter(1, time.Hour)})
+	release, err := p.WaitToFetch(context.Background(), "https://a.example/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	release() // releasing twice is harmless
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = p.WaitToFetch(ctx, "https://b.example/")
+	var limited *limitron.LimitedError
+	if !errors.As(err, &limited) && !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want a limit or deadline error", err)
+	}
+
+	if _, err := p.WaitToFetch(context.Background(), "/relative"); !errors.Is(err, ErrNoHost) {
+		t.Fatalf("err = %v, want ErrNoHost", err)
+	}
+}
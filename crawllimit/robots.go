@@ -0,0 +1,95 @@
+package crawllimit
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRobotsSize caps the robots.txt bytes read, as recommended by RFC 9309.
+const maxRobotsSize = 500 << 10
+
+// RobotsDelay returns a DelayProvider reading the Crawl-delay directive of a domain's
+// https://<host>/robots.txt with `client` (http.DefaultClient if nil). The delay of
+// the group naming `userAgent` is preferred over that of the "*" group.
+// Domains whose robots.txt cannot be fetched specify no delay.
+func RobotsDelay(client *http.Client, userAgent string) DelayProvider {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return DelayFunc(func(ctx context.Context, host string) (time.Duration, bool) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://"+host+"/robots.txt", nil)
+		if err != nil {
+			return 0, false
+		}
+		if userAgent != "" {
+			req.Header.Set("User-Agent", userAgent)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return 0, false
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return 0, false
+		}
+		return ParseCrawlDelay(io.LimitReader(resp.Body, maxRobotsSize), userAgent)
+	})
+}
+
+// ParseCrawlDelay returns the Crawl-delay (in seconds, possibly fractional) that
+// a robots.txt document asks of `userAgent`, falling back to the "*" group.
+// Returns false if neither group has one.
+func ParseCrawlDelay(r io.Reader, userAgent string) (time.Duration, bool) {
+	userAgent = strings.ToLower(userAgent)
+	var (
+		agents          []string
+		inRules         bool
+		own, star       time.Duration
+		hasOwn, hasStar bool
+	)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		field, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		field = strings.ToLower(strings.TrimSpace(field))
+		value = strings.TrimSpace(value)
+
+		switch field {
+		case "user-agent":
+			if inRules {
+				// a new group starts
+				agents, inRules = nil, false
+			}
+			agents = append(agents, strings.ToLower(value))
+		case "crawl-delay":
+			inRules = true
+			secs, err := strconv.ParseFloat(value, 64)
+			if err != nil || secs < 0 {
+				continue
+			}
+			delay := time.Duration(secs * float64(time.Second))
+			for _, a := range agents {
+				switch {
+				case userAgent != "" && a != "*" && strings.Contains(userAgent, a):
+					own, hasOwn = delay, true
+				case a == "*":
+					star, hasStar = delay, true
+				}
+			}
+		default:
+			inRules = true
+		}
+	}
+	if hasOwn {
+		return own, true
+	}
+	return star, hasStar
+}
@@ -0,0 +1,63 @@
+package crawllimit
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const robotsTxt = `
+# comment
+User-agent: *
+Disallow: /private
+Crawl-delay: 2
+
+User-agent: OtherBot
+User-agent: MyBot
+Crawl-delay: 0.5 # seconds
+`
+
+func TestParseCrawlDelay(t *testing.T) {
+	cases := []struct {
+		ua   string
+		want time.Duration
+		ok   bool
+	}{
+		{"MyBot/1.0", 500 * time.Millisecond, true},
+		{"SomeBot", 2 * time.Second, true},
+		{"", 2 * time.Second, true},
+	}
+	for _, c := range cases {
+		got, ok := ParseCrawlDelay(strings.NewReader(robotsTxt), c.ua)
+		if got != c.want || ok != c.ok {
+			t.Errorf("ParseCrawlDelay(%q) = %v, %v; want %v, %v", c.ua, got, ok, c.want, c.ok)
+		}
+	}
+	if _, ok := ParseCrawlDelay(strings.NewReader("User-agent: *\nDisallow: /\n"), "MyBot"); ok {
+		t.Errorf("found a delay in robots.txt without one")
+	}
+}
+
+func TestRobotsDelay(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, robotsTxt)
+	}))
+	defer srv.Close()
+
+	provider := RobotsDelay(srv.Client(), "MyBot")
+	host := strings.TrimPrefix(srv.URL, "https://")
+	if d, ok := provider.CrawlDelay(context.Background(), host); !ok || d != 500*time.Millisecond {
+		t.Fatalf("CrawlDelay = %v, %v; want 500ms", d, ok)
+	}
+	if _, ok := provider.CrawlDelay(context.Background(), "127.0.0.1:1"); ok {
+		t.Fatalf("unreachable host reported a delay")
+	}
+}
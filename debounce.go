@@ -0,0 +1,132 @@
+package limitron
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// DebounceEdge selects on which edge of a burst a Debouncer executes.
+type DebounceEdge uint8
+
+const (
+	// DebounceLeading executes on the first trigger of a burst.
+	DebounceLeading DebounceEdge = 1 << iota
+	// DebounceTrailing executes once after the interval if triggers were suppressed.
+	DebounceTrailing
+)
+
+// debouncePending is set in the flags part of the state while a trailing execution is scheduled.
+const debouncePending uint16 = 1
+
+// Debouncer suppresses bursts of identical triggers down to at most one
+// execution per interval.
+//
+// Like RateLimiter it is a stateless configuration: the per-trigger-source state
+// is a single uint64 created with New(), packed as follows:
+//
+//	64 bits: [ 16-bit flags ][ 48-bit deadline in ms ]
+//
+// The deadline marks the end of the current suppression window, the flags hold
+// whether a trailing execution is pending.
+// Concurrent Trigger calls on the same state are safe.
+type Debouncer struct {
+	// interval is the suppression window in milliseconds.
+	interval uint64
+
+	// edge selects leading and/or trailing execution.
+	edge DebounceEdge
+
+	// retries controls the number of atomic CAS attempts made by Trigger.
+	retries int
+}
+
+// BuildDebouncer returns a Debouncer that executes at most once per `interval`
+// on the given edge(s).
+//
+// Example:
+//
+//	d := BuildDebouncer(time.Second, DebounceLeading|DebounceTrailing)
+//	st := d.New()
+//	d.Trigger(st, refreshCache)
+//
+// If edge is zero, DebounceTrailing is used.
+func BuildDebouncer(interval time.Duration, edge DebounceEdge) Debouncer {
+	if edge == 0 {
+		edge = DebounceTrailing
+	}
+	return Debouncer{
+		interval: uint64(interval.Milliseconds()),
+		edge:     edge,
+		retries:  UpdateRetries,
+	}
+}
+
+// New creates a brand-new, idle debouncer state.
+func (d Debouncer) New() *uint64 {
+	st := packUint16AndUint48(0, 0)
+	return &st
+}
+
+// Trigger registers a trigger on state `st`.
+//
+// If the state is idle and the leading edge is enabled, fn is executed synchronously
+// and Trigger returns true. If the trigger falls into the current suppression window
+// and the trailing edge is enabled, a single execution of fn is scheduled at the end
+// of the window (on its own goroutine); Trigger returns false in that case,
+// as well as when the trigger is dropped.
+func (d Debouncer) Trigger(st *uint64, fn func()) bool {
+	for i := 0; i < d.retries; i++ {
+		stval := atomic.LoadUint64(st)
+		flags, deadline := unpackUint16Uint48(stval)
+		now := uint64(time.Now().UnixMilli())
+
+		if now >= deadline && flags&debouncePending == 0 {
+			// idle: open a new suppression window
+			if d.edge&DebounceLeading != 0 {
+				if atomic.CompareAndSwapUint64(st, stval, packUint16AndUint48(0, now+d.interval)) {
+					fn()
+					return true
+				}
+				continue
+			}
+			if atomic.CompareAndSwapUint64(st, stval, packUint16AndUint48(debouncePending, now+d.interval)) {
+				d.scheduleTrailing(st, d.interval, fn)
+				return false
+			}
+			continue
+		}
+
+		// within the window: coalesce into the trailing execution, if any
+		if d.edge&DebounceTrailing == 0 || flags&debouncePending != 0 {
+			return false
+		}
+		if atomic.CompareAndSwapUint64(st, stval, packUint16AndUint48(debouncePending, deadline)) {
+			d.scheduleTrailing(st, deadline-now, fn)
+			return false
+		}
+	}
+
+	// CAS failed because concurrent triggers updated the state,
+	// so this trigger is coalesced with theirs.
+	return false
+}
+
+// scheduleTrailing arranges the pending trailing execution to run after `delayMillis`.
+func (d Debouncer) scheduleTrailing(st *uint64, delayMillis uint64, fn func()) {
+	time.AfterFunc(time.Duration(delayMillis)*time.Millisecond, func() {
+		for {
+			stval := atomic.LoadUint64(st)
+			flags, _ := unpackUint16Uint48(stval)
+			if flags&debouncePending == 0 {
+				return
+			}
+			// the trailing execution opens a new window, so that
+			// executions are never closer than the interval
+			now := uint64(time.Now().UnixMilli())
+			if atomic.CompareAndSwapUint64(st, stval, packUint16AndUint48(0, now+d.interval)) {
+				fn()
+				return
+			}
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package limitron
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDebouncer_LeadingOnly(t *testing.T) {
+	d := BuildDebouncer(200*time.Millisecond, DebounceLeading)
+	st := d.New()
+
+	var calls int32
+	fn := func() { atomic.AddInt32(&calls, 1) }
+
+	if !d.Trigger(st, fn) {
+		t.Fatal("first trigger should execute on the leading edge")
+	}
+	for i := 0; i < 10; i++ {
+		if d.Trigger(st, fn) {
+			t.Fatalf("trigger %d within window should be suppressed", i)
+		}
+	}
+	time.Sleep(250 * time.Millisecond)
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("calls = %d, want 1 (no trailing edge)", got)
+	}
+
+	if !d.Trigger(st, fn) {
+		t.Fatal("trigger after window should execute again")
+	}
+}
+
+func TestDebouncer_TrailingOnly(t *testing.T) {
+	d := BuildDebouncer(100*time.Millisecond, DebounceTrailing)
+	st := d.New()
+
+	var calls int32
+	fn := func() { atomic.AddInt32(&calls, 1) }
+
+	for i := 0; i < 20; i++ {
+		if d.Trigger(st, fn) {
+			t.Fatal("trailing-only debouncer must not execute synchronously")
+		}
+	}
+	if got := atomic.LoadInt32(&calls); got != 0 {
+		t.Fatalf("calls = %d before window end, want 0", got)
+	}
+
+	time.Sleep(200 * time.Millisecond)
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("calls = %d after window end, want 1", got)
+	}
+}
+
+func TestDebouncer_LeadingAndTrailing(t *testing.T) {
+	d := BuildDebouncer(100*time.Millisecond, DebounceLeading|DebounceTrailing)
+	st := d.New()
+
+	var calls int32
+	fn := func() { atomic.AddInt32(&calls, 1) }
+
+	if !d.Trigger(st, fn) {
+		t.Fatal("first trigger should execute on the leading edge")
+	}
+	d.Trigger(st, fn)
+	d.Trigger(st, fn)
+
+	time.Sleep(200 * time.Millisecond)
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Fatalf("calls = %d, want 2 (leading + trailing)", got)
+	}
+}
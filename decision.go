@@ -0,0 +1,98 @@
+package limitron
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// Decision is the detailed outcome of consuming tokens, carrying what
+// callers need to report quota information (e.g., in rate limit response headers).
+type Decision struct {
+	// Allowed reports whether the tokens were consumed.
+	Allowed bool
+	// Remaining is the number of tokens left after the decision.
+	Remaining uint16
+	// Limit is the bucket capacity.
+	Limit uint16
+	// RetryAfter is the wait suggested to a denied request; 0 when allowed.
+	RetryAfter time.Duration
+	// ResetAt is the time the bucket will be full again, if nothing else is consumed.
+	ResetAt time.Time
+}
+
+// decision builds the Decision of a TakeN call that returned (waitMillis, allowed),
+// given the limiter state `rlval` after the call.
+func (s RateLimiter) decision(rlval uint64, waitMillis int64, allowed bool) Decision {
+	now := s.nowTicks()
+	remaining, ts := s.calcNewRequestsAt(rlval, now)
+	d := Decision{
+		Allowed:   allowed,
+		Remaining: remaining,
+		Limit:     s.maxreq,
+		ResetAt:   s.timeOf(now),
+	}
+	if !allowed {
+		d.RetryAfter = millisToDuration(waitMillis)
+	}
+	if s.debt > 0 && ts > now {
+		// the outstanding debt is repaid first
+		d.ResetAt = s.timeOf(ts)
+	}
+	if missing := s.maxreq - remaining; missing > 0 {
+		d.ResetAt = d.ResetAt.Add(s.tickDuration(uint64(math.Ceil(float64(missing) / s.rrpt()))))
+	}
+	return d
+}
+
+// TakeNResult is TakeN returning a Decision, with the remaining tokens and reset time of `*rl`.
+//
+// Example:
+//
+//	d := limiter.TakeNResult(state, 1)
+//	w.Header().Set("X-RateLimit-Limit", strconv.Itoa(int(d.Limit)))
+//	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(int(d.Remaining)))
+//	if !d.Allowed {
+//		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(d.RetryAfter.Seconds()))))
+//	}
+func (s RateLimiter) TakeNResult(rl *uint64, requests uint16) Decision {
+	waitMillis, allowed := s.TakeN(rl, requests)
+	return s.decision(atomic.LoadUint64(rl), waitMillis, allowed)
+}
+
+// Take1Result is TakeNResult for 1 token.
+func (s RateLimiter) Take1Result(rl *uint64) Decision {
+	return s.TakeNResult(rl, 1)
+}
+
+// TakeNResult is TakeN returning a Decision, with the remaining tokens and reset time of `key`.
+//
+// Remaining and ResetAt reflect the limit of the key as configured, not any
+// temporary scaling by penalty or reputation policies.
+//
+// Example:
+//
+//	d := kl.TakeNResult(userID, 1)
+//	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(int(d.Remaining)))
+func (kl *KeyedLimiter[K]) TakeNResult(key K, requests uint16) Decision {
+	waitMillis, allowed := kl.TakeN(key, requests)
+	return kl.entryDecision(key, kl.lookup(key), waitMillis, allowed)
+}
+
+// entryDecision builds the Decision of a take from entry `e` of `key`, or from a new bucket if `e` is nil.
+func (kl *KeyedLimiter[K]) entryDecision(key K, e *keyedEntry, waitMillis int64, allowed bool) Decision {
+	limiter := kl.limiterOf(key)
+	rlval := limiter.initialState()
+	if e != nil {
+		if l := kl.limiterFor(key, e); l.maxreq != 0 {
+			limiter = l
+		}
+		rlval = atomic.LoadUint64(&e.state)
+	}
+	return limiter.decision(rlval, waitMillis, allowed)
+}
+
+// Take1Result is TakeNResult for 1 token.
+func (kl *KeyedLimiter[K]) Take1Result(key K) Decision {
+	return kl.TakeNResult(key, 1)
+}
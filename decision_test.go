@@ -0,0 +1,65 @@
+package limitron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyedLimiter_TakeNResult(t *testing.T) {
+	kl := NewKeyedLimiter[string](BuildRateLimiter(10, time.Second))
+
+	start := time.Now()
+	d := kl.TakeNResult("k", 4)
+	if !d.Allowed || d.Remaining != 6 || d.Limit != 10 || d.RetryAfter != 0 {
+		t.Fatalf("decision = %+v, want allowed with 6 of 10 remaining", d)
+	}
+	// 4 tokens refill in 400ms
+	if reset := d.ResetAt.Sub(start); reset < 390*time.Millisecond || reset > 420*time.Millisecond {
+		t.Errorf("reset in %v, want about 400ms", reset)
+	}
+
+	d = kl.TakeNResult("k", 8)
+	if d.Allowed || d.Remaining != 6 || d.RetryAfter <= 0 {
+		t.Fatalf("decision = %+v, want denied with 6 remaining and a retry delay", d)
+	}
+
+	d = kl.Take1Result("other")
+	if !d.Allowed || d.Remaining != 9 {
+		t.Fatalf("other key decision = %+v, want allowed with 9 remaining", d)
+	}
+}
+
+func TestKeyedLimiter_TakeNResultOffMode(t *testing.T) {
+	kl := NewKeyedLimiter[string](BuildRateLimiter(10, time.Second))
+	kl.SetNamespaceMode("", Off)
+
+	d := kl.TakeNResult("k", 4)
+	if !d.Allowed || d.Remaining != 10 || kl.Len() != 0 {
+		t.Fatalf("decision = %+v with %d keys, want a full untouched bucket", d, kl.Len())
+	}
+}
+
+func TestRateLimiter_TakeNResult(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	limiter := BuildRateLimiter(10, time.Second, WithClock(clock))
+	state := limiter.New()
+
+	d := limiter.TakeNResult(state, 4)
+	if !d.Allowed || d.Remaining != 6 || d.Limit != 10 || d.RetryAfter != 0 {
+		t.Fatalf("decision = %+v, want allowed with 6 of 10 remaining", d)
+	}
+	if want := clock.t.Add(400 * time.Millisecond); !d.ResetAt.Equal(want) {
+		t.Errorf("ResetAt = %v, want %v", d.ResetAt, want)
+	}
+
+	d = limiter.TakeNResult(state, 8)
+	if d.Allowed || d.Remaining != 6 || d.RetryAfter < 200*time.Millisecond || d.RetryAfter > 210*time.Millisecond {
+		t.Fatalf("decision = %+v, want denied with 6 remaining and about a 200ms retry", d)
+	}
+
+	clock.t = clock.t.Add(time.Second)
+	d = limiter.Take1Result(state)
+	if !d.Allowed || d.Remaining != 9 {
+		t.Fatalf("decision after refill = %+v, want allowed with 9 remaining", d)
+	}
+}
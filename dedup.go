@@ -0,0 +1,90 @@
+package limitron
+
+import (
+	"sync"
+	"time"
+)
+
+// Deduper admits a given key at most once per window, which is handy for
+// idempotency-style suppression of duplicate notifications or jobs.
+//
+// For every admitted key the Deduper stores only the expiry of its window
+// in Unix milliseconds (a plain uint64, no pointers), so it stays GC-friendly
+// for large key sets. Expired entries are swept automatically whenever the
+// number of tracked keys doubles since the previous sweep, or explicitly by Purge.
+//
+// The zero value is not usable; create instances with NewDeduper.
+type Deduper[K comparable] struct {
+	mu        sync.Mutex
+	expiry    map[K]uint64
+	sweepSize int
+}
+
+// minDedupSweepSize is the number of tracked keys below which no automatic sweep happens.
+const minDedupSweepSize = 1024
+
+// NewDeduper returns an empty Deduper.
+//
+// Example:
+//
+//	dedup := NewDeduper[string]()
+//	if dedup.AllowOnce(orderID, 10*time.Minute) {
+//	    sendConfirmationEmail(orderID)
+//	}
+func NewDeduper[K comparable]() *Deduper[K] {
+	return &Deduper[K]{
+		expiry:    make(map[K]uint64),
+		sweepSize: minDedupSweepSize,
+	}
+}
+
+// AllowOnce reports whether `key` is admitted. The first call for a key returns true
+// and opens a window of the given duration; further calls for the same key return
+// false until the window expires.
+func (d *Deduper[K]) AllowOnce(key K, window time.Duration) bool {
+	now := uint64(time.Now().UnixMilli())
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if exp, ok := d.expiry[key]; ok && now < exp {
+		return false
+	}
+	d.expiry[key] = now + uint64(window.Milliseconds())
+
+	if len(d.expiry) >= d.sweepSize {
+		d.purgeLocked(now)
+		d.sweepSize = max(2*len(d.expiry), minDedupSweepSize)
+	}
+	return true
+}
+
+// Forget removes `key`, so that the next AllowOnce call for it is admitted.
+func (d *Deduper[K]) Forget(key K) {
+	d.mu.Lock()
+	delete(d.expiry, key)
+	d.mu.Unlock()
+}
+
+// Len returns the number of tracked keys, including expired ones not yet purged.
+func (d *Deduper[K]) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.expiry)
+}
+
+// Purge removes all keys whose window has expired.
+func (d *Deduper[K]) Purge() {
+	now := uint64(time.Now().UnixMilli())
+	d.mu.Lock()
+	d.purgeLocked(now)
+	d.mu.Unlock()
+}
+
+func (d *Deduper[K]) purgeLocked(now uint64) {
+	for k, exp := range d.expiry {
+		if now >= exp {
+			delete(d.expiry, k)
+		}
+	}
+}
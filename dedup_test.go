@@ -0,0 +1,61 @@
+package limitron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDeduper_AllowOncePerWindow(t *testing.T) {
+	d := NewDeduper[string]()
+
+	if !d.AllowOnce("job-1", 100*time.Millisecond) {
+		t.Fatal("first AllowOnce should be admitted")
+	}
+	if d.AllowOnce("job-1", 100*time.Millisecond) {
+		t.Fatal("duplicate within window should be suppressed")
+	}
+	if !d.AllowOnce("job-2", 100*time.Millisecond) {
+		t.Fatal("other keys should be admitted independently")
+	}
+
+	time.Sleep(120 * time.Millisecond)
+	if !d.AllowOnce("job-1", 100*time.Millisecond) {
+		t.Fatal("key should be admitted again after the window expired")
+	}
+}
+
+func TestDeduper_Forget(t *testing.T) {
+	d := NewDeduper[int]()
+	d.AllowOnce(1, time.Hour)
+	d.Forget(1)
+	if !d.AllowOnce(1, time.Hour) {
+		t.Fatal("forgotten key should be admitted again")
+	}
+}
+
+func TestDeduper_PurgeExpired(t *testing.T) {
+	d := NewDeduper[int]()
+	for i := 0; i < 10; i++ {
+		d.AllowOnce(i, time.Millisecond)
+	}
+	d.AllowOnce(100, time.Hour)
+
+	time.Sleep(5 * time.Millisecond)
+	d.Purge()
+	if got := d.Len(); got != 1 {
+		t.Fatalf("Len after purge = %d, want 1", got)
+	}
+}
+
+func TestDeduper_AutomaticSweep(t *testing.T) {
+	d := NewDeduper[int]()
+	for i := 0; i < minDedupSweepSize-1; i++ {
+		d.AllowOnce(i, time.Millisecond)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	d.AllowOnce(-1, time.Hour) // reaches sweep size
+	if got := d.Len(); got != 1 {
+		t.Fatalf("Len after automatic sweep = %d, want 1", got)
+	}
+}
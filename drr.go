@@ -0,0 +1,183 @@
+package limitron
+
+import (
+	"math"
+	"sync"
+)
+
+// DRRDispatcher is a deficit round-robin (DRR) scheduler over many per-key queues.
+//
+// Pending items are dequeued proportionally to each key's quantum, while every key
+// is additionally held to its own rate limit. This gives job systems fairness between
+// keys (a key with a deep backlog cannot starve others) together with per-key rate limits.
+//
+// Every item carries a cost, which is both its DRR cost and the number of tokens
+// it consumes from the key's limiter state.
+//
+// Per-key limiter states are kept after a key's queue drains, so that a key cannot
+// reset its limit by emptying its queue.
+//
+// The zero value is not usable; create instances with NewDRRDispatcher.
+// All methods are safe for concurrent use.
+type DRRDispatcher[K comparable, T any] struct {
+	limiter RateLimiter
+	quantum int
+
+	mu     sync.Mutex
+	queues map[K]*drrQueue[T]
+	active []K
+	next   int
+}
+
+// drrQueue is the per-key queue with its DRR deficit and limiter state.
+type drrQueue[T any] struct {
+	items   []drrItem[T]
+	quantum int
+	deficit int
+	// visiting is true while the round-robin pointer stays on this queue,
+	// i.e. its quantum for the current round was already granted.
+	visiting bool
+	active   bool
+	state    *uint64
+}
+
+type drrItem[T any] struct {
+	item T
+	cost uint16
+}
+
+// NewDRRDispatcher returns a DRRDispatcher where every key is limited by `limiter`
+// and receives `quantum` cost units per round unless overridden with SetQuantum.
+// A non-positive quantum is treated as 1.
+func NewDRRDispatcher[K comparable, T any](limiter RateLimiter, quantum int) *DRRDispatcher[K, T] {
+	return &DRRDispatcher[K, T]{
+		limiter: limiter,
+		quantum: max(quantum, 1),
+		queues:  make(map[K]*drrQueue[T]),
+	}
+}
+
+// SetQuantum overrides the per-round quantum of `key`.
+// A non-positive quantum is treated as 1.
+func (d *DRRDispatcher[K, T]) SetQuantum(key K, quantum int) {
+	d.mu.Lock()
+	d.queue(key).quantum = max(quantum, 1)
+	d.mu.Unlock()
+}
+
+// Enqueue appends `item` with the given `cost` to the queue of `key`. Items costing
+// more than the burst of the per-key limiter could never be dispatched and would block
+// the queue of `key` forever, so they are rejected with ErrExceedsBurst.
+func (d *DRRDispatcher[K, T]) Enqueue(key K, item T, cost uint16) error {
+	if cost > d.limiter.maxreq {
+		return ErrExceedsBurst
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	q := d.queue(key)
+	q.items = append(q.items, drrItem[T]{item: item, cost: cost})
+	if !q.active {
+		q.active = true
+		d.active = append(d.active, key)
+	}
+	return nil
+}
+
+// Len returns the total number of pending items.
+func (d *DRRDispatcher[K, T]) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	n := 0
+	for _, k := range d.active {
+		n += len(d.queues[k].items)
+	}
+	return n
+}
+
+// Dequeue returns the next item in DRR order whose key's limiter admits it.
+//
+// If no item can be dispatched right now, because every key with pending work
+// is rate limited, Dequeue returns ok=false and the number of milliseconds
+// to wait before calling it again (math.MaxInt64 when nothing is pending).
+func (d *DRRDispatcher[K, T]) Dequeue() (key K, item T, waitMillis int64, ok bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	waitMillis = math.MaxInt64
+	// number of consecutive visits that were denied by a limiter;
+	// a full pass of denials means no progress is possible without waiting
+	denied := 0
+
+	for len(d.active) > 0 && denied < len(d.active) {
+		if d.next >= len(d.active) {
+			d.next = 0
+		}
+		k := d.active[d.next]
+		q := d.queues[k]
+
+		granted := false
+		if !q.visiting {
+			q.visiting = true
+			q.deficit += q.quantum
+			granted = true
+		}
+
+		head := q.items[0]
+		if int(head.cost) > q.deficit {
+			// not enough deficit: the rest is carried to the next round
+			denied = 0
+			d.advance(q)
+			continue
+		}
+
+		if wait, taken := d.limiter.TakeN(q.state, head.cost); !taken {
+			// rate limited: don't let the key accumulate quanta while limited
+			if granted {
+				q.deficit -= q.quantum
+			}
+			waitMillis = min(waitMillis, wait)
+			denied++
+			d.advance(q)
+			continue
+		}
+
+		q.deficit -= int(head.cost)
+		var zero drrItem[T]
+		q.items[0] = zero
+		q.items = q.items[1:]
+		if len(q.items) == 0 {
+			d.deactivate(q)
+		}
+		return k, head.item, 0, true
+	}
+
+	return key, item, waitMillis, false
+}
+
+// queue returns the queue of `key`, creating it if needed. Must be called with d.mu held.
+func (d *DRRDispatcher[K, T]) queue(key K) *drrQueue[T] {
+	q, ok := d.queues[key]
+	if !ok {
+		q = &drrQueue[T]{quantum: d.quantum, state: d.limiter.New()}
+		d.queues[key] = q
+	}
+	return q
+}
+
+// advance moves the round-robin pointer past q. Must be called with d.mu held.
+func (d *DRRDispatcher[K, T]) advance(q *drrQueue[T]) {
+	q.visiting = false
+	d.next++
+}
+
+// deactivate removes the current (emptied) queue from the active list.
+// Must be called with d.mu held.
+func (d *DRRDispatcher[K, T]) deactivate(q *drrQueue[T]) {
+	q.active = false
+	q.visiting = false
+	q.deficit = 0
+	q.items = nil
+	d.active = append(d.active[:d.next], d.active[d.next+1:]...)
+}
@@ -0,0 +1,87 @@
+package limitron
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func drainDRR(d *DRRDispatcher[string, int]) string {
+	var sb strings.Builder
+	for {
+		k, _, _, ok := d.Dequeue()
+		if !ok {
+			return sb.String()
+		}
+		sb.WriteString(k)
+	}
+}
+
+func TestDRRDispatcher_ProportionalToQuantum(t *testing.T) {
+	d := NewDRRDispatcher[string, int](BuildRateLimiter(1000, time.Hour), 1)
+	d.SetQuantum("a", 2)
+	for i := 0; i < 6; i++ {
+		d.Enqueue("a", i, 1)
+	}
+	for i := 0; i < 3; i++ {
+		d.Enqueue("b", i, 1)
+	}
+
+	if got, want := drainDRR(d), "aabaabaab"; got != want {
+		t.Fatalf("dispatch order = %q, want %q", got, want)
+	}
+}
+
+func TestDRRDispatcher_CostCarriesDeficit(t *testing.T) {
+	d := NewDRRDispatcher[string, int](BuildRateLimiter(1000, time.Hour), 2)
+	d.Enqueue("a", 0, 4) // needs two rounds of deficit
+	d.Enqueue("b", 0, 1)
+	d.Enqueue("b", 1, 1)
+	d.Enqueue("b", 2, 1)
+
+	if got, want := drainDRR(d), "bbab"; got != want {
+		t.Fatalf("dispatch order = %q, want %q", got, want)
+	}
+}
+
+func TestDRRDispatcher_RespectsPerKeyLimiter(t *testing.T) {
+	d := NewDRRDispatcher[string, int](BuildRateLimiter(2, time.Hour), 1)
+	for i := 0; i < 5; i++ {
+		d.Enqueue("a", i, 1)
+		d.Enqueue("b", i, 1)
+	}
+
+	if got, want := drainDRR(d), "abab"; got != want {
+		t.Fatalf("dispatch order = %q, want %q", got, want)
+	}
+
+	_, _, wait, ok := d.Dequeue()
+	if ok || wait <= 0 || wait == math.MaxInt64 {
+		t.Fatalf("Dequeue with all keys limited => wait=%d ok=%v, want finite positive,false", wait, ok)
+	}
+	if got := d.Len(); got != 6 {
+		t.Fatalf("Len = %d, want 6", got)
+	}
+}
+
+func TestDRRDispatcher_EmptyDequeue(t *testing.T) {
+	d := NewDRRDispatcher[string, int](BuildRateLimiterRps(1), 1)
+	if _, _, wait, ok := d.Dequeue(); ok || wait != math.MaxInt64 {
+		t.Fatalf("empty Dequeue => wait=%d ok=%v, want MaxInt64,false", wait, ok)
+	}
+}
+
+func TestDRRDispatcher_RejectsCostOverBurst(t *testing.T) {
+	d := NewDRRDispatcher[string, int](BuildRateLimiter(5, time.Hour), 10)
+	if err := d.Enqueue("a", 0, 6); !errors.Is(err, ErrExceedsBurst) {
+		t.Fatalf("Enqueue over the burst = %v, want ErrExceedsBurst", err)
+	}
+	if err := d.Enqueue("a", 1, 5); err != nil {
+		t.Fatal(err)
+	}
+	if _, item, _, ok := d.Dequeue(); !ok || item != 1 {
+		t.Fatalf("Dequeue = %d, %v; want item 1", item, ok)
+	}
+}
@@ -0,0 +1,76 @@
+package limitron
+
+import (
+	"math"
+	"time"
+)
+
+// TakeNDuration is TakeN returning the wait as a time.Duration, ready for time.Sleep
+// or time.After, instead of a number of milliseconds.
+//
+// Example:
+//
+//	if wait, ok := limiter.TakeNDuration(state, 1); !ok {
+//		time.Sleep(wait)
+//	}
+func (s RateLimiter) TakeNDuration(rl *uint64, requests uint16) (time.Duration, bool) {
+	waitMillis, ok := s.TakeN(rl, requests)
+	return millisToDuration(waitMillis), ok
+}
+
+// Take1Duration is TakeNDuration for 1 token.
+func (s RateLimiter) Take1Duration(rl *uint64) (time.Duration, bool) {
+	return s.TakeNDuration(rl, 1)
+}
+
+// TakeNUntil is TakeN returning the time the tokens are expected to be available,
+// according to the limiter's clock: now if the request was allowed, and the zero
+// Time if it can never be (see TakeN).
+//
+// Example:
+//
+//	if at, ok := limiter.TakeNUntil(state, 1); !ok && !at.IsZero() {
+//		retryQueue.Schedule(job, at)
+//	}
+func (s RateLimiter) TakeNUntil(rl *uint64, requests uint16) (time.Time, bool) {
+	now := s.now()
+	waitMillis, ok := s.TakeN(rl, requests)
+	return untilOf(now, waitMillis), ok
+}
+
+// Take1Until is TakeNUntil for 1 token.
+func (s RateLimiter) Take1Until(rl *uint64) (time.Time, bool) {
+	return s.TakeNUntil(rl, 1)
+}
+
+// TakeNDuration is TakeN returning the wait as a time.Duration. See RateLimiter.TakeNDuration.
+func (kl *KeyedLimiter[K]) TakeNDuration(key K, requests uint16) (time.Duration, bool) {
+	waitMillis, ok := kl.TakeN(key, requests)
+	return millisToDuration(waitMillis), ok
+}
+
+// Take1Duration is TakeNDuration for 1 token.
+func (kl *KeyedLimiter[K]) Take1Duration(key K) (time.Duration, bool) {
+	return kl.TakeNDuration(key, 1)
+}
+
+// TakeNUntil is TakeN returning the time the tokens of `key` are expected to be available.
+// See RateLimiter.TakeNUntil.
+func (kl *KeyedLimiter[K]) TakeNUntil(key K, requests uint16) (time.Time, bool) {
+	now := kl.limiter.now()
+	waitMillis, ok := kl.TakeN(key, requests)
+	return untilOf(now, waitMillis), ok
+}
+
+// Take1Until is TakeNUntil for 1 token.
+func (kl *KeyedLimiter[K]) Take1Until(key K) (time.Time, bool) {
+	return kl.TakeNUntil(key, 1)
+}
+
+// untilOf returns `now` plus a wait of `waitMillis`, or the zero Time if the wait is unbounded.
+func untilOf(now time.Time, waitMillis int64) time.Time {
+	if waitMillis == math.MaxInt64 {
+		return time.Time{}
+	}
+	return now.Add(millisToDuration(waitMillis))
+}
@@ -0,0 +1,47 @@
+package limitron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_TakeNDuration(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	limiter := BuildRateLimiter(10, time.Second, WithClock(clock))
+	state := limiter.New()
+
+	if wait, ok := limiter.TakeNDuration(state, 10); !ok || wait != 0 {
+		t.Fatalf("TakeNDuration(10) = %v, %v, want 0, true", wait, ok)
+	}
+	wait, ok := limiter.Take1Duration(state)
+	if ok || wait < 100*time.Millisecond || wait > 110*time.Millisecond {
+		t.Fatalf("Take1Duration = %v, %v, want about 100ms, false", wait, ok)
+	}
+
+	at, ok := limiter.Take1Until(state)
+	if ok || !at.Equal(clock.t.Add(wait)) {
+		t.Fatalf("Take1Until = %v, %v, want %v, false", at, ok, clock.t.Add(wait))
+	}
+	if at, ok := limiter.TakeNUntil(state, 11); ok || !at.IsZero() {
+		t.Fatalf("TakeNUntil(11) = %v, %v, want the zero time, false", at, ok)
+	}
+
+	clock.t = clock.t.Add(time.Second)
+	if at, ok := limiter.Take1Until(state); !ok || !at.Equal(clock.t) {
+		t.Fatalf("Take1Until after refill = %v, %v, want now, true", at, ok)
+	}
+}
+
+func TestKeyedLimiter_TakeNDuration(t *testing.T) {
+	kl := NewKeyedLimiter[string](BuildRateLimiter(1, time.Second))
+
+	if wait, ok := kl.Take1Duration("k"); !ok || wait != 0 {
+		t.Fatalf("first Take1Duration = %v, %v, want 0, true", wait, ok)
+	}
+	if wait, ok := kl.Take1Duration("k"); ok || wait < 900*time.Millisecond {
+		t.Fatalf("second Take1Duration = %v, %v, want about 1s, false", wait, ok)
+	}
+	if at, ok := kl.Take1Until("k"); ok || time.Until(at) < 900*time.Millisecond {
+		t.Fatalf("Take1Until = %v, %v, want about 1s from now, false", at, ok)
+	}
+}
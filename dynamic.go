@@ -0,0 +1,89 @@
+package limitron
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DynamicLimiter is a RateLimiter whose rate and burst can be changed at runtime,
+// e.g., by a control plane, without rebuilding the per-key states.
+//
+// States are packed as for RateLimiter and keep their token counts across changes:
+//   - when the burst shrinks, tokens above the new burst are dropped on the next take
+//   - when the burst grows, tokens refill up to the new burst, at the current rate
+//   - a new rate applies to the refill from the next take on; the refill accrued since
+//     a state's last take is computed at the new rate
+//
+// The zero value is not usable; create instances with NewDynamicLimiter.
+// All methods are safe for concurrent use.
+type DynamicLimiter struct {
+	mu      sync.Mutex
+	limiter atomic.Pointer[RateLimiter]
+}
+
+// NewDynamicLimiter returns a DynamicLimiter starting with the configuration of `limiter`,
+// including its options.
+//
+// Example:
+//
+//	dl := NewDynamicLimiter(BuildRateLimiterRps(100))
+//	state := dl.New()
+//	dl.TakeN(state, 1)
+//	dl.SetRate(500, time.Second) // pushed by the control plane
+func NewDynamicLimiter(limiter RateLimiter) *DynamicLimiter {
+	dl := &DynamicLimiter{}
+	dl.limiter.Store(&limiter)
+	return dl
+}
+
+// Limiter returns the current configuration.
+func (dl *DynamicLimiter) Limiter() RateLimiter {
+	return *dl.limiter.Load()
+}
+
+// New creates a brand-new, zero-use limiter state with the current burst.
+func (dl *DynamicLimiter) New() *uint64 {
+	return dl.limiter.Load().New()
+}
+
+// TakeN attempts to consume `requests` tokens from `*rl` with the current configuration.
+// See RateLimiter.TakeN.
+func (dl *DynamicLimiter) TakeN(rl *uint64, requests uint16) (int64, bool) {
+	return dl.limiter.Load().TakeN(rl, requests)
+}
+
+// Take1 attempts to consume 1 token from `*rl` with the current configuration. See TakeN.
+func (dl *DynamicLimiter) Take1(rl *uint64) (int64, bool) {
+	return dl.TakeN(rl, 1)
+}
+
+// SetRate sets the refill rate to `req` tokens per `interval`, keeping the burst.
+func (dl *DynamicLimiter) SetRate(req uint16, interval time.Duration) {
+	dl.update(func(s *RateLimiter) {
+		s.rrpm = ratePerMilli(req, interval)
+	})
+}
+
+// SetBurst sets the burst (bucket capacity) to `burst` tokens, keeping the rate.
+func (dl *DynamicLimiter) SetBurst(burst uint16) {
+	dl.update(func(s *RateLimiter) {
+		s.maxreq = burst
+	})
+}
+
+// Set replaces the whole configuration with that of `limiter`, including its options.
+func (dl *DynamicLimiter) Set(limiter RateLimiter) {
+	dl.update(func(s *RateLimiter) {
+		*s = limiter
+	})
+}
+
+// update applies `fn` to a copy of the configuration and publishes it.
+func (dl *DynamicLimiter) update(fn func(*RateLimiter)) {
+	dl.mu.Lock()
+	defer dl.mu.Unlock()
+	next := *dl.limiter.Load()
+	fn(&next)
+	dl.limiter.Store(&next)
+}
@@ -0,0 +1,73 @@
+package limitron
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDynamicLimiter_ShrinkAndGrowBurst(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	dl := NewDynamicLimiter(BuildRateLimiter(10, time.Second, WithClock(clock))) // 1 token per 100ms
+	state := dl.New()
+	dl.TakeN(state, 2)
+
+	// 8 tokens left, capped at the new burst of 5
+	dl.SetBurst(5)
+	if _, ok := dl.TakeN(state, 6); ok {
+		t.Fatal("6 tokens allowed with a burst of 5")
+	}
+	if _, ok := dl.TakeN(state, 5); !ok {
+		t.Fatal("5 tokens denied with a burst of 5")
+	}
+
+	// a larger burst refills at the current rate
+	dl.SetBurst(20)
+	clock.t = clock.t.Add(time.Second)
+	if _, ok := dl.TakeN(state, 10); !ok {
+		t.Fatal("10 tokens denied after a second of refill")
+	}
+	if _, ok := dl.Take1(state); ok {
+		t.Fatal("tokens refilled beyond the elapsed time")
+	}
+}
+
+func TestDynamicLimiter_SetRate(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	dl := NewDynamicLimiter(BuildRateLimiter(10, time.Second, WithClock(clock)))
+	state := dl.New()
+	dl.TakeN(state, 10)
+
+	dl.SetRate(100, time.Second) // 1 token per 10ms
+	clock.t = clock.t.Add(50 * time.Millisecond)
+	if _, ok := dl.TakeN(state, 5); !ok {
+		t.Fatal("5 tokens denied after 50ms at 100/s")
+	}
+	if got := dl.Limiter().maxreq; got != 10 {
+		t.Fatalf("burst = %d after SetRate, want 10", got)
+	}
+}
+
+func TestDynamicLimiter_ConcurrentUpdates(t *testing.T) {
+	dl := NewDynamicLimiter(BuildRateLimiterRps(100))
+	state := dl.New()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				dl.SetBurst(uint16(50 + i*10 + j%10))
+				dl.SetRate(uint16(100+j), time.Second)
+			}
+		}(i)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				dl.Take1(state)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,105 @@
+package limitron
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Errors of the error-returning API (see RateLimiter.TakeNErr).
+var (
+	// ErrExceedsBurst reports requests for more tokens than the burst, which can never
+	// be admitted. A *LimitedError with the maximum RetryAfter matches it with errors.Is.
+	ErrExceedsBurst = errors.New("limitron: requests exceed the burst")
+	// ErrContended reports a take that failed only because concurrent updates of the state
+	// won every CAS retry; the request may be retried right away.
+	ErrContended = errors.New("limitron: limiter state contended")
+)
+
+// LimitedError is returned by error-returning helpers when a request
+// is denied by the rate limiter.
+//
+// RetryAfter holds the suggested wait before the request may be repeated,
+// derived from the wait millis reported by TakeN.
+type LimitedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LimitedError) Error() string {
+	return fmt.Sprintf("limitron: rate limit exceeded, retry after %s", e.RetryAfter)
+}
+
+// Is reports whether the error matches `target`: a *LimitedError for requests that can
+// never be admitted matches ErrExceedsBurst.
+func (e *LimitedError) Is(target error) bool {
+	return target == ErrExceedsBurst && e.RetryAfter == time.Duration(math.MaxInt64)
+}
+
+// TakeNErr is TakeN reporting its outcome as an error, for layers mapping errors to
+// status codes: nil if the tokens were consumed, ErrExceedsBurst if `requests` can never
+// be admitted, ErrContended if concurrent updates won every CAS retry, and otherwise
+// a *LimitedError with the suggested wait.
+//
+// Example:
+//
+//	var limited *LimitedError
+//	switch err := limiter.TakeNErr(state, 1); {
+//	case err == nil:
+//	case errors.As(err, &limited):
+//	    w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(limited.RetryAfter.Seconds()))))
+//	    w.WriteHeader(http.StatusTooManyRequests)
+//	case errors.Is(err, ErrExceedsBurst):
+//	    w.WriteHeader(http.StatusRequestEntityTooLarge)
+//	default: // ErrContended
+//	    w.WriteHeader(http.StatusServiceUnavailable)
+//	}
+func (s RateLimiter) TakeNErr(rl *uint64, requests uint16) error {
+	waitMillis, ok, contended := s.tryTakeNAt(rl, requests, s.nowTicks())
+	s.observe(waitMillis, ok)
+	return takeError(waitMillis, ok, contended)
+}
+
+// Take1Err is TakeNErr for 1 token.
+func (s RateLimiter) Take1Err(rl *uint64) error {
+	return s.TakeNErr(rl, 1)
+}
+
+// TakeNErr is TakeN reporting its outcome as an error, as RateLimiter.TakeNErr does,
+// except that contention is reported as a *LimitedError with a 1ms wait.
+func (kl *KeyedLimiter[K]) TakeNErr(key K, requests uint16) error {
+	waitMillis, ok := kl.TakeN(key, requests)
+	return takeError(waitMillis, ok, false)
+}
+
+// Take1Err is TakeNErr for 1 token.
+func (kl *KeyedLimiter[K]) Take1Err(key K) error {
+	return kl.TakeNErr(key, 1)
+}
+
+// takeError converts the outcome of a take into the errors of TakeNErr.
+func takeError(waitMillis int64, ok bool, contended bool) error {
+	switch {
+	case ok:
+		return nil
+	case contended:
+		return ErrContended
+	case waitMillis == math.MaxInt64:
+		return ErrExceedsBurst
+	}
+	return limitedError(waitMillis)
+}
+
+// limitedError converts the wait millis reported by TakeN into a *LimitedError.
+func limitedError(waitMillis int64) *LimitedError {
+	return &LimitedError{RetryAfter: millisToDuration(waitMillis)}
+}
+
+// millisToDuration converts wait millis into a time.Duration,
+// saturating at the maximum representable duration (TakeN may return math.MaxInt64).
+func millisToDuration(millis int64) time.Duration {
+	if millis > math.MaxInt64/int64(time.Millisecond) {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(millis) * time.Millisecond
+}
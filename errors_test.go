@@ -0,0 +1,56 @@
+package limitron
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_TakeNErr(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	limiter := BuildRateLimiter(10, time.Second, WithClock(clock))
+	state := limiter.New()
+
+	if err := limiter.TakeNErr(state, 10); err != nil {
+		t.Fatalf("TakeNErr(10) = %v, want nil", err)
+	}
+	var limited *LimitedError
+	if err := limiter.Take1Err(state); !errors.As(err, &limited) || limited.RetryAfter < 100*time.Millisecond {
+		t.Fatalf("Take1Err = %v, want a *LimitedError with about 100ms", err)
+	}
+	if err := limiter.TakeNErr(state, 11); !errors.Is(err, ErrExceedsBurst) {
+		t.Fatalf("TakeNErr(11) = %v, want ErrExceedsBurst", err)
+	}
+
+	// no CAS attempt succeeds without retries
+	contended := BuildRateLimiter(10, time.Second, WithRetries(0))
+	if err := contended.Take1Err(contended.New()); !errors.Is(err, ErrContended) {
+		t.Fatalf("Take1Err without retries = %v, want ErrContended", err)
+	}
+}
+
+func TestKeyedLimiter_TakeNErr(t *testing.T) {
+	kl := NewKeyedLimiter[string](BuildRateLimiter(1, time.Hour))
+	if err := kl.Take1Err("k"); err != nil {
+		t.Fatalf("first Take1Err = %v, want nil", err)
+	}
+	var limited *LimitedError
+	if err := kl.Take1Err("k"); !errors.As(err, &limited) {
+		t.Fatalf("second Take1Err = %v, want a *LimitedError", err)
+	}
+	if err := kl.TakeNErr("k", 2); !errors.Is(err, ErrExceedsBurst) {
+		t.Fatalf("TakeNErr(2) = %v, want ErrExceedsBurst", err)
+	}
+}
+
+func TestLimitedError_IsExceedsBurst(t *testing.T) {
+	limiter := BuildRateLimiterRps(5)
+	err := limiter.WaitN(context.Background(), limiter.New(), 6)
+	if !errors.Is(err, ErrExceedsBurst) {
+		t.Fatalf("WaitN over the burst = %v, want an error matching ErrExceedsBurst", err)
+	}
+	if errors.Is(&LimitedError{RetryAfter: time.Second}, ErrExceedsBurst) {
+		t.Fatal("a finite wait matches ErrExceedsBurst")
+	}
+}
@@ -0,0 +1,121 @@
+package limitron
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// EWMA is the name of the EwmaLimiter algorithm in the registry (see NewLimiter).
+const EWMA = "ewma"
+
+func init() {
+	RegisterAlgorithm(EWMA, func(req uint16, interval time.Duration, opts ...Option) Limiter {
+		return BuildEwmaLimiter(req, interval, opts...)
+	})
+}
+
+// ewmaScale is the fixed-point scale of the decayed count in EwmaLimiter states.
+const ewmaScale = math.MaxUint16
+
+// EwmaLimiter limits the exponentially weighted moving average (EWMA) of the request
+// rate: requests are denied while they would raise the smoothed rate over the target
+// of `req` per `interval`, the time constant of the average being `interval`.
+//
+// The average is kept as an exponentially decaying count of the admitted requests:
+// it decays by a factor e every interval, and the target rate corresponds to a count
+// of `req`. Like a token bucket, it admits bursts of up to `req` requests, but
+// their weight fades out gradually instead of being refilled linearly.
+//
+// It stores the entire per-key state in a single uint64 updated lock-free, packed like
+// RateLimiter states: the decayed count as a fraction of `req` in 16-bit fixed point
+// in the upper 16 bits, and the time of the last admission in Unix milliseconds in
+// the lower 48 bits.
+//
+// Options WithClock and WithMetrics apply as for RateLimiter; WithPunitive has no effect.
+type EwmaLimiter struct {
+	// base holds the target count (maxreq), CAS retries, clock and metrics.
+	base RateLimiter
+	// tau is the time constant of the average in milliseconds.
+	tau float64
+}
+
+// BuildEwmaLimiter returns an EwmaLimiter keeping the smoothed rate at most `req`
+// requests per `interval`.
+//
+// Example:
+//
+//	limiter := BuildEwmaLimiter(600, time.Minute) // a smoothed 10 requests per second
+//	state := limiter.New()
+//	if wait, ok := limiter.Take1(state); !ok {
+//	    // retry after `wait` millis
+//	}
+func BuildEwmaLimiter(req uint16, interval time.Duration, opts ...Option) EwmaLimiter {
+	return EwmaLimiter{
+		base: BuildRateLimiter(req, interval, opts...),
+		tau:  float64(max(interval.Milliseconds(), 1)),
+	}
+}
+
+// New creates a brand-new limiter state with no requests counted.
+func (l EwmaLimiter) New() *uint64 {
+	var rl uint64
+	return &rl
+}
+
+// Take1 attempts to admit 1 request. See TakeN.
+func (l EwmaLimiter) Take1(rl *uint64) (int64, bool) {
+	return l.TakeN(rl, 1)
+}
+
+// TakeN attempts to atomically admit `requests` requests against the limiter state `*rl`.
+//
+// It has the same contract as RateLimiter.TakeN: it returns 0, true if the requests
+// are allowed, or the number of millis until the decayed count leaves room for them,
+// and false. If `requests > req`, it returns (math.MaxInt64, false) immediately.
+func (l EwmaLimiter) TakeN(rl *uint64, requests uint16) (int64, bool) {
+	return l.base.observe(l.takeNAt(rl, requests, l.base.nowMillis()))
+}
+
+// Rate returns the smoothed rate of the limiter state `*rl` in requests per second.
+func (l EwmaLimiter) Rate(rl *uint64) float64 {
+	return l.countAt(atomic.LoadUint64(rl), l.base.nowMillis()) / l.tau * 1000
+}
+
+// takeNAt is TakeN without metrics, evaluated at time `now` in Unix milliseconds.
+func (l EwmaLimiter) takeNAt(rl *uint64, requests uint16, now uint64) (int64, bool) {
+	if requests == 0 {
+		return 0, true
+	} else if requests > l.base.maxreq {
+		return math.MaxInt64, false
+	}
+
+	limit := float64(l.base.maxreq)
+	// half a fixed-point unit: counts closer to the limit are rounded to it
+	half := limit / ewmaScale / 2
+	for i := 0; i < l.base.retries; i++ {
+		rlval := atomic.LoadUint64(rl)
+		count := l.countAt(rlval, now)
+		if count+float64(requests) > limit+half {
+			// the count must decay to the room left for the requests
+			room := max(limit-float64(requests), half)
+			return 1 + int64(l.tau*math.Log(count/room)), false
+		}
+		_, ts := unpackUint16Uint48(rlval)
+		count = min(count+float64(requests), limit)
+		if atomic.CompareAndSwapUint64(rl, rlval, packUint16AndUint48(uint16(math.Round(count/limit*ewmaScale)), max(ts, now))) {
+			return 0, true
+		}
+	}
+	return 1, false
+}
+
+// countAt returns the decayed count of the state `rl` at time `now` in Unix milliseconds.
+func (l EwmaLimiter) countAt(rl uint64, now uint64) float64 {
+	fixed, ts := unpackUint16Uint48(rl)
+	count := float64(fixed) / ewmaScale * float64(l.base.maxreq)
+	if now <= ts {
+		return count
+	}
+	return count * math.Exp(-float64(now-ts)/l.tau)
+}
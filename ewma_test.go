@@ -0,0 +1,62 @@
+package limitron
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+var _ Limiter = EwmaLimiter{}
+
+func TestEwmaLimiter_BurstThenDecay(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	l := BuildEwmaLimiter(10, time.Second, WithClock(clock))
+	state := l.New()
+
+	if _, ok := l.TakeN(state, 10); !ok {
+		t.Fatal("burst of 10 denied")
+	}
+	wait, ok := l.Take1(state)
+	if ok {
+		t.Fatal("request allowed over the target")
+	}
+	// 10 * e^(-t/1s) <= 9 after 1s * ln(10/9) = 105ms
+	if wait < 105 || wait > 107 {
+		t.Fatalf("wait = %dms, want about 105ms", wait)
+	}
+	clock.t = clock.t.Add(time.Duration(wait) * time.Millisecond)
+	if _, ok := l.Take1(state); !ok {
+		t.Fatal("request denied after the suggested wait")
+	}
+	if r := l.Rate(state); math.Abs(r-10) > 0.01 {
+		t.Fatalf("Rate() = %v, want about 10/s", r)
+	}
+}
+
+func TestEwmaLimiter_SteadyRateBelowTarget(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	l := BuildEwmaLimiter(100, time.Second, WithClock(clock))
+	state := l.New()
+
+	// 80/s for 10 time constants: never denied, smoothed to about 80/s
+	for i := 0; i < 800; i++ {
+		if _, ok := l.Take1(state); !ok {
+			t.Fatalf("request %d denied at 80%% of the target", i)
+		}
+		clock.t = clock.t.Add(12500 * time.Microsecond)
+	}
+	if r := l.Rate(state); r < 78 || r > 82 {
+		t.Fatalf("Rate() = %v, want about 80/s", r)
+	}
+}
+
+func TestEwmaLimiter_EdgeCases(t *testing.T) {
+	l := BuildEwmaLimiter(2, time.Second)
+	state := l.New()
+	if wait, ok := l.TakeN(state, 0); !ok || wait != 0 {
+		t.Fatalf("TakeN(0) = %d, %v", wait, ok)
+	}
+	if wait, ok := l.TakeN(state, 3); ok || wait != math.MaxInt64 {
+		t.Fatalf("TakeN over the target = %d, %v", wait, ok)
+	}
+}
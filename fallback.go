@@ -0,0 +1,156 @@
+package limitron
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// RemoteLimiter is a limiter keeping its states in a remote backend,
+// such as StoreLimiter or PostgresLimiter.
+type RemoteLimiter interface {
+	TakeN(ctx context.Context, key string, requests uint16) (int64, bool, error)
+}
+
+// FallbackConfig configures a FallbackLimiter.
+type FallbackConfig struct {
+	// Fraction of the limit enforced per key by the in-process fallback, e.g., 1/N
+	// for N application instances. Defaults to 1.
+	Fraction float64
+	// LatencyBudget is the maximum duration of a backend call; slower calls count
+	// as failures and are answered by the fallback. Zero means no budget.
+	LatencyBudget time.Duration
+	// FailureThreshold is the number of consecutive failures that opens the circuit,
+	// sending all calls to the fallback. Defaults to 5.
+	FailureThreshold int
+	// Cooldown is how long the circuit stays open before a single probe call
+	// is sent to the backend again. Defaults to 5 seconds.
+	Cooldown time.Duration
+}
+
+// Circuit states of a FallbackLimiter.
+const (
+	circuitClosed int32 = iota
+	circuitOpen
+	circuitProbing
+)
+
+// FallbackLimiter wraps a RemoteLimiter with a circuit-breaking fallback to
+// an in-process limiter, so that limiting degrades gracefully instead of failing
+// when the backend errors or is slow.
+//
+// Failed and over-budget calls are answered by the fallback. After FailureThreshold
+// consecutive failures the circuit opens and the backend is not called at all;
+// once Cooldown has passed, a single probe call is let through, and its success
+// closes the circuit again (automatic recovery).
+//
+// The fallback keeps its own per-key states at Fraction of the limit; they are not
+// synchronized with the backend.
+//
+// The zero value is not usable; create instances with NewFallbackLimiter.
+// All methods are safe for concurrent use.
+type FallbackLimiter struct {
+	remote RemoteLimiter
+	local  *KeyedLimiter[string]
+	cfg    FallbackConfig
+
+	state     atomic.Int32
+	failures  atomic.Int32
+	openUntil atomic.Int64 // Unix nanoseconds
+}
+
+// NewFallbackLimiter returns a FallbackLimiter calling `remote`, whose limit is `limiter`,
+// and falling back to `limiter` scaled by cfg.Fraction.
+//
+// Example:
+//
+//	limiter := BuildRateLimiter(1000, time.Minute)
+//	remote := NewStoreLimiter(redisStore, limiter)
+//	fl := NewFallbackLimiter(remote, limiter, FallbackConfig{Fraction: 0.25, LatencyBudget: 20 * time.Millisecond})
+func NewFallbackLimiter(remote RemoteLimiter, limiter RateLimiter, cfg FallbackConfig) *FallbackLimiter {
+	if cfg.Fraction <= 0 || cfg.Fraction > 1 {
+		cfg.Fraction = 1
+	}
+	if cfg.FailureThreshold <= 0 {
+		cfg.FailureThreshold = 5
+	}
+	if cfg.Cooldown <= 0 {
+		cfg.Cooldown = 5 * time.Second
+	}
+	return &FallbackLimiter{
+		remote: remote,
+		local:  NewKeyedLimiter[string](limiter.scaled(cfg.Fraction)),
+		cfg:    cfg,
+	}
+}
+
+// TakeN attempts to consume `requests` tokens of `key` from the backend,
+// or from the fallback if the backend is unavailable. See RateLimiter.TakeN.
+//
+// Backend errors are not returned; only the cancellation of ctx is.
+func (fl *FallbackLimiter) TakeN(ctx context.Context, key string, requests uint16) (int64, bool, error) {
+	if !fl.tryRemote() {
+		waitMillis, ok := fl.local.TakeN(key, requests)
+		return waitMillis, ok, nil
+	}
+
+	callCtx := ctx
+	if fl.cfg.LatencyBudget > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, fl.cfg.LatencyBudget)
+		defer cancel()
+	}
+	start := time.Now()
+	waitMillis, ok, err := fl.remote.TakeN(callCtx, key, requests)
+	if err == nil && (fl.cfg.LatencyBudget <= 0 || time.Since(start) <= fl.cfg.LatencyBudget) {
+		fl.succeeded()
+		return waitMillis, ok, nil
+	}
+
+	if ctx.Err() != nil {
+		// the caller gave up, which says nothing about the backend
+		if fl.state.Load() == circuitProbing {
+			fl.state.Store(circuitOpen)
+		}
+		return 0, false, ctx.Err()
+	}
+	fl.failed()
+	waitMillis, ok = fl.local.TakeN(key, requests)
+	return waitMillis, ok, nil
+}
+
+// Take1 attempts to consume 1 token of `key`. See TakeN.
+func (fl *FallbackLimiter) Take1(ctx context.Context, key string) (int64, bool, error) {
+	return fl.TakeN(ctx, key, 1)
+}
+
+// Degraded reports whether the circuit is open, i.e., calls are answered by the fallback.
+func (fl *FallbackLimiter) Degraded() bool {
+	return fl.state.Load() != circuitClosed
+}
+
+// tryRemote reports whether the backend should be called, claiming the probe
+// call if the circuit is open and the cooldown has passed.
+func (fl *FallbackLimiter) tryRemote() bool {
+	switch fl.state.Load() {
+	case circuitClosed:
+		return true
+	case circuitOpen:
+		return time.Now().UnixNano() >= fl.openUntil.Load() &&
+			fl.state.CompareAndSwap(circuitOpen, circuitProbing)
+	default:
+		return false
+	}
+}
+
+func (fl *FallbackLimiter) succeeded() {
+	fl.failures.Store(0)
+	fl.state.Store(circuitClosed)
+}
+
+func (fl *FallbackLimiter) failed() {
+	if fl.failures.Add(1) >= int32(fl.cfg.FailureThreshold) || fl.state.Load() == circuitProbing {
+		fl.openUntil.Store(time.Now().Add(fl.cfg.Cooldown).UnixNano())
+		fl.state.Store(circuitOpen)
+	}
+}
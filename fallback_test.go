@@ -0,0 +1,98 @@
+package limitron
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var (
+	_ RemoteLimiter = (*StoreLimiter)(nil)
+	_ RemoteLimiter = (*PostgresLimiter)(nil)
+)
+
+// flakyRemote is a RemoteLimiter that fails while `down` is set.
+type flakyRemote struct {
+	down  atomic.Bool
+	delay time.Duration
+	calls atomic.Int64
+}
+
+func (r *flakyRemote) TakeN(ctx context.Context, _ string, _ uint16) (int64, bool, error) {
+	r.calls.Add(1)
+	if r.delay > 0 {
+		select {
+		case <-time.After(r.delay):
+		case <-ctx.Done():
+			return 0, false, ctx.Err()
+		}
+	}
+	if r.down.Load() {
+		return 0, false, errors.New("connection refused")
+	}
+	return 0, true, nil
+}
+
+func TestFallbackLimiter_OpensAndRecovers(t *testing.T) {
+	remote := &flakyRemote{}
+	remote.down.Store(true)
+	fl := NewFallbackLimiter(remote, BuildRateLimiter(10, time.Hour), FallbackConfig{
+		Fraction:         0.3,
+		FailureThreshold: 2,
+		Cooldown:         50 * time.Millisecond,
+	})
+	ctx := context.Background()
+
+	admitted := 0
+	for i := 0; i < 10; i++ {
+		_, ok, err := fl.Take1(ctx, "k")
+		if err != nil {
+			t.Fatalf("Take1 %d: %v", i, err)
+		}
+		if ok {
+			admitted++
+		}
+	}
+	if admitted != 3 {
+		t.Fatalf("fallback admitted %d, want 30%% of 10", admitted)
+	}
+	if !fl.Degraded() || remote.calls.Load() != 2 {
+		t.Fatalf("degraded=%v calls=%d, want an open circuit after 2 failures", fl.Degraded(), remote.calls.Load())
+	}
+
+	remote.down.Store(false)
+	time.Sleep(60 * time.Millisecond)
+	if _, ok, _ := fl.Take1(ctx, "k"); !ok || fl.Degraded() {
+		t.Fatalf("probe after cooldown => ok=%v degraded=%v, want recovery", ok, fl.Degraded())
+	}
+}
+
+func TestFallbackLimiter_LatencyBudget(t *testing.T) {
+	remote := &flakyRemote{delay: 50 * time.Millisecond}
+	fl := NewFallbackLimiter(remote, BuildRateLimiter(1, time.Hour), FallbackConfig{LatencyBudget: 5 * time.Millisecond})
+
+	start := time.Now()
+	if _, ok, err := fl.Take1(context.Background(), "k"); err != nil || !ok {
+		t.Fatalf("Take1 => ok=%v err=%v, want a fallback admission", ok, err)
+	}
+	if elapsed := time.Since(start); elapsed > 30*time.Millisecond {
+		t.Fatalf("Take1 took %s, want it cut at the latency budget", elapsed)
+	}
+	if _, ok, _ := fl.Take1(context.Background(), "k"); ok {
+		t.Fatal("fallback should enforce its own limit")
+	}
+}
+
+func TestFallbackLimiter_CallerCancellation(t *testing.T) {
+	fl := NewFallbackLimiter(&flakyRemote{delay: time.Second}, BuildRateLimiterRps(1), FallbackConfig{})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, _, err := fl.Take1(ctx, "k"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if fl.Degraded() {
+		t.Fatal("caller cancellation should not count as a backend failure")
+	}
+}
@@ -0,0 +1,99 @@
+package limitron
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// FixedWindow is the name of the FixedWindowLimiter algorithm in the registry (see NewLimiter).
+const FixedWindow = "fixed-window"
+
+func init() {
+	RegisterAlgorithm(FixedWindow, func(req uint16, interval time.Duration, opts ...Option) Limiter {
+		return BuildFixedWindowLimiter(req, interval, opts...)
+	})
+}
+
+// FixedWindowLimiter is a fixed window counter rate limiter: it allows `req` requests
+// per window of `interval`, and resets the count at the start of every window.
+//
+// Windows are aligned to the Unix epoch, so that windows of a second, a minute or an hour
+// are the calendar (UTC) seconds, minutes and hours, expressing limits such as
+// "N requests per calendar minute" that a continuously refilling token bucket cannot.
+// Note that up to twice the limit may pass around a window boundary.
+//
+// It stores the entire per-key state in a single uint64 updated lock-free, packed like
+// RateLimiter states: the count in the upper 16 bits, and the start of its window
+// in Unix milliseconds in the lower 48 bits.
+//
+// Options WithClock and WithMetrics apply as for RateLimiter; WithPunitive has no effect.
+type FixedWindowLimiter struct {
+	// base holds the limit (maxreq), CAS retries, clock and metrics.
+	base RateLimiter
+	// window is the window length in milliseconds.
+	window uint64
+}
+
+// BuildFixedWindowLimiter returns a FixedWindowLimiter allowing up to `req` requests
+// per window of `interval`. The interval is truncated to whole milliseconds.
+//
+// Example:
+//
+//	limiter := BuildFixedWindowLimiter(60, time.Minute) // 60 requests per calendar minute
+//	state := limiter.New()
+//	if wait, ok := limiter.Take1(state); !ok {
+//	    // the next minute starts in `wait` millis
+//	}
+func BuildFixedWindowLimiter(req uint16, interval time.Duration, opts ...Option) FixedWindowLimiter {
+	return FixedWindowLimiter{
+		base:   BuildRateLimiter(req, interval, opts...),
+		window: uint64(max(interval.Milliseconds(), 1)),
+	}
+}
+
+// New creates a brand-new limiter state with no requests counted.
+func (l FixedWindowLimiter) New() *uint64 {
+	var rl uint64
+	return &rl
+}
+
+// Take1 attempts to count 1 request. See TakeN.
+func (l FixedWindowLimiter) Take1(rl *uint64) (int64, bool) {
+	return l.TakeN(rl, 1)
+}
+
+// TakeN attempts to atomically count `requests` requests in the current window of
+// the limiter state `*rl`.
+//
+// It has the same contract as RateLimiter.TakeN: it returns 0, true if the requests
+// are allowed, or the number of millis until the next window, and false.
+// If `requests > req`, it returns (math.MaxInt64, false) immediately.
+func (l FixedWindowLimiter) TakeN(rl *uint64, requests uint16) (int64, bool) {
+	return l.base.observe(l.takeNAt(rl, requests, l.base.nowMillis()))
+}
+
+// takeNAt is TakeN without metrics, evaluated at time `now` in Unix milliseconds.
+func (l FixedWindowLimiter) takeNAt(rl *uint64, requests uint16, now uint64) (int64, bool) {
+	if requests == 0 {
+		return 0, true
+	} else if requests > l.base.maxreq {
+		return math.MaxInt64, false
+	}
+
+	start := now - now%l.window
+	for i := 0; i < l.base.retries; i++ {
+		rlval := atomic.LoadUint64(rl)
+		count, stored := unpackUint16Uint48(rlval)
+		if stored != start {
+			count = 0
+		}
+		if count+requests > l.base.maxreq {
+			return int64(start + l.window - now), false
+		}
+		if atomic.CompareAndSwapUint64(rl, rlval, packUint16AndUint48(count+requests, start)) {
+			return 0, true
+		}
+	}
+	return 1, false
+}
@@ -0,0 +1,43 @@
+package limitron
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+var _ Limiter = FixedWindowLimiter{}
+
+func TestFixedWindowLimiter_ResetsAtCalendarMinute(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 45, 0, time.UTC)}
+	l := BuildFixedWindowLimiter(3, time.Minute, WithClock(clock))
+	state := l.New()
+
+	if _, ok := l.TakeN(state, 3); !ok {
+		t.Fatal("3 requests denied")
+	}
+	wait, ok := l.Take1(state)
+	if ok || wait != 15_000 {
+		t.Fatalf("Take1 = %d, %v, want 15000 (until 10:01:00), false", wait, ok)
+	}
+
+	clock.t = time.Date(2026, 5, 1, 10, 0, 59, 999e6, time.UTC)
+	if _, ok := l.Take1(state); ok {
+		t.Fatal("request allowed before the minute ended")
+	}
+	clock.t = time.Date(2026, 5, 1, 10, 1, 0, 0, time.UTC)
+	if _, ok := l.TakeN(state, 3); !ok {
+		t.Fatal("full limit denied at the start of the next minute")
+	}
+}
+
+func TestFixedWindowLimiter_EdgeCases(t *testing.T) {
+	l := BuildFixedWindowLimiter(2, time.Second)
+	state := l.New()
+	if wait, ok := l.TakeN(state, 0); !ok || wait != 0 {
+		t.Fatalf("TakeN(0) = %d, %v", wait, ok)
+	}
+	if wait, ok := l.TakeN(state, 3); ok || wait != math.MaxInt64 {
+		t.Fatalf("TakeN over the limit = %d, %v", wait, ok)
+	}
+}
@@ -0,0 +1,96 @@
+package limitron
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// GCRA is the name of the GcraLimiter algorithm in the registry (see NewLimiter).
+const GCRA = "gcra"
+
+func init() {
+	RegisterAlgorithm(GCRA, func(req uint16, interval time.Duration, opts ...Option) Limiter {
+		return BuildGcraLimiter(req, interval, opts...)
+	})
+}
+
+// GcraLimiter is a rate limiter implementing the Generic Cell Rate Algorithm.
+// Like RateLimiter, it stores the entire per-key state in a single uint64 updated
+// lock-free, and has the same New/TakeN shape.
+//
+// The state holds the theoretical arrival time (TAT) of the next request, in Unix
+// microseconds. Every request moves the TAT forward by the emission interval
+// (interval / req), and a request is allowed while the TAT stays within a burst
+// of `req` emission intervals ahead of now. Unlike the token bucket, whose refill
+// is truncated to whole tokens per millisecond, admission is spaced exactly
+// by the emission interval.
+//
+// Options WithClock and WithMetrics apply as for RateLimiter; WithPunitive has no effect.
+type GcraLimiter struct {
+	// base holds the burst, CAS retries, clock and metrics.
+	base RateLimiter
+	// emission is the emission interval in microseconds.
+	emission float64
+}
+
+// BuildGcraLimiter returns a GcraLimiter that allows up to `req` requests per `interval`,
+// with a burst capacity of `req`.
+//
+// Example:
+//
+//	limiter := BuildGcraLimiter(100, time.Second) // one request every 10ms, bursts of 100
+//	state := limiter.New()
+//	if wait, ok := limiter.Take1(state); !ok {
+//	    // retry after `wait` millis
+//	}
+func BuildGcraLimiter(req uint16, interval time.Duration, opts ...Option) GcraLimiter {
+	return GcraLimiter{
+		base:     BuildRateLimiter(req, interval, opts...),
+		emission: float64(interval.Microseconds()) / float64(req),
+	}
+}
+
+// New creates a brand-new limiter state, allowing a full burst.
+func (g GcraLimiter) New() *uint64 {
+	var tat uint64
+	return &tat
+}
+
+// Take1 attempts to consume 1 request. See TakeN.
+func (g GcraLimiter) Take1(rl *uint64) (int64, bool) {
+	return g.TakeN(rl, 1)
+}
+
+// TakeN attempts to atomically admit `requests` requests against the limiter state `*rl`.
+//
+// It has the same contract as RateLimiter.TakeN: it returns 0, true if the requests
+// are allowed, or the number of millis to wait before they would be, and false.
+// If `requests > req`, it returns (math.MaxInt64, false) immediately.
+func (g GcraLimiter) TakeN(rl *uint64, requests uint16) (int64, bool) {
+	return g.base.observe(g.takeNAt(rl, requests, uint64(g.base.now().UnixMicro())))
+}
+
+// takeNAt is TakeN without metrics, evaluated at time `now` in Unix microseconds.
+func (g GcraLimiter) takeNAt(rl *uint64, requests uint16, now uint64) (int64, bool) {
+	if requests == 0 {
+		return 0, true
+	} else if requests > g.base.maxreq {
+		return math.MaxInt64, false
+	}
+
+	// the TAT may run at most a burst of emission intervals ahead of now
+	limit := g.emission * float64(g.base.maxreq)
+	for i := 0; i < g.base.retries; i++ {
+		tat := atomic.LoadUint64(rl)
+		newTat := max(tat, now) + uint64(g.emission*float64(requests))
+		if ahead := float64(newTat - now); ahead > limit {
+			// microseconds until the TAT falls back within the limit, rounded up to millis
+			return 1 + int64((ahead-limit)/1000), false
+		}
+		if atomic.CompareAndSwapUint64(rl, tat, newTat) {
+			return 0, true
+		}
+	}
+	return 1, false
+}
@@ -0,0 +1,84 @@
+package limitron
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+var _ Limiter = GcraLimiter{}
+
+func TestGcraLimiter_BurstThenSpacing(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)}
+	g := BuildGcraLimiter(4, 100*time.Millisecond, WithClock(clock)) // one per 25ms
+	state := g.New()
+
+	for i := 0; i < 4; i++ {
+		if _, ok := g.Take1(state); !ok {
+			t.Fatalf("burst request %d denied", i)
+		}
+	}
+	wait, ok := g.Take1(state)
+	if ok {
+		t.Fatal("request over the burst allowed")
+	}
+	if wait < 25 || wait > 26 {
+		t.Fatalf("wait = %dms, want about one emission interval (25ms)", wait)
+	}
+
+	clock.t = clock.t.Add(10 * time.Millisecond)
+	if _, ok := g.Take1(state); ok {
+		t.Fatal("request allowed before the emission interval elapsed")
+	}
+	clock.t = clock.t.Add(15 * time.Millisecond)
+	if _, ok := g.Take1(state); !ok {
+		t.Fatal("request denied after the emission interval elapsed")
+	}
+	if _, ok := g.Take1(state); ok {
+		t.Fatal("second request allowed within one emission interval")
+	}
+}
+
+func TestGcraLimiter_NoTruncationDrift(t *testing.T) {
+	// 3 requests per 10ms: the emission interval is 3.33ms, which a whole-millisecond
+	// token refill cannot represent exactly
+	clock := &testClock{t: time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)}
+	g := BuildGcraLimiter(3, 10*time.Millisecond, WithClock(clock))
+	state := g.New()
+	g.TakeN(state, 3)
+
+	allowed := 0
+	for i := 0; i < 1000; i++ {
+		clock.t = clock.t.Add(time.Millisecond)
+		if _, ok := g.Take1(state); ok {
+			allowed++
+		}
+	}
+	if allowed < 299 || allowed > 300 {
+		t.Fatalf("allowed %d requests in 1s at 300/s", allowed)
+	}
+}
+
+func TestGcraLimiter_EdgeCases(t *testing.T) {
+	g := BuildGcraLimiter(2, time.Second)
+	state := g.New()
+	if wait, ok := g.TakeN(state, 0); !ok || wait != 0 {
+		t.Fatalf("TakeN(0) = %d, %v", wait, ok)
+	}
+	if wait, ok := g.TakeN(state, 3); ok || wait != math.MaxInt64 {
+		t.Fatalf("TakeN over the burst = %d, %v", wait, ok)
+	}
+	if _, ok := g.TakeN(state, 2); !ok {
+		t.Fatal("full burst denied")
+	}
+}
+
+func TestNewLimiter_GCRA(t *testing.T) {
+	limiter, err := NewLimiter(GCRA, 1, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := limiter.(GcraLimiter); !ok {
+		t.Fatalf("NewLimiter(GCRA) = %T", limiter)
+	}
+}
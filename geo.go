@@ -0,0 +1,131 @@
+package limitron
+
+import (
+	"net/netip"
+	"sync"
+)
+
+// GeoInfo is the location of a client IP address as reported by a GeoLookup.
+type GeoInfo struct {
+	// Country is the ISO 3166-1 alpha-2 country code, e.g., "DE".
+	Country string
+	// ASN is the autonomous system number of the network announcing the address.
+	ASN uint32
+}
+
+// GeoLookup maps client IP addresses to their country and ASN,
+// e.g., backed by a MaxMind or IPinfo database.
+// Implementations must be safe for concurrent use.
+type GeoLookup interface {
+	Lookup(ip netip.Addr) (GeoInfo, bool)
+}
+
+// GeoRule is the limit applied to clients of a country or ASN.
+type GeoRule struct {
+	// Limiter is the limit to apply.
+	Limiter RateLimiter
+	// Shared makes all addresses of the country or ASN share a single state,
+	// instead of each address having its own. This is the usual way to throttle
+	// datacenter-originated scraping spread over many addresses.
+	Shared bool
+}
+
+// GeoLimiter applies per-country and per-ASN limiter configurations to client IP addresses.
+//
+// Rules are resolved by ASN first, then by country; addresses matching no rule
+// (or not found by the lookup) get the default limiter, per address.
+//
+// The zero value is not usable; create instances with NewGeoLimiter.
+// All methods are safe for concurrent use.
+type GeoLimiter struct {
+	lookup   GeoLookup
+	fallback *KeyedLimiter[netip.Addr]
+
+	mu        sync.RWMutex
+	byASN     map[uint32]*geoRuleState
+	byCountry map[string]*geoRuleState
+}
+
+// geoRuleState is a GeoRule with its states.
+type geoRuleState struct {
+	rule   GeoRule
+	shared *uint64
+	perIP  *KeyedLimiter[netip.Addr]
+}
+
+// NewGeoLimiter returns a GeoLimiter resolving addresses with `lookup`
+// and applying `fallback` to addresses matching no rule.
+//
+// Example:
+//
+//	geo := NewGeoLimiter(lookup, BuildRateLimiterRps(20))
+//	geo.SetASNRule(16509, GeoRule{Limiter: BuildRateLimiterRps(50), Shared: true}) // a cloud provider
+//	geo.SetCountryRule("XX", GeoRule{Limiter: BuildRateLimiterRps(2)})
+//	_, ok := geo.Take1(clientIP)
+func NewGeoLimiter(lookup GeoLookup, fallback RateLimiter) *GeoLimiter {
+	return &GeoLimiter{
+		lookup:    lookup,
+		fallback:  NewKeyedLimiter[netip.Addr](fallback),
+		byASN:     make(map[uint32]*geoRuleState),
+		byCountry: make(map[string]*geoRuleState),
+	}
+}
+
+// SetASNRule sets the rule for addresses announced by `asn`, replacing
+// any previous rule and its states.
+func (g *GeoLimiter) SetASNRule(asn uint32, rule GeoRule) {
+	g.mu.Lock()
+	g.byASN[asn] = newGeoRuleState(rule)
+	g.mu.Unlock()
+}
+
+// SetCountryRule sets the rule for addresses located in `country`, replacing
+// any previous rule and its states.
+func (g *GeoLimiter) SetCountryRule(country string, rule GeoRule) {
+	g.mu.Lock()
+	g.byCountry[country] = newGeoRuleState(rule)
+	g.mu.Unlock()
+}
+
+// TakeN attempts to consume `requests` tokens for client address `ip`
+// under the rule matching its location. See RateLimiter.TakeN.
+func (g *GeoLimiter) TakeN(ip netip.Addr, requests uint16) (int64, bool) {
+	ip = ip.Unmap()
+	if rs := g.rule(ip); rs != nil {
+		if rs.rule.Shared {
+			return rs.rule.Limiter.TakeN(rs.shared, requests)
+		}
+		return rs.perIP.TakeN(ip, requests)
+	}
+	return g.fallback.TakeN(ip, requests)
+}
+
+// Take1 attempts to consume 1 token for client address `ip`. See TakeN.
+func (g *GeoLimiter) Take1(ip netip.Addr) (int64, bool) {
+	return g.TakeN(ip, 1)
+}
+
+// rule returns the rule state matching `ip`, or nil.
+func (g *GeoLimiter) rule(ip netip.Addr) *geoRuleState {
+	info, ok := g.lookup.Lookup(ip)
+	if !ok {
+		return nil
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if rs, ok := g.byASN[info.ASN]; ok {
+		return rs
+	}
+	return g.byCountry[info.Country]
+}
+
+func newGeoRuleState(rule GeoRule) *geoRuleState {
+	rs := &geoRuleState{rule: rule}
+	if rule.Shared {
+		rs.shared = rule.Limiter.New()
+	} else {
+		rs.perIP = NewKeyedLimiter[netip.Addr](rule.Limiter)
+	}
+	return rs
+}
@@ -0,0 +1,59 @@
+package limitron
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+)
+
+type staticGeo map[netip.Addr]GeoInfo
+
+func (s staticGeo) Lookup(ip netip.Addr) (GeoInfo, bool) {
+	info, ok := s[ip]
+	return info, ok
+}
+
+func TestGeoLimiter_Rules(t *testing.T) {
+	dc1 := netip.MustParseAddr("203.0.113.1")
+	dc2 := netip.MustParseAddr("203.0.113.2")
+	home := netip.MustParseAddr("198.51.100.7")
+	unknown := netip.MustParseAddr("192.0.2.9")
+
+	geo := NewGeoLimiter(staticGeo{
+		dc1:  {Country: "US", ASN: 64500},
+		dc2:  {Country: "US", ASN: 64500},
+		home: {Country: "DE", ASN: 64501},
+	}, BuildRateLimiter(3, time.Hour))
+	geo.SetASNRule(64500, GeoRule{Limiter: BuildRateLimiter(2, time.Hour), Shared: true})
+	geo.SetCountryRule("DE", GeoRule{Limiter: BuildRateLimiter(1, time.Hour)})
+
+	// shared ASN budget across addresses
+	if _, ok := geo.Take1(dc1); !ok {
+		t.Fatal("dc1: first take should succeed")
+	}
+	if _, ok := geo.Take1(dc2); !ok {
+		t.Fatal("dc2: second take of the shared ASN budget should succeed")
+	}
+	if _, ok := geo.Take1(dc1); ok {
+		t.Fatal("shared ASN budget should be exhausted")
+	}
+
+	// per-IP country rule
+	if _, ok := geo.Take1(home); !ok {
+		t.Fatal("home: first take should succeed")
+	}
+	if _, ok := geo.Take1(home); ok {
+		t.Fatal("home: country rule should allow only 1")
+	}
+
+	// fallback for unknown addresses, also for IPv4-mapped IPv6
+	mapped := netip.AddrFrom16(unknown.As16())
+	for i := 0; i < 3; i++ {
+		if _, ok := geo.Take1(mapped); !ok {
+			t.Fatalf("unknown: take %d should succeed under the fallback", i)
+		}
+	}
+	if _, ok := geo.Take1(unknown); ok {
+		t.Fatal("unmapped and mapped forms of an address should share a state")
+	}
+}
@@ -0,0 +1,34 @@
+package limitron
+
+import "time"
+
+// WithGracePeriod grants newly created keys a grace window of duration `d`
+// during which the `relaxed` limiter applies instead of the regular one,
+// reducing false positives for legitimate new users (e.g., many users behind a shared NAT
+// showing up at once). Pass a zero RateLimiter as `relaxed` to disable limiting
+// entirely during the grace window.
+//
+// The relaxed limiter works on the key's regular state, so when the grace window
+// ends, the key continues with the tokens it has left, capped at the regular burst.
+//
+// Example:
+//
+//	kl := NewKeyedLimiter[string](BuildRateLimiterRps(5),
+//	    WithGracePeriod[string](time.Minute, BuildRateLimiterRps(20)))
+func WithGracePeriod[K comparable](d time.Duration, relaxed RateLimiter) KeyedOption[K] {
+	return func(kl *KeyedLimiter[K]) {
+		kl.grace = uint64(max(d.Milliseconds(), 0))
+		kl.graceLimiter = relaxed
+	}
+}
+
+// limiterFor returns the limiter applying to entry `e` of `key` right now, including its tier
+// and any warm-up and boost. A zero limiter (no burst) means that no limit applies.
+func (kl *KeyedLimiter[K]) limiterFor(key K, e *keyedEntry) RateLimiter {
+	limiter := kl.limiterOf(key)
+	now := kl.limiter.nowMillis()
+	if kl.grace > 0 && now < e.created+kl.grace {
+		limiter = kl.graceLimiter
+	}
+	return kl.boosted(e, kl.warmedUp(e, limiter, now))
+}
@@ -0,0 +1,41 @@
+package limitron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyedLimiter_GracePeriodRelaxed(t *testing.T) {
+	kl := NewKeyedLimiter(BuildRateLimiter(2, time.Hour),
+		WithGracePeriod[string](80*time.Millisecond, BuildRateLimiter(5, time.Hour)))
+
+	for i := 0; i < 5; i++ {
+		if _, ok := kl.Take1("new"); !ok {
+			t.Fatalf("take %d within grace should use the relaxed limit", i)
+		}
+	}
+	if _, ok := kl.Take1("new"); ok {
+		t.Fatal("relaxed limit should still apply during grace")
+	}
+}
+
+func TestKeyedLimiter_GracePeriodUnlimitedThenEnforced(t *testing.T) {
+	kl := NewKeyedLimiter(BuildRateLimiter(2, time.Hour),
+		WithGracePeriod[string](50*time.Millisecond, RateLimiter{}))
+
+	for i := 0; i < 100; i++ {
+		if _, ok := kl.Take1("new"); !ok {
+			t.Fatalf("take %d within unlimited grace should succeed", i)
+		}
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		if _, ok := kl.Take1("new"); !ok {
+			t.Fatalf("take %d after grace should succeed within the regular burst", i)
+		}
+	}
+	if _, ok := kl.Take1("new"); ok {
+		t.Fatal("regular limit should apply after grace")
+	}
+}
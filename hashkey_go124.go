@@ -0,0 +1,11 @@
+//go:build go1.24
+
+package limitron
+
+import "hash/maphash"
+
+// hashComparable hashes a key of any comparable type by value, consistently with ==,
+// without allocating.
+func hashComparable[K comparable](seed maphash.Seed, key K) uint64 {
+	return maphash.Comparable(seed, key)
+}
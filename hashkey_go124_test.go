@@ -0,0 +1,17 @@
+//go:build go1.24
+
+package limitron
+
+import (
+	"hash/maphash"
+	"testing"
+)
+
+func TestHashKey_DoesNotAllocate(t *testing.T) {
+	seed := maphash.MakeSeed()
+	type pair struct{ a, b int }
+	key := pair{1, 2}
+	if n := testing.AllocsPerRun(100, func() { hashKey(seed, key) }); n != 0 {
+		t.Fatalf("hashing a struct key allocates %v times", n)
+	}
+}
@@ -0,0 +1,65 @@
+package limitron
+
+// HierarchicalLimiter limits child keys by their own buckets and by the shared bucket
+// of their parent key at once, e.g., per-endpoint children under a per-tenant parent.
+// A request is admitted only if both the child and the parent allow it; when the parent
+// denies, the child's tokens are returned, so that a denial never leaks child tokens.
+//
+// Children and parents live in their own KeyedLimiters, with all of their options.
+// Use HTBClass instead for a fixed tree of classes borrowing unused capacity.
+//
+// The zero value is not usable; create instances with NewHierarchicalLimiter.
+// All methods are safe for concurrent use.
+type HierarchicalLimiter[C, P comparable] struct {
+	child    *KeyedLimiter[C]
+	parent   *KeyedLimiter[P]
+	parentOf func(C) P
+}
+
+// NewHierarchicalLimiter returns a HierarchicalLimiter limiting every child key by
+// `child`, and by `parent` under the parent key returned by `parentOf`.
+//
+// Example:
+//
+//	type endpointKey struct{ tenant, endpoint string }
+//	h := NewHierarchicalLimiter(
+//	    NewKeyedLimiter[endpointKey](BuildRateLimiterRps(50)), // per endpoint
+//	    NewKeyedLimiter[string](BuildRateLimiterRps(200)),     // per tenant
+//	    func(k endpointKey) string { return k.tenant },
+//	)
+//	if _, ok := h.Take1(endpointKey{tenant, "/search"}); !ok {
+//	    // rate limited
+//	}
+func NewHierarchicalLimiter[C, P comparable](child *KeyedLimiter[C], parent *KeyedLimiter[P], parentOf func(C) P) *HierarchicalLimiter[C, P] {
+	return &HierarchicalLimiter[C, P]{child: child, parent: parent, parentOf: parentOf}
+}
+
+// TakeN attempts to consume `requests` tokens from the bucket of `key` and from
+// the bucket of its parent, atomically with respect to denials: either both are
+// consumed, or none is. Returns the wait of the denying bucket. See RateLimiter.TakeN.
+func (h *HierarchicalLimiter[C, P]) TakeN(key C, requests uint16) (int64, bool) {
+	waitMillis, ok := h.child.TakeN(key, requests)
+	if !ok {
+		return waitMillis, false
+	}
+	if waitMillis, ok = h.parent.TakeN(h.parentOf(key), requests); !ok {
+		h.child.ReturnN(key, requests)
+		return waitMillis, false
+	}
+	return 0, true
+}
+
+// Take1 attempts to consume 1 token from the bucket of `key` and of its parent. See TakeN.
+func (h *HierarchicalLimiter[C, P]) Take1(key C) (int64, bool) {
+	return h.TakeN(key, 1)
+}
+
+// Child returns the limiter of the child keys.
+func (h *HierarchicalLimiter[C, P]) Child() *KeyedLimiter[C] {
+	return h.child
+}
+
+// Parent returns the limiter of the parent keys.
+func (h *HierarchicalLimiter[C, P]) Parent() *KeyedLimiter[P] {
+	return h.parent
+}
@@ -0,0 +1,59 @@
+package limitron
+
+import (
+	"testing"
+	"time"
+)
+
+type testEndpoint struct{ tenant, endpoint string }
+
+func newTestHierarchy() *HierarchicalLimiter[testEndpoint, string] {
+	return NewHierarchicalLimiter(
+		NewKeyedLimiter[testEndpoint](BuildRateLimiter(3, time.Hour)),
+		NewKeyedLimiter[string](BuildRateLimiter(5, time.Hour)),
+		func(k testEndpoint) string { return k.tenant },
+	)
+}
+
+func TestHierarchicalLimiter_ChildAndParent(t *testing.T) {
+	h := newTestHierarchy()
+	search := testEndpoint{"acme", "/search"}
+	orders := testEndpoint{"acme", "/orders"}
+
+	for i := 0; i < 3; i++ {
+		if _, ok := h.Take1(search); !ok {
+			t.Fatalf("search request %d denied", i)
+		}
+	}
+	if _, ok := h.Take1(search); ok {
+		t.Fatal("search request allowed over the endpoint limit")
+	}
+	// 2 tokens left in the tenant's bucket
+	for i := 0; i < 2; i++ {
+		if _, ok := h.Take1(orders); !ok {
+			t.Fatalf("orders request %d denied", i)
+		}
+	}
+	if _, ok := h.Take1(orders); ok {
+		t.Fatal("orders request allowed over the tenant limit")
+	}
+	// another tenant is not affected
+	if _, ok := h.Take1(testEndpoint{"globex", "/orders"}); !ok {
+		t.Fatal("request of another tenant denied")
+	}
+}
+
+func TestHierarchicalLimiter_ParentDenialReturnsChildTokens(t *testing.T) {
+	h := newTestHierarchy()
+	h.Parent().TakeN("acme", 5)
+
+	orders := testEndpoint{"acme", "/orders"}
+	for i := 0; i < 10; i++ {
+		if _, ok := h.Take1(orders); ok {
+			t.Fatal("request allowed with the tenant bucket empty")
+		}
+	}
+	if wait, ok := h.Child().Peek(orders, 3); !ok {
+		t.Fatalf("endpoint bucket leaked tokens on parent denials, wait %d", wait)
+	}
+}
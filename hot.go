@@ -0,0 +1,89 @@
+package limitron
+
+import (
+	"math/bits"
+	"sync/atomic"
+)
+
+// hotMaxHits caps the hit count of a hot slot, so that hits on a saturated slot
+// are read-only and do not bounce its cache line between cores.
+const hotMaxHits = 16
+
+// hotKeys is the first tier of a two-tier KeyedLimiter: a small direct-mapped,
+// lock-free array caching the entries of the most frequently used keys in front of
+// the sharded map, which remains the authoritative store of all entries.
+//
+// Each slot keeps a saturating hit count of its key. A miss on another key mapping
+// to the same slot decrements the count; once it reaches zero, the missing key
+// replaces the occupant. Hot keys thus stay promoted, while keys that cool down are
+// demoted by the traffic of others.
+type hotKeys[K comparable] struct {
+	slots []atomic.Pointer[hotEntry[K]]
+	mask  uint64
+}
+
+// hotEntry is the immutable occupant of a hot slot, apart from its hit count.
+type hotEntry[K comparable] struct {
+	key  K
+	e    *keyedEntry
+	hits atomic.Int32
+}
+
+// WithHotKeys puts a lock-free array of `slots` entries (rounded up to a power of two)
+// in front of the sharded map of a KeyedLimiter, caching the entries of the hottest
+// keys. Lookups of cached keys take no lock at all, which improves latency when
+// a few keys generate most of the traffic. Keys are promoted and demoted automatically.
+//
+// A few hundred slots are usually enough; the array costs about 16 bytes per slot.
+//
+// Example:
+//
+//	kl := NewKeyedLimiter[string](limiter, WithHotKeys[string](256))
+func WithHotKeys[K comparable](slots int) KeyedOption[K] {
+	return func(kl *KeyedLimiter[K]) {
+		if slots <= 0 {
+			kl.hot = nil
+			return
+		}
+		n := 1 << bits.Len(uint(slots-1))
+		kl.hot = &hotKeys[K]{slots: make([]atomic.Pointer[hotEntry[K]], n), mask: uint64(n - 1)}
+	}
+}
+
+// slot returns the slot of a key with hash `h`. It uses other hash bits than shard selection.
+func (hk *hotKeys[K]) slot(h uint64) *atomic.Pointer[hotEntry[K]] {
+	return &hk.slots[(h>>32)&hk.mask]
+}
+
+// get returns the cached entry of `key`, or nil.
+func (hk *hotKeys[K]) get(key K, h uint64) *keyedEntry {
+	he := hk.slot(h).Load()
+	if he == nil || he.key != key {
+		return nil
+	}
+	if he.hits.Load() < hotMaxHits {
+		he.hits.Add(1)
+	}
+	return he.e
+}
+
+// miss records a lookup of `key`, with entry `e`, that was not served by its slot,
+// promoting it if the slot is free or its occupant cooled down. Reports whether it promoted `key`.
+func (hk *hotKeys[K]) miss(key K, h uint64, e *keyedEntry) bool {
+	slot := hk.slot(h)
+	he := slot.Load()
+	if he != nil && he.hits.Add(-1) > 0 {
+		return false
+	}
+	promoted := &hotEntry[K]{key: key, e: e}
+	promoted.hits.Store(1)
+	return slot.CompareAndSwap(he, promoted)
+}
+
+// remove drops `e` from the slot of `key`, after the entry was removed from the map.
+func (hk *hotKeys[K]) remove(h uint64, e *keyedEntry) {
+	slot := hk.slot(h)
+	if he := slot.Load(); he != nil && he.e == e {
+		slot.CompareAndSwap(he, nil)
+	}
+}
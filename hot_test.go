@@ -0,0 +1,88 @@
+package limitron
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestKeyedLimiter_HotKeysPromotion(t *testing.T) {
+	kl := NewKeyedLimiter[string](BuildRateLimiterRps(1000), WithHotKeys[string](1))
+	if len(kl.hot.slots) != 1 {
+		t.Fatalf("slots = %d, want 1", len(kl.hot.slots))
+	}
+
+	for i := 0; i < 10; i++ {
+		kl.Take1("hot")
+	}
+	if he := kl.hot.slots[0].Load(); he == nil || he.key != "hot" {
+		t.Fatalf("hot key not promoted")
+	}
+
+	// a few lookups of a colder key do not demote the hot one
+	kl.Take1("cold")
+	kl.Take1("cold")
+	if he := kl.hot.slots[0].Load(); he.key != "hot" {
+		t.Fatalf("hot key demoted by a cold one")
+	}
+
+	// once the hot key cools down, the other key takes over
+	for i := 0; i < 2*hotMaxHits; i++ {
+		kl.Take1("cold")
+	}
+	if he := kl.hot.slots[0].Load(); he.key != "cold" {
+		t.Fatalf("slot holds %q, want cold", he.key)
+	}
+
+	// both tiers share the same states
+	kl2 := NewKeyedLimiter[string](BuildRateLimiter(5, time.Hour), WithHotKeys[string](4))
+	for i := 0; i < 5; i++ {
+		if _, ok := kl2.Take1("k"); !ok {
+			t.Fatalf("take %d denied", i)
+		}
+	}
+	if _, ok := kl2.Take1("k"); ok {
+		t.Fatalf("hot tier allowed more than the burst")
+	}
+}
+
+func TestKeyedLimiter_HotKeysEviction(t *testing.T) {
+	kl := NewKeyedLimiter[string](BuildRateLimiter(2, time.Hour), WithHotKeys[string](8))
+	kl.Take1("k")
+	kl.Take1("k")
+	time.Sleep(20 * time.Millisecond)
+	kl.EvictIdle(10 * time.Millisecond)
+
+	// the evicted key starts over instead of using a stale cached entry
+	if _, ok := kl.TakeN("k", 2); !ok {
+		t.Fatalf("evicted key did not start over")
+	}
+	if kl.Len() != 1 {
+		t.Fatalf("Len() = %d, want 1", kl.Len())
+	}
+}
+
+func TestKeyedLimiter_HotKeysConcurrent(t *testing.T) {
+	kl := NewKeyedLimiter[string](BuildRateLimiter(1000, time.Hour), WithHotKeys[string](2))
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				kl.Take1(fmt.Sprint("k", (g+i)%4))
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	total := 0
+	for i := 0; i < 4; i++ {
+		d := kl.TakeNResult(fmt.Sprint("k", i), 0)
+		total += int(d.Limit - d.Remaining)
+	}
+	if total != 800 {
+		t.Fatalf("consumed %d tokens in total, want 800", total)
+	}
+}
@@ -0,0 +1,105 @@
+package limitron
+
+// HTBClass is a node of an HTB-style (hierarchical token bucket) tree, in which
+// classes have a guaranteed rate and may borrow unused capacity from their
+// ancestors up to an optional ceiling. It is useful for fair sharing among
+// tenants under one global cap.
+//
+// Every class owns two limiter states:
+//   - rate: the assured rate of the class. Traffic within it is always admitted
+//     (subject to the ceiling) and is also charged to all ancestors on a
+//     best-effort basis, so that an ancestor's remaining tokens reflect its unused capacity.
+//   - ceil: the maximum rate of the class including borrowed capacity. Optional.
+//
+// When a class runs out of its own tokens, it borrows from its parent, which in turn
+// lends from its own rate or borrows from its parent, up to the root.
+// A denied borrow rolls back any ceiling tokens consumed on the way, so a denial
+// never leaks tokens.
+//
+// Build the whole tree with NewHTBRoot and AddChild before using it concurrently;
+// TakeN is safe for concurrent use, AddChild is not.
+type HTBClass struct {
+	parent *HTBClass
+
+	rate      RateLimiter
+	rateState *uint64
+
+	hasCeil   bool
+	ceil      RateLimiter
+	ceilState *uint64
+}
+
+// NewHTBRoot creates the root class of an HTB tree. Its `rate` is the global cap
+// shared by all descendants.
+//
+// Example:
+//
+//	root := NewHTBRoot(BuildRateLimiterRps(1000))
+//	free := root.AddChild(BuildRateLimiterRps(100), BuildRateLimiterRps(500))
+//	paid := root.AddChild(BuildRateLimiterRps(600), BuildRateLimiterRps(1000))
+//	_, ok := paid.TakeN(1)
+func NewHTBRoot(rate RateLimiter) *HTBClass {
+	return &HTBClass{
+		rate:      rate,
+		rateState: rate.New(),
+	}
+}
+
+// AddChild adds a class with assured `rate` and maximum `ceil` rate under c.
+// Pass a zero RateLimiter as `ceil` to let the class borrow without its own ceiling.
+func (c *HTBClass) AddChild(rate, ceil RateLimiter) *HTBClass {
+	child := &HTBClass{
+		parent:    c,
+		rate:      rate,
+		rateState: rate.New(),
+	}
+	if ceil.maxreq > 0 {
+		child.hasCeil = true
+		child.ceil = ceil
+		child.ceilState = ceil.New()
+	}
+	return child
+}
+
+// TakeN attempts to consume `requests` tokens for class c, first from its assured rate,
+// then by borrowing from its ancestors.
+//
+// Returns the same (waitMillis, ok) pair as RateLimiter.TakeN. When denied,
+// the wait is the shortest of the waits reported along the borrowing chain.
+func (c *HTBClass) TakeN(requests uint16) (int64, bool) {
+	if requests == 0 {
+		return 0, true
+	}
+
+	if c.hasCeil {
+		if waitMillis, ok := c.ceil.TakeN(c.ceilState, requests); !ok {
+			return waitMillis, false
+		}
+	}
+
+	waitMillis, ok := c.rate.TakeN(c.rateState, requests)
+	if ok {
+		for p := c.parent; p != nil; p = p.parent {
+			p.rate.drainN(p.rateState, requests)
+		}
+		return 0, true
+	}
+
+	if c.parent != nil {
+		parentWait, parentOk := c.parent.TakeN(requests)
+		if parentOk {
+			return 0, true
+		}
+		waitMillis = min(waitMillis, parentWait)
+	}
+
+	if c.hasCeil {
+		c.ceil.ReturnN(c.ceilState, requests)
+	}
+	return waitMillis, false
+}
+
+// Take1 attempts to consume 1 token for class c. See TakeN.
+func (c *HTBClass) Take1() (int64, bool) {
+	return c.TakeN(1)
+}
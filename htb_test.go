@@ -0,0 +1,71 @@
+package limitron
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestHTB_BorrowUpToCeil(t *testing.T) {
+	root := NewHTBRoot(BuildRateLimiter(10, time.Hour))
+	a := root.AddChild(BuildRateLimiter(2, time.Hour), BuildRateLimiter(8, time.Hour))
+	b := root.AddChild(BuildRateLimiter(2, time.Hour), RateLimiter{})
+
+	// assured traffic of a, charged to root on a best-effort basis
+	if _, ok := a.TakeN(2); !ok {
+		t.Fatal("a: assured take should succeed")
+	}
+	// borrowed from root
+	for i := 0; i < 6; i++ {
+		if _, ok := a.Take1(); !ok {
+			t.Fatalf("a: borrow %d should succeed", i)
+		}
+	}
+	// ceiling of a reached even though root still has 2 tokens
+	if _, ok := a.Take1(); ok {
+		t.Fatal("a: take above ceil should be denied")
+	}
+
+	// b gets its assured rate regardless of what a borrowed
+	if _, ok := b.TakeN(2); !ok {
+		t.Fatal("b: assured take should succeed")
+	}
+	// root is exhausted now: nothing left to borrow
+	if wait, ok := b.Take1(); ok || wait <= 0 {
+		t.Fatalf("b: borrow from exhausted root => wait=%d ok=%v, want positive,false", wait, ok)
+	}
+}
+
+func TestHTB_DeniedBorrowRollsBackCeil(t *testing.T) {
+	root := NewHTBRoot(BuildRateLimiter(1, time.Hour))
+	c := root.AddChild(BuildRateLimiter(1, time.Hour), BuildRateLimiter(5, time.Hour))
+
+	if _, ok := c.Take1(); !ok {
+		t.Fatal("assured take should succeed")
+	}
+	if _, ok := c.Take1(); ok {
+		t.Fatal("borrow from exhausted root should be denied")
+	}
+
+	req, _ := unpackUint16Uint48(atomic.LoadUint64(c.ceilState))
+	if req != 4 {
+		t.Fatalf("ceil tokens = %d, want 4 (denied borrow must be rolled back)", req)
+	}
+}
+
+func TestHTB_NestedBorrowing(t *testing.T) {
+	root := NewHTBRoot(BuildRateLimiter(4, time.Hour))
+	tenant := root.AddChild(BuildRateLimiter(1, time.Hour), RateLimiter{})
+	endpoint := tenant.AddChild(BuildRateLimiter(1, time.Hour), RateLimiter{})
+
+	// 1 own token (drains tenant and root), then borrowed from root via tenant
+	granted := 0
+	for i := 0; i < 10; i++ {
+		if _, ok := endpoint.Take1(); ok {
+			granted++
+		}
+	}
+	if granted != 4 {
+		t.Fatalf("granted = %d, want 4 (bounded by root)", granted)
+	}
+}
@@ -0,0 +1,81 @@
+package httplimit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BypassHeader is the request header carrying a bypass token (see WithBypass).
+const BypassHeader = "X-RateLimit-Bypass"
+
+// SignBypassToken returns a token exempting requests from limiting until `expires`,
+// signed with HMAC-SHA256 under `secret`. `subject` names the holder (e.g., a support
+// engineer or incident ticket) and is returned by VerifyBypassToken for auditing.
+// Tokens have the form "<expiry unix seconds>.<subject>.<signature>".
+//
+// Example:
+//
+//	token := httplimit.SignBypassToken(secret, "support:ticket-1234", time.Now().Add(time.Hour))
+//	req.Header.Set(httplimit.BypassHeader, token)
+func SignBypassToken(secret []byte, subject string, expires time.Time) string {
+	payload := strconv.FormatInt(expires.Unix(), 10) + "." + subject
+	return payload + "." + bypassSignature(secret, payload)
+}
+
+// VerifyBypassToken checks a token produced by SignBypassToken and returns its subject.
+// Returns false if the signature is invalid or the token expired at `now`.
+func VerifyBypassToken(secret []byte, token string, now time.Time) (string, bool) {
+	i := strings.LastIndexByte(token, '.')
+	if i <= 0 {
+		return "", false
+	}
+	payload, sig := token[:i], token[i+1:]
+	if !hmac.Equal([]byte(sig), []byte(bypassSignature(secret, payload))) {
+		return "", false
+	}
+	exp, subject, ok := strings.Cut(payload, ".")
+	if !ok {
+		return "", false
+	}
+	expires, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil || now.Unix() >= expires {
+		return "", false
+	}
+	return subject, true
+}
+
+// bypassSignature signs a bypass token payload. The domain prefix keeps bypass
+// signatures distinct from session ID signatures made with the same secret.
+func bypassSignature(secret []byte, payload string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte("limitron-bypass\x00"))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// WithBypass exempts requests carrying a valid, unexpired bypass token
+// signed with `secret` in the BypassHeader from limiting, for support tooling
+// and emergency access. An empty secret disables bypassing.
+func WithBypass(secret []byte) Option {
+	return func(c *config) {
+		c.bypass = secret
+	}
+}
+
+// bypassed reports whether `r` carries a valid bypass token signed with `secret`.
+func bypassed(r *http.Request, secret []byte) bool {
+	if len(secret) == 0 {
+		return false
+	}
+	token := r.Header.Get(BypassHeader)
+	if token == "" {
+		return false
+	}
+	_, ok := VerifyBypassToken(secret, token, time.Now())
+	return ok
+}
@@ -0,0 +1,68 @@
+package httplimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/iryndin/limitron"
+)
+
+func TestBypassToken_SignVerify(t *testing.T) {
+	secret := []byte("s3cret")
+	now := time.Now()
+	token := SignBypassToken(secret, "support:a.b", now.Add(time.Minute))
+
+	if subject, ok := VerifyBypassToken(secret, token, now); !ok || subject != "support:a.b" {
+		t.Fatalf("VerifyBypassToken = %q, %v; want support:a.b, true", subject, ok)
+	}
+	if _, ok := VerifyBypassToken(secret, token, now.Add(2*time.Minute)); ok {
+		t.Errorf("expired token accepted")
+	}
+	if _, ok := VerifyBypassToken([]byte("other"), token, now); ok {
+		t.Errorf("token accepted under another secret")
+	}
+	if _, ok := VerifyBypassToken(secret, "9999999999.x"+token[len(token)-44:], now); ok {
+		t.Errorf("tampered token accepted")
+	}
+	// a signed session ID must not work as a bypass token
+	if _, ok := VerifyBypassToken(secret, SignSessionID(secret, "9999999999.x"), now); ok {
+		t.Errorf("session signature accepted as a bypass token")
+	}
+}
+
+func TestMiddleware_Bypass(t *testing.T) {
+	secret := []byte("s3cret")
+	kl := limitron.NewKeyedLimiter[string](limitron.BuildRateLimiter(1, time.Minute))
+	h := Middleware(kl, WithBypass(secret))(okHandler)
+	chained := NewChain(nil).PerIP(kl).Bypass(secret).Middleware()(okHandler)
+
+	do := func(h http.Handler, token string) int {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		if token != "" {
+			r.Header.Set(BypassHeader, token)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	if code := do(h, ""); code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", code)
+	}
+	if code := do(h, ""); code != http.StatusTooManyRequests {
+		t.Fatalf("status = %d, want 429", code)
+	}
+	valid := SignBypassToken(secret, "oncall", time.Now().Add(time.Minute))
+	expired := SignBypassToken(secret, "oncall", time.Now().Add(-time.Second))
+	if code := do(h, valid); code != http.StatusOK {
+		t.Fatalf("bypass status = %d, want 200", code)
+	}
+	if code := do(h, expired); code != http.StatusTooManyRequests {
+		t.Fatalf("expired bypass status = %d, want 429", code)
+	}
+	if code := do(chained, valid); code != http.StatusOK {
+		t.Fatalf("chain bypass status = %d, want 200", code)
+	}
+}
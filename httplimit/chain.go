@@ -0,0 +1,205 @@
+package httplimit
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/iryndin/limitron"
+)
+
+// chainKey selects the request key a Chain layer is limited by.
+type chainKey uint8
+
+const (
+	globalKey chainKey = iota
+	ipKey
+	userKey
+	// clientKey is the user key if the request has one, else the IP key.
+	clientKey
+)
+
+// chainLayer is a single limit of a Chain.
+type chainLayer struct {
+	key     chainKey
+	limiter *limitron.KeyedLimiter[string]
+	routes  *RouteTable
+}
+
+// Chain composes several limits into a single middleware, typically
+// global → per-IP → per-user → per-route. Compared to stacking Middleware
+// calls, request keys are extracted once and shared by all layers,
+// and rate limit headers are written once, for the most restrictive layer.
+//
+// Layers are checked in the order they are added; the first denying layer
+// answers the request with 429 Too Many Requests. Tokens consumed by earlier
+// layers of a denied request are returned, so that requests denied by an inner
+// layer (e.g., a user over their limit) do not drain the outer ones.
+//
+// Every response carries X-RateLimit-Limit, X-RateLimit-Remaining and
+// X-RateLimit-Reset (seconds until the bucket is full) of the layer with
+// the fewest remaining tokens, or of the denying layer. Its Decision is
+// also available to handlers via DecisionFromContext.
+//
+// Example:
+//
+//	chain := httplimit.NewChain(httplimit.CookieKey("sid", secret)).
+//	    Global(limitron.BuildRateLimiterRps(5000)).
+//	    PerIP(limitron.NewKeyedLimiter[string](limitron.BuildRateLimiterRps(50))).
+//	    PerUser(limitron.NewKeyedLimiter[string](limitron.BuildRateLimiterRps(10))).
+//	    PerRoute(httplimit.NewRouteTable(routes))
+//	http.ListenAndServe(":8080", chain.Middleware()(mux))
+type Chain struct {
+	user   KeyFunc
+	bypass []byte
+	layers []chainLayer
+}
+
+// NewChain returns an empty Chain identifying users with `user`,
+// which may be nil if no PerUser layer is added.
+func NewChain(user KeyFunc) *Chain {
+	return &Chain{user: user}
+}
+
+// Global adds a limit shared by all requests.
+func (c *Chain) Global(limiter limitron.RateLimiter) *Chain {
+	return c.add(chainLayer{key: globalKey, limiter: limitron.NewKeyedLimiter[string](limiter)})
+}
+
+// PerIP adds a limit per client IP address (see IPKey).
+func (c *Chain) PerIP(kl *limitron.KeyedLimiter[string]) *Chain {
+	return c.add(chainLayer{key: ipKey, limiter: kl})
+}
+
+// PerUser adds a limit per user, as identified by the KeyFunc of the Chain.
+// Requests without a user key are not limited by this layer.
+func (c *Chain) PerUser(kl *limitron.KeyedLimiter[string]) *Chain {
+	return c.add(chainLayer{key: userKey, limiter: kl})
+}
+
+// PerRoute adds the limits of route table `t`, kept per user,
+// or per IP address for requests without a user key.
+// Requests matching no route are not limited by this layer.
+func (c *Chain) PerRoute(t *RouteTable) *Chain {
+	return c.add(chainLayer{key: clientKey, routes: t})
+}
+
+// Bypass exempts requests carrying a valid bypass token signed with `secret`
+// from all layers of the Chain, see WithBypass.
+func (c *Chain) Bypass(secret []byte) *Chain {
+	c.bypass = secret
+	return c
+}
+
+func (c *Chain) add(l chainLayer) *Chain {
+	c.layers = append(c.layers, l)
+	return c
+}
+
+// Middleware returns the middleware enforcing the limits of the Chain.
+// Layers added afterwards do not affect it.
+func (c *Chain) Middleware() func(http.Handler) http.Handler {
+	user, bypass := c.user, c.bypass
+	layers := append([]chainLayer(nil), c.layers...)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if bypassed(r, bypass) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			keys := requestKeys{r: r, user: user}
+			var binding limitron.Decision
+			limited := false
+			// charged holds the layers that took a token, to refund on denial
+			var charged []chargedLayer
+
+			for _, l := range layers {
+				limiter := l.limiter
+				if l.routes != nil {
+					_, limiter, _ = l.routes.Match(r)
+				}
+				key, ok := keys.get(l.key)
+				if limiter == nil || !ok {
+					continue
+				}
+
+				d := limiter.Take1Result(key)
+				if !d.Allowed {
+					for _, ch := range charged {
+						ch.limiter.ReturnN(ch.key, 1)
+					}
+					setRateLimitHeaders(w, d)
+					tooManyRequests(w, d.RetryAfter.Milliseconds())
+					return
+				}
+				if !limited || d.Remaining < binding.Remaining {
+					binding = d
+				}
+				limited = true
+				charged = append(charged, chargedLayer{limiter, key})
+			}
+
+			if limited {
+				setRateLimitHeaders(w, binding)
+				r = r.WithContext(context.WithValue(r.Context(), decisionKey{}, binding))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// chargedLayer is a limiter and key a request took a token from.
+type chargedLayer struct {
+	limiter *limitron.KeyedLimiter[string]
+	key     string
+}
+
+// requestKeys extracts the keys of a request at most once each.
+type requestKeys struct {
+	r                *http.Request
+	user             KeyFunc
+	ip, userID       string
+	ipDone, userDone bool
+	hasUser          bool
+}
+
+// get returns the key of `kind`, or false if the request has none.
+func (k *requestKeys) get(kind chainKey) (string, bool) {
+	switch kind {
+	case globalKey:
+		return "", true
+	case ipKey:
+		if !k.ipDone {
+			k.ip, _ = IPKey(k.r)
+			k.ipDone = true
+		}
+		return k.ip, true
+	case userKey:
+		if !k.userDone {
+			if k.user != nil {
+				k.userID, k.hasUser = k.user(k.r)
+			}
+			k.userDone = true
+		}
+		return k.userID, k.hasUser
+	default:
+		if id, ok := k.get(userKey); ok {
+			return id, true
+		}
+		return k.get(ipKey)
+	}
+}
+
+// setRateLimitHeaders writes the X-RateLimit-* headers of decision `d`.
+func setRateLimitHeaders(w http.ResponseWriter, d limitron.Decision) {
+	h := w.Header()
+	h.Set("X-RateLimit-Limit", strconv.Itoa(int(d.Limit)))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(int(d.Remaining)))
+	reset := int64(0)
+	if ms := time.Until(d.ResetAt).Milliseconds(); ms > 0 {
+		reset = retryAfterSeconds(ms)
+	}
+	h.Set("X-RateLimit-Reset", strconv.FormatInt(reset, 10))
+}
@@ -0,0 +1,110 @@
+package httplimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/iryndin/limitron"
+)
+
+func TestChain_LayersAndHeaders(t *testing.T) {
+	userCalls := 0
+	user := func(r *http.Request) (string, bool) {
+		userCalls++
+		id := r.Header.Get("X-User")
+		return id, id != ""
+	}
+	routes := NewRouteTable([]Route{{Method: http.MethodPost, Pattern: "/orders", Requests: 1, Interval: time.Minute}})
+	chain := NewChain(user).
+		Global(limitron.BuildRateLimiter(100, time.Minute)).
+		PerIP(limitron.NewKeyedLimiter[string](limitron.BuildRateLimiter(10, time.Minute))).
+		PerUser(limitron.NewKeyedLimiter[string](limitron.BuildRateLimiter(3, time.Minute))).
+		PerRoute(routes)
+	h := chain.Middleware()(okHandler)
+
+	do := func(method, userID string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/orders", nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		if userID != "" {
+			r.Header.Set("X-User", userID)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	w := do(http.MethodGet, "alice")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	// the per-user layer is the most restrictive one
+	if got := w.Header().Get("X-RateLimit-Remaining"); got != "2" {
+		t.Errorf("X-RateLimit-Remaining = %q, want 2", got)
+	}
+	if got := w.Header().Get("X-RateLimit-Limit"); got != "3" {
+		t.Errorf("X-RateLimit-Limit = %q, want 3", got)
+	}
+	if userCalls != 1 {
+		t.Errorf("user key extracted %d times, want once", userCalls)
+	}
+
+	// the route layer allows a single POST per user
+	if w := do(http.MethodPost, "alice"); w.Code != http.StatusOK {
+		t.Fatalf("first POST status = %d, want 200", w.Code)
+	}
+	w = do(http.MethodPost, "alice")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("second POST status = %d, want 429", w.Code)
+	}
+	if w.Header().Get("Retry-After") == "" || w.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("denied headers = %v", w.Header())
+	}
+	// the denied POST returned its per-user token: alice has one left, bob has all
+	if w := do(http.MethodGet, "alice"); w.Code != http.StatusOK {
+		t.Fatalf("alice status = %d, want 200", w.Code)
+	}
+	if w := do(http.MethodGet, "alice"); w.Code != http.StatusTooManyRequests {
+		t.Fatalf("alice status = %d, want 429", w.Code)
+	}
+	if w := do(http.MethodGet, "bob"); w.Code != http.StatusOK {
+		t.Fatalf("bob status = %d, want 200", w.Code)
+	}
+	// anonymous requests skip the per-user layer, and are limited by IP
+	w = do(http.MethodGet, "")
+	if w.Code != http.StatusOK || w.Header().Get("X-RateLimit-Limit") != "10" {
+		t.Fatalf("anonymous status = %d, headers = %v", w.Code, w.Header())
+	}
+}
+
+func TestChain_RefundsOuterLayers(t *testing.T) {
+	perIP := limitron.NewKeyedLimiter[string](limitron.BuildRateLimiter(3, time.Minute))
+	chain := NewChain(func(r *http.Request) (string, bool) { return "mallory", true }).
+		PerIP(perIP).
+		PerUser(limitron.NewKeyedLimiter[string](limitron.BuildRateLimiter(1, time.Minute)))
+	h := chain.Middleware()(okHandler)
+
+	for i := 0; i < 5; i++ {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	// only the admitted request keeps its per-IP token
+	if _, ok := perIP.Peek("192.0.2.1", 2); !ok {
+		t.Fatal("requests denied by the per-user layer drained the per-IP layer")
+	}
+}
+
+func TestChain_DecisionInContext(t *testing.T) {
+	var d limitron.Decision
+	var found bool
+	h := NewChain(nil).Global(limitron.BuildRateLimiter(5, time.Minute)).Middleware()(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			d, found = DecisionFromContext(r.Context())
+		}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if !found || d.Remaining != 4 {
+		t.Fatalf("decision = %+v (found %v), want 4 remaining", d, found)
+	}
+}
@@ -0,0 +1,93 @@
+package httplimit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// KeyFunc extracts the rate limiting key of a request.
+// It returns false if the request carries no usable key.
+type KeyFunc func(r *http.Request) (key string, ok bool)
+
+// IPKey keys requests by the client IP address taken from r.RemoteAddr.
+// Keys have the form "ip:<address>".
+//
+// If the service runs behind a reverse proxy, make sure r.RemoteAddr holds the real
+// client address (e.g., by a trusted-proxy middleware) before limiting.
+func IPKey(r *http.Request) (string, bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if host == "" {
+		return "", false
+	}
+	return "ip:" + host, true
+}
+
+// CookieKey keys requests by the value of the cookie `name`, such as a session cookie
+// or a server-assigned anonymous ID, so that per-user limits work even behind CGNAT,
+// where per-IP limiting is unfair. Keys have the form "session:<id>".
+//
+// If `secret` is not empty, the cookie value must be signed with SignSessionID using
+// the same secret; unsigned or tampered values are rejected, so clients cannot
+// escape their limit by inventing fresh IDs.
+func CookieKey(name string, secret []byte) KeyFunc {
+	return func(r *http.Request) (string, bool) {
+		c, err := r.Cookie(name)
+		if err != nil || c.Value == "" {
+			return "", false
+		}
+		id := c.Value
+		if len(secret) > 0 {
+			var ok bool
+			if id, ok = VerifySessionID(secret, c.Value); !ok {
+				return "", false
+			}
+		}
+		return "session:" + id, true
+	}
+}
+
+// SignSessionID returns `id` signed with HMAC-SHA256 under `secret`, in the form
+// "<id>.<signature>", for use as a cookie value checked by CookieKey.
+func SignSessionID(secret []byte, id string) string {
+	return id + "." + sessionSignature(secret, id)
+}
+
+// VerifySessionID checks a value produced by SignSessionID and returns the signed ID.
+func VerifySessionID(secret []byte, value string) (string, bool) {
+	i := strings.LastIndexByte(value, '.')
+	if i <= 0 {
+		return "", false
+	}
+	id, sig := value[:i], value[i+1:]
+	if !hmac.Equal([]byte(sig), []byte(sessionSignature(secret, id))) {
+		return "", false
+	}
+	return id, true
+}
+
+func sessionSignature(secret []byte, id string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// FirstKey returns a KeyFunc that tries each of `fns` in order and returns
+// the first key found, e.g., FirstKey(CookieKey("sid", secret), IPKey)
+// to limit per session and fall back to per-IP for anonymous clients.
+func FirstKey(fns ...KeyFunc) KeyFunc {
+	return func(r *http.Request) (string, bool) {
+		for _, fn := range fns {
+			if key, ok := fn(r); ok {
+				return key, true
+			}
+		}
+		return "", false
+	}
+}
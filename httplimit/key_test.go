@@ -0,0 +1,60 @@
+package httplimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIPKey(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	if key, ok := IPKey(r); !ok || key != "ip:192.0.2.1" {
+		t.Fatalf("IPKey = %q,%v, want ip:192.0.2.1,true", key, ok)
+	}
+}
+
+func TestCookieKey_Unsigned(t *testing.T) {
+	fn := CookieKey("sid", nil)
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	if _, ok := fn(r); ok {
+		t.Fatal("request without cookie should have no key")
+	}
+	r.AddCookie(&http.Cookie{Name: "sid", Value: "abc"})
+	if key, ok := fn(r); !ok || key != "session:abc" {
+		t.Fatalf("CookieKey = %q,%v, want session:abc,true", key, ok)
+	}
+}
+
+func TestCookieKey_Signed(t *testing.T) {
+	secret := []byte("s3cret")
+	fn := CookieKey("sid", secret)
+
+	valid := httptest.NewRequest(http.MethodGet, "/", nil)
+	valid.AddCookie(&http.Cookie{Name: "sid", Value: SignSessionID(secret, "user-1")})
+	if key, ok := fn(valid); !ok || key != "session:user-1" {
+		t.Fatalf("signed CookieKey = %q,%v, want session:user-1,true", key, ok)
+	}
+
+	for _, v := range []string{"user-1", "user-2." + SignSessionID(secret, "user-1")[7:], SignSessionID([]byte("other"), "user-1")} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.AddCookie(&http.Cookie{Name: "sid", Value: v})
+		if key, ok := fn(r); ok {
+			t.Fatalf("tampered cookie %q accepted as %q", v, key)
+		}
+	}
+}
+
+func TestFirstKey(t *testing.T) {
+	fn := FirstKey(CookieKey("sid", nil), IPKey)
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "192.0.2.1:1234"
+	if key, _ := fn(r); key != "ip:192.0.2.1" {
+		t.Fatalf("FirstKey without cookie = %q, want ip fallback", key)
+	}
+	r.AddCookie(&http.Cookie{Name: "sid", Value: "abc"})
+	if key, _ := fn(r); key != "session:abc" {
+		t.Fatalf("FirstKey with cookie = %q, want session key", key)
+	}
+}
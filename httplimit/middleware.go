@@ -0,0 +1,119 @@
+// Package httplimit provides net/http integration for limitron:
+// a rate limiting middleware and request key extraction.
+package httplimit
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/iryndin/limitron"
+)
+
+// config holds the settings of a Middleware.
+type config struct {
+	key    KeyFunc
+	routes *RouteTable
+	bypass []byte
+}
+
+// Option configures a Middleware.
+type Option func(*config)
+
+// WithKeyFunc sets how requests are mapped to limiter keys. The default is IPKey.
+// Requests for which `fn` finds no key are limited by IPKey.
+func WithKeyFunc(fn KeyFunc) Option {
+	return func(c *config) {
+		c.key = fn
+	}
+}
+
+// Middleware returns a middleware limiting requests per key with `kl`.
+//
+// Denied requests are answered with 429 Too Many Requests and a Retry-After header.
+// Allowed requests carry the limiter's Decision in their context, see DecisionFromContext.
+// With WithRoutes, requests matching a route are limited by the route's limiter instead,
+// and `kl` may be nil to leave other requests unlimited.
+//
+// Example:
+//
+//	kl := limitron.NewKeyedLimiter[string](limitron.BuildRateLimiterRps(10))
+//	mw := httplimit.Middleware(kl, httplimit.WithKeyFunc(
+//	    httplimit.FirstKey(httplimit.CookieKey("sid", secret), httplimit.IPKey)))
+//	http.ListenAndServe(":8080", mw(mux))
+func Middleware(kl *limitron.KeyedLimiter[string], opts ...Option) func(http.Handler) http.Handler {
+	cfg := config{key: IPKey}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if bypassed(r, cfg.bypass) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			key, ok := cfg.key(r)
+			if !ok {
+				key, _ = IPKey(r)
+			}
+
+			limiter := kl
+			if _, routeLimiter, ok := cfg.routes.Match(r); ok {
+				limiter = routeLimiter
+			}
+			if limiter == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			d := limiter.Take1Result(key)
+			if !d.Allowed {
+				tooManyRequests(w, d.RetryAfter.Milliseconds())
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), decisionKey{}, d)))
+		})
+	}
+}
+
+// decisionKey is the context key of the Decision of a request.
+type decisionKey struct{}
+
+// DecisionFromContext returns the Decision the Middleware made for the request
+// with context `ctx`, so that handlers can include quota information (remaining
+// tokens, limit, reset time) in response bodies or business logic.
+// Returns false for requests that were not limited.
+//
+// Example:
+//
+//	func handler(w http.ResponseWriter, r *http.Request) {
+//	    if d, ok := httplimit.DecisionFromContext(r.Context()); ok {
+//	        json.NewEncoder(w).Encode(map[string]any{"quota_remaining": d.Remaining, "quota_reset": d.ResetAt})
+//	    }
+//	}
+func DecisionFromContext(ctx context.Context) (limitron.Decision, bool) {
+	d, ok := ctx.Value(decisionKey{}).(limitron.Decision)
+	return d, ok
+}
+
+// tooManyRequests writes a 429 response with a Retry-After header
+// holding the wait rounded up to whole seconds.
+func tooManyRequests(w http.ResponseWriter, waitMillis int64) {
+	w.Header().Set("Retry-After", strconv.FormatInt(retryAfterSeconds(waitMillis), 10))
+	http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+}
+
+// maxRetryAfter caps Retry-After for requests that can never succeed
+// (limitron reports math.MaxInt64 millis for those).
+const maxRetryAfter = int64(365 * 24 * time.Hour / time.Second)
+
+// retryAfterSeconds rounds a wait in millis up to whole seconds, at least 1.
+func retryAfterSeconds(waitMillis int64) int64 {
+	secs := waitMillis / 1000
+	if waitMillis%1000 != 0 {
+		secs++
+	}
+	return min(max(secs, 1), maxRetryAfter)
+}
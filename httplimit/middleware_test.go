@@ -0,0 +1,81 @@
+package httplimit
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/iryndin/limitron"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+})
+
+func TestMiddleware_LimitsPerKey(t *testing.T) {
+	kl := limitron.NewKeyedLimiter[string](limitron.BuildRateLimiter(2, time.Minute))
+	h := Middleware(kl, WithKeyFunc(CookieKey("sid", nil)))(okHandler)
+
+	do := func(sid string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = "192.0.2.1:1234"
+		if sid != "" {
+			r.AddCookie(&http.Cookie{Name: "sid", Value: sid})
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := do("a"); w.Code != http.StatusOK {
+			t.Fatalf("a: request %d status = %d, want 200", i, w.Code)
+		}
+	}
+	w := do("a")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("a: status = %d, want 429", w.Code)
+	}
+	if ra := w.Header().Get("Retry-After"); ra != "30" && ra != "31" {
+		t.Fatalf("Retry-After = %q, want about 30", ra)
+	}
+
+	// same IP, different session: separate budget
+	if w := do("b"); w.Code != http.StatusOK {
+		t.Fatalf("b: status = %d, want 200", w.Code)
+	}
+	// no cookie: falls back to the IP key
+	if w := do(""); w.Code != http.StatusOK {
+		t.Fatalf("ip fallback: status = %d, want 200", w.Code)
+	}
+}
+
+func TestMiddleware_DecisionInContext(t *testing.T) {
+	kl := limitron.NewKeyedLimiter[string](limitron.BuildRateLimiter(5, time.Minute))
+	var got limitron.Decision
+	var found bool
+	h := Middleware(kl)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, found = DecisionFromContext(r.Context())
+	}))
+
+	for i := 0; i < 2; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}
+	if !found || !got.Allowed || got.Remaining != 3 || got.Limit != 5 {
+		t.Fatalf("decision = %+v (found %v), want allowed with 3 of 5 remaining", got, found)
+	}
+	if _, ok := DecisionFromContext(httptest.NewRequest(http.MethodGet, "/", nil).Context()); ok {
+		t.Fatalf("found a decision in an unlimited request")
+	}
+}
+
+func TestRetryAfterSeconds(t *testing.T) {
+	cases := map[int64]int64{0: 1, 1: 1, 1000: 1, 1001: 2, math.MaxInt64: maxRetryAfter}
+	for in, want := range cases {
+		if got := retryAfterSeconds(in); got != want {
+			t.Fatalf("retryAfterSeconds(%d) = %d, want %d", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package httplimit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"time"
+)
+
+// OpenAPIExtension is the OpenAPI vendor extension holding a route limit.
+// It may be set on a path item (applies to all its operations) or on an operation:
+//
+//	"paths": {
+//	  "/users/{id}": {
+//	    "x-ratelimit": {"requests": 100, "interval": "1m"},
+//	    "post": {"x-ratelimit": {"requests": 10, "interval": "1m"}}
+//	  }
+//	}
+const OpenAPIExtension = "x-ratelimit"
+
+// openAPIRateLimit is the value of the x-ratelimit extension.
+type openAPIRateLimit struct {
+	Requests uint16 `json:"requests"`
+	Interval string `json:"interval"`
+}
+
+// openAPIMethods lists the operation keys of an OpenAPI path item.
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// RoutesFromOpenAPI reads the x-ratelimit extensions of a JSON OpenAPI (or Swagger 2.0)
+// document and returns the corresponding route-limit table, ordered so that
+// concrete paths are matched before templated ones, as OpenAPI requires.
+//
+// Path-level limits produce a route for any method; operation-level limits
+// produce a route for that method, matched before the path-level one.
+// The interval is a Go duration string such as "1s", "1m" or "1h30m".
+func RoutesFromOpenAPI(spec []byte) ([]Route, error) {
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(spec, &doc); err != nil {
+		return nil, fmt.Errorf("httplimit: parse OpenAPI document: %w", err)
+	}
+
+	var routes []Route
+	for path, item := range doc.Paths {
+		if raw, ok := item[OpenAPIExtension]; ok {
+			rt, err := openAPIRoute("", path, raw)
+			if err != nil {
+				return nil, err
+			}
+			routes = append(routes, rt)
+		}
+		for _, method := range openAPIMethods {
+			rawOp, ok := item[method]
+			if !ok {
+				continue
+			}
+			var op map[string]json.RawMessage
+			if err := json.Unmarshal(rawOp, &op); err != nil {
+				return nil, fmt.Errorf("httplimit: parse operation %s %s: %w", method, path, err)
+			}
+			if raw, ok := op[OpenAPIExtension]; ok {
+				rt, err := openAPIRoute(strings.ToUpper(method), path, raw)
+				if err != nil {
+					return nil, err
+				}
+				routes = append(routes, rt)
+			}
+		}
+	}
+
+	sort.Slice(routes, func(i, j int) bool { return routeLess(routes[i], routes[j]) })
+	return routes, nil
+}
+
+func openAPIRoute(method, path string, raw json.RawMessage) (Route, error) {
+	var rl openAPIRateLimit
+	if err := json.Unmarshal(raw, &rl); err != nil {
+		return Route{}, fmt.Errorf("httplimit: parse %s of %s %s: %w", OpenAPIExtension, method, path, err)
+	}
+	interval, err := time.ParseDuration(rl.Interval)
+	if err != nil || interval < time.Millisecond || rl.Requests == 0 {
+		return Route{}, fmt.Errorf("httplimit: invalid %s of %s %s: need requests > 0 and an interval of at least 1ms",
+			OpenAPIExtension, method, path)
+	}
+	return Route{Method: method, Pattern: path, Requests: rl.Requests, Interval: interval}, nil
+}
+
+// routeLess orders routes by match priority: longer paths first, concrete segments
+// before templated ones, method-specific before any-method, then lexically.
+func routeLess(a, b Route) bool {
+	sa, sb := splitPath(a.Pattern), splitPath(b.Pattern)
+	if len(sa) != len(sb) {
+		return len(sa) > len(sb)
+	}
+	for i := range sa {
+		pa, pb := strings.HasPrefix(sa[i], "{"), strings.HasPrefix(sb[i], "{")
+		if pa != pb {
+			return !pa
+		}
+	}
+	if a.Pattern != b.Pattern {
+		return a.Pattern < b.Pattern
+	}
+	if (a.Method == "") != (b.Method == "") {
+		return a.Method != ""
+	}
+	return a.Method < b.Method
+}
+
+// GenerateRoutesGo renders `routes` as Go source declaring `varName` as a []httplimit.Route
+// in package `pkg`, for use with go:generate (see cmd/limitron-openapi).
+func GenerateRoutesGo(routes []Route, pkg, varName string) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by limitron-openapi. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+	fmt.Fprintf(&buf, "import (\n\t\"time\"\n\n\t\"github.com/iryndin/limitron/httplimit\"\n)\n\n")
+	fmt.Fprintf(&buf, "var %s = []httplimit.Route{\n", varName)
+	for _, rt := range routes {
+		// nanoseconds, so that fractional intervals such as 1.5ms are kept exactly
+		fmt.Fprintf(&buf, "\t{Method: %q, Pattern: %q, Requests: %d, Interval: time.Duration(%d)},\n",
+			rt.Method, rt.Pattern, rt.Requests, int64(rt.Interval))
+	}
+	fmt.Fprintf(&buf, "}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("httplimit: format generated routes: %w", err)
+	}
+	return src, nil
+}
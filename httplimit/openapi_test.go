@@ -0,0 +1,77 @@
+package httplimit
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testSpec = `{
+  "openapi": "3.0.0",
+  "paths": {
+    "/users/{id}": {
+      "x-ratelimit": {"requests": 100, "interval": "1m"},
+      "get": {"summary": "get user"},
+      "post": {"x-ratelimit": {"requests": 10, "interval": "1m"}}
+    },
+    "/users/me": {
+      "get": {"x-ratelimit": {"requests": 50, "interval": "1s"}}
+    },
+    "/health": {
+      "get": {}
+    }
+  }
+}`
+
+func TestRoutesFromOpenAPI(t *testing.T) {
+	routes, err := RoutesFromOpenAPI([]byte(testSpec))
+	if err != nil {
+		t.Fatalf("RoutesFromOpenAPI: %v", err)
+	}
+
+	want := []Route{
+		{Method: "GET", Pattern: "/users/me", Requests: 50, Interval: time.Second},
+		{Method: "POST", Pattern: "/users/{id}", Requests: 10, Interval: time.Minute},
+		{Method: "", Pattern: "/users/{id}", Requests: 100, Interval: time.Minute},
+	}
+	if len(routes) != len(want) {
+		t.Fatalf("routes = %+v, want %+v", routes, want)
+	}
+	for i := range want {
+		if routes[i] != want[i] {
+			t.Fatalf("routes[%d] = %+v, want %+v", i, routes[i], want[i])
+		}
+	}
+}
+
+func TestRoutesFromOpenAPI_Invalid(t *testing.T) {
+	for _, spec := range []string{
+		`not json`,
+		`{"paths": {"/a": {"x-ratelimit": {"requests": 0, "interval": "1m"}}}}`,
+		`{"paths": {"/a": {"get": {"x-ratelimit": {"requests": 1, "interval": "soon"}}}}}`,
+	} {
+		if _, err := RoutesFromOpenAPI([]byte(spec)); err == nil {
+			t.Fatalf("RoutesFromOpenAPI(%s) should fail", spec)
+		}
+	}
+}
+
+func TestGenerateRoutesGo(t *testing.T) {
+	src, err := GenerateRoutesGo([]Route{
+		{Method: "GET", Pattern: "/users/{id}", Requests: 100, Interval: time.Minute},
+		{Pattern: "/search", Requests: 3, Interval: 1500 * time.Microsecond},
+	}, "api", "Routes")
+	if err != nil {
+		t.Fatalf("GenerateRoutesGo: %v", err)
+	}
+	for _, want := range []string{
+		"// Code generated by limitron-openapi. DO NOT EDIT.",
+		"package api",
+		`{Method: "GET", Pattern: "/users/{id}", Requests: 100, Interval: time.Duration(60000000000)},`,
+		`{Method: "", Pattern: "/search", Requests: 3, Interval: time.Duration(1500000)},`,
+	} {
+		if !strings.Contains(string(src), want) {
+			t.Fatalf("generated source misses %q:\n%s", want, src)
+		}
+	}
+}
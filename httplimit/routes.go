@@ -0,0 +1,129 @@
+package httplimit
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/iryndin/limitron"
+)
+
+// Route is an entry of a route-limit table: requests matching Method and Pattern
+// are limited to Requests per Interval, per client key.
+type Route struct {
+	// Method is the HTTP method, e.g., "GET". Empty matches any method.
+	Method string
+	// Pattern is a path template such as "/users/{id}/orders". Segments in braces
+	// match any single path segment; a trailing "/*" matches any remainder.
+	Pattern string
+	// Requests per Interval, see limitron.BuildRateLimiter.
+	Requests uint16
+	Interval time.Duration
+}
+
+// RouteTable resolves requests to per-route limiters.
+// Each route has its own KeyedLimiter, so client budgets are kept per route;
+// bound them with the options of NewRouteTable, or sweep them through Limiters.
+//
+// The zero value is an empty table; create populated tables with NewRouteTable.
+type RouteTable struct {
+	routes []compiledRoute
+}
+
+type compiledRoute struct {
+	Route
+	segments []string
+	wildcard bool
+	limiter  *limitron.KeyedLimiter[string]
+}
+
+// NewRouteTable compiles `routes` into a RouteTable. Routes are matched in order,
+// so list more specific patterns first. Options are applied to the KeyedLimiter of
+// every route, e.g., limitron.WithMaxKeys to cap the clients tracked per route.
+//
+// Example:
+//
+//	table := NewRouteTable(routes, limitron.WithMaxKeys[string](100_000, nil))
+func NewRouteTable(routes []Route, opts ...limitron.KeyedOption[string]) *RouteTable {
+	t := &RouteTable{routes: make([]compiledRoute, 0, len(routes))}
+	for _, rt := range routes {
+		segments := splitPath(rt.Pattern)
+		wildcard := len(segments) > 0 && segments[len(segments)-1] == "*"
+		if wildcard {
+			segments = segments[:len(segments)-1]
+		}
+		t.routes = append(t.routes, compiledRoute{
+			Route:    rt,
+			segments: segments,
+			wildcard: wildcard,
+			limiter:  limitron.NewKeyedLimiter[string](limitron.BuildRateLimiter(rt.Requests, rt.Interval), opts...),
+		})
+	}
+	return t
+}
+
+// Match returns the first route matching `r` and its limiter.
+func (t *RouteTable) Match(r *http.Request) (Route, *limitron.KeyedLimiter[string], bool) {
+	if t == nil {
+		return Route{}, nil, false
+	}
+	path := splitPath(r.URL.Path)
+	for i := range t.routes {
+		rt := &t.routes[i]
+		if rt.Method != "" && rt.Method != r.Method {
+			continue
+		}
+		if rt.matchPath(path) {
+			return rt.Route, rt.limiter, true
+		}
+	}
+	return Route{}, nil, false
+}
+
+// Limiters returns the KeyedLimiter of every route, in route order, e.g., to run
+// EvictIdle or RunEviction on them.
+//
+// Example:
+//
+//	for _, kl := range table.Limiters() {
+//	    go kl.RunEviction(ctx, 10*time.Minute, time.Minute)
+//	}
+func (t *RouteTable) Limiters() []*limitron.KeyedLimiter[string] {
+	if t == nil {
+		return nil
+	}
+	out := make([]*limitron.KeyedLimiter[string], len(t.routes))
+	for i := range t.routes {
+		out[i] = t.routes[i].limiter
+	}
+	return out
+}
+
+// WithRoutes makes the Middleware limit requests matching a route of `t` with that
+// route's limiter. Requests matching no route are limited by the Middleware's
+// KeyedLimiter, or pass unlimited if it is nil.
+func WithRoutes(t *RouteTable) Option {
+	return func(c *config) {
+		c.routes = t
+	}
+}
+
+func (rt *compiledRoute) matchPath(path []string) bool {
+	if len(path) < len(rt.segments) || (!rt.wildcard && len(path) != len(rt.segments)) {
+		return false
+	}
+	for i, seg := range rt.segments {
+		if strings.HasPrefix(seg, "{") && strings.HasSuffix(seg, "}") {
+			continue
+		}
+		if seg != path[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// splitPath splits a URL path into its non-empty segments.
+func splitPath(p string) []string {
+	return strings.FieldsFunc(p, func(r rune) bool { return r == '/' })
+}
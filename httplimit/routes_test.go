@@ -0,0 +1,81 @@
+package httplimit
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/iryndin/limitron"
+)
+
+func TestRouteTable_Match(t *testing.T) {
+	table := NewRouteTable([]Route{
+		{Method: http.MethodPost, Pattern: "/users/{id}", Requests: 1, Interval: time.Minute},
+		{Pattern: "/users/{id}", Requests: 5, Interval: time.Minute},
+		{Pattern: "/static/*", Requests: 100, Interval: time.Minute},
+	})
+
+	cases := []struct {
+		method, path string
+		want         string
+		ok           bool
+	}{
+		{http.MethodPost, "/users/42", "POST /users/{id}", true},
+		{http.MethodGet, "/users/42", " /users/{id}", true},
+		{http.MethodGet, "/users/42/orders", "", false},
+		{http.MethodGet, "/static/css/app.css", " /static/*", true},
+		{http.MethodGet, "/other", "", false},
+	}
+	for _, c := range cases {
+		rt, _, ok := table.Match(httptest.NewRequest(c.method, c.path, nil))
+		if ok != c.ok || (ok && rt.Method+" "+rt.Pattern != c.want) {
+			t.Fatalf("Match(%s %s) = %q,%v, want %q,%v", c.method, c.path, rt.Method+" "+rt.Pattern, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestMiddleware_WithRoutes(t *testing.T) {
+	table := NewRouteTable([]Route{{Pattern: "/login", Requests: 1, Interval: time.Minute}})
+	h := Middleware(nil, WithRoutes(table))(okHandler)
+
+	do := func(path string) int {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code
+	}
+
+	if code := do("/login"); code != http.StatusOK {
+		t.Fatalf("first login status = %d, want 200", code)
+	}
+	if code := do("/login"); code != http.StatusTooManyRequests {
+		t.Fatalf("second login status = %d, want 429", code)
+	}
+	for i := 0; i < 5; i++ {
+		if code := do("/home"); code != http.StatusOK {
+			t.Fatalf("unmatched route status = %d, want 200 (no default limiter)", code)
+		}
+	}
+}
+
+func TestNewRouteTable_KeyedOptions(t *testing.T) {
+	table := NewRouteTable([]Route{
+		{Pattern: "/a", Requests: 10, Interval: time.Minute},
+		{Pattern: "/b", Requests: 10, Interval: time.Minute},
+	}, limitron.WithMaxKeys[string](64, nil))
+
+	limiters := table.Limiters()
+	if len(limiters) != 2 {
+		t.Fatalf("Limiters() returned %d limiters, want 2", len(limiters))
+	}
+	for i := 0; i < 1000; i++ {
+		limiters[0].Take1(fmt.Sprintf("10.0.%d.%d", i/256, i%256))
+	}
+	if n := limiters[0].Len(); n > 64 {
+		t.Fatalf("route keeps %d clients, want at most 64", n)
+	}
+	if _, kl, _ := table.Match(httptest.NewRequest(http.MethodGet, "/b", nil)); kl != limiters[1] {
+		t.Fatal("Limiters() is not in route order")
+	}
+}
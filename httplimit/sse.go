@@ -0,0 +1,103 @@
+package httplimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/iryndin/limitron"
+)
+
+// SSEWriter paces Server-Sent Events emitted on a single connection, so that
+// dashboards and feeds do not overwhelm slow clients.
+//
+// Every event (see Event) or explicit Flush consumes one token of a per-connection
+// limiter state, blocking until the token is available or the request is cancelled.
+// Plain writes are passed through and reach the client with the next paced flush.
+//
+// SSEWriter implements http.ResponseWriter and http.Flusher, so it can be handed
+// to code that already writes SSE streams itself.
+type SSEWriter struct {
+	http.ResponseWriter
+	flusher http.Flusher
+	ctx     context.Context
+	limiter limitron.RateLimiter
+	state   *uint64
+}
+
+// ErrNoFlusher is returned by NewSSEWriter when the ResponseWriter cannot flush,
+// which Server-Sent Events require.
+var ErrNoFlusher = errors.New("httplimit: ResponseWriter does not implement http.Flusher")
+
+// NewSSEWriter wraps `w` to emit events of request `r` at most at the rate of `limiter`.
+// It sets the Server-Sent Events response headers.
+//
+// Example:
+//
+//	sse, err := httplimit.NewSSEWriter(w, r, limitron.BuildRateLimiterRps(5))
+//	if err != nil { ... }
+//	for update := range updates {
+//	    if err := sse.Event("update", update); err != nil {
+//	        return // client went away
+//	    }
+//	}
+func NewSSEWriter(w http.ResponseWriter, r *http.Request, limiter limitron.RateLimiter) (*SSEWriter, error) {
+	f, ok := w.(http.Flusher)
+	if !ok {
+		return nil, ErrNoFlusher
+	}
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	return &SSEWriter{
+		ResponseWriter: w,
+		flusher:        f,
+		ctx:            r.Context(),
+		limiter:        limiter,
+		state:          limiter.New(),
+	}, nil
+}
+
+// Event waits for the connection's pace, then writes and flushes a single event
+// with the given type (omitted if empty) and data. Multi-line data is split into
+// several data fields, as the SSE format requires.
+//
+// Returns the request context's error if the client went away while waiting,
+// or the write error.
+func (s *SSEWriter) Event(event, data string) error {
+	if err := s.limiter.WaitN(s.ctx, s.state, 1); err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	if event != "" {
+		fmt.Fprintf(&b, "event: %s\n", event)
+	}
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+
+	if _, err := s.ResponseWriter.Write([]byte(b.String())); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// Flush waits for the connection's pace and flushes buffered data to the client.
+// If the request is cancelled while waiting, nothing is flushed.
+func (s *SSEWriter) Flush() {
+	if err := s.limiter.WaitN(s.ctx, s.state, 1); err != nil {
+		return
+	}
+	s.flusher.Flush()
+}
+
+// Unwrap returns the underlying ResponseWriter, for use by http.ResponseController.
+func (s *SSEWriter) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
@@ -0,0 +1,66 @@
+package httplimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iryndin/limitron"
+)
+
+func TestSSEWriter_PacesEvents(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodGet, "/events", nil)
+	sse, err := NewSSEWriter(w, r, limitron.BuildRateLimiter(1, 50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewSSEWriter: %v", err)
+	}
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := sse.Event("tick", "a\nb"); err != nil {
+			t.Fatalf("Event %d: %v", i, err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Fatalf("3 events took %s, want paced at 1 per 50ms", elapsed)
+	}
+
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Content-Type = %q", ct)
+	}
+	if !w.Flushed {
+		t.Fatal("events should be flushed")
+	}
+	want := "event: tick\ndata: a\ndata: b\n\n"
+	if got := w.Body.String(); got != strings.Repeat(want, 3) {
+		t.Fatalf("body = %q, want 3x %q", got, want)
+	}
+}
+
+func TestSSEWriter_CancelledClient(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := httptest.NewRequest(http.MethodGet, "/events", nil).WithContext(ctx)
+	sse, _ := NewSSEWriter(httptest.NewRecorder(), r, limitron.BuildRateLimiter(1, time.Hour))
+
+	if err := sse.Event("", "first"); err != nil {
+		t.Fatalf("first Event: %v", err)
+	}
+	cancel()
+	if err := sse.Event("", "second"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Event after cancel = %v, want context.Canceled", err)
+	}
+}
+
+type noFlushWriter struct{ http.ResponseWriter }
+
+func TestNewSSEWriter_RequiresFlusher(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/events", nil)
+	if _, err := NewSSEWriter(noFlushWriter{httptest.NewRecorder()}, r, limitron.BuildRateLimiterRps(1)); !errors.Is(err, ErrNoFlusher) {
+		t.Fatalf("err = %v, want ErrNoFlusher", err)
+	}
+}
@@ -0,0 +1,135 @@
+package httplimit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/iryndin/limitron"
+)
+
+// DefaultHostTTL is the default idle time after which the limiter state of
+// a destination host is evicted (see WithHostTTL).
+const DefaultHostTTL = 10 * time.Minute
+
+// Transport is an http.RoundTripper limiting the rate of outbound requests
+// per destination host, plus optionally in total, much like
+// http.Transport.MaxConnsPerHost limits connections. Requests over the limit
+// wait for their turn, until their context is done.
+//
+// Host limiters are created on first use and evicted once idle for the host TTL.
+//
+// Example:
+//
+//	client := &http.Client{Transport: httplimit.NewTransport(http.DefaultTransport,
+//	    limitron.BuildRateLimiterRps(5),
+//	    httplimit.WithGlobalRate(limitron.BuildRateLimiterRps(50)))}
+type Transport struct {
+	base    http.RoundTripper
+	perHost *limitron.KeyedLimiter[string]
+	// global holds the total limit under the "" key; nil without one.
+	global *limitron.KeyedLimiter[string]
+
+	ttl time.Duration
+	// lastEvict is the time of the last eviction of idle hosts, in Unix nanoseconds.
+	lastEvict atomic.Int64
+}
+
+// TransportOption configures a Transport.
+type TransportOption func(*Transport)
+
+// WithGlobalRate caps the total rate of requests over all hosts at `limiter`.
+func WithGlobalRate(limiter limitron.RateLimiter) TransportOption {
+	return func(t *Transport) {
+		t.global = limitron.NewKeyedLimiter[string](limiter)
+	}
+}
+
+// WithHostTTL sets the idle time after which the state of a host is evicted.
+// It should be at least the refill time of the per-host limiter. Defaults to DefaultHostTTL.
+func WithHostTTL(ttl time.Duration) TransportOption {
+	return func(t *Transport) {
+		t.ttl = ttl
+	}
+}
+
+// NewTransport returns a Transport sending requests through `base`
+// (http.DefaultTransport if nil), limiting each destination host to `perHost`.
+func NewTransport(base http.RoundTripper, perHost limitron.RateLimiter, opts ...TransportOption) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	t := &Transport{
+		base:    base,
+		perHost: limitron.NewKeyedLimiter[string](perHost),
+		ttl:     DefaultHostTTL,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	t.lastEvict.Store(time.Now().UnixNano())
+	return t
+}
+
+// RoundTrip implements http.RoundTripper. It waits for the request's host limit,
+// then for the global limit, and returns the context error if the request's
+// context is done first, or a *limitron.LimitedError if a limit can never admit it.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.evictIdle()
+	if err := waitKey(req.Context(), t.perHost, req.URL.Host); err != nil {
+		return nil, err
+	}
+	if t.global != nil {
+		if err := waitKey(req.Context(), t.global, ""); err != nil {
+			return nil, err
+		}
+	}
+	return t.base.RoundTrip(req)
+}
+
+// Hosts returns the number of hosts with a limiter state.
+func (t *Transport) Hosts() int {
+	return t.perHost.Len()
+}
+
+// evictIdle evicts idle hosts in the background, at most once per TTL.
+func (t *Transport) evictIdle() {
+	last := t.lastEvict.Load()
+	now := time.Now().UnixNano()
+	if now-last < int64(t.ttl) || !t.lastEvict.CompareAndSwap(last, now) {
+		return
+	}
+	go t.perHost.EvictIdle(t.ttl)
+}
+
+// waitKey blocks until a token of `key` is consumed from `kl`, or until ctx is done.
+func waitKey(ctx context.Context, kl *limitron.KeyedLimiter[string], key string) error {
+	var timer *time.Timer
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+	for {
+		d := kl.Take1Result(key)
+		if d.Allowed {
+			return nil
+		}
+		if d.RetryAfter == math.MaxInt64 {
+			// can never be admitted
+			return &limitron.LimitedError{RetryAfter: d.RetryAfter}
+		}
+		if timer == nil {
+			timer = time.NewTimer(d.RetryAfter)
+		} else {
+			timer.Reset(d.RetryAfter)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package httplimit
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/iryndin/limitron"
+)
+
+// countingTransport answers every request with 200 and counts requests per host.
+type countingTransport map[string]int
+
+func (c countingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	c[r.URL.Host]++
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: r}, nil
+}
+
+func TestTransport_PerHostAndGlobal(t *testing.T) {
+	base := countingTransport{}
+	tr := NewTransport(base, limitron.BuildRateLimiter(2, time.Hour),
+		WithGlobalRate(limitron.BuildRateLimiter(3, time.Hour)))
+
+	get := func(host string, timeout time.Duration) error {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		req := httptest.NewRequest(http.MethodGet, "http://"+host+"/", nil).WithContext(ctx)
+		_, err := tr.RoundTrip(req)
+		return err
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := get("a.example", time.Second); err != nil {
+			t.Fatalf("request %d to a: %v", i, err)
+		}
+	}
+	if err := get("a.example", 20*time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("third request to a: err = %v, want a deadline error", err)
+	}
+	if err := get("b.example", time.Second); err != nil {
+		t.Fatalf("request to b: %v", err)
+	}
+	// the global cap of 3 is reached
+	if err := get("c.example", 20*time.Millisecond); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("request to c: err = %v, want a deadline error", err)
+	}
+	if base["a.example"] != 2 || base["b.example"] != 1 || base["c.example"] != 0 {
+		t.Fatalf("requests sent = %v", base)
+	}
+	if tr.Hosts() != 3 {
+		t.Fatalf("Hosts() = %d, want 3", tr.Hosts())
+	}
+}
+
+func TestTransport_WaitsForItsTurn(t *testing.T) {
+	tr := NewTransport(countingTransport{}, limitron.BuildRateLimiter(1, 30*time.Millisecond))
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		req := httptest.NewRequest(http.MethodGet, "http://a.example/", nil)
+		if _, err := tr.RoundTrip(req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("3 requests at 1 per 30ms took %v", elapsed)
+	}
+}
+
+func TestTransport_EvictsIdleHosts(t *testing.T) {
+	tr := NewTransport(countingTransport{}, limitron.BuildRateLimiterRps(100), WithHostTTL(20*time.Millisecond))
+	tr.RoundTrip(httptest.NewRequest(http.MethodGet, "http://a.example/", nil))
+	time.Sleep(30 * time.Millisecond)
+	tr.RoundTrip(httptest.NewRequest(http.MethodGet, "http://b.example/", nil))
+	time.Sleep(10 * time.Millisecond)
+	if tr.Hosts() != 1 {
+		t.Fatalf("Hosts() = %d after eviction, want 1", tr.Hosts())
+	}
+}
@@ -0,0 +1,33 @@
+package limitron
+
+import (
+	"math"
+	"time"
+)
+
+// WithJitter adds a per-key offset in [0, spread) to the wait suggested to denied
+// requests, so that clients throttled at the same time do not all retry at once.
+//
+// The offset is derived from the key's hash rather than drawn at random, so a given
+// client's retries are consistently offset from other clients' for the lifetime of
+// the KeyedLimiter, spreading retry load deterministically.
+// A non-positive spread disables jitter (the default).
+//
+// Example:
+//
+//	kl := NewKeyedLimiter[string](BuildRateLimiterRps(5), WithJitter[string](250*time.Millisecond))
+func WithJitter[K comparable](spread time.Duration) KeyedOption[K] {
+	return func(kl *KeyedLimiter[K]) {
+		kl.jitter = uint64(max(spread.Milliseconds(), 0))
+	}
+}
+
+// jittered adds the jitter offset of `key` to the suggested wait of a denied request.
+func (kl *KeyedLimiter[K]) jittered(key K, waitMillis int64) int64 {
+	if kl.jitter == 0 || waitMillis == math.MaxInt64 {
+		return waitMillis
+	}
+	// re-hash, so that the offset is independent of the shard index taken from the low bits
+	offset := mixUint64(kl.seed, hashKey(kl.seed, key)) % kl.jitter
+	return waitMillis + int64(offset)
+}
@@ -0,0 +1,41 @@
+package limitron
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestKeyedLimiter_JitterDeterministicPerKey(t *testing.T) {
+	kl := NewKeyedLimiter[string](BuildRateLimiter(1, time.Hour), WithJitter[string](time.Second))
+	base := NewKeyedLimiter[string](BuildRateLimiter(1, time.Hour))
+
+	offsets := map[int64]bool{}
+	for i := 0; i < 20; i++ {
+		key := fmt.Sprint("client-", i)
+		kl.Take1(key)
+		base.Take1(key)
+		w1, _ := kl.Take1(key)
+		w2, _ := kl.Take1(key)
+		plain, _ := base.Take1(key)
+
+		offset := w1 - plain
+		if offset < 0 || offset >= 1000+2 {
+			t.Fatalf("%s: offset %d outside [0, 1s)", key, offset)
+		}
+		if d := w1 - w2; d < -2 || d > 2 {
+			t.Fatalf("%s: retries got waits %d and %d, want the same offset", key, w1, w2)
+		}
+		offsets[offset/10] = true
+	}
+	if len(offsets) < 10 {
+		t.Fatalf("offsets of 20 keys fell into %d distinct 10ms slots, want them spread", len(offsets))
+	}
+}
+
+func TestKeyedLimiter_JitterKeepsNeverSucceeding(t *testing.T) {
+	kl := NewKeyedLimiter[string](BuildRateLimiter(1, time.Hour), WithJitter[string](time.Second))
+	if w, ok := kl.TakeN("a", 2); ok || w != 1<<63-1 {
+		t.Fatalf("TakeN over burst => %d,%v, want MaxInt64,false", w, ok)
+	}
+}
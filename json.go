@@ -0,0 +1,105 @@
+package limitron
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+)
+
+// rateLimiterJSON is the JSON form of a RateLimiter configuration.
+type rateLimiterJSON struct {
+	Requests uint16 `json:"requests"`
+	// Interval is a Go duration string such as "1s" or "1m".
+	Interval string `json:"interval"`
+	Burst    uint16 `json:"burst,omitempty"`
+	Retries  int    `json:"retries,omitempty"`
+
+	Penalty      string  `json:"penalty,omitempty"`
+	Debt         uint16  `json:"debt,omitempty"`
+	MinSpacing   bool    `json:"min_spacing,omitempty"`
+	Shedding     float64 `json:"shedding,omitempty"`
+	Microseconds bool    `json:"microseconds,omitempty"`
+	StartEmpty   bool    `json:"start_empty,omitempty"`
+}
+
+// MarshalJSON encodes the configuration of the limiter as a JSON object with the number
+// of requests per interval, the burst and the CAS retries, along with the options set:
+//
+//	{"requests": 100, "interval": "1m0s", "burst": 100, "retries": 5}
+//
+// The rate is expressed as the burst per the time to refill it, which may differ from the
+// requests and interval the limiter was built with but is equivalent. The clock and
+// metrics are not encoded.
+func (s RateLimiter) MarshalJSON() ([]byte, error) {
+	if !(s.rrpm > 0) || math.IsInf(s.rrpm, 0) || s.maxreq == 0 {
+		return nil, fmt.Errorf("limitron: cannot encode RateLimiter with burst %d and refill rate %v", s.maxreq, s.rrpm)
+	}
+	v := rateLimiterJSON{
+		Requests:     s.maxreq,
+		Interval:     time.Duration(math.Round(float64(s.maxreq) / s.rrpm * float64(time.Millisecond))).String(),
+		Burst:        s.maxreq,
+		Retries:      s.retries,
+		Debt:         s.debt,
+		MinSpacing:   s.spaced,
+		Shedding:     s.shedding,
+		Microseconds: s.micros,
+		StartEmpty:   s.startEmpty,
+	}
+	if s.penalty > 0 {
+		v.Penalty = millisToDuration(int64(s.penalty)).String()
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON decodes a configuration encoded by MarshalJSON into the limiter, keeping
+// its clock and metrics. The burst defaults to the requests and the retries to
+// UpdateRetries; the configuration is validated as by NewRateLimiter.
+//
+// Example:
+//
+//	var limiter RateLimiter
+//	err := json.Unmarshal([]byte(`{"requests": 100, "interval": "1m"}`), &limiter)
+func (s *RateLimiter) UnmarshalJSON(data []byte) error {
+	var v rateLimiterJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	interval, err := time.ParseDuration(v.Interval)
+	if err != nil {
+		return fmt.Errorf("limitron: interval: %w", err)
+	}
+	opts := []Option{WithDebt(v.Debt)}
+	if v.Burst > 0 {
+		opts = append(opts, WithBurst(v.Burst))
+	}
+	if v.Retries != 0 {
+		opts = append(opts, WithRetries(v.Retries))
+	}
+	if v.Penalty != "" {
+		penalty, err := time.ParseDuration(v.Penalty)
+		if err != nil {
+			return fmt.Errorf("limitron: penalty: %w", err)
+		}
+		opts = append(opts, WithPunitive(penalty))
+	}
+	if v.MinSpacing {
+		opts = append(opts, WithMinSpacing())
+	}
+	if v.Microseconds {
+		opts = append(opts, WithMicroseconds())
+	}
+	if v.StartEmpty {
+		opts = append(opts, WithStartEmpty())
+	}
+	if v.Shedding > 0 {
+		opts = append(opts, WithShedding(v.Shedding))
+	}
+	limiter, err := NewRateLimiter(v.Requests, interval, opts...)
+	if err != nil {
+		return err
+	}
+	limiter.clock, limiter.metrics = s.clock, s.metrics
+	*s = limiter
+	return nil
+}
@@ -0,0 +1,55 @@
+package limitron
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter_JSONRoundTrip(t *testing.T) {
+	for _, limiter := range []RateLimiter{
+		BuildRateLimiter(100, time.Minute),
+		BuildRateLimiterRps(10, WithBurst(50), WithRetries(8)),
+		BuildRateLimiter(3, time.Hour, WithPunitive(time.Second), WithDebt(2), WithMinSpacing(),
+			WithShedding(0.5), WithMicroseconds(), WithStartEmpty()),
+	} {
+		data, err := json.Marshal(limiter)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var restored RateLimiter
+		if err := json.Unmarshal(data, &restored); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", data, err)
+		}
+		if restored.maxreq != limiter.maxreq || restored.retries != limiter.retries ||
+			math.Abs(restored.rrpm-limiter.rrpm) > 1e-12 {
+			t.Fatalf("restored %+v from %s, want %+v", restored, data, limiter)
+		}
+		restored.rrpm = limiter.rrpm
+		if restored != limiter {
+			t.Fatalf("restored %+v from %s, want %+v", restored, data, limiter)
+		}
+	}
+}
+
+func TestRateLimiter_UnmarshalJSON(t *testing.T) {
+	var limiter RateLimiter
+	if err := json.Unmarshal([]byte(`{"requests": 100, "interval": "1m"}`), &limiter); err != nil {
+		t.Fatal(err)
+	}
+	if want := BuildRateLimiter(100, time.Minute); limiter != want {
+		t.Fatalf("limiter = %+v, want %+v", limiter, want)
+	}
+	data, _ := json.Marshal(limiter)
+	if got, want := string(data), `{"requests":100,"interval":"1m0s","burst":100,"retries":5}`; got != want {
+		t.Fatalf("Marshal = %s, want %s", got, want)
+	}
+
+	for _, bad := range []string{`{"requests": 0, "interval": "1s"}`, `{"requests": 5, "interval": "soon"}`,
+		`{"requests": 5, "interval": "1s", "penalty": "x"}`, `[]`} {
+		if err := json.Unmarshal([]byte(bad), &limiter); err == nil {
+			t.Errorf("Unmarshal(%s): no error", bad)
+		}
+	}
+}
@@ -0,0 +1,550 @@
+package limitron
+
+import (
+	"hash/maphash"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// keyedShards is the number of shards of a KeyedLimiter. Must be a power of two.
+const keyedShards = 64
+
+// KeyedLimiter owns the per-key limiter states of a RateLimiter.
+//
+// States are created lazily on first use and stored in a sharded map, so that
+// lookups of different keys rarely contend on the same lock. Once obtained,
+// a state is updated with the usual lock-free CAS loop.
+//
+// The zero value is not usable; create instances with NewKeyedLimiter.
+// All methods are safe for concurrent use.
+type KeyedLimiter[K comparable] struct {
+	limiter RateLimiter
+	seed    maphash.Seed
+	shards  [keyedShards]keyedShard[K]
+
+	// namespace maps a key to its namespace; nil puts all keys into the "" namespace.
+	namespace func(K) string
+	// modes is a copy-on-write map of per-namespace enforcement modes.
+	modes   atomic.Pointer[map[string]EnforcementMode]
+	modesMu sync.Mutex
+	// onShadowDeny is called for requests that would have been denied in Shadow mode.
+	onShadowDeny func(key K, waitMillis int64)
+
+	// grace is the grace period of new keys in milliseconds (see WithGracePeriod).
+	grace uint64
+	// graceLimiter applies during the grace period; a zero limiter means no limit.
+	graceLimiter RateLimiter
+
+	// warmUp is the warm-up period of new keys in milliseconds (see WithWarmUp); 0 disables it.
+	warmUp uint64
+	// warmUpFrom is the factor of the limits at the start of the warm-up.
+	warmUpFrom float64
+
+	// penalty escalates limits for repeat offenders (see WithPenaltyPolicy); nil when disabled.
+	penalty *penaltyLevels
+
+	// reputation scales limits by a per-key reputation score (see WithReputation); nil when disabled.
+	reputation *reputationScaler[K]
+
+	// jitter is the spread in milliseconds of per-key wait offsets (see WithJitter); 0 disables it.
+	jitter uint64
+
+	// audit receives every decision of TakeN (see WithAudit); nil when disabled.
+	audit func(AuditEvent[K])
+
+	// usage counts evictions by namespace (see MemoryUsage).
+	usage keyedUsage
+
+	// bans holds the banned keys (see Ban).
+	bans banList[K]
+
+	// hot caches the entries of the hottest keys (see WithHotKeys); nil when disabled.
+	hot *hotKeys[K]
+
+	// lru bounds the number of keys (see WithMaxKeys); nil when unbounded.
+	lru *keyedLRU[K]
+
+	// tier maps a key to its tier; nil puts all keys on the default limiter (see WithTierFunc).
+	tier func(K) string
+	// tiers is a copy-on-write map of per-tier limiters.
+	tiers   atomic.Pointer[map[string]RateLimiter]
+	tiersMu sync.Mutex
+}
+
+// KeyedOption configures optional behavior of a KeyedLimiter.
+type KeyedOption[K comparable] func(*KeyedLimiter[K])
+
+// keyedShard is a single lock-protected part of the key space.
+type keyedShard[K comparable] struct {
+	mu      sync.RWMutex
+	entries map[K]*keyedEntry
+	// lruMu guards lru, the sentinel of the list of keys of WithMaxKeys, from the most
+	// (lru.next) to the least (lru.prev) recently used. It is separate from mu so that
+	// lookups under the read lock can reorder the list.
+	lruMu sync.Mutex
+	lru   lruNode[K]
+	_     [32]byte // reduce false sharing between neighbouring shards
+}
+
+// keyedEntry is the per-key data of a KeyedLimiter.
+type keyedEntry struct {
+	// state is the packed limiter state of the key.
+	state uint64
+	// created is the creation time of the entry in Unix milliseconds (limiter clock).
+	created uint64
+	// offenses is the packed offense state used by penalty policies:
+	// [ 16-bit offense count ][ 48-bit last offense time in ms ].
+	offenses uint64
+	// reputation is the packed reputation state (see WithReputation):
+	// [ 16-bit score in thousandths ][ 48-bit last refresh time in ms ].
+	reputation uint64
+	// boost is the packed temporary limit boost (see Boost):
+	// [ 16-bit factor in thousandths ][ 48-bit expiry time in ms ].
+	boost uint64
+	// node is the *lruNode[K] of the entry with WithMaxKeys; nil otherwise.
+	node any
+}
+
+// NewKeyedLimiter returns an empty KeyedLimiter applying `limiter` to every key.
+//
+// Example:
+//
+//	perIP := NewKeyedLimiter[string](BuildRateLimiterRps(10))
+//	if _, ok := perIP.Take1(clientIP); !ok {
+//	    // rate limited
+//	}
+func NewKeyedLimiter[K comparable](limiter RateLimiter, opts ...KeyedOption[K]) *KeyedLimiter[K] {
+	kl := &KeyedLimiter[K]{
+		limiter: limiter,
+		seed:    maphash.MakeSeed(),
+	}
+	for i := range kl.shards {
+		kl.shards[i].entries = make(map[K]*keyedEntry)
+	}
+	for _, opt := range opts {
+		opt(kl)
+	}
+	return kl
+}
+
+// Limiter returns the RateLimiter applied to every key.
+func (kl *KeyedLimiter[K]) Limiter() RateLimiter {
+	return kl.limiter
+}
+
+// TakeN attempts to consume `requests` tokens from the state of `key`,
+// creating the state if needed. See RateLimiter.TakeN.
+//
+// The result depends on the enforcement mode of the key's namespace
+// (see SetNamespaceMode): in Off mode the state is not touched and the request
+// is always allowed; in Shadow mode tokens are consumed as usual, but denials
+// are only reported to the WithShadowDenied callback and the request is allowed.
+// Banned keys (see Ban) are denied in every mode.
+func (kl *KeyedLimiter[K]) TakeN(key K, requests uint16) (int64, bool) {
+	return kl.take(key, nil, requests, kl.limiter.nowTicks())
+}
+
+// TakeNAt is TakeN with the refill of the state of `key` evaluated at time `t`.
+// See RateLimiter.TakeNAt.
+func (kl *KeyedLimiter[K]) TakeNAt(key K, requests uint16, t time.Time) (int64, bool) {
+	return kl.take(key, nil, requests, kl.limiter.ticksOf(t))
+}
+
+// Take1At is TakeNAt for 1 token.
+func (kl *KeyedLimiter[K]) Take1At(key K, t time.Time) (int64, bool) {
+	return kl.TakeNAt(key, 1, t)
+}
+
+// take implements TakeN with the refill evaluated at time `now` in state clock ticks (see ticksOf).
+// `e` is the entry of `key` if already resolved, or nil to look it up when needed.
+func (kl *KeyedLimiter[K]) take(key K, e *keyedEntry, requests uint16, now uint64) (int64, bool) {
+	mode := kl.mode(key)
+	if waitMillis, banned := kl.banned(key); banned {
+		kl.record(key, requests, mode, waitMillis, false)
+		return waitMillis, false
+	}
+	if mode == Off {
+		kl.record(key, requests, mode, 0, true)
+		return 0, true
+	}
+
+	if e == nil {
+		e = kl.entry(key)
+	}
+	waitMillis, ok := kl.takeEntry(key, e, requests, now, false)
+	if !ok && mode == Shadow {
+		if kl.onShadowDeny != nil {
+			kl.onShadowDeny(key, waitMillis)
+		}
+		kl.record(key, requests, mode, waitMillis, true)
+		return 0, true
+	}
+	if !ok {
+		waitMillis = kl.jittered(key, waitMillis)
+	}
+	kl.record(key, requests, mode, waitMillis, ok)
+	return waitMillis, ok
+}
+
+// takeEntry consumes `requests` tokens from entry `e` with the limiter currently in effect
+// for it, taking grace periods and penalties into account. The bucket is refilled up to `now`.
+// The decision is reported to the limiter's Metrics unless `peek` is set.
+func (kl *KeyedLimiter[K]) takeEntry(key K, e *keyedEntry, requests uint16, now uint64, peek bool) (int64, bool) {
+	limiter := kl.limiterFor(key, e)
+	if kl.reputation != nil {
+		var waitMillis int64
+		var banned bool
+		if limiter, waitMillis, banned = kl.reputation.apply(key, e, limiter); banned {
+			return waitMillis, false
+		}
+	}
+	if kl.penalty != nil {
+		penalized, waitMillis, banned := kl.penalty.limiterFor(e, limiter)
+		if banned {
+			return waitMillis, false
+		}
+		if penalized.maxreq == 0 {
+			return 0, true
+		}
+		if peek {
+			penalized.metrics = nil
+		}
+		waitMillis, ok := penalized.observe(penalized.takeNAt(&e.state, requests, now))
+		if !ok {
+			kl.penalty.recordOffense(e)
+		}
+		return waitMillis, ok
+	}
+	if limiter.maxreq == 0 {
+		return 0, true
+	}
+	if peek {
+		limiter.metrics = nil
+	}
+	return limiter.observe(limiter.takeNAt(&e.state, requests, now))
+}
+
+// ReturnN returns `n` tokens taken by TakeN to the state of `key`, capped at the burst
+// of the limiter in effect for it, to roll back a take whose overall operation was denied.
+// Keys without a state, or in Off mode, are left alone.
+func (kl *KeyedLimiter[K]) ReturnN(key K, n uint16) {
+	if kl.mode(key) == Off {
+		return
+	}
+	e := kl.lookup(key)
+	if e == nil {
+		return
+	}
+	if limiter := kl.limiterFor(key, e); limiter.maxreq > 0 {
+		limiter.ReturnN(&e.state, n)
+	}
+}
+
+// Merge combines the state of `src` into the state of `dst`, creating it if needed,
+// as RateLimiter.Merge does. It reports false, leaving `dst` alone, if `src` has no state.
+// The state of `src` is kept.
+//
+// Example:
+//
+//	for _, ip := range subnetIPs {
+//	    kl.Merge(subnet.String(), ip.String())
+//	}
+func (kl *KeyedLimiter[K]) Merge(dst, src K) bool {
+	se := kl.lookup(src)
+	if se == nil {
+		return false
+	}
+	de := kl.entry(dst)
+	if de != se {
+		kl.limiterOf(dst).Merge(&de.state, &se.state)
+	}
+	return true
+}
+
+// Penalize removes `n` tokens from the state of `key` as a punishment, creating the state
+// if needed, with the limiter in effect for it. Keys in Off mode are left alone.
+// See RateLimiter.Penalize.
+func (kl *KeyedLimiter[K]) Penalize(key K, n uint16) {
+	if kl.mode(key) == Off {
+		return
+	}
+	e := kl.entry(key)
+	if limiter := kl.limiterFor(key, e); limiter.maxreq > 0 {
+		limiter.Penalize(&e.state, n)
+	}
+}
+
+// Take1 attempts to consume 1 token from the state of `key`. See RateLimiter.Take1.
+func (kl *KeyedLimiter[K]) Take1(key K) (int64, bool) {
+	return kl.TakeN(key, 1)
+}
+
+// Peek reports what TakeN(key, requests) would return right now, without consuming
+// tokens, recording offenses or creating a state for `key`.
+//
+// Keys without a state are evaluated as new keys with a neutral reputation.
+// Concurrent updates may change the outcome before a subsequent TakeN.
+func (kl *KeyedLimiter[K]) Peek(key K, requests uint16) (int64, bool) {
+	if waitMillis, banned := kl.banned(key); banned {
+		return waitMillis, false
+	}
+	mode := kl.mode(key)
+	if mode == Off {
+		return 0, true
+	}
+
+	sh := kl.shard(key)
+	sh.mu.RLock()
+	e, ok := sh.entries[key]
+	sh.mu.RUnlock()
+
+	// evaluate a copy of the entry, so that nothing is recorded
+	var cp keyedEntry
+	if ok {
+		cp = keyedEntry{
+			state:      atomic.LoadUint64(&e.state),
+			created:    e.created,
+			offenses:   atomic.LoadUint64(&e.offenses),
+			reputation: atomic.LoadUint64(&e.reputation),
+			boost:      atomic.LoadUint64(&e.boost),
+		}
+	} else {
+		now := kl.limiter.nowMillis()
+		cp = keyedEntry{
+			state:      kl.limiterOf(key).initialState(),
+			created:    now,
+			reputation: packUint16AndUint48(1000, now),
+		}
+	}
+	if kl.reputation != nil {
+		// a stale score must not schedule a refresh on behalf of a copy
+		score, _ := unpackUint16Uint48(cp.reputation)
+		cp.reputation = packUint16AndUint48(score, kl.limiter.nowMillis())
+	}
+
+	waitMillis, allowed := kl.takeEntry(key, &cp, requests, kl.limiter.nowTicks(), true)
+	if !allowed && mode == Shadow {
+		return 0, true
+	}
+	if !allowed {
+		waitMillis = kl.jittered(key, waitMillis)
+	}
+	return waitMillis, allowed
+}
+
+// Len returns the number of keys with a state.
+func (kl *KeyedLimiter[K]) Len() int {
+	n := 0
+	for i := range kl.shards {
+		sh := &kl.shards[i]
+		sh.mu.RLock()
+		n += len(sh.entries)
+		sh.mu.RUnlock()
+	}
+	return n
+}
+
+// GetOrCreate returns the packed state of `key`, creating it if needed, for use with
+// the methods of Limiter() that take a state, such as ReserveN or TakeNResult.
+// Updates through the pointer bypass the enforcement modes, bans and per-key policies
+// of the KeyedLimiter. Once the key is deleted or evicted, the pointer is detached:
+// updates through it no longer apply to the key.
+//
+// Example:
+//
+//	r := kl.Limiter().ReserveN(kl.GetOrCreate(userID), 5)
+func (kl *KeyedLimiter[K]) GetOrCreate(key K) *uint64 {
+	return &kl.entry(key).state
+}
+
+// Delete removes the state of `key`, reporting whether it had one. The key starts over
+// with a new state on its next use. Deletions are counted as evictions (see MemoryUsage).
+func (kl *KeyedLimiter[K]) Delete(key K) bool {
+	h := hashKey(kl.seed, key)
+	sh := &kl.shards[h&(keyedShards-1)]
+	sh.mu.Lock()
+	e, ok := sh.entries[key]
+	if ok {
+		delete(sh.entries, key)
+		kl.unlinkLRU(sh, e)
+		if kl.hot != nil {
+			kl.hot.remove(h, e)
+		}
+	}
+	sh.mu.Unlock()
+	if ok {
+		kl.evicted(key)
+	}
+	return ok
+}
+
+// LastAccess returns the time `key` last consumed tokens, as recorded in its packed state.
+//
+// Returns false if the key has no state. A key whose state was created but never
+// updated reports the zero time. The refill clock keeps the progress towards the next
+// token, so the time recorded may precede the actual access by up to the refill
+// time of one token.
+func (kl *KeyedLimiter[K]) LastAccess(key K) (time.Time, bool) {
+	sh := kl.shard(key)
+	sh.mu.RLock()
+	e, ok := sh.entries[key]
+	sh.mu.RUnlock()
+	if !ok {
+		return time.Time{}, false
+	}
+	return stateLastAccess(kl.limiter, atomic.LoadUint64(&e.state)), true
+}
+
+// RangeIdle calls fn for every key that has not been accessed for at least `idle`,
+// passing its last access time. If fn returns false, the iteration stops.
+//
+// Keys are visited shard by shard, and fn is called without holding any lock,
+// so it may safely call other methods of the KeyedLimiter. Keys added or accessed
+// concurrently may or may not be visited.
+func (kl *KeyedLimiter[K]) RangeIdle(idle time.Duration, fn func(key K, lastAccess time.Time) bool) {
+	cutoff := time.UnixMilli(int64(kl.limiter.nowMillis())).Add(-idle)
+
+	type idleKey struct {
+		key        K
+		lastAccess time.Time
+	}
+	var batch []idleKey
+
+	for i := range kl.shards {
+		sh := &kl.shards[i]
+		batch = batch[:0]
+		sh.mu.RLock()
+		for k, e := range sh.entries {
+			if last := stateLastAccess(kl.limiter, atomic.LoadUint64(&e.state)); !last.After(cutoff) {
+				batch = append(batch, idleKey{k, last})
+			}
+		}
+		sh.mu.RUnlock()
+
+		for _, ik := range batch {
+			if !fn(ik.key, ik.lastAccess) {
+				return
+			}
+		}
+	}
+}
+
+// entry returns the entry of `key`, creating it if needed.
+func (kl *KeyedLimiter[K]) entry(key K) *keyedEntry {
+	h := hashKey(kl.seed, key)
+	sh := &kl.shards[h&(keyedShards-1)]
+	if kl.hot != nil {
+		if e := kl.hot.get(key, h); e != nil {
+			kl.touch(sh, e)
+			return e
+		}
+	}
+
+	sh.mu.RLock()
+	e, ok := sh.entries[key]
+	sh.mu.RUnlock()
+	if ok {
+		if kl.hot != nil && kl.hot.miss(key, h, e) {
+			// an eviction may have removed the entry before it was promoted
+			sh.mu.RLock()
+			cur := sh.entries[key]
+			sh.mu.RUnlock()
+			if cur != e {
+				kl.hot.remove(h, e)
+			}
+		}
+		kl.touch(sh, e)
+		return e
+	}
+
+	// consult external providers before taking the lock, as they may be slow
+	var reputation uint64
+	if kl.reputation != nil {
+		reputation = kl.reputation.initial(key)
+	}
+
+	var evicted K
+	var didEvict bool
+	sh.mu.Lock()
+	if e, ok = sh.entries[key]; !ok {
+		e = &keyedEntry{
+			state:      kl.limiterOf(key).initialState(),
+			created:    kl.limiter.nowMillis(),
+			reputation: reputation,
+		}
+		evicted, didEvict = kl.insert(sh, key, e)
+	}
+	sh.mu.Unlock()
+	kl.notifyEvicted(evicted, didEvict)
+	return e
+}
+
+// lookup returns the entry of `key`, or nil if it has none.
+func (kl *KeyedLimiter[K]) lookup(key K) *keyedEntry {
+	sh := kl.shard(key)
+	sh.mu.RLock()
+	defer sh.mu.RUnlock()
+	return sh.entries[key]
+}
+
+// shard returns the shard responsible for `key`.
+func (kl *KeyedLimiter[K]) shard(key K) *keyedShard[K] {
+	return &kl.shards[hashKey(kl.seed, key)&(keyedShards-1)]
+}
+
+// stateLastAccess decodes the last access timestamp of a packed state of `limiter`.
+func stateLastAccess(limiter RateLimiter, rlval uint64) time.Time {
+	_, ts := unpackUint16Uint48(rlval)
+	if ts == 0 {
+		return time.Time{}
+	}
+	return limiter.timeOf(ts)
+}
+
+// hashKey hashes a comparable key. Strings, integers and IP addresses are hashed directly;
+// other key types are hashed by value with hashComparable, so that keys that are == hash
+// alike.
+func hashKey[K comparable](seed maphash.Seed, key K) uint64 {
+	switch k := any(key).(type) {
+	case string:
+		return maphash.String(seed, k)
+	case int:
+		return mixUint64(seed, uint64(k))
+	case int8:
+		return mixUint64(seed, uint64(k))
+	case int16:
+		return mixUint64(seed, uint64(k))
+	case int32:
+		return mixUint64(seed, uint64(k))
+	case int64:
+		return mixUint64(seed, uint64(k))
+	case uint:
+		return mixUint64(seed, uint64(k))
+	case uint8:
+		return mixUint64(seed, uint64(k))
+	case uint16:
+		return mixUint64(seed, uint64(k))
+	case uint32:
+		return mixUint64(seed, uint64(k))
+	case uint64:
+		return mixUint64(seed, k)
+	case uintptr:
+		return mixUint64(seed, uint64(k))
+	case netip.Addr:
+		b := k.As16()
+		return maphash.Bytes(seed, b[:])
+	default:
+		return hashComparable(seed, key)
+	}
+}
+
+// mixUint64 hashes an integer key with the given seed.
+func mixUint64(seed maphash.Seed, v uint64) uint64 {
+	var buf [8]byte
+	for i := range buf {
+		buf[i] = byte(v >> (8 * i))
+	}
+	return maphash.Bytes(seed, buf[:])
+}
@@ -0,0 +1,266 @@
+package limitron
+
+import (
+	"fmt"
+	"hash/maphash"
+	"math"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestKeyedLimiter_IndependentKeys(t *testing.T) {
+	kl := NewKeyedLimiter[string](BuildRateLimiter(2, time.Hour))
+
+	for i := 0; i < 2; i++ {
+		if _, ok := kl.Take1("a"); !ok {
+			t.Fatalf("a: take %d should succeed", i)
+		}
+	}
+	if _, ok := kl.Take1("a"); ok {
+		t.Fatal("a: take over limit should be denied")
+	}
+	if _, ok := kl.Take1("b"); !ok {
+		t.Fatal("b: take should succeed independently of a")
+	}
+	if got := kl.Len(); got != 2 {
+		t.Fatalf("Len = %d, want 2", got)
+	}
+}
+
+func TestKeyedLimiter_Peek(t *testing.T) {
+	kl := NewKeyedLimiter[string](BuildRateLimiter(2, time.Hour))
+
+	if _, ok := kl.Peek("a", 2); !ok {
+		t.Fatal("Peek of an unseen key within the burst should be allowed")
+	}
+	if kl.Len() != 0 {
+		t.Fatal("Peek should not create a state")
+	}
+
+	kl.Take1("a")
+	kl.Take1("a")
+	if wait, ok := kl.Peek("a", 1); ok || wait <= 0 {
+		t.Fatalf("Peek of an exhausted key => wait=%d ok=%v, want positive,false", wait, ok)
+	}
+	for i := 0; i < 3; i++ {
+		kl.Peek("b", 1)
+	}
+	for i := 0; i < 2; i++ {
+		if _, ok := kl.Take1("b"); !ok {
+			t.Fatalf("b: take %d after Peek calls should succeed", i)
+		}
+	}
+}
+
+func TestKeyedLimiter_PeekNotObserved(t *testing.T) {
+	hist := NewWaitHistogram(10*time.Millisecond, time.Second)
+	kl := NewKeyedLimiter[string](BuildRateLimiter(2, time.Hour, WithMetrics(hist)))
+	for i := 0; i < 10; i++ {
+		kl.Peek("a", 1)
+	}
+	kl.Take1("a")
+	if snap := hist.Snapshot(); snap.Allowed != 1 || snap.Denied != 0 {
+		t.Fatalf("allowed=%d denied=%d, want only the take observed", snap.Allowed, snap.Denied)
+	}
+}
+
+func TestKeyedLimiter_ConcurrentSameKey(t *testing.T) {
+	kl := NewKeyedLimiter[int](BuildRateLimiter(50, time.Hour))
+
+	var mu sync.Mutex
+	success := 0
+	var wg sync.WaitGroup
+	wg.Add(100)
+	for i := 0; i < 100; i++ {
+		go func() {
+			defer wg.Done()
+			if _, ok := kl.Take1(7); ok {
+				mu.Lock()
+				success++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if success > 50 {
+		t.Fatalf("successes=%d exceed burst=50", success)
+	}
+	if got := kl.Len(); got != 1 {
+		t.Fatalf("Len = %d, want 1", got)
+	}
+}
+
+func TestKeyedLimiter_LastAccess(t *testing.T) {
+	kl := NewKeyedLimiter[string](BuildRateLimiterRps(10))
+
+	if _, ok := kl.LastAccess("missing"); ok {
+		t.Fatal("LastAccess of unknown key should report false")
+	}
+
+	before := time.Now().Truncate(time.Millisecond)
+	kl.Take1("a")
+	last, ok := kl.LastAccess("a")
+	if !ok {
+		t.Fatal("LastAccess of known key should report true")
+	}
+	if last.Before(before) || last.After(time.Now()) {
+		t.Fatalf("LastAccess = %v, want between %v and now", last, before)
+	}
+}
+
+func TestKeyedLimiter_RangeIdle(t *testing.T) {
+	kl := NewKeyedLimiter[string](BuildRateLimiterRps(10))
+	kl.Take1("old")
+	time.Sleep(60 * time.Millisecond)
+	kl.Take1("fresh")
+
+	var idle []string
+	kl.RangeIdle(50*time.Millisecond, func(key string, lastAccess time.Time) bool {
+		idle = append(idle, key)
+		return true
+	})
+	if len(idle) != 1 || idle[0] != "old" {
+		t.Fatalf("idle keys = %v, want [old]", idle)
+	}
+
+	// stop early
+	for i := 0; i < 10; i++ {
+		kl.Take1(fmt.Sprint("k", i))
+	}
+	visited := 0
+	kl.RangeIdle(0, func(string, time.Time) bool {
+		visited++
+		return false
+	})
+	if visited != 1 {
+		t.Fatalf("visited = %d after returning false, want 1", visited)
+	}
+}
+
+func TestHashKey_EqualKeysEqualHashes(t *testing.T) {
+	seed := maphash.MakeSeed()
+	type pair struct{ a, b int }
+	if hashKey(seed, "x") != hashKey(seed, "x") {
+		t.Fatal("string hash is not stable")
+	}
+	if hashKey(seed, uint32(42)) != hashKey(seed, uint32(42)) {
+		t.Fatal("integer hash is not stable")
+	}
+	if hashKey(seed, pair{1, 2}) != hashKey(seed, pair{1, 2}) {
+		t.Fatal("struct hash is not stable")
+	}
+	if hashKey(seed, 0.0) != hashKey(seed, math.Copysign(0, -1)) {
+		t.Fatal("0.0 and -0.0 are equal keys but hash differently")
+	}
+	type wrapped struct {
+		name any
+		x    float64
+	}
+	if hashKey(seed, wrapped{"a", 0}) != hashKey(seed, wrapped{"a", math.Copysign(0, -1)}) {
+		t.Fatal("equal struct keys hash differently")
+	}
+	// a Stringer whose text changes must keep its hash
+	c := &counterKey{}
+	h := hashKey(seed, c)
+	c.n++
+	if hashKey(seed, c) != h {
+		t.Fatal("pointer key hashed by its String method")
+	}
+}
+
+// counterKey is a key whose String method changes with its contents.
+type counterKey struct{ n int }
+
+func (c *counterKey) String() string { return fmt.Sprint(c.n) }
+
+func TestKeyedLimiter_ReturnN(t *testing.T) {
+	kl := NewKeyedLimiter[string](BuildRateLimiter(2, time.Hour))
+	kl.TakeN("a", 2)
+	kl.ReturnN("a", 1)
+	kl.ReturnN("unknown", 1)
+
+	if _, ok := kl.Take1("a"); !ok {
+		t.Fatal("refunded token not available")
+	}
+	if _, ok := kl.Take1("a"); ok {
+		t.Fatal("more tokens refunded than returned")
+	}
+	if kl.Len() != 1 {
+		t.Fatalf("Len() = %d, ReturnN must not create states", kl.Len())
+	}
+}
+
+func TestKeyedLimiter_TakeNAt(t *testing.T) {
+	kl := NewKeyedLimiter[string](BuildRateLimiter(1, time.Minute))
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if _, ok := kl.Take1At("k", start); !ok {
+		t.Fatal("first event denied")
+	}
+	if _, ok := kl.Take1At("k", start.Add(30*time.Second)); ok {
+		t.Fatal("second event allowed within the minute")
+	}
+	if _, ok := kl.TakeNAt("k", 1, start.Add(time.Minute)); !ok {
+		t.Fatal("event denied a minute later")
+	}
+}
+
+func TestKeyedLimiter_Penalize(t *testing.T) {
+	kl := NewKeyedLimiter[string](BuildRateLimiter(10, time.Hour))
+	kl.Penalize("abuser", 8)
+	if _, ok := kl.TakeN("abuser", 3); ok {
+		t.Fatal("penalized tokens still available")
+	}
+	if _, ok := kl.TakeN("abuser", 2); !ok {
+		t.Fatal("tokens left after the penalty denied")
+	}
+	if _, ok := kl.TakeN("other", 10); !ok {
+		t.Fatal("penalty applied to another key")
+	}
+}
+
+func TestKeyedLimiter_Merge(t *testing.T) {
+	kl := NewKeyedLimiter[string](BuildRateLimiter(10, time.Hour))
+	kl.TakeN("10.0.0.1", 6)
+	kl.TakeN("10.0.0.2", 2)
+
+	if kl.Merge("10.0.0.0/24", "10.0.0.9") {
+		t.Fatal("Merge from a key without a state reported true")
+	}
+	kl.Merge("10.0.0.0/24", "10.0.0.1")
+	kl.Merge("10.0.0.0/24", "10.0.0.2")
+	if _, ok := kl.TakeN("10.0.0.0/24", 5); ok {
+		t.Fatal("merged key allows more than its most consumed source")
+	}
+	if _, ok := kl.TakeN("10.0.0.0/24", 4); !ok {
+		t.Fatal("merged key denied the tokens left")
+	}
+}
+
+func TestKeyedLimiter_GetOrCreateAndDelete(t *testing.T) {
+	kl := NewKeyedLimiter[string](BuildRateLimiter(10, time.Hour), WithHotKeys[string](4))
+
+	state := kl.GetOrCreate("k")
+	if state != kl.GetOrCreate("k") {
+		t.Fatal("GetOrCreate returned different states for the same key")
+	}
+	kl.Limiter().TakeN(state, 10)
+	for i := 0; i < 10; i++ {
+		kl.Take1("k") // promote to a hot slot
+	}
+	if _, ok := kl.Take1("k"); ok {
+		t.Fatal("tokens taken through GetOrCreate not applied to the key")
+	}
+
+	if !kl.Delete("k") || kl.Delete("k") {
+		t.Fatal("Delete did not report the state once")
+	}
+	if kl.Len() != 0 || kl.MemoryUsage()[""].Evictions != 1 {
+		t.Fatalf("after Delete: %d keys, usage %+v", kl.Len(), kl.MemoryUsage()[""])
+	}
+	if _, ok := kl.TakeN("k", 10); !ok {
+		t.Fatal("deleted key did not start over with a full bucket")
+	}
+}
@@ -0,0 +1,137 @@
+package limitron
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// LeakyBucket is the name of the LeakyBucketLimiter algorithm in the registry (see NewLimiter).
+const LeakyBucket = "leaky-bucket"
+
+func init() {
+	RegisterAlgorithm(LeakyBucket, func(req uint16, interval time.Duration, opts ...Option) Limiter {
+		return BuildLeakyBucketLimiter(req, interval, opts...)
+	})
+}
+
+// LeakyBucketLimiter is a leaky bucket (as a queue) rate limiter: admitted requests
+// enter a bucket of `req` requests that drains at a constant rate of `req` per interval,
+// and requests are rejected while the bucket is full.
+// It stores the entire per-key state in a single uint64, packed like RateLimiter states:
+// the lower 48 bits hold the Unix millisecond at which the bucket runs empty, and
+// the upper 16 bits its sub-millisecond fraction, so that the drain rate is kept
+// exactly even when a request drains in a fraction of a millisecond.
+// The bucket level is the time left until then, divided by the drain time of a request.
+//
+// Unlike a token bucket, which lets a full burst through at once, a leaky bucket
+// smooths admitted requests to the drain rate: TakeN admits a request with the delay
+// after which the request drains out of the bucket, i.e., its turn to proceed.
+// Callers sleeping that delay before doing the work send downstream at most
+// the drain rate, however bursty their arrivals are.
+//
+// Options WithClock and WithMetrics apply as for RateLimiter; WithPunitive has no effect.
+type LeakyBucketLimiter struct {
+	// base holds the capacity (maxreq), drain rate per millisecond (rrpm),
+	// CAS retries, clock and metrics.
+	base RateLimiter
+}
+
+// BuildLeakyBucketLimiter returns a LeakyBucketLimiter with a bucket of `req` requests
+// draining at `req` requests per `interval`.
+//
+// Example:
+//
+//	limiter := BuildLeakyBucketLimiter(50, time.Second) // 50 per second, evenly spaced
+//	state := limiter.New()
+//	delay, ok := limiter.Take1(state)
+//	if !ok {
+//	    return errBusy // the bucket is full; retry after `delay` millis
+//	}
+//	time.Sleep(time.Duration(delay) * time.Millisecond)
+//	db.Exec(query)
+func BuildLeakyBucketLimiter(req uint16, interval time.Duration, opts ...Option) LeakyBucketLimiter {
+	return LeakyBucketLimiter{base: BuildRateLimiter(req, interval, opts...)}
+}
+
+// New creates a brand-new limiter state with an empty bucket.
+func (l LeakyBucketLimiter) New() *uint64 {
+	var rl uint64
+	return &rl
+}
+
+// Take1 attempts to add 1 request to the bucket. See TakeN.
+func (l LeakyBucketLimiter) Take1(rl *uint64) (int64, bool) {
+	return l.TakeN(rl, 1)
+}
+
+// TakeN attempts to atomically add `requests` requests to the bucket in the limiter state `*rl`.
+//
+// Returns:
+//   - D, true if the requests were admitted; D is the delay in millis after which the
+//     requests ahead of them have drained, 0 for an empty bucket
+//   - N, false if the bucket has no room for the requests; N is the number of millis
+//     to wait before it has
+//
+// Edge cases:
+//   - If `requests == 0`: always returns (0, true) - noop
+//   - If `requests` exceed the capacity: returns (math.MaxInt64, false) immediately
+func (l LeakyBucketLimiter) TakeN(rl *uint64, requests uint16) (int64, bool) {
+	delay, ok := l.takeNAt(rl, requests, l.base.nowMillis())
+	if ok {
+		l.base.observe(0, true)
+		return delay, true
+	}
+	return l.base.observe(delay, false)
+}
+
+// takeNAt is TakeN without metrics, evaluated at time `now` in Unix milliseconds.
+func (l LeakyBucketLimiter) takeNAt(rl *uint64, requests uint16, now uint64) (int64, bool) {
+	if requests == 0 {
+		return 0, true
+	} else if requests > l.base.maxreq {
+		return math.MaxInt64, false
+	}
+
+	emission := l.emission()
+	nowFixed := now << leakyFracBits
+	for i := 0; i < l.base.retries; i++ {
+		rlval := atomic.LoadUint64(rl)
+		start := max(unpackLeaky(rlval), nowFixed)
+		empty := start + uint64(requests)*emission
+
+		// the level after admission, as the time it takes to drain, must fit the capacity
+		if over := int64(empty-nowFixed) - int64(uint64(l.base.maxreq)*emission); over > 0 {
+			return max(fixedToMillis(uint64(over)), 1), false
+		}
+		if atomic.CompareAndSwapUint64(rl, rlval, packLeaky(empty)) {
+			return fixedToMillis(start - nowFixed), true
+		}
+	}
+	return 1, false
+}
+
+// leakyFracBits is the number of fractional bits of the millisecond times of LeakyBucketLimiter states.
+const leakyFracBits = 16
+
+// emission returns the time it takes to drain one request, in fixed-point milliseconds.
+func (l LeakyBucketLimiter) emission() uint64 {
+	return uint64(math.Round((1 << leakyFracBits) / l.base.rrpm))
+}
+
+// unpackLeaky returns the time at which the bucket of state `rl` runs empty,
+// in fixed-point Unix milliseconds.
+func unpackLeaky(rl uint64) uint64 {
+	frac, ms := unpackUint16Uint48(rl)
+	return ms<<leakyFracBits | uint64(frac)
+}
+
+// packLeaky packs the time at which a bucket runs empty, in fixed-point Unix milliseconds.
+func packLeaky(empty uint64) uint64 {
+	return packUint16AndUint48(uint16(empty), empty>>leakyFracBits)
+}
+
+// fixedToMillis rounds fixed-point milliseconds up to whole milliseconds.
+func fixedToMillis(d uint64) int64 {
+	return int64((d + 1<<leakyFracBits - 1) >> leakyFracBits)
+}
@@ -0,0 +1,65 @@
+package limitron
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+var _ Limiter = LeakyBucketLimiter{}
+
+func TestLeakyBucketLimiter_SpacesBurst(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)}
+	l := BuildLeakyBucketLimiter(4, 100*time.Millisecond, WithClock(clock)) // drains one per 25ms
+	state := l.New()
+
+	for i, want := range []int64{0, 25, 50, 75} {
+		delay, ok := l.Take1(state)
+		if !ok || delay != want {
+			t.Fatalf("request %d = %d, %v, want %d, true", i, delay, ok, want)
+		}
+	}
+	wait, ok := l.Take1(state)
+	if ok || wait != 25 {
+		t.Fatalf("request into a full bucket = %d, %v, want 25, false", wait, ok)
+	}
+
+	clock.t = clock.t.Add(30 * time.Millisecond)
+	delay, ok := l.Take1(state)
+	if !ok || delay != 70 {
+		t.Fatalf("request after one drain = %d, %v, want 70, true", delay, ok)
+	}
+}
+
+func TestLeakyBucketLimiter_DrainsAtConstantRate(t *testing.T) {
+	// 3 per 10ms drains one request every 3.33ms, which is not a whole number of millis
+	clock := &testClock{t: time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)}
+	l := BuildLeakyBucketLimiter(3, 10*time.Millisecond, WithClock(clock))
+	state := l.New()
+	l.TakeN(state, 3)
+
+	admitted := 0
+	for i := 0; i < 1000; i++ {
+		clock.t = clock.t.Add(time.Millisecond)
+		if _, ok := l.Take1(state); ok {
+			admitted++
+		}
+	}
+	if admitted < 297 || admitted > 300 {
+		t.Fatalf("admitted %d requests in 1s at 300/s", admitted)
+	}
+}
+
+func TestLeakyBucketLimiter_EdgeCases(t *testing.T) {
+	l := BuildLeakyBucketLimiter(2, time.Second)
+	state := l.New()
+	if wait, ok := l.TakeN(state, 0); !ok || wait != 0 {
+		t.Fatalf("TakeN(0) = %d, %v", wait, ok)
+	}
+	if wait, ok := l.TakeN(state, 3); ok || wait != math.MaxInt64 {
+		t.Fatalf("TakeN over the capacity = %d, %v", wait, ok)
+	}
+	if _, ok := NewLimiter(LeakyBucket, 1, time.Second); ok != nil {
+		t.Fatal(ok)
+	}
+}
@@ -0,0 +1,95 @@
+package limitron
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Limiter is implemented by every rate-limiting algorithm of limitron.
+// Like RateLimiter, a Limiter keeps the entire per-key state in a single uint64,
+// created by New and updated lock-free by TakeN.
+//
+// TakeN returns the number of milliseconds to wait and whether the `requests`
+// were allowed, with the same contract as RateLimiter.TakeN.
+type Limiter interface {
+	New() *uint64
+	TakeN(rl *uint64, requests uint16) (int64, bool)
+}
+
+// Names of the algorithms registered by limitron.
+const (
+	// TokenBucket is the token bucket of RateLimiter.
+	TokenBucket = "token-bucket"
+)
+
+// AlgorithmFunc builds a Limiter allowing `req` requests per `interval`.
+// Algorithms honor the Options that apply to them, such as WithClock and WithMetrics.
+type AlgorithmFunc func(req uint16, interval time.Duration, opts ...Option) Limiter
+
+var (
+	algorithmsMu sync.RWMutex
+	algorithms   = map[string]AlgorithmFunc{
+		TokenBucket: func(req uint16, interval time.Duration, opts ...Option) Limiter {
+			return BuildRateLimiter(req, interval, opts...)
+		},
+	}
+)
+
+// RegisterAlgorithm makes an algorithm available to NewLimiter under `name`.
+// It panics if `fn` is nil or `name` is already registered.
+//
+// Example:
+//
+//	func init() {
+//	    limitron.RegisterAlgorithm("my-algorithm", buildMyLimiter)
+//	}
+func RegisterAlgorithm(name string, fn AlgorithmFunc) {
+	algorithmsMu.Lock()
+	defer algorithmsMu.Unlock()
+
+	if fn == nil {
+		panic("limitron: RegisterAlgorithm " + name + " with a nil func")
+	}
+	if _, dup := algorithms[name]; dup {
+		panic("limitron: RegisterAlgorithm called twice for " + name)
+	}
+	algorithms[name] = fn
+}
+
+// NewLimiter returns a Limiter of the algorithm registered as `algorithm`, allowing
+// `req` requests per `interval`, so that the algorithm can be selected by configuration.
+//
+// Example:
+//
+//	limiter, err := NewLimiter(cfg.Algorithm, 100, time.Minute)
+//	if err != nil {
+//	    return err
+//	}
+//	state := limiter.New()
+//	if _, ok := limiter.TakeN(state, 1); ok {
+//	    // allowed
+//	}
+func NewLimiter(algorithm string, req uint16, interval time.Duration, opts ...Option) (Limiter, error) {
+	algorithmsMu.RLock()
+	fn, ok := algorithms[algorithm]
+	algorithmsMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("limitron: unknown algorithm %q", algorithm)
+	}
+	return fn(req, interval, opts...), nil
+}
+
+// Algorithms returns the sorted names of the registered algorithms.
+func Algorithms() []string {
+	algorithmsMu.RLock()
+	defer algorithmsMu.RUnlock()
+
+	names := make([]string, 0, len(algorithms))
+	for name := range algorithms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
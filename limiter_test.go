@@ -0,0 +1,60 @@
+package limitron
+
+import (
+	"testing"
+	"time"
+)
+
+var _ Limiter = RateLimiter{}
+
+func TestNewLimiter_TokenBucket(t *testing.T) {
+	limiter, err := NewLimiter(TokenBucket, 2, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := limiter.New()
+	for i := 0; i < 2; i++ {
+		if _, ok := limiter.TakeN(state, 1); !ok {
+			t.Fatalf("request %d denied", i)
+		}
+	}
+	if _, ok := limiter.TakeN(state, 1); ok {
+		t.Fatal("third request allowed at 2 per hour")
+	}
+}
+
+func TestNewLimiter_Unknown(t *testing.T) {
+	if _, err := NewLimiter("no-such-algorithm", 1, time.Second); err == nil {
+		t.Fatal("NewLimiter of an unknown algorithm returned no error")
+	}
+}
+
+func TestRegisterAlgorithm(t *testing.T) {
+	const name = "test-algorithm"
+	RegisterAlgorithm(name, func(req uint16, interval time.Duration, opts ...Option) Limiter {
+		return BuildRateLimiter(req, interval, opts...)
+	})
+	t.Cleanup(func() {
+		algorithmsMu.Lock()
+		delete(algorithms, name)
+		algorithmsMu.Unlock()
+	})
+
+	found := false
+	for _, n := range Algorithms() {
+		found = found || n == name
+	}
+	if !found {
+		t.Fatalf("Algorithms() = %v, missing %q", Algorithms(), name)
+	}
+	if _, err := NewLimiter(name, 1, time.Second); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("registering a duplicate algorithm did not panic")
+		}
+	}()
+	RegisterAlgorithm(name, func(uint16, time.Duration, ...Option) Limiter { return RateLimiter{} })
+}
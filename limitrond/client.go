@@ -0,0 +1,184 @@
+package limitrond
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/iryndin/limitron"
+)
+
+// DefaultClientTimeout bounds each call of a Client to the daemon.
+const DefaultClientTimeout = 250 * time.Millisecond
+
+// Client calls the check/consume API of a limitrond daemon.
+//
+// With WithFallback, calls that cannot reach the daemon are decided by local
+// per-key limiters instead, at a fraction of the global rate, so that limiting
+// degrades gracefully while the daemon is unavailable: every instance
+// enforces its share instead of failing open or closed.
+//
+// The zero value is not usable; create instances with NewClient.
+// All methods are safe for concurrent use.
+type Client struct {
+	base string
+	http *http.Client
+
+	// fallback maps limit names to their local limiters; nil without WithFallback.
+	fallback map[string]*limitron.KeyedLimiter[string]
+}
+
+// ClientOption configures a Client.
+type ClientOption func(*Client) error
+
+// WithHTTPClient sets the HTTP client used to call the daemon.
+// The default is a client with DefaultClientTimeout.
+func WithHTTPClient(hc *http.Client) ClientOption {
+	return func(c *Client) error {
+		c.http = hc
+		return nil
+	}
+}
+
+// WithFallback enables local fallback for the given limits, typically the limits
+// of the daemon's configuration. Locally, every key of a limit gets `fraction`
+// of the configured requests per interval (at least 1), e.g., 1/N for N instances.
+// As on the daemon, the keys of a limit are capped at its MaxKeys.
+func WithFallback(limits map[string]Limit, fraction float64) ClientOption {
+	return func(c *Client) error {
+		if fraction <= 0 || fraction > 1 {
+			return fmt.Errorf("limitrond: fallback fraction %v is not in (0, 1]", fraction)
+		}
+		c.fallback = make(map[string]*limitron.KeyedLimiter[string], len(limits))
+		for name, l := range limits {
+			l.Requests = uint16(max(math.Round(float64(l.Requests)*fraction), 1))
+			limiter, err := l.RateLimiter()
+			if err != nil {
+				return fmt.Errorf("fallback limit %q: %w", name, err)
+			}
+			c.fallback[name] = limitron.NewKeyedLimiter(limiter, limitron.WithMaxKeys[string](l.maxKeys(), nil))
+		}
+		return nil
+	}
+}
+
+// NewClient returns a Client of the daemon at `baseURL`, e.g., "http://127.0.0.1:7420".
+//
+// Example:
+//
+//	cfg, _ := limitrond.LoadConfig("/etc/limitrond.json")
+//	c, err := limitrond.NewClient("http://127.0.0.1:7420",
+//	    limitrond.WithFallback(cfg.Limits, 0.25)) // 4 instances
+//	resp, err := c.Consume(ctx, "api", userID, 1)
+func NewClient(baseURL string, opts ...ClientOption) (*Client, error) {
+	c := &Client{
+		base: strings.TrimRight(baseURL, "/"),
+		http: &http.Client{Timeout: DefaultClientTimeout},
+	}
+	for _, opt := range opts {
+		if err := opt(c); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Consume takes `n` tokens of `key` in `limit`. See Server.Consume.
+//
+// If the daemon cannot be reached and the limit has a local fallback,
+// the local decision is returned with CheckResponse.Local set and a nil error.
+func (c *Client) Consume(ctx context.Context, limit, key string, n uint16) (CheckResponse, error) {
+	return c.call(ctx, "/v1/consume", CheckRequest{Limit: limit, Key: key, N: n})
+}
+
+// Check reports whether Consume would currently succeed. See Server.Check.
+// The local fallback is used as for Consume.
+func (c *Client) Check(ctx context.Context, limit, key string, n uint16) (CheckResponse, error) {
+	return c.call(ctx, "/v1/check", CheckRequest{Limit: limit, Key: key, N: n})
+}
+
+func (c *Client) call(ctx context.Context, path string, req CheckRequest) (CheckResponse, error) {
+	var resp CheckResponse
+	err := c.post(ctx, path, req, &resp)
+	if _, unavailable := err.(*unavailableError); !unavailable {
+		return resp, err
+	}
+	if resp, ok := c.local(req, path == "/v1/consume"); ok {
+		return resp, nil
+	}
+	return CheckResponse{}, err
+}
+
+// local decides `req` with the local fallback of its limit. It returns false
+// if the limit has no fallback.
+func (c *Client) local(req CheckRequest, consume bool) (CheckResponse, bool) {
+	kl, ok := c.fallback[req.Limit]
+	if !ok {
+		return CheckResponse{}, false
+	}
+	n := max(req.N, 1)
+	var waitMillis int64
+	var allowed bool
+	if consume {
+		waitMillis, allowed = kl.TakeN(req.Key, n)
+	} else {
+		waitMillis, allowed = kl.Peek(req.Key, n)
+	}
+	if allowed {
+		waitMillis = 0
+	}
+	return CheckResponse{Allowed: allowed, WaitMillis: waitMillis, Local: true}, true
+}
+
+// unavailableError reports that the daemon could not be reached or failed,
+// as opposed to rejecting the request.
+type unavailableError struct {
+	err error
+}
+
+func (e *unavailableError) Error() string { return "limitrond: daemon unavailable: " + e.err.Error() }
+
+func (e *unavailableError) Unwrap() error { return e.err }
+
+// post performs a call against the daemon, decoding the answer into `resp`.
+func (c *Client) post(ctx context.Context, path string, req, resp any) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	hreq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.base+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	hreq.Header.Set("Content-Type", "application/json")
+
+	hresp, err := c.http.Do(hreq)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return &unavailableError{err}
+	}
+	defer hresp.Body.Close()
+
+	switch {
+	case hresp.StatusCode == http.StatusOK:
+		if err := json.NewDecoder(hresp.Body).Decode(resp); err != nil {
+			return &unavailableError{err}
+		}
+		return nil
+	case hresp.StatusCode == http.StatusNotFound:
+		return ErrUnknownLimit
+	case hresp.StatusCode >= 500:
+		return &unavailableError{fmt.Errorf("status %s", hresp.Status)}
+	default:
+		msg, _ := io.ReadAll(io.LimitReader(hresp.Body, 512))
+		return fmt.Errorf("limitrond: %s: %s", hresp.Status, bytes.TrimSpace(msg))
+	}
+}
@@ -0,0 +1,89 @@
+package limitrond
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient_Remote(t *testing.T) {
+	ts := httptest.NewServer(newTestServer(t).Handler())
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if resp, err := c.Consume(ctx, "api", "u1", 1); err != nil || !resp.Allowed || resp.Local {
+			t.Fatalf("Consume %d = %+v, %v; want remote allow", i, resp, err)
+		}
+	}
+	if resp, err := c.Check(ctx, "api", "u1", 1); err != nil || resp.Allowed {
+		t.Fatalf("Check over limit = %+v, %v; want denied", resp, err)
+	}
+	if _, err := c.Consume(ctx, "nope", "u1", 1); !errors.Is(err, ErrUnknownLimit) {
+		t.Fatalf("unknown limit err = %v, want ErrUnknownLimit", err)
+	}
+}
+
+func TestClient_LocalFallback(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	limits := map[string]Limit{"api": {Requests: 10, Interval: "1h"}}
+	c, err := NewClient(ts.URL, WithFallback(limits, 0.2))
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if resp, err := c.Consume(ctx, "api", "u1", 1); err != nil || !resp.Allowed || !resp.Local {
+			t.Fatalf("Consume %d = %+v, %v; want local allow", i, resp, err)
+		}
+	}
+	if resp, _ := c.Consume(ctx, "api", "u1", 1); resp.Allowed {
+		t.Fatal("local fallback should enforce 20% of 10 requests")
+	}
+	if _, err := c.Consume(ctx, "other", "u1", 1); err == nil {
+		t.Fatal("limits without fallback should report the daemon error")
+	}
+}
+
+func TestClient_UnreachableDaemon(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	url := ts.URL
+	ts.Close()
+
+	c, _ := NewClient(url, WithFallback(map[string]Limit{"api": {Requests: 1, Interval: "1h"}}, 1))
+	if resp, err := c.Consume(context.Background(), "api", "u1", 1); err != nil || !resp.Local {
+		t.Fatalf("Consume = %+v, %v; want local decision", resp, err)
+	}
+}
+
+func TestWithFallback_MaxKeys(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	url := ts.URL
+	ts.Close()
+
+	c, _ := NewClient(url, WithFallback(map[string]Limit{"api": {Requests: 2, Interval: "1h", MaxKeys: 128}}, 1))
+	ctx := context.Background()
+	for i := 0; i < 1000; i++ {
+		c.Consume(ctx, "api", fmt.Sprint("ip-", i), 1)
+	}
+	if keys := c.fallback["api"].Len(); keys > 128 {
+		t.Fatalf("%d fallback keys kept, want at most 128", keys)
+	}
+}
+
+func TestWithFallback_InvalidFraction(t *testing.T) {
+	if _, err := NewClient("http://localhost", WithFallback(nil, 0)); err == nil {
+		t.Fatal("fraction 0 should be rejected")
+	}
+}
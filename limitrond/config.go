@@ -0,0 +1,136 @@
+package limitrond
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/iryndin/limitron"
+)
+
+// Default listen addresses of the daemon.
+const (
+	DefaultListen      = ":7420"
+	DefaultAdminListen = "127.0.0.1:7421"
+)
+
+// DefaultMaxKeys is the default maximum number of keys of a limit (see Limit.MaxKeys).
+const DefaultMaxKeys = 1_000_000
+
+// Config is the daemon configuration, read from a JSON file:
+//
+//	{
+//	  "listen": ":7420",
+//	  "admin_listen": "127.0.0.1:7421",
+//	  "grpc_listen": ":7422",
+//	  "tls_cert": "/etc/limitrond/cert.pem",
+//	  "tls_key": "/etc/limitrond/key.pem",
+//	  "limits": {
+//	    "api":   {"requests": 100, "interval": "1m"},
+//	    "login": {"requests": 5, "interval": "15m"},
+//	    "batch": {"requests": 1000, "interval": "1s", "fair": true}
+//	  },
+//	  "schedules": [
+//	    {"cron": "0 0 27 11 *", "duration": "24h", "limit": "api", "set": {"requests": 200, "interval": "1m"}}
+//	  ]
+//	}
+type Config struct {
+	// Listen is the address of the check/consume API. Defaults to DefaultListen.
+	Listen string `json:"listen"`
+	// GRPCListen is the address of the check/consume API over gRPC (see Server.GRPCHandler);
+	// empty disables it. gRPC is served over TLS and requires TLSCert and TLSKey.
+	GRPCListen string `json:"grpc_listen,omitempty"`
+	// TLSCert and TLSKey are the PEM files of the certificate and key of the gRPC API.
+	TLSCert string `json:"tls_cert,omitempty"`
+	TLSKey  string `json:"tls_key,omitempty"`
+	// AdminListen is the address of the admin API. Defaults to DefaultAdminListen;
+	// "-" disables the admin API.
+	AdminListen string `json:"admin_listen"`
+	// Limits maps limit names to their configuration.
+	Limits map[string]Limit `json:"limits"`
+	// Schedules override limits during recurring time windows.
+	Schedules []Schedule `json:"schedules,omitempty"`
+}
+
+// Limit is the configuration of a named limit. Every key of the limit gets
+// its own bucket of `Requests` tokens, refilled over `Interval`.
+type Limit struct {
+	Requests uint16 `json:"requests"`
+	// Interval is a Go duration string such as "1s", "1m" or "1h30m".
+	Interval string `json:"interval"`
+	// Fair makes all keys (tenants) share a single bucket instead, divided max-min
+	// fairly by their demand in every interval, so that one tenant's burst cannot
+	// consume the entire shared budget during contention.
+	Fair bool `json:"fair,omitempty"`
+	// MaxKeys caps the number of keys with a state, since keys come straight from
+	// requests. Beyond it, the least recently used keys are evicted and start over with
+	// a full bucket on their next request. Defaults to DefaultMaxKeys.
+	MaxKeys int `json:"max_keys,omitempty"`
+}
+
+// RateLimiter returns the limiter of the limit configuration.
+func (l Limit) RateLimiter() (limitron.RateLimiter, error) {
+	if l.Requests == 0 {
+		return limitron.RateLimiter{}, fmt.Errorf("limitrond: requests must be positive")
+	}
+	interval, err := time.ParseDuration(l.Interval)
+	if err != nil {
+		return limitron.RateLimiter{}, fmt.Errorf("limitrond: interval: %w", err)
+	}
+	if interval < time.Millisecond {
+		return limitron.RateLimiter{}, fmt.Errorf("limitrond: interval %q is shorter than 1ms", l.Interval)
+	}
+	if l.MaxKeys < 0 {
+		return limitron.RateLimiter{}, fmt.Errorf("limitrond: max_keys must not be negative")
+	}
+	return limitron.BuildRateLimiter(l.Requests, interval), nil
+}
+
+// maxKeys returns MaxKeys, or DefaultMaxKeys if unset.
+func (l Limit) maxKeys() int {
+	if l.MaxKeys == 0 {
+		return DefaultMaxKeys
+	}
+	return l.MaxKeys
+}
+
+// ParseConfig parses and validates a JSON configuration, filling in defaults.
+func ParseConfig(data []byte) (*Config, error) {
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("limitrond: parse config: %w", err)
+	}
+	if cfg.Listen == "" {
+		cfg.Listen = DefaultListen
+	}
+	if cfg.AdminListen == "" {
+		cfg.AdminListen = DefaultAdminListen
+	}
+	if cfg.GRPCListen != "" && (cfg.TLSCert == "" || cfg.TLSKey == "") {
+		return nil, fmt.Errorf("limitrond: grpc_listen requires tls_cert and tls_key")
+	}
+	for name, l := range cfg.Limits {
+		if _, err := l.RateLimiter(); err != nil {
+			return nil, fmt.Errorf("limit %q: %w", name, err)
+		}
+	}
+	if _, err := compileSchedules(cfg.Schedules); err != nil {
+		return nil, err
+	}
+	for i, sc := range cfg.Schedules {
+		if _, ok := cfg.Limits[sc.Limit]; !ok {
+			return nil, fmt.Errorf("schedule %d: unknown limit %q", i, sc.Limit)
+		}
+	}
+	return &cfg, nil
+}
+
+// LoadConfig reads the JSON configuration file at `path`. See ParseConfig.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfig(data)
+}
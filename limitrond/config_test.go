@@ -0,0 +1,41 @@
+package limitrond
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseConfig(t *testing.T) {
+	cfg, err := ParseConfig([]byte(`{"limits": {"api": {"requests": 10, "interval": "1m"}}}`))
+	if err != nil {
+		t.Fatalf("ParseConfig: %v", err)
+	}
+	if cfg.Listen != DefaultListen || cfg.AdminListen != DefaultAdminListen {
+		t.Fatalf("defaults not applied: %+v", cfg)
+	}
+	if l := cfg.Limits["api"]; l.Requests != 10 || l.Interval != "1m" {
+		t.Fatalf("limit api = %+v", l)
+	}
+}
+
+func TestParseConfig_InvalidLimit(t *testing.T) {
+	for _, tc := range []string{
+		`{"limits": {"api": {"requests": 0, "interval": "1m"}}}`,
+		`{"limits": {"api": {"requests": 1, "interval": "soon"}}}`,
+		`{"limits": {"api": {"requests": 1, "interval": "1us"}}}`,
+	} {
+		if _, err := ParseConfig([]byte(tc)); err == nil || !strings.Contains(err.Error(), `"api"`) {
+			t.Errorf("ParseConfig(%s) err = %v, want an error naming the limit", tc, err)
+		}
+	}
+}
+
+func TestParseConfig_GRPCRequiresTLS(t *testing.T) {
+	if _, err := ParseConfig([]byte(`{"grpc_listen": ":7422", "tls_cert": "cert.pem"}`)); err == nil {
+		t.Fatal("grpc_listen without tls_key should be an error")
+	}
+	cfg, err := ParseConfig([]byte(`{"grpc_listen": ":7422", "tls_cert": "cert.pem", "tls_key": "key.pem"}`))
+	if err != nil || cfg.GRPCListen != ":7422" {
+		t.Fatalf("ParseConfig = %+v, %v", cfg, err)
+	}
+}
@@ -0,0 +1,48 @@
+package limitrond
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCron(t *testing.T) {
+	at := func(s string) time.Time {
+		tm, err := time.Parse("2006-01-02 15:04 Mon", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+	cases := []struct {
+		spec string
+		t    string
+		want bool
+	}{
+		{"* * * * *", "2026-11-27 13:37 Fri", true},
+		{"0 0 27 11 *", "2026-11-27 00:00 Fri", true},
+		{"0 0 27 11 *", "2026-11-27 00:01 Fri", false},
+		{"*/15 9-17 * * 1-5", "2026-11-27 09:45 Fri", true},
+		{"*/15 9-17 * * 1-5", "2026-11-28 09:45 Sat", false},
+		{"*/15 9-17 * * 1-5", "2026-11-27 18:00 Fri", false},
+		{"0,30 12 * * 7", "2026-11-29 12:30 Sun", true},
+		{"5/20 * * * *", "2026-11-27 10:45 Fri", true},
+		// both day fields restricted: either matches
+		{"0 0 1 * 5", "2026-11-27 00:00 Fri", true},
+		{"0 0 1 * 5", "2026-11-26 00:00 Thu", false},
+	}
+	for _, c := range cases {
+		spec, err := parseCron(c.spec)
+		if err != nil {
+			t.Fatalf("parseCron(%q): %v", c.spec, err)
+		}
+		if got := spec.matches(at(c.t)); got != c.want {
+			t.Errorf("%q matches %s = %v, want %v", c.spec, c.t, got, c.want)
+		}
+	}
+
+	for _, bad := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		if _, err := parseCron(bad); err == nil {
+			t.Errorf("parseCron(%q) succeeded", bad)
+		}
+	}
+}
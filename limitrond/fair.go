@@ -0,0 +1,235 @@
+package limitrond
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/iryndin/limitron"
+)
+
+// fairPool is the single bucket of a Fair limit, divided max-min fairly between keys.
+//
+// Demand is measured per window of the limit's interval: every key's demand is the
+// larger of the tokens it asked for in the current and in the previous window.
+// The capacity of a window is divided so that keys demanding less than an equal
+// share get all they ask for, and the rest is split equally between the others:
+// every key's share is its demand capped at the water level of the window.
+// A key is granted tokens only within its share and while the shared bucket has them.
+//
+// The water level is cached, and only recomputed when a demand below it grows,
+// since demands at or above the level do not move it.
+type fairPool struct {
+	limiter  limitron.RateLimiter
+	capacity float64
+	window   time.Duration
+
+	mu          sync.Mutex
+	state       *uint64
+	windowStart time.Time
+	tenants     map[string]*fairTenant
+
+	// total is the sum of the demands of all keys.
+	total float64
+	// level is the cached water level, +Inf while the demands fit in the capacity;
+	// only up to date if levelValid.
+	level      float64
+	levelValid bool
+}
+
+// fairTenant is the per-key demand and usage of a fairPool.
+type fairTenant struct {
+	demand, prevDemand float64
+	granted            float64
+}
+
+// want returns the demand of the tenant: the larger of its current and previous window.
+func (t *fairTenant) want() float64 {
+	return max(t.demand, t.prevDemand)
+}
+
+func newFairPool(limiter limitron.RateLimiter, l Limit, window time.Duration) *fairPool {
+	return &fairPool{
+		limiter:     limiter,
+		capacity:    float64(l.Requests),
+		window:      window,
+		state:       limiter.New(),
+		windowStart: time.Now(),
+		tenants:     make(map[string]*fairTenant),
+	}
+}
+
+// take grants tokens of `key` within its fair share. With `partial`, it grants
+// as many of the `n` tokens as possible (see Server.Lease), otherwise all or nothing.
+// Without `record`, nothing is recorded and the shared bucket is not touched.
+func (p *fairPool) take(key string, n uint16, partial, record bool) (granted uint16, waitMillis int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	p.roll(now)
+	untilNextWindow := max(p.windowStart.Add(p.window).Sub(now).Milliseconds(), 1)
+
+	t, ok := p.tenants[key]
+	if !ok {
+		t = &fairTenant{}
+		if record {
+			p.tenants[key] = t
+		}
+	}
+	extra := float64(n)
+	if record {
+		before := t.want()
+		t.demand += float64(n)
+		p.grow(before, t.want())
+		extra = 0
+	}
+
+	allowance := p.share(key, t, extra) - t.granted
+	want := n
+	if allowance < float64(n) {
+		if !partial || allowance < 1 {
+			return 0, untilNextWindow
+		}
+		want = uint16(allowance)
+	}
+
+	st := p.state
+	if !record {
+		cp := *p.state
+		st = &cp
+	}
+	for ; want > 0; want /= 2 {
+		var allowed bool
+		if waitMillis, allowed = p.limiter.TakeN(st, want); allowed {
+			if record {
+				t.granted += float64(want)
+			}
+			return want, 0
+		}
+		if !partial {
+			break
+		}
+	}
+	return 0, waitMillis
+}
+
+// carryFrom takes over the shared bucket, window and demand of `old`, which the pool
+// replaces after a configuration change. The tokens of the bucket are capped at the
+// new burst on the next take.
+func (p *fairPool) carryFrom(old *fairPool) {
+	old.mu.Lock()
+	defer old.mu.Unlock()
+	state := *old.state
+	p.state = &state
+	p.windowStart = old.windowStart
+	for key, t := range old.tenants {
+		cp := *t
+		p.tenants[key] = &cp
+	}
+	p.total = old.total
+	p.levelValid = false
+}
+
+// len returns the number of keys with a recent demand.
+func (p *fairPool) len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.roll(time.Now())
+	return len(p.tenants)
+}
+
+// roll starts a new window if the current one is over, forgetting keys without
+// demand in the last two windows. Must be called with p.mu held.
+func (p *fairPool) roll(now time.Time) {
+	elapsed := now.Sub(p.windowStart)
+	if elapsed < p.window {
+		return
+	}
+	windows := elapsed / p.window
+	p.windowStart = p.windowStart.Add(windows * p.window)
+	p.total = 0
+	for key, t := range p.tenants {
+		if windows > 1 || t.demand == 0 {
+			delete(p.tenants, key)
+			continue
+		}
+		t.prevDemand, t.demand, t.granted = t.demand, 0, 0
+		p.total += t.prevDemand
+	}
+	p.levelValid = false
+}
+
+// grow accounts for a demand growing from `before` to `after`. Must be called with p.mu held.
+func (p *fairPool) grow(before, after float64) {
+	if after <= before {
+		return
+	}
+	if p.moves(before, after-before) {
+		p.levelValid = false
+	}
+	p.total += after - before
+}
+
+// moves reports whether a demand of `before` growing by `delta` can change the cached
+// water level: only demands below the level count in full, and an infinite level
+// stays so while the demands fit in the capacity. Must be called with p.mu held.
+func (p *fairPool) moves(before, delta float64) bool {
+	if !p.levelValid {
+		return true
+	}
+	if math.IsInf(p.level, 1) {
+		return p.total+delta > p.capacity
+	}
+	return before < p.level
+}
+
+// share returns the max-min fair share of `key` in the current window, counting
+// `extra` tokens of additional demand for it. Must be called with p.mu held.
+func (p *fairPool) share(key string, t *fairTenant, extra float64) float64 {
+	own := t.want() + extra
+	if extra > 0 && p.moves(t.want(), extra) {
+		// the extra demand lowers the level, which is not cached
+		return min(own, p.waterLevel(key, extra))
+	}
+	if !p.levelValid {
+		p.level = p.waterLevel("", 0)
+		p.levelValid = true
+	}
+	return min(own, p.level)
+}
+
+// waterLevel computes the water level of the current window, counting `extra`
+// tokens of additional demand for `key`. Must be called with p.mu held.
+func (p *fairPool) waterLevel(key string, extra float64) float64 {
+	demands := make([]float64, 0, len(p.tenants)+1)
+	found := false
+	for k, t := range p.tenants {
+		d := t.want()
+		if k == key {
+			d += extra
+			found = true
+		}
+		demands = append(demands, d)
+	}
+	if !found && extra > 0 {
+		demands = append(demands, extra)
+	}
+	return waterLevel(p.capacity, demands)
+}
+
+// waterLevel returns the level dividing `capacity` between `demands` max-min fairly:
+// demands below it are satisfied in full, and the larger ones are granted the level.
+// It is +Inf if the demands fit in the capacity. `demands` is sorted in place.
+func waterLevel(capacity float64, demands []float64) float64 {
+	sort.Float64s(demands)
+	remaining := capacity
+	for i, d := range demands {
+		if share := remaining / float64(len(demands)-i); d >= share {
+			return share
+		}
+		remaining -= d
+	}
+	return math.Inf(1)
+}
@@ -0,0 +1,93 @@
+package limitrond
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/iryndin/limitron"
+)
+
+func TestWaterLevel(t *testing.T) {
+	if got := waterLevel(10, []float64{8, 2, 5}); got != 4 {
+		t.Fatalf("waterLevel = %v, want 4", got)
+	}
+	if got := waterLevel(10, []float64{1, 2}); !math.IsInf(got, 1) {
+		t.Fatalf("waterLevel below capacity = %v, want +Inf", got)
+	}
+}
+
+func TestFairPool_CachedLevel(t *testing.T) {
+	limiter := limitron.BuildRateLimiter(100, time.Hour)
+	p := newFairPool(limiter, Limit{Requests: 100, Interval: "1h"}, time.Hour)
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 500; i++ {
+		key := fmt.Sprint("tenant-", rng.Intn(8))
+		p.take(key, uint16(1+rng.Intn(5)), rng.Intn(2) == 0, rng.Intn(4) != 0)
+
+		p.mu.Lock()
+		fresh := p.waterLevel("", 0)
+		for k, tenant := range p.tenants {
+			if got, want := p.share(k, tenant, 0), min(tenant.want(), fresh); got != want {
+				p.mu.Unlock()
+				t.Fatalf("take %d: share of %s = %v, want %v", i, k, got, want)
+			}
+		}
+		p.mu.Unlock()
+	}
+}
+
+func TestServer_FairLimitDividesByDemand(t *testing.T) {
+	srv, err := NewServer(&Config{Limits: map[string]Limit{
+		"shared": {Requests: 10, Interval: "200ms", Fair: true},
+	}})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	consume := func(key string, times int) int {
+		n := 0
+		for i := 0; i < times; i++ {
+			if resp, _ := srv.Consume(CheckRequest{Limit: "shared", Key: key}); resp.Allowed {
+				n++
+			}
+		}
+		return n
+	}
+
+	// Without contention, a single tenant may use the whole budget.
+	if got := consume("noisy", 12); got != 10 {
+		t.Fatalf("window 1: noisy got %d, want 10", got)
+	}
+	if got := consume("quiet", 2); got != 0 {
+		t.Fatalf("window 1: quiet got %d from an empty bucket", got)
+	}
+
+	time.Sleep(210 * time.Millisecond)
+	// Knowing both demands, the noisy tenant is capped so that the quiet one gets its 2.
+	if got := consume("noisy", 12); got != 8 {
+		t.Fatalf("window 2: noisy got %d, want its fair share of 8", got)
+	}
+	if got := consume("quiet", 2); got != 2 {
+		t.Fatalf("window 2: quiet got %d, want 2", got)
+	}
+	if got := srv.Limits()["shared"].Keys; got != 2 {
+		t.Fatalf("Keys = %d, want 2 tenants", got)
+	}
+}
+
+func TestServer_FairLease(t *testing.T) {
+	srv, _ := NewServer(&Config{Limits: map[string]Limit{
+		"shared": {Requests: 10, Interval: "1h", Fair: true},
+	}})
+	resp, _ := srv.Lease(LeaseRequest{Limit: "shared", Key: "a", N: 4})
+	if resp.Granted != 4 {
+		t.Fatalf("Lease a = %+v, want 4 granted", resp)
+	}
+	// b's demand of 8 entitles it to 6 of the 10; 6 are left in the bucket
+	resp, _ = srv.Lease(LeaseRequest{Limit: "shared", Key: "b", N: 8})
+	if resp.Granted != 6 {
+		t.Fatalf("Lease b = %+v, want its fair share of 6", resp)
+	}
+}
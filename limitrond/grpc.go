@@ -0,0 +1,200 @@
+package limitrond
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// grpcService is the full name of the gRPC service of limitrond.proto.
+const grpcService = "limitrond.v1.Limitrond"
+
+// grpcMaxMessage bounds the size of gRPC request messages.
+const grpcMaxMessage = 64 << 10
+
+// gRPC status codes answered by GRPCHandler.
+const (
+	grpcOK              = 0
+	grpcInvalidArgument = 3
+	grpcNotFound        = 5
+	grpcUnimplemented   = 12
+)
+
+// GRPCHandler returns the handler of the check/consume API over gRPC, as service
+// limitrond.v1.Limitrond of limitrond.proto, for clients generated from that file:
+//
+//	rpc Consume(CheckRequest) returns (CheckResponse);
+//	rpc Check(CheckRequest) returns (CheckResponse);
+//	rpc Lease(LeaseRequest) returns (LeaseResponse);
+//
+// gRPC runs over HTTP/2, which net/http serves over TLS only, so the handler must be
+// served with ListenAndServeTLS (see Config.GRPCListen). Unary calls without message
+// compression are supported; unknown limits are answered with status NOT_FOUND.
+func (s *Server) GRPCHandler() http.Handler {
+	return http.HandlerFunc(s.serveGRPC)
+}
+
+func (s *Server) serveGRPC(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		writeError(w, http.StatusUnsupportedMediaType, "gRPC requests only")
+		return
+	}
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+
+	method, ok := strings.CutPrefix(r.URL.Path, "/"+grpcService+"/")
+	if !ok || (method != "Consume" && method != "Check" && method != "Lease") {
+		writeGRPCStatus(w, grpcUnimplemented, fmt.Sprintf("unknown method %s", r.URL.Path))
+		return
+	}
+	msg, code, err := readGRPCMessage(r.Body)
+	if err != nil {
+		writeGRPCStatus(w, code, err.Error())
+		return
+	}
+	req, err := decodeCheckRequest(msg)
+	if err != nil {
+		writeGRPCStatus(w, grpcInvalidArgument, err.Error())
+		return
+	}
+
+	var out []byte
+	if method == "Lease" {
+		resp, err := s.Lease(LeaseRequest(req))
+		if err != nil {
+			writeGRPCStatus(w, grpcNotFound, fmt.Sprintf("unknown limit %q", req.Limit))
+			return
+		}
+		out = appendVarintField(nil, 1, uint64(resp.Granted))
+		out = appendVarintField(out, 2, uint64(resp.WaitMillis))
+	} else {
+		resp, err := s.do(req, method == "Consume")
+		if err != nil {
+			writeGRPCStatus(w, grpcNotFound, fmt.Sprintf("unknown limit %q", req.Limit))
+			return
+		}
+		if resp.Allowed {
+			out = appendVarintField(nil, 1, 1)
+		}
+		out = appendVarintField(out, 2, uint64(resp.WaitMillis))
+	}
+
+	frame := make([]byte, 5, 5+len(out))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(out)))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(append(frame, out...))
+	w.Header().Set("Grpc-Status", strconv.Itoa(grpcOK))
+}
+
+// readGRPCMessage reads the single length-prefixed message of a unary call. On error,
+// it also returns the gRPC status code to answer with.
+func readGRPCMessage(body io.Reader) ([]byte, int, error) {
+	var prefix [5]byte
+	if _, err := io.ReadFull(body, prefix[:]); err != nil {
+		return nil, grpcInvalidArgument, errors.New("missing request message")
+	}
+	if prefix[0] != 0 {
+		return nil, grpcUnimplemented, errors.New("compressed messages are not supported")
+	}
+	size := binary.BigEndian.Uint32(prefix[1:])
+	if size > grpcMaxMessage {
+		return nil, grpcInvalidArgument, fmt.Errorf("request message of %d bytes is too large", size)
+	}
+	msg := make([]byte, size)
+	if _, err := io.ReadFull(body, msg); err != nil {
+		return nil, grpcInvalidArgument, errors.New("truncated request message")
+	}
+	return msg, grpcOK, nil
+}
+
+// writeGRPCStatus answers a call with status `code` and no response message.
+func writeGRPCStatus(w http.ResponseWriter, code int, msg string) {
+	w.WriteHeader(http.StatusOK)
+	w.Header().Set("Grpc-Status", strconv.Itoa(code))
+	w.Header().Set("Grpc-Message", grpcPercentEncode(msg))
+}
+
+// grpcPercentEncode encodes a status message as required for the grpc-message trailer.
+func grpcPercentEncode(msg string) string {
+	var sb strings.Builder
+	for i := 0; i < len(msg); i++ {
+		if c := msg[i]; c < 0x20 || c > 0x7e || c == '%' {
+			fmt.Fprintf(&sb, "%%%02X", c)
+		} else {
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// decodeCheckRequest decodes a CheckRequest or LeaseRequest message of limitrond.proto,
+// which share their fields: limit = 1, key = 2, n = 3.
+func decodeCheckRequest(msg []byte) (CheckRequest, error) {
+	var req CheckRequest
+	err := protoFields(msg, func(field uint64, v uint64, b []byte) {
+		switch field {
+		case 1:
+			req.Limit = string(b)
+		case 2:
+			req.Key = string(b)
+		case 3:
+			req.N = uint16(min(v, math.MaxUint16))
+		}
+	})
+	return req, err
+}
+
+// protoFields calls `fn` with every field of the protobuf message `msg`: with the value
+// of varint fields, or the bytes of length-delimited ones. Fixed-size fields are skipped.
+func protoFields(msg []byte, fn func(field uint64, v uint64, b []byte)) error {
+	for len(msg) > 0 {
+		tag, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return errors.New("malformed message")
+		}
+		msg = msg[n:]
+		switch field, wire := tag>>3, tag&7; wire {
+		case 0:
+			v, n := binary.Uvarint(msg)
+			if n <= 0 {
+				return errors.New("malformed varint")
+			}
+			msg = msg[n:]
+			fn(field, v, nil)
+		case 2:
+			size, n := binary.Uvarint(msg)
+			if n <= 0 || size > uint64(len(msg)-n) {
+				return errors.New("malformed length-delimited field")
+			}
+			fn(field, 0, msg[n:n+int(size)])
+			msg = msg[n+int(size):]
+		case 1, 5:
+			size := 8
+			if wire == 5 {
+				size = 4
+			}
+			if len(msg) < size {
+				return errors.New("malformed fixed-size field")
+			}
+			msg = msg[size:]
+		default:
+			return fmt.Errorf("unsupported wire type %d", wire)
+		}
+	}
+	return nil
+}
+
+// appendVarintField appends varint field `field` with value `v` to a protobuf message,
+// omitting it if zero as proto3 does.
+func appendVarintField(b []byte, field uint64, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = binary.AppendUvarint(b, field<<3)
+	return binary.AppendUvarint(b, v)
+}
@@ -0,0 +1,85 @@
+package limitrond
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// grpcCall makes a unary call to `method` with the CheckRequest or LeaseRequest of
+// `limit`, `key` and `n`, returning the varint fields of the response and its status.
+func grpcCall(t *testing.T, ts *httptest.Server, method, limit, key string, n uint64) (map[uint64]uint64, string) {
+	t.Helper()
+	var msg []byte
+	msg = binary.AppendUvarint(msg, 1<<3|2)
+	msg = binary.AppendUvarint(msg, uint64(len(limit)))
+	msg = append(msg, limit...)
+	msg = binary.AppendUvarint(msg, 2<<3|2)
+	msg = binary.AppendUvarint(msg, uint64(len(key)))
+	msg = append(msg, key...)
+	msg = appendVarintField(msg, 3, n)
+	frame := make([]byte, 5, 5+len(msg))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(msg)))
+
+	req, err := http.NewRequest(http.MethodPost, ts.URL+"/"+grpcService+"/"+method, bytes.NewReader(append(frame, msg...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s: %v", method, err)
+	}
+	defer resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("%s served over %s", method, resp.Proto)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("%s: read response: %v", method, err)
+	}
+
+	fields := map[uint64]uint64{}
+	if len(body) > 0 {
+		if len(body) < 5 || int(binary.BigEndian.Uint32(body[1:5])) != len(body)-5 {
+			t.Fatalf("%s: malformed response frame %x", method, body)
+		}
+		err := protoFields(body[5:], func(field uint64, v uint64, b []byte) {
+			fields[field] = v
+		})
+		if err != nil {
+			t.Fatalf("%s: decode response: %v", method, err)
+		}
+	}
+	return fields, resp.Trailer.Get("Grpc-Status")
+}
+
+func TestServer_GRPC(t *testing.T) {
+	ts := httptest.NewUnstartedServer(newTestServer(t).GRPCHandler())
+	ts.EnableHTTP2 = true
+	ts.StartTLS()
+	defer ts.Close()
+
+	if resp, status := grpcCall(t, ts, "Check", "api", "u1", 0); status != "0" || resp[1] != 1 {
+		t.Fatalf("Check = %v, status %s; want allowed", resp, status)
+	}
+	if resp, status := grpcCall(t, ts, "Consume", "api", "u1", 2); status != "0" || resp[1] != 1 {
+		t.Fatalf("Consume = %v, status %s; want allowed", resp, status)
+	}
+	if resp, status := grpcCall(t, ts, "Consume", "api", "u1", 1); status != "0" || resp[1] != 0 || resp[2] == 0 {
+		t.Fatalf("Consume over limit = %v, status %s; want denied with a wait", resp, status)
+	}
+	if resp, status := grpcCall(t, ts, "Lease", "api", "u2", 5); status != "0" || resp[1] != 2 {
+		t.Fatalf("Lease = %v, status %s; want 2 tokens granted", resp, status)
+	}
+
+	if _, status := grpcCall(t, ts, "Consume", "nope", "u1", 1); status != "5" {
+		t.Fatalf("unknown limit: status %s, want 5 (NOT_FOUND)", status)
+	}
+	if _, status := grpcCall(t, ts, "Reset", "api", "u1", 1); status != "12" {
+		t.Fatalf("unknown method: status %s, want 12 (UNIMPLEMENTED)", status)
+	}
+}
@@ -0,0 +1,95 @@
+package limitrond
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestServer_Lease(t *testing.T) {
+	srv, _ := NewServer(&Config{Limits: map[string]Limit{"api": {Requests: 10, Interval: "1h"}}})
+
+	want := []uint16{8, 2, 0}
+	for i, w := range want {
+		resp, err := srv.Lease(LeaseRequest{Limit: "api", Key: "u1", N: 8})
+		if err != nil || resp.Granted != w {
+			t.Fatalf("Lease %d = %+v, %v; want %d granted", i, resp, err, w)
+		}
+	}
+	if resp, _ := srv.Lease(LeaseRequest{Limit: "api", Key: "u2", N: 1000}); resp.Granted != 10 {
+		t.Fatalf("Lease over burst granted %d, want the burst of 10", resp.Granted)
+	}
+}
+
+func TestLeaser_EnforcesGlobalLimitWithFewCalls(t *testing.T) {
+	srv, _ := NewServer(&Config{Limits: map[string]Limit{"api": {Requests: 40, Interval: "1h"}}})
+	var calls atomic.Int64
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		srv.Handler().ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+
+	c, _ := NewClient(ts.URL)
+	l := c.NewLeaser("api", 8, time.Minute)
+	ctx := context.Background()
+
+	admitted := 0
+	for i := 0; i < 60; i++ {
+		resp, err := l.Take1(ctx, "u1")
+		if err != nil {
+			t.Fatalf("Take1 %d: %v", i, err)
+		}
+		if resp.Allowed {
+			admitted++
+		}
+		time.Sleep(time.Millisecond) // let background renewals land
+	}
+	if admitted > 40 || admitted < 32 {
+		t.Fatalf("admitted %d requests, want close to the global limit of 40", admitted)
+	}
+	if got := calls.Load(); got > 15 {
+		t.Fatalf("%d daemon calls for 60 requests, want about one per block", got)
+	}
+}
+
+func TestLeaser_FallbackWhenUnreachable(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	url := ts.URL
+	ts.Close()
+
+	c, _ := NewClient(url, WithFallback(map[string]Limit{"api": {Requests: 1, Interval: "1h"}}, 1))
+	l := c.NewLeaser("api", 8, time.Minute)
+	if resp, err := l.Take1(context.Background(), "u1"); err != nil || !resp.Allowed || !resp.Local {
+		t.Fatalf("Take1 = %+v, %v; want local allow", resp, err)
+	}
+}
+
+func TestLeaser_DropsExpiredLeases(t *testing.T) {
+	srv, _ := NewServer(&Config{Limits: map[string]Limit{"api": {Requests: 100, Interval: "1s"}}})
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	c, _ := NewClient(ts.URL)
+	l := c.NewLeaser("api", 4, 20*time.Millisecond)
+	ctx := context.Background()
+	for _, key := range []string{"u1", "u2", "u3"} {
+		if resp, err := l.Take1(ctx, key); err != nil || !resp.Allowed {
+			t.Fatalf("Take1(%s) = %+v, %v; want allowed", key, resp, err)
+		}
+	}
+	time.Sleep(30 * time.Millisecond)
+	if resp, err := l.Take1(ctx, "u4"); err != nil || !resp.Allowed {
+		t.Fatalf("Take1(u4) = %+v, %v; want allowed", resp, err)
+	}
+
+	l.mu.Lock()
+	n := len(l.leases)
+	l.mu.Unlock()
+	if n != 1 {
+		t.Fatalf("%d leases kept, want only the one of u4", n)
+	}
+}
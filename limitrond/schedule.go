@@ -0,0 +1,119 @@
+package limitrond
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Schedule overrides a limit during recurring time windows, e.g., to double
+// the search limit during Black Friday:
+//
+//	{"cron": "0 0 27 11 *", "duration": "24h", "limit": "search",
+//	 "set": {"requests": 200, "interval": "1m"}}
+//
+// A window starts at every minute matching Cron, in the daemon's local time zone,
+// and lasts Duration. While windows of several schedules of the same limit
+// overlap, the first schedule in the configuration applies.
+type Schedule struct {
+	// Cron is a five-field cron expression: minute, hour, day of month, month
+	// and day of week, e.g., "0 9 * * 1-5" for 9:00 on weekdays.
+	Cron string `json:"cron"`
+	// Duration is a Go duration string: the length of each window.
+	Duration string `json:"duration"`
+	// Limit is the name of the overridden limit.
+	Limit string `json:"limit"`
+	// Set is the configuration of the limit during the windows.
+	Set Limit `json:"set"`
+}
+
+// schedule is a validated Schedule.
+type schedule struct {
+	Schedule
+	spec     *cronSpec
+	duration time.Duration
+}
+
+// compileSchedules validates `schedules`.
+func compileSchedules(schedules []Schedule) ([]schedule, error) {
+	out := make([]schedule, 0, len(schedules))
+	for i, sc := range schedules {
+		spec, err := parseCron(sc.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("schedule %d: %w", i, err)
+		}
+		d, err := time.ParseDuration(sc.Duration)
+		if err != nil || d < time.Minute {
+			return nil, fmt.Errorf("schedule %d: duration %q must be at least 1m", i, sc.Duration)
+		}
+		if _, err := sc.Set.RateLimiter(); err != nil {
+			return nil, fmt.Errorf("schedule %d: %w", i, err)
+		}
+		out = append(out, schedule{Schedule: sc, spec: spec, duration: d})
+	}
+	return out, nil
+}
+
+// active reports whether a window of the schedule covers time `now`.
+func (sc *schedule) active(now time.Time) bool {
+	last := now.Truncate(time.Minute)
+	for t := last; now.Sub(t) < sc.duration; t = t.Add(-time.Minute) {
+		if sc.spec.matches(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// effectiveLimits returns the limits in effect at `now`: `base` with the overrides
+// of active schedules, and the set of overridden limit names.
+func effectiveLimits(base map[string]Limit, schedules []schedule, now time.Time) (map[string]Limit, map[string]bool) {
+	limits := make(map[string]Limit, len(base))
+	for name, l := range base {
+		limits[name] = l
+	}
+	scheduled := make(map[string]bool)
+	for i := range schedules {
+		sc := &schedules[i]
+		if _, ok := base[sc.Limit]; !ok || scheduled[sc.Limit] || !sc.active(now) {
+			continue
+		}
+		limits[sc.Limit] = sc.Set
+		scheduled[sc.Limit] = true
+	}
+	return limits, scheduled
+}
+
+// SetSchedules replaces the scheduled limit overrides with `schedules`
+// and applies those active right now. Overrides take effect at minute
+// boundaries while RunSchedules runs. On error, the schedules are left unchanged.
+func (s *Server) SetSchedules(schedules []Schedule) error {
+	compiled, err := compileSchedules(schedules)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.applyLocked(s.base, compiled)
+}
+
+// RunSchedules applies the scheduled overrides at every minute boundary
+// until `ctx` is done. All limits changing at a boundary are switched at once.
+// Keys keep their tokens when a limit switches between its regular and scheduled
+// configuration, capped at the new burst, so that the end of a window does not
+// hand every key a fresh burst at the same time (see Server.Apply).
+func (s *Server) RunSchedules(ctx context.Context) {
+	for {
+		now := s.now()
+		timer := time.NewTimer(now.Truncate(time.Minute).Add(time.Minute).Sub(now))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		s.mu.Lock()
+		_ = s.applyLocked(s.base, s.schedules) // all configurations were validated already
+		s.mu.Unlock()
+	}
+}
@@ -0,0 +1,164 @@
+package limitrond
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestServer_Schedules(t *testing.T) {
+	now := time.Date(2026, 11, 26, 23, 59, 30, 0, time.Local)
+	srv, err := NewServer(&Config{
+		Limits: map[string]Limit{
+			"search": {Requests: 1, Interval: "1h"},
+			"other":  {Requests: 1, Interval: "1h"},
+		},
+		Schedules: []Schedule{
+			{Cron: "0 0 27 11 *", Duration: "24h", Limit: "search", Set: Limit{Requests: 2, Interval: "1h"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.now = func() time.Time { return now }
+	consume := func(limit string) bool {
+		resp, err := srv.Consume(CheckRequest{Limit: limit, Key: "k"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.Allowed
+	}
+
+	if !consume("other") || srv.Limits()["search"].Scheduled {
+		t.Fatalf("unexpected state before the window")
+	}
+
+	for _, tc := range []struct {
+		at        time.Time
+		requests  uint16
+		scheduled bool
+	}{
+		{time.Date(2026, 11, 27, 0, 0, 0, 0, time.Local), 2, true},
+		{time.Date(2026, 11, 27, 23, 59, 0, 0, time.Local), 2, true},
+		{time.Date(2026, 11, 28, 0, 0, 0, 0, time.Local), 1, false},
+	} {
+		now = tc.at
+		srv.mu.Lock()
+		srv.applyLocked(srv.base, srv.schedules)
+		srv.mu.Unlock()
+		st := srv.Limits()["search"]
+		if st.Requests != tc.requests || st.Scheduled != tc.scheduled {
+			t.Errorf("at %v: search = %+v, want %d requests, scheduled %v", tc.at, st, tc.requests, tc.scheduled)
+		}
+	}
+
+	// unchanged limits keep their states across switches
+	if consume("other") {
+		t.Errorf("other was reset by a schedule switch")
+	}
+}
+
+func TestServer_RunSchedulesStops(t *testing.T) {
+	srv := newTestServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		srv.RunSchedules(ctx)
+		close(done)
+	}()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("RunSchedules did not stop")
+	}
+}
+
+func TestParseConfig_Schedules(t *testing.T) {
+	for _, bad := range []string{
+		`{"limits": {"a": {"requests": 1, "interval": "1s"}}, "schedules": [{"cron": "0 0 * * *", "duration": "1h", "limit": "b", "set": {"requests": 2, "interval": "1s"}}]}`,
+		`{"limits": {"a": {"requests": 1, "interval": "1s"}}, "schedules": [{"cron": "0 0 * *", "duration": "1h", "limit": "a", "set": {"requests": 2, "interval": "1s"}}]}`,
+		`{"limits": {"a": {"requests": 1, "interval": "1s"}}, "schedules": [{"cron": "0 0 * * *", "duration": "1s", "limit": "a", "set": {"requests": 2, "interval": "1s"}}]}`,
+		`{"limits": {"a": {"requests": 1, "interval": "1s"}}, "schedules": [{"cron": "0 0 * * *", "duration": "1h", "limit": "a", "set": {"requests": 0, "interval": "1s"}}]}`,
+	} {
+		if _, err := ParseConfig([]byte(bad)); err == nil || !strings.Contains(err.Error(), "schedule 0") {
+			t.Errorf("ParseConfig(%s) error = %v, want a schedule error", bad, err)
+		}
+	}
+}
+
+func TestServer_Reload(t *testing.T) {
+	srv := newTestServer(t)
+	srv.now = func() time.Time { return time.Date(2026, 11, 27, 12, 0, 0, 0, time.Local) }
+
+	err := srv.Reload(&Config{
+		Limits: map[string]Limit{
+			"api":    {Requests: 2, Interval: "1h"},
+			"search": {Requests: 1, Interval: "1h"},
+		},
+		Schedules: []Schedule{
+			{Cron: "0 0 27 11 *", Duration: "24h", Limit: "search", Set: Limit{Requests: 5, Interval: "1h"}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the new limit and its schedule are applied together
+	if st := srv.Limits()["search"]; st.Requests != 5 || !st.Scheduled {
+		t.Fatalf("search = %+v, want the scheduled 5 requests", st)
+	}
+
+	bad := &Config{Limits: map[string]Limit{"api": {Requests: 2, Interval: "1h"}},
+		Schedules: []Schedule{{Cron: "bad", Duration: "1h", Limit: "api", Set: Limit{Requests: 1, Interval: "1h"}}}}
+	if err := srv.Reload(bad); err == nil {
+		t.Fatal("Reload with an invalid schedule succeeded")
+	}
+	if _, ok := srv.Limits()["search"]; !ok {
+		t.Fatal("failed Reload changed the limits")
+	}
+}
+
+func TestServer_ScheduleSwitchKeepsTokens(t *testing.T) {
+	for _, fair := range []bool{false, true} {
+		now := time.Date(2026, 11, 26, 23, 59, 30, 0, time.Local)
+		srv, err := NewServer(&Config{
+			Limits: map[string]Limit{"search": {Requests: 2, Interval: "1h", Fair: fair}},
+			Schedules: []Schedule{
+				{Cron: "0 0 27 11 *", Duration: "1h", Limit: "search", Set: Limit{Requests: 10, Interval: "1h", Fair: fair}},
+			},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		srv.now = func() time.Time { return now }
+		switchAt := func(at time.Time) {
+			now = at
+			srv.mu.Lock()
+			srv.applyLocked(srv.base, srv.schedules)
+			srv.mu.Unlock()
+		}
+		consume := func(n uint16) bool {
+			resp, err := srv.Consume(CheckRequest{Limit: "search", Key: "k", N: n})
+			if err != nil {
+				t.Fatal(err)
+			}
+			return resp.Allowed
+		}
+
+		// the peak window starts: the drained bucket must not be refilled
+		if !consume(2) {
+			t.Fatalf("fair=%v: initial burst denied", fair)
+		}
+		switchAt(time.Date(2026, 11, 27, 0, 0, 0, 0, time.Local))
+		if consume(5) {
+			t.Fatalf("fair=%v: the schedule start handed out a fresh burst", fair)
+		}
+
+		// the peak window ends: no fresh burst either
+		switchAt(time.Date(2026, 11, 27, 1, 0, 0, 0, time.Local))
+		if consume(1) {
+			t.Fatalf("fair=%v: the schedule end handed out a fresh burst", fair)
+		}
+	}
+}
@@ -0,0 +1,456 @@
+// Package limitrond implements limitrond, a daemon sharing limitron-enforced limits
+// with the services and scripts of a host or cluster over a small JSON/HTTP API,
+// and a Go client for it (see Client).
+//
+// Check/consume API (Server.Handler):
+//
+//	POST /v1/consume  {"limit": "api", "key": "user:42", "n": 1}
+//	POST /v1/check    {"limit": "api", "key": "user:42", "n": 1}
+//	=> 200 {"allowed": false, "wait_ms": 1200}
+//
+// consume takes the tokens if available; check only reports whether consume would
+// succeed. An omitted n means 1. Unknown limits are answered with 404. The same calls
+// are served over gRPC by Server.GRPCHandler (see limitrond.proto).
+//
+// In coordinator mode, clients lease blocks of tokens and enforce them locally
+// (see Leaser), making one call per block instead of one per request:
+//
+//	POST /v1/lease    {"limit": "api", "key": "user:42", "n": 100}
+//	=> 200 {"granted": 50, "wait_ms": 0}
+//
+// Admin API (Server.AdminHandler):
+//
+//	GET    /admin/limits         list limits and their number of keys
+//	PUT    /admin/limits/{name}  create or replace a limit: {"requests": 100, "interval": "1m"}
+//	DELETE /admin/limits/{name}  remove a limit
+//
+// Limits can be overridden during recurring time windows with cron-like
+// schedules (see Schedule and Server.RunSchedules).
+package limitrond
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iryndin/limitron"
+)
+
+// ErrUnknownLimit is returned for operations on a limit name that is not configured.
+var ErrUnknownLimit = errors.New("limitrond: unknown limit")
+
+// CheckRequest is the body of check and consume calls.
+type CheckRequest struct {
+	Limit string `json:"limit"`
+	Key   string `json:"key"`
+	N     uint16 `json:"n,omitempty"`
+}
+
+// CheckResponse is the answer to check and consume calls.
+type CheckResponse struct {
+	Allowed bool `json:"allowed"`
+	// WaitMillis is the suggested wait before retrying a denied call.
+	WaitMillis int64 `json:"wait_ms"`
+	// Local reports that the decision was made by a Client's local fallback.
+	Local bool `json:"-"`
+}
+
+// LeaseRequest is the body of lease calls, asking for a block of up to N tokens.
+type LeaseRequest struct {
+	Limit string `json:"limit"`
+	Key   string `json:"key"`
+	N     uint16 `json:"n"`
+}
+
+// LeaseResponse is the answer to lease calls.
+type LeaseResponse struct {
+	// Granted is the number of tokens taken on behalf of the client; 0 if none are available.
+	Granted uint16 `json:"granted"`
+	// WaitMillis is the suggested wait before leasing again if nothing was granted.
+	WaitMillis int64 `json:"wait_ms"`
+}
+
+// LimitStatus describes a configured limit in the admin API.
+type LimitStatus struct {
+	// Limit is the configuration in effect, which is a schedule's if Scheduled.
+	Limit
+	// Keys is the number of keys with a state.
+	Keys int `json:"keys"`
+	// Scheduled reports that a Schedule currently overrides the configured limit.
+	Scheduled bool `json:"scheduled,omitempty"`
+}
+
+// Server holds the named limits of the daemon.
+//
+// The zero value is not usable; create instances with NewServer.
+// All methods are safe for concurrent use.
+type Server struct {
+	mu sync.RWMutex
+	// base holds the configured limits, before schedule overrides.
+	base      map[string]Limit
+	schedules []schedule
+	// limits holds the limits in effect; scheduled marks those overridden by a schedule.
+	limits    map[string]*namedLimit
+	scheduled map[string]bool
+	// now returns the current time, for evaluating schedules.
+	now func() time.Time
+}
+
+// limitTier is the tier of all keys of a namedLimit. The limiter of a changed
+// configuration is installed as its tier limiter, so that keys keep their tokens.
+const limitTier = "limit"
+
+// namedLimit is a configured limit with the states of its keys.
+type namedLimit struct {
+	cfg     Limit
+	limiter limitron.RateLimiter
+	// keys holds the per-key buckets; nil for Fair limits.
+	keys *limitron.KeyedLimiter[string]
+	// fair is the shared bucket of Fair limits; nil otherwise.
+	fair *fairPool
+}
+
+// NewServer returns a Server with the limits and schedules of `cfg`.
+// Call RunSchedules to apply scheduled overrides as time passes.
+func NewServer(cfg *Config) (*Server, error) {
+	s := &Server{limits: make(map[string]*namedLimit), now: time.Now}
+	schedules, err := compileSchedules(cfg.Schedules)
+	if err != nil {
+		return nil, err
+	}
+	s.schedules = schedules
+	if err := s.Apply(cfg.Limits); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Apply replaces the configured limits with `limits`, e.g., after a configuration reload.
+// Keys keep their states across configuration changes, with their tokens capped at the
+// new burst, unless a limit switches between Fair and per-key buckets or changes MaxKeys.
+// On error, the configuration is left unchanged.
+func (s *Server) Apply(limits map[string]Limit) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.applyLocked(limits, s.schedules)
+}
+
+// Reload replaces the limits and schedules with those of `cfg` at once, e.g., after
+// a configuration reload, so that no request sees the new limits with the old schedules
+// or vice versa. Keys keep their states as with Apply.
+// Listen addresses are ignored. On error, the configuration is left unchanged.
+func (s *Server) Reload(cfg *Config) error {
+	schedules, err := compileSchedules(cfg.Schedules)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.applyLocked(cfg.Limits, schedules)
+}
+
+// applyLocked makes `base` and `schedules` the configuration and switches to the limits
+// in effect right now. Limits whose configuration changed take over the key states of
+// the current ones where possible (see namedLimit.carryFrom), so that a schedule starting
+// or ending does not hand every key a fresh burst at once.
+// On error, the configuration is left unchanged. Must be called with s.mu held.
+func (s *Server) applyLocked(base map[string]Limit, schedules []schedule) error {
+	return s.applyResetLocked(base, schedules, "")
+}
+
+// applyResetLocked is applyLocked, starting the limit `reset` over with fresh key
+// states even if its configuration did not change. Must be called with s.mu held.
+func (s *Server) applyResetLocked(base map[string]Limit, schedules []schedule, reset string) error {
+	limits, scheduled := effectiveLimits(base, schedules, s.now())
+	next := make(map[string]*namedLimit, len(limits))
+	var carried [][2]*namedLimit
+	for name, l := range limits {
+		cur, ok := s.limits[name]
+		if name == reset {
+			ok = false
+		}
+		if ok && cur.cfg == l {
+			next[name] = cur
+			continue
+		}
+		nl, err := newNamedLimit(l)
+		if err != nil {
+			return fmt.Errorf("limit %q: %w", name, err)
+		}
+		if ok && cur.cfg.Fair == l.Fair && cur.cfg.MaxKeys == l.MaxKeys {
+			carried = append(carried, [2]*namedLimit{nl, cur})
+		}
+		next[name] = nl
+	}
+	for _, c := range carried {
+		c[0].carryFrom(c[1])
+	}
+	s.base, s.schedules = base, schedules
+	s.limits, s.scheduled = next, scheduled
+	return nil
+}
+
+// SetLimit creates or replaces the limit `name`. Replacing a limit resets the states of its keys.
+func (s *Server) SetLimit(name string, l Limit) error {
+	if _, err := l.RateLimiter(); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	base := make(map[string]Limit, len(s.base)+1)
+	for n, cur := range s.base {
+		base[n] = cur
+	}
+	base[name] = l
+	return s.applyResetLocked(base, s.schedules, name)
+}
+
+// DeleteLimit removes the limit `name` and reports whether it existed.
+func (s *Server) DeleteLimit(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.base[name]; !ok {
+		return false
+	}
+	base := make(map[string]Limit, len(s.base))
+	for n, cur := range s.base {
+		if n != name {
+			base[n] = cur
+		}
+	}
+	_ = s.applyLocked(base, s.schedules) // the remaining limits are unchanged
+	return true
+}
+
+// Limits returns the status of all configured limits by name.
+func (s *Server) Limits() map[string]LimitStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make(map[string]LimitStatus, len(s.limits))
+	for name, nl := range s.limits {
+		out[name] = LimitStatus{Limit: nl.cfg, Keys: nl.len(), Scheduled: s.scheduled[name]}
+	}
+	return out
+}
+
+// Consume takes the tokens of `req` if available.
+// Returns ErrUnknownLimit if the limit is not configured.
+func (s *Server) Consume(req CheckRequest) (CheckResponse, error) {
+	return s.do(req, true)
+}
+
+// Check reports whether Consume would currently succeed, without taking tokens.
+// Returns ErrUnknownLimit if the limit is not configured.
+func (s *Server) Check(req CheckRequest) (CheckResponse, error) {
+	return s.do(req, false)
+}
+
+// Lease takes a block of tokens on behalf of a client that enforces them locally.
+// It grants the largest of N, N/2, N/4, ... tokens available, capped at the burst
+// of the limit. Returns ErrUnknownLimit if the limit is not configured.
+func (s *Server) Lease(req LeaseRequest) (LeaseResponse, error) {
+	s.mu.RLock()
+	nl, ok := s.limits[req.Limit]
+	s.mu.RUnlock()
+	if !ok {
+		return LeaseResponse{}, ErrUnknownLimit
+	}
+
+	n := min(max(req.N, 1), nl.cfg.Requests)
+	if nl.fair != nil {
+		granted, waitMillis := nl.fair.take(req.Key, n, true, true)
+		return LeaseResponse{Granted: granted, WaitMillis: waitMillis}, nil
+	}
+
+	var waitMillis int64
+	for ; n > 0; n /= 2 {
+		var allowed bool
+		if waitMillis, allowed = nl.keys.TakeN(req.Key, n); allowed {
+			return LeaseResponse{Granted: n}, nil
+		}
+	}
+	return LeaseResponse{WaitMillis: waitMillis}, nil
+}
+
+func (s *Server) do(req CheckRequest, consume bool) (CheckResponse, error) {
+	s.mu.RLock()
+	nl, ok := s.limits[req.Limit]
+	s.mu.RUnlock()
+	if !ok {
+		return CheckResponse{}, ErrUnknownLimit
+	}
+
+	n := max(req.N, 1)
+	var waitMillis int64
+	var allowed bool
+	switch {
+	case nl.fair != nil:
+		var granted uint16
+		granted, waitMillis = nl.fair.take(req.Key, n, false, consume)
+		allowed = granted > 0
+	case consume:
+		waitMillis, allowed = nl.keys.TakeN(req.Key, n)
+	default:
+		waitMillis, allowed = nl.keys.Peek(req.Key, n)
+	}
+	if allowed {
+		waitMillis = 0
+	}
+	return CheckResponse{Allowed: allowed, WaitMillis: waitMillis}, nil
+}
+
+func newNamedLimit(l Limit) (*namedLimit, error) {
+	limiter, err := l.RateLimiter()
+	if err != nil {
+		return nil, err
+	}
+	if l.Fair {
+		window, _ := time.ParseDuration(l.Interval)
+		return &namedLimit{cfg: l, limiter: limiter, fair: newFairPool(limiter, l, window)}, nil
+	}
+	keys := limitron.NewKeyedLimiter(limiter,
+		limitron.WithMaxKeys[string](l.maxKeys(), nil),
+		limitron.WithTierFunc(func(string) string { return limitTier }))
+	return &namedLimit{cfg: l, limiter: limiter, keys: keys}, nil
+}
+
+// carryFrom makes the new limit `nl` take over the key states of `prev`, a limit of the
+// same kind and MaxKeys that it replaces: keys keep their tokens, capped at the new burst,
+// and refill at the new rate. `prev` must not be used afterwards.
+func (nl *namedLimit) carryFrom(prev *namedLimit) {
+	if nl.fair != nil {
+		nl.fair.carryFrom(prev.fair)
+		return
+	}
+	nl.keys = prev.keys
+	_ = nl.keys.SetTierLimiter(limitTier, nl.limiter) // limits share the tick resolution
+}
+
+// len returns the number of keys with a state.
+func (nl *namedLimit) len() int {
+	if nl.fair != nil {
+		return nl.fair.len()
+	}
+	return nl.keys.Len()
+}
+
+// Handler returns the HTTP handler of the check/consume API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/consume", func(w http.ResponseWriter, r *http.Request) {
+		s.serveCheck(w, r, true)
+	})
+	mux.HandleFunc("/v1/check", func(w http.ResponseWriter, r *http.Request) {
+		s.serveCheck(w, r, false)
+	})
+	mux.HandleFunc("/v1/lease", s.serveLease)
+	return mux
+}
+
+func (s *Server) serveLease(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var req LeaseRequest
+	if status, err := readJSON(w, r, &req); err != nil {
+		writeError(w, status, "invalid request: "+err.Error())
+		return
+	}
+	resp, err := s.Lease(req)
+	if err != nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("unknown limit %q", req.Limit))
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) serveCheck(w http.ResponseWriter, r *http.Request, consume bool) {
+	if r.Method != http.MethodPost {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	var req CheckRequest
+	if status, err := readJSON(w, r, &req); err != nil {
+		writeError(w, status, "invalid request: "+err.Error())
+		return
+	}
+	resp, err := s.do(req, consume)
+	if err != nil {
+		writeError(w, http.StatusNotFound, fmt.Sprintf("unknown limit %q", req.Limit))
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// AdminHandler returns the HTTP handler of the admin API.
+// It should only be exposed to operators.
+func (s *Server) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/admin/limits", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		writeJSON(w, http.StatusOK, s.Limits())
+	})
+	mux.HandleFunc("/admin/limits/", func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/admin/limits/")
+		if name == "" || strings.Contains(name, "/") {
+			writeError(w, http.StatusNotFound, "not found")
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			var l Limit
+			if status, err := readJSON(w, r, &l); err != nil {
+				writeError(w, status, "invalid limit: "+err.Error())
+				return
+			}
+			if err := s.SetLimit(name, l); err != nil {
+				writeError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodDelete:
+			if !s.DeleteLimit(name) {
+				writeError(w, http.StatusNotFound, fmt.Sprintf("unknown limit %q", name))
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+	})
+	return mux
+}
+
+// maxRequestBody bounds the size of JSON request bodies.
+const maxRequestBody = 64 << 10
+
+// readJSON decodes the JSON body of `r` into `v`, reading at most maxRequestBody bytes.
+// On error, it also returns the HTTP status to answer with.
+func readJSON(w http.ResponseWriter, r *http.Request, v any) (int, error) {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBody)).Decode(v); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return http.StatusRequestEntityTooLarge, err
+		}
+		return http.StatusBadRequest, err
+	}
+	return http.StatusOK, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,160 @@
+package limitrond
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestServer(t *testing.T) *Server {
+	t.Helper()
+	srv, err := NewServer(&Config{Limits: map[string]Limit{
+		"api": {Requests: 2, Interval: "1h"},
+	}})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	return srv
+}
+
+func post(t *testing.T, h http.Handler, path, body string) (*httptest.ResponseRecorder, CheckResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+	var resp CheckResponse
+	if w.Code == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+	}
+	return w, resp
+}
+
+func TestServer_ConsumeAndCheck(t *testing.T) {
+	h := newTestServer(t).Handler()
+	req := `{"limit": "api", "key": "u1"}`
+
+	for i := 0; i < 2; i++ {
+		if _, resp := post(t, h, "/v1/check", req); !resp.Allowed {
+			t.Fatalf("check %d should be allowed", i)
+		}
+		if _, resp := post(t, h, "/v1/consume", req); !resp.Allowed {
+			t.Fatalf("consume %d should be allowed", i)
+		}
+	}
+	_, resp := post(t, h, "/v1/consume", req)
+	if resp.Allowed || resp.WaitMillis <= 0 {
+		t.Fatalf("consume over limit = %+v, want denied with a wait", resp)
+	}
+	if _, resp := post(t, h, "/v1/check", req); resp.Allowed {
+		t.Fatal("check over limit should be denied")
+	}
+	if _, resp := post(t, h, "/v1/consume", `{"limit": "api", "key": "u2", "n": 2}`); !resp.Allowed {
+		t.Fatal("other keys should be independent")
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+	h := newTestServer(t).Handler()
+	if w, _ := post(t, h, "/v1/consume", `{"limit": "nope", "key": "u1"}`); w.Code != http.StatusNotFound {
+		t.Fatalf("unknown limit => %d, want 404", w.Code)
+	}
+	if w, _ := post(t, h, "/v1/consume", `{`); w.Code != http.StatusBadRequest {
+		t.Fatalf("malformed body => %d, want 400", w.Code)
+	}
+	huge := `{"limit": "api", "key": "` + strings.Repeat("x", maxRequestBody) + `"}`
+	if w, _ := post(t, h, "/v1/consume", huge); w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("oversized body => %d, want 413", w.Code)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/consume", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("GET => %d, want 405", w.Code)
+	}
+}
+
+func TestServer_Admin(t *testing.T) {
+	srv := newTestServer(t)
+	admin := srv.AdminHandler()
+
+	w := httptest.NewRecorder()
+	admin.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/admin/limits/login", strings.NewReader(`{"requests": 1, "interval": "1m"}`)))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("PUT => %d %s", w.Code, w.Body)
+	}
+	srv.Consume(CheckRequest{Limit: "login", Key: "u1"})
+
+	w = httptest.NewRecorder()
+	admin.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/admin/limits", nil))
+	var limits map[string]LimitStatus
+	if err := json.Unmarshal(w.Body.Bytes(), &limits); err != nil {
+		t.Fatalf("decode limits: %v", err)
+	}
+	if got := limits["login"]; got.Requests != 1 || got.Keys != 1 {
+		t.Fatalf("login status = %+v, want 1 request and 1 key", got)
+	}
+
+	w = httptest.NewRecorder()
+	admin.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/admin/limits/api", nil))
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("DELETE => %d", w.Code)
+	}
+	if _, err := srv.Consume(CheckRequest{Limit: "api", Key: "u1"}); err != ErrUnknownLimit {
+		t.Fatalf("Consume of deleted limit err = %v, want ErrUnknownLimit", err)
+	}
+}
+
+func TestServer_ApplyKeepsUnchangedState(t *testing.T) {
+	srv := newTestServer(t)
+	srv.Consume(CheckRequest{Limit: "api", Key: "u1", N: 2})
+
+	err := srv.Apply(map[string]Limit{
+		"api":   {Requests: 2, Interval: "1h"},
+		"other": {Requests: 1, Interval: "1s"},
+	})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if resp, _ := srv.Consume(CheckRequest{Limit: "api", Key: "u1"}); resp.Allowed {
+		t.Fatal("unchanged limit should keep its state across Apply")
+	}
+	if err := srv.Apply(map[string]Limit{"bad": {}}); err == nil {
+		t.Fatal("Apply with an invalid limit should fail")
+	}
+	if _, err := srv.Check(CheckRequest{Limit: "other", Key: "u1"}); err != nil {
+		t.Fatalf("failed Apply should keep the previous limits: %v", err)
+	}
+}
+
+func TestServer_SetLimitResetsState(t *testing.T) {
+	srv := newTestServer(t)
+	srv.Consume(CheckRequest{Limit: "api", Key: "u1", N: 2})
+
+	if err := srv.SetLimit("api", Limit{Requests: 2, Interval: "1h"}); err != nil {
+		t.Fatalf("SetLimit: %v", err)
+	}
+	if resp, _ := srv.Consume(CheckRequest{Limit: "api", Key: "u1", N: 2}); !resp.Allowed {
+		t.Fatal("SetLimit should start the limit over with fresh states")
+	}
+	if _, ok := srv.Limits()["api"]; !ok {
+		t.Fatal("SetLimit lost the limit")
+	}
+}
+
+func TestServer_MaxKeys(t *testing.T) {
+	srv, err := NewServer(&Config{Limits: map[string]Limit{
+		"api": {Requests: 2, Interval: "1h", MaxKeys: 128},
+	}})
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	for i := 0; i < 1000; i++ {
+		srv.Consume(CheckRequest{Limit: "api", Key: fmt.Sprint("ip-", i)})
+	}
+	if keys := srv.Limits()["api"].Keys; keys > 128 {
+		t.Fatalf("%d keys kept, want at most 128", keys)
+	}
+}
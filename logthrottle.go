@@ -0,0 +1,168 @@
+package limitron
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// logThrottleMaxKeys bounds the message keys of a ThrottledHandler or ThrottledWriter.
+// Beyond it, the least recently used keys are forgotten.
+const logThrottleMaxKeys = 4096
+
+// logThrottle is the per-message-key state shared by ThrottledHandler and ThrottledWriter.
+type logThrottle struct {
+	keys *KeyedLimiter[string]
+	// suppressed maps a message key to the *atomic.Uint64 count of records
+	// dropped since the last admitted one.
+	suppressed sync.Map
+}
+
+func newLogThrottle(limiter RateLimiter) *logThrottle {
+	t := &logThrottle{}
+	t.keys = NewKeyedLimiter[string](limiter, WithMaxKeys(logThrottleMaxKeys, func(key string) {
+		t.suppressed.Delete(key)
+	}))
+	return t
+}
+
+// admit reports whether a record with `key` may be logged. If it may, it also returns
+// the number of records with the same key suppressed since the previous admitted one.
+func (t *logThrottle) admit(key string) (suppressed uint64, ok bool) {
+	if _, ok = t.keys.Take1(key); !ok {
+		c, _ := t.suppressed.LoadOrStore(key, new(atomic.Uint64))
+		c.(*atomic.Uint64).Add(1)
+		return 0, false
+	}
+	if c, found := t.suppressed.Load(key); found {
+		suppressed = c.(*atomic.Uint64).Swap(0)
+	}
+	return suppressed, true
+}
+
+// ThrottledHandler is a slog.Handler that drops log records above the rate of
+// a limiter, separately for every message key, to protect against log storms.
+//
+// When a record is admitted after others with the same key were dropped,
+// it carries an extra "suppressed" attribute with the number of dropped records.
+//
+// Message keys are expected to come from a bounded set (typically the constant
+// message of the log call). At most about 4096 keys are tracked; beyond that, the least
+// recently used ones are forgotten, along with their suppressed counts.
+type ThrottledHandler struct {
+	next     slog.Handler
+	key      func(slog.Record) string
+	throttle *logThrottle
+}
+
+// NewThrottledHandler returns a handler passing at most the rate of `limiter` records
+// per message key on to `next`. `key` derives the message key of a record;
+// nil uses the record's message.
+//
+// Example:
+//
+//	h := NewThrottledHandler(slog.NewJSONHandler(os.Stderr, nil), BuildRateLimiter(10, time.Minute), nil)
+//	logger := slog.New(h)
+func NewThrottledHandler(next slog.Handler, limiter RateLimiter, key func(slog.Record) string) *ThrottledHandler {
+	if key == nil {
+		key = func(r slog.Record) string { return r.Message }
+	}
+	return &ThrottledHandler{next: next, key: key, throttle: newLogThrottle(limiter)}
+}
+
+// Enabled reports whether the wrapped handler handles records of the given level.
+func (h *ThrottledHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle passes `r` on to the wrapped handler unless its message key is over the rate.
+// Dropped records are not an error.
+func (h *ThrottledHandler) Handle(ctx context.Context, r slog.Record) error {
+	suppressed, ok := h.throttle.admit(h.key(r))
+	if !ok {
+		return nil
+	}
+	if suppressed > 0 {
+		r = r.Clone()
+		r.AddAttrs(slog.Uint64("suppressed", suppressed))
+	}
+	return h.next.Handle(ctx, r)
+}
+
+// WithAttrs returns a handler with the given attributes, sharing the rate state of h.
+func (h *ThrottledHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &ThrottledHandler{next: h.next.WithAttrs(attrs), key: h.key, throttle: h.throttle}
+}
+
+// WithGroup returns a handler with the given group, sharing the rate state of h.
+func (h *ThrottledHandler) WithGroup(name string) slog.Handler {
+	return &ThrottledHandler{next: h.next.WithGroup(name), key: h.key, throttle: h.throttle}
+}
+
+// ThrottledWriter is an io.Writer that drops writes above the rate of a limiter,
+// separately for every distinct line, for use with line-oriented loggers such as
+// the standard log package.
+//
+// Before a line admitted after others equal to it were dropped, a summary line
+// "(N similar messages suppressed)" is written. Lines are tracked as bounded as
+// by ThrottledHandler.
+type ThrottledWriter struct {
+	w        io.Writer
+	key      func(line string) string
+	mu       sync.Mutex
+	throttle *logThrottle
+}
+
+// logTimestamp matches the date and time written by log.Logger with the Ldate,
+// Ltime and Lmicroseconds flags.
+var logTimestamp = regexp.MustCompile(`\d{4}/\d\d/\d\d (\d\d:\d\d:\d\d(\.\d{6})? )?|\d\d:\d\d:\d\d(\.\d{6})? `)
+
+// NewThrottledWriter returns a writer passing at most the rate of `limiter` writes
+// per distinct line on to `w`. Every Write is expected to be a single log line,
+// as with log.Logger.
+//
+// `key` derives the message key of a line, without its trailing newline; nil uses the
+// line without the first date and time written by log.Logger, so that repeats of the
+// same message logged at different times share a key.
+//
+// Example:
+//
+//	log.SetOutput(NewThrottledWriter(os.Stderr, BuildRateLimiter(10, time.Minute), nil))
+func NewThrottledWriter(w io.Writer, limiter RateLimiter, key func(line string) string) *ThrottledWriter {
+	if key == nil {
+		key = stripLogTimestamp
+	}
+	return &ThrottledWriter{w: w, key: key, throttle: newLogThrottle(limiter)}
+}
+
+// stripLogTimestamp removes the first date and time written by log.Logger from `line`.
+// It may follow the logger prefix, or precede it with the Lmsgprefix flag.
+func stripLogTimestamp(line string) string {
+	if loc := logTimestamp.FindStringIndex(line); loc != nil {
+		return line[:loc[0]] + line[loc[1]:]
+	}
+	return line
+}
+
+// Write writes `p` to the underlying writer unless its line is over the rate.
+// Dropped writes report success, so that loggers do not treat them as failures.
+func (tw *ThrottledWriter) Write(p []byte) (int, error) {
+	suppressed, ok := tw.throttle.admit(tw.key(strings.TrimRight(string(p), "\r\n")))
+	if !ok {
+		return len(p), nil
+	}
+
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if suppressed > 0 {
+		if _, err := fmt.Fprintf(tw.w, "(%d similar messages suppressed)\n", suppressed); err != nil {
+			return 0, err
+		}
+	}
+	return tw.w.Write(p)
+}
@@ -0,0 +1,92 @@
+package limitron
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestThrottledHandler_SuppressesPerMessage(t *testing.T) {
+	var buf bytes.Buffer
+	next := slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})
+	logger := slog.New(NewThrottledHandler(next, BuildRateLimiter(2, 100*time.Millisecond), nil))
+
+	for i := 0; i < 5; i++ {
+		logger.Info("disk full", "i", i)
+		logger.Warn("other")
+	}
+	if got := strings.Count(buf.String(), "disk full"); got != 2 {
+		t.Fatalf("%d 'disk full' records logged, want 2:\n%s", got, buf.String())
+	}
+	if got := strings.Count(buf.String(), "other"); got != 2 {
+		t.Fatalf("%d 'other' records logged, want 2 (keys are independent)", got)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	buf.Reset()
+	logger.With("component", "db").Info("disk full")
+	if want := "suppressed=3"; !strings.Contains(buf.String(), want) {
+		t.Fatalf("record after suppression = %q, want it to contain %q", buf.String(), want)
+	}
+	if !strings.Contains(buf.String(), "component=db") {
+		t.Fatalf("derived handler lost attributes: %q", buf.String())
+	}
+}
+
+func TestThrottledWriter_SummarizesSuppressed(t *testing.T) {
+	var buf bytes.Buffer
+	l := log.New(NewThrottledWriter(&buf, BuildRateLimiter(1, 50*time.Millisecond), nil), "", 0)
+
+	for i := 0; i < 4; i++ {
+		l.Print("retrying")
+	}
+	if buf.String() != "retrying\n" {
+		t.Fatalf("output = %q, want a single line", buf.String())
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	l.Print("retrying")
+	want := "retrying\n(3 similar messages suppressed)\nretrying\n"
+	if buf.String() != want {
+		t.Fatalf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestThrottledWriter_IgnoresTimestamps(t *testing.T) {
+	var buf bytes.Buffer
+	tw := NewThrottledWriter(&buf, BuildRateLimiter(1, time.Hour), nil)
+	l := log.New(tw, "app: ", log.LstdFlags|log.Lmicroseconds)
+
+	for i := 0; i < 3; i++ {
+		l.Print("retrying")
+		time.Sleep(time.Millisecond)
+	}
+	if got := strings.Count(buf.String(), "retrying"); got != 1 {
+		t.Fatalf("%d lines logged, want 1:\n%s", got, buf.String())
+	}
+	if got := stripLogTimestamp("2009/01/23 01:23:23.123123 app: disk full"); got != "app: disk full" {
+		t.Fatalf("stripLogTimestamp = %q", got)
+	}
+}
+
+func TestThrottledWriter_BoundsKeys(t *testing.T) {
+	tw := NewThrottledWriter(io.Discard, BuildRateLimiter(1, time.Hour), nil)
+	for i := 0; i < 3*logThrottleMaxKeys; i++ {
+		fmt.Fprintf(tw, "request %d failed\n", i)
+	}
+	if n := tw.throttle.keys.Len(); n > logThrottleMaxKeys+keyedShards {
+		t.Fatalf("%d keys tracked, want at most about %d", n, logThrottleMaxKeys)
+	}
+}
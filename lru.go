@@ -0,0 +1,143 @@
+package limitron
+
+// keyedLRU bounds the number of keys of a KeyedLimiter (see WithMaxKeys).
+//
+// Each shard keeps its keys in a doubly linked list ordered from the most to the
+// least recently used: lookups move their key to the front, and a full shard evicts
+// the key at the back. Keys removed by other means (Delete, EvictIdle) are unlinked
+// as they are removed, so the back of the list is always the least recently used key.
+type keyedLRU[K comparable] struct {
+	// perShard is the maximum number of keys of a shard.
+	perShard int
+	// onEvict is called with every key evicted to make room; nil when not needed.
+	onEvict func(key K)
+}
+
+// lruNode is the node of a key in the LRU list of its shard.
+type lruNode[K comparable] struct {
+	key        K
+	e          *keyedEntry
+	prev, next *lruNode[K]
+}
+
+// WithMaxKeys caps the number of keys of a KeyedLimiter at `n`, as a hard memory ceiling
+// when clients spray many distinct keys such as source IPs. Once a shard is full, creating
+// a key evicts the least recently used key of that shard and calls `onEvict` (if not nil)
+// with it, after the shard lock is released.
+//
+// The cap is enforced per shard, so the limiter holds at most `n` rounded up to a multiple
+// of 64 keys, and the evicted key is the least recently used one of its shard rather than
+// of the whole limiter. An evicted key that comes back starts over like a new one, with a
+// full bucket (or an empty one with WithStartEmpty), so `n` should comfortably exceed the
+// number of keys active within a refill interval. A non-positive `n` disables the cap.
+//
+// Keeping the order exact costs a short critical section on the list of the shard
+// on every lookup, except for the key already at its front.
+//
+// Example:
+//
+//	perIP := NewKeyedLimiter[netip.Addr](limiter, WithMaxKeys(100_000, func(ip netip.Addr) {
+//	    evictions.Inc()
+//	}))
+func WithMaxKeys[K comparable](n int, onEvict func(key K)) KeyedOption[K] {
+	return func(kl *KeyedLimiter[K]) {
+		if n <= 0 {
+			kl.lru = nil
+			return
+		}
+		kl.lru = &keyedLRU[K]{perShard: (n + keyedShards - 1) / keyedShards, onEvict: onEvict}
+	}
+}
+
+// touch moves entry `e` of shard `sh` to the front of its LRU list. Entries removed
+// concurrently are no longer linked and stay so.
+func (kl *KeyedLimiter[K]) touch(sh *keyedShard[K], e *keyedEntry) {
+	if kl.lru == nil {
+		return
+	}
+	n := e.node.(*lruNode[K])
+	sh.lruMu.Lock()
+	if n.next != nil && sh.lru.next != n {
+		n.unlink()
+		sh.pushFront(n)
+	}
+	sh.lruMu.Unlock()
+}
+
+// insert adds the new entry `e` of `key` to shard `sh`, which must be write-locked.
+// When the shard is full, it evicts another key first and returns it; the caller
+// reports it with notifyEvicted once the lock is released.
+func (kl *KeyedLimiter[K]) insert(sh *keyedShard[K], key K, e *keyedEntry) (evicted K, ok bool) {
+	if kl.lru == nil {
+		sh.entries[key] = e
+		return evicted, false
+	}
+	if len(sh.entries) >= kl.lru.perShard {
+		evicted, ok = kl.evictLRU(sh)
+	}
+	sh.entries[key] = e
+	n := &lruNode[K]{key: key, e: e}
+	e.node = n
+	sh.lruMu.Lock()
+	sh.pushFront(n)
+	sh.lruMu.Unlock()
+	return evicted, ok
+}
+
+// evictLRU removes the least recently used key of shard `sh`, which must be write-locked.
+func (kl *KeyedLimiter[K]) evictLRU(sh *keyedShard[K]) (K, bool) {
+	sh.lruMu.Lock()
+	n := sh.lru.prev
+	if n == nil || n == &sh.lru {
+		sh.lruMu.Unlock()
+		var zero K
+		return zero, false
+	}
+	n.unlink()
+	sh.lruMu.Unlock()
+
+	delete(sh.entries, n.key)
+	if kl.hot != nil {
+		kl.hot.remove(hashKey(kl.seed, n.key), n.e)
+	}
+	kl.evicted(n.key)
+	return n.key, true
+}
+
+// unlinkLRU removes entry `e`, which is being deleted from shard `sh`, from the LRU list.
+// Must be called with the shard write-locked.
+func (kl *KeyedLimiter[K]) unlinkLRU(sh *keyedShard[K], e *keyedEntry) {
+	if kl.lru == nil {
+		return
+	}
+	n := e.node.(*lruNode[K])
+	sh.lruMu.Lock()
+	if n.next != nil {
+		n.unlink()
+	}
+	sh.lruMu.Unlock()
+}
+
+// pushFront links `n` at the front of the LRU list of the shard. Must be called with sh.lruMu held.
+func (sh *keyedShard[K]) pushFront(n *lruNode[K]) {
+	if sh.lru.next == nil {
+		sh.lru.next, sh.lru.prev = &sh.lru, &sh.lru
+	}
+	n.prev, n.next = &sh.lru, sh.lru.next
+	n.next.prev = n
+	sh.lru.next = n
+}
+
+// unlink removes `n` from its list, leaving it unlinked (nil next).
+func (n *lruNode[K]) unlink() {
+	n.prev.next = n.next
+	n.next.prev = n.prev
+	n.prev, n.next = nil, nil
+}
+
+// notifyEvicted calls the eviction callback with `key` if `ok`. Must be called without shard locks.
+func (kl *KeyedLimiter[K]) notifyEvicted(key K, ok bool) {
+	if ok && kl.lru.onEvict != nil {
+		kl.lru.onEvict(key)
+	}
+}
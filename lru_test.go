@@ -0,0 +1,100 @@
+package limitron
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestKeyedLimiter_MaxKeys(t *testing.T) {
+	var mu sync.Mutex
+	var evicted []int
+	kl := NewKeyedLimiter[int](BuildRateLimiter(2, time.Hour), WithMaxKeys(keyedShards*4, func(key int) {
+		mu.Lock()
+		evicted = append(evicted, key)
+		mu.Unlock()
+	}))
+
+	for i := 0; i < 10_000; i++ {
+		kl.Take1(i)
+		if kl.Len() > keyedShards*4 {
+			t.Fatalf("Len() = %d after %d keys, want at most %d", kl.Len(), i+1, keyedShards*4)
+		}
+	}
+	if kl.Len() != keyedShards*4 {
+		t.Fatalf("Len() = %d, want %d", kl.Len(), keyedShards*4)
+	}
+	if len(evicted) != 10_000-keyedShards*4 {
+		t.Fatalf("%d evictions reported, want %d", len(evicted), 10_000-keyedShards*4)
+	}
+	if u := kl.MemoryUsage()[""]; u.Evictions != uint64(len(evicted)) {
+		t.Fatalf("MemoryUsage evictions = %d, want %d", u.Evictions, len(evicted))
+	}
+
+	// an evicted key starts over like a new one
+	if _, ok := kl.TakeN(evicted[0], 2); !ok {
+		t.Fatalf("evicted key did not start over")
+	}
+}
+
+func TestKeyedLimiter_MaxKeysKeepsRecentlyUsed(t *testing.T) {
+	kl := NewKeyedLimiter[string](BuildRateLimiter(2, time.Hour), WithMaxKeys[string](keyedShards*8, nil),
+		WithHotKeys[string](16))
+	kl.TakeN("busy", 2)
+
+	for i := 0; i < 5000; i++ {
+		kl.Take1(fmt.Sprintf("spray-%d", i))
+		// the busy key keeps being used and is never evicted, hot tier or not
+		if _, ok := kl.Take1("busy"); ok {
+			t.Fatalf("busy key was evicted after %d sprayed keys", i+1)
+		}
+	}
+}
+
+func TestKeyedLimiter_MaxKeysEvictsLeastRecentlyUsed(t *testing.T) {
+	var evicted []string
+	kl := NewKeyedLimiter[string](BuildRateLimiter(2, time.Hour), WithMaxKeys(keyedShards*3, func(key string) {
+		evicted = append(evicted, key)
+	}))
+	// six keys of the same shard, which holds three
+	var keys []string
+	shard := hashKey(kl.seed, "k0") & (keyedShards - 1)
+	for i := 0; len(keys) < 6; i++ {
+		if k := fmt.Sprint("k", i); hashKey(kl.seed, k)&(keyedShards-1) == shard {
+			keys = append(keys, k)
+		}
+	}
+
+	for _, k := range keys[:3] {
+		kl.Take1(k)
+	}
+	// used in reverse order of creation: keys[2] is now the least recently used
+	kl.Take1(keys[2])
+	kl.Take1(keys[1])
+	kl.Take1(keys[0])
+	kl.Take1(keys[3])
+	kl.Take1(keys[4])
+	if want := []string{keys[2], keys[1]}; fmt.Sprint(evicted) != fmt.Sprint(want) {
+		t.Fatalf("evicted %v, want %v", evicted, want)
+	}
+
+	// a deleted key makes room without an eviction
+	kl.Delete(keys[0])
+	kl.Take1(keys[5])
+	if len(evicted) != 2 {
+		t.Fatalf("evicted %v after a deletion made room", evicted)
+	}
+}
+
+func TestKeyedLimiter_MaxKeysBatch(t *testing.T) {
+	kl := NewKeyedLimiter[int](BuildRateLimiter(2, time.Hour), WithMaxKeys[int](keyedShards, nil))
+	keys := make([]int, 1000)
+	for i := range keys {
+		keys[i] = i
+	}
+	kl.AllowBatch(keys, 1)
+	if kl.Len() > keyedShards {
+		t.Fatalf("Len() = %d after a batch, want at most %d", kl.Len(), keyedShards)
+	}
+}
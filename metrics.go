@@ -0,0 +1,162 @@
+package limitron
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// Metrics receives the decisions of a RateLimiter for export to a metrics system,
+// set with WithMetrics. Implementations must be safe for concurrent use and cheap,
+// as they are called on the request path.
+type Metrics interface {
+	// ObserveTake is called for every TakeN attempt with its outcome and, for denied
+	// attempts, the suggested wait (math.MaxInt64 nanoseconds if it can never succeed).
+	ObserveTake(allowed bool, suggestedWait time.Duration)
+
+	// ObserveWait is called when a blocking WaitN obtains its tokens,
+	// with the time it actually blocked.
+	ObserveWait(actualWait time.Duration)
+}
+
+// WithMetrics makes the limiter report its decisions to `m`.
+func WithMetrics(m Metrics) Option {
+	return func(s *RateLimiter) {
+		s.metrics = m
+	}
+}
+
+// DefaultWaitBuckets are the upper bounds of the buckets of a WaitHistogram
+// created without explicit bounds.
+var DefaultWaitBuckets = []time.Duration{
+	time.Millisecond, 5 * time.Millisecond, 10 * time.Millisecond, 25 * time.Millisecond,
+	50 * time.Millisecond, 100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 2500 * time.Millisecond, 5 * time.Second, 10 * time.Second,
+	30 * time.Second, time.Minute, 5 * time.Minute,
+}
+
+// WaitHistogram is a Metrics implementation recording histograms of suggested and
+// actual wait durations, so operators can see how throttled their clients actually
+// are, not just how often they are denied.
+//
+// Counters are updated atomically without locks; read them with Snapshot and
+// export them in the format of the metrics system in use.
+//
+// The zero value is not usable; create instances with NewWaitHistogram.
+type WaitHistogram struct {
+	bounds    []time.Duration
+	allowed   atomic.Uint64
+	denied    atomic.Uint64
+	suggested histogramCounts
+	actual    histogramCounts
+}
+
+// histogramCounts holds the bucket counters of a single histogram.
+type histogramCounts struct {
+	// counts has one counter per bound, plus one for values above the last bound.
+	counts []atomic.Uint64
+	// sum is the total of observed values in nanoseconds, saturating.
+	sum atomic.Uint64
+}
+
+// Histogram is a point-in-time copy of a histogram of a WaitHistogram.
+type Histogram struct {
+	// Bounds are the upper bounds of the buckets (inclusive).
+	Bounds []time.Duration
+	// Counts holds the number of observations per bucket (not cumulative);
+	// the last element counts observations above the last bound.
+	Counts []uint64
+	// Count is the total number of observations.
+	Count uint64
+	// Sum is the total of the observed durations, excluding never-succeeding
+	// requests, saturating at the maximum Duration.
+	Sum time.Duration
+}
+
+// WaitHistogramSnapshot is a point-in-time copy of a WaitHistogram.
+type WaitHistogramSnapshot struct {
+	Allowed, Denied uint64
+	// Suggested is the histogram of waits suggested to denied requests.
+	Suggested Histogram
+	// Actual is the histogram of times blocked by WaitN.
+	Actual Histogram
+}
+
+// NewWaitHistogram returns an empty WaitHistogram with the given ascending bucket
+// bounds, or DefaultWaitBuckets if none are given.
+//
+// Example:
+//
+//	hist := NewWaitHistogram()
+//	limiter := BuildRateLimiterRps(100, WithMetrics(hist))
+//	...
+//	snap := hist.Snapshot()
+func NewWaitHistogram(bounds ...time.Duration) *WaitHistogram {
+	if len(bounds) == 0 {
+		bounds = DefaultWaitBuckets
+	}
+	h := &WaitHistogram{bounds: append([]time.Duration(nil), bounds...)}
+	h.suggested.counts = make([]atomic.Uint64, len(bounds)+1)
+	h.actual.counts = make([]atomic.Uint64, len(bounds)+1)
+	return h
+}
+
+// ObserveTake implements Metrics.
+func (h *WaitHistogram) ObserveTake(allowed bool, suggestedWait time.Duration) {
+	if allowed {
+		h.allowed.Add(1)
+		return
+	}
+	h.denied.Add(1)
+	h.suggested.observe(h.bounds, suggestedWait)
+}
+
+// ObserveWait implements Metrics.
+func (h *WaitHistogram) ObserveWait(actualWait time.Duration) {
+	h.actual.observe(h.bounds, actualWait)
+}
+
+// Snapshot returns a copy of the current counters. Counters are read one by one,
+// so a snapshot taken under load may be slightly inconsistent.
+func (h *WaitHistogram) Snapshot() WaitHistogramSnapshot {
+	return WaitHistogramSnapshot{
+		Allowed:   h.allowed.Load(),
+		Denied:    h.denied.Load(),
+		Suggested: h.suggested.snapshot(h.bounds),
+		Actual:    h.actual.snapshot(h.bounds),
+	}
+}
+
+func (c *histogramCounts) observe(bounds []time.Duration, d time.Duration) {
+	i := 0
+	for i < len(bounds) && d > bounds[i] {
+		i++
+	}
+	c.counts[i].Add(1)
+	if d == math.MaxInt64 {
+		return
+	}
+	for {
+		sum := c.sum.Load()
+		next := sum + uint64(max(d, 0))
+		if next < sum || next > math.MaxInt64 {
+			next = math.MaxInt64
+		}
+		if c.sum.CompareAndSwap(sum, next) {
+			return
+		}
+	}
+}
+
+func (c *histogramCounts) snapshot(bounds []time.Duration) Histogram {
+	h := Histogram{
+		Bounds: append([]time.Duration(nil), bounds...),
+		Counts: make([]uint64, len(c.counts)),
+		Sum:    time.Duration(c.sum.Load()),
+	}
+	for i := range c.counts {
+		h.Counts[i] = c.counts[i].Load()
+		h.Count += h.Counts[i]
+	}
+	return h
+}
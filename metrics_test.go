@@ -0,0 +1,45 @@
+package limitron
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWaitHistogram_RecordsSuggestedWaits(t *testing.T) {
+	hist := NewWaitHistogram(10*time.Millisecond, time.Second)
+	s := BuildRateLimiter(2, 200*time.Millisecond, WithMetrics(hist))
+	rl := s.New()
+
+	s.Take1(rl)
+	s.Take1(rl)
+	s.Take1(rl) // denied, ~100ms suggested
+	s.TakeN(rl, 3)
+
+	snap := hist.Snapshot()
+	if snap.Allowed != 2 || snap.Denied != 2 {
+		t.Fatalf("allowed=%d denied=%d, want 2 and 2", snap.Allowed, snap.Denied)
+	}
+	if want := []uint64{0, 1, 1}; !slices.Equal(snap.Suggested.Counts, want) {
+		t.Fatalf("suggested counts = %v, want %v", snap.Suggested.Counts, want)
+	}
+	if snap.Suggested.Sum < 90*time.Millisecond || snap.Suggested.Sum > 110*time.Millisecond {
+		t.Fatalf("suggested sum = %s, want about 100ms (never-succeeding waits excluded)", snap.Suggested.Sum)
+	}
+}
+
+func TestWaitHistogram_RecordsActualWaits(t *testing.T) {
+	hist := NewWaitHistogram()
+	s := BuildRateLimiterRps(20, WithMetrics(hist))
+	rl := s.New()
+	s.TakeN(rl, 20)
+
+	if err := s.WaitN(context.Background(), rl, 1); err != nil {
+		t.Fatalf("WaitN: %v", err)
+	}
+	snap := hist.Snapshot()
+	if snap.Actual.Count != 1 || snap.Actual.Sum < 30*time.Millisecond {
+		t.Fatalf("actual = %+v, want one wait of about 50ms", snap.Actual)
+	}
+}
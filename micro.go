@@ -0,0 +1,83 @@
+package limitron
+
+import (
+	"math"
+	"time"
+)
+
+// microEpoch is the epoch of microsecond-resolution states (see WithMicroseconds),
+// 2025-01-01T00:00:00Z, in Unix microseconds.
+const microEpoch = 1735689600_000000
+
+// WithMicroseconds stores the refill clock of limiter states at microsecond resolution
+// instead of milliseconds, so that high rates and intervals shorter than a few
+// milliseconds refill and compute waits without millisecond rounding errors.
+//
+// Microsecond timestamps are counted from 2025-01-01 UTC so that they fit the 48 bits
+// of the packed state until late 2033. States are not interchangeable between
+// resolutions: all limiters sharing states (including grace period limiters) must use
+// the same one. Waits are still reported in milliseconds, rounded up.
+//
+// Example:
+//
+//	limiter := BuildRateLimiter(50, 5*time.Millisecond, WithMicroseconds()) // 10 per ms, paced per µs
+func WithMicroseconds() Option {
+	return func(s *RateLimiter) {
+		s.micros = true
+	}
+}
+
+// ticksPerMilli returns the number of state clock ticks per millisecond.
+func (s RateLimiter) ticksPerMilli() uint64 {
+	if s.micros {
+		return 1000
+	}
+	return 1
+}
+
+// rrpt returns the refill rate per state clock tick.
+func (s RateLimiter) rrpt() float64 {
+	return s.rrpm / float64(s.ticksPerMilli())
+}
+
+// nowTicks returns the current time of the limiter's clock in state clock ticks.
+func (s RateLimiter) nowTicks() uint64 {
+	return s.ticksOf(s.now())
+}
+
+// ticksOf returns `t` in state clock ticks: Unix milliseconds, or microseconds since
+// microEpoch in microsecond mode. Earlier times are treated as the epoch.
+func (s RateLimiter) ticksOf(t time.Time) uint64 {
+	if !s.micros {
+		return unixMillis(t)
+	}
+	if us := t.UnixMicro() - microEpoch; us > 0 {
+		return uint64(us)
+	}
+	return 0
+}
+
+// timeOf returns the time of `ticks` state clock ticks. See ticksOf.
+func (s RateLimiter) timeOf(ticks uint64) time.Time {
+	if !s.micros {
+		return time.UnixMilli(int64(ticks))
+	}
+	return time.UnixMicro(microEpoch + int64(ticks))
+}
+
+// tickMillis converts a wait of `ticks` state clock ticks into millis, rounding up.
+// math.MaxInt64 is kept as is.
+func (s RateLimiter) tickMillis(ticks int64) int64 {
+	if !s.micros || ticks == math.MaxInt64 {
+		return ticks
+	}
+	return (ticks + 999) / 1000
+}
+
+// tickDuration converts `ticks` state clock ticks into a time.Duration.
+func (s RateLimiter) tickDuration(ticks uint64) time.Duration {
+	if !s.micros {
+		return millisToDuration(int64(ticks))
+	}
+	return time.Duration(ticks) * time.Microsecond
+}
@@ -0,0 +1,72 @@
+package limitron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithMicroseconds_RefillsWithinAMillisecond(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	micro := BuildRateLimiter(10, time.Millisecond, WithClock(clock), WithMicroseconds())
+	milli := BuildRateLimiter(10, time.Millisecond, WithClock(clock))
+	ms, us := milli.New(), micro.New()
+	milli.TakeN(ms, 10)
+	micro.TakeN(us, 10)
+
+	clock.t = clock.t.Add(250 * time.Microsecond)
+	if _, ok := micro.TakeN(us, 2); !ok {
+		t.Fatal("2 tokens denied after 250µs at 10 per ms")
+	}
+	if _, ok := milli.Take1(ms); ok {
+		t.Fatal("millisecond state refilled within the same millisecond")
+	}
+	if got := micro.Available(us); got != 0 {
+		t.Fatalf("Available = %d, want 0", got)
+	}
+	// half a token has refilled towards the next one
+	if got := micro.TimeToFull(us); got != 950*time.Microsecond {
+		t.Fatalf("TimeToFull = %v, want 950µs", got)
+	}
+}
+
+func TestWithMicroseconds_WaitsRoundUp(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	limiter := BuildRateLimiter(2, 5*time.Millisecond, WithClock(clock), WithMicroseconds()) // 1 per 2.5ms
+	state := limiter.New()
+	limiter.TakeN(state, 2)
+
+	if waitMillis, ok := limiter.Take1(state); ok || waitMillis != 3 {
+		t.Fatalf("Take1 = %d, %v, want 3, false", waitMillis, ok)
+	}
+	clock.t = clock.t.Add(2500 * time.Microsecond)
+	if _, ok := limiter.Take1(state); !ok {
+		t.Fatal("token denied after 2.5ms")
+	}
+}
+
+func TestWithMicroseconds_SubMillisecondInterval(t *testing.T) {
+	if _, err := NewRateLimiter(5, 500*time.Microsecond); err == nil {
+		t.Fatal("sub-millisecond interval accepted at millisecond resolution")
+	}
+	if _, err := NewRateLimiter(5, 500*time.Microsecond, WithMicroseconds()); err != nil {
+		t.Fatalf("sub-millisecond interval at microsecond resolution: %v", err)
+	}
+	if _, err := NewRateLimiter(5, 500*time.Nanosecond, WithMicroseconds()); err == nil {
+		t.Fatal("sub-microsecond interval accepted")
+	}
+}
+
+func TestWithMicroseconds_KeyedLastAccess(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 123456000, time.UTC)}
+	kl := NewKeyedLimiter[string](BuildRateLimiterRps(10, WithClock(clock), WithMicroseconds()))
+	kl.Take1("k")
+
+	last, ok := kl.LastAccess("k")
+	if !ok || !last.Equal(clock.t) {
+		t.Fatalf("LastAccess = %v, %v, want %v", last, ok, clock.t)
+	}
+	clock.t = clock.t.Add(time.Minute)
+	if n := kl.EvictIdle(30 * time.Second); n != 1 {
+		t.Fatalf("EvictIdle evicted %d keys, want 1", n)
+	}
+}
@@ -0,0 +1,63 @@
+package limitron
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func tenantOf(key string) string {
+	tenant, _, _ := strings.Cut(key, "/")
+	return tenant
+}
+
+func TestKeyedLimiter_NamespaceModes(t *testing.T) {
+	var shadowDenied []string
+	kl := NewKeyedLimiter(BuildRateLimiter(1, time.Hour),
+		WithNamespaceFunc(tenantOf),
+		WithShadowDenied(func(key string, waitMillis int64) {
+			shadowDenied = append(shadowDenied, key)
+		}),
+	)
+	kl.SetNamespaceMode("shadowed", Shadow)
+	kl.SetNamespaceMode("disabled", Off)
+
+	for i := 0; i < 3; i++ {
+		if _, ok := kl.Take1("shadowed/u1"); !ok {
+			t.Fatalf("shadow: take %d should be allowed", i)
+		}
+		if _, ok := kl.Take1("disabled/u1"); !ok {
+			t.Fatalf("off: take %d should be allowed", i)
+		}
+	}
+	if len(shadowDenied) != 2 {
+		t.Fatalf("shadow denials = %v, want 2", shadowDenied)
+	}
+	if _, ok := kl.LastAccess("disabled/u1"); ok {
+		t.Fatal("off mode should not create state")
+	}
+
+	kl.Take1("enforced/u1")
+	if _, ok := kl.Take1("enforced/u1"); ok {
+		t.Fatal("enforced namespace should deny over the limit")
+	}
+
+	// back to enforcement at runtime
+	kl.SetNamespaceMode("shadowed", Enforce)
+	if _, ok := kl.Take1("shadowed/u1"); ok {
+		t.Fatal("re-enforced namespace should deny over the limit")
+	}
+	if got := kl.NamespaceMode("disabled"); got != Off {
+		t.Fatalf("NamespaceMode(disabled) = %v, want off", got)
+	}
+}
+
+func TestKeyedLimiter_DefaultNamespace(t *testing.T) {
+	kl := NewKeyedLimiter[int](BuildRateLimiter(1, time.Hour))
+	kl.SetNamespaceMode("", Off)
+	for i := 0; i < 3; i++ {
+		if _, ok := kl.Take1(1); !ok {
+			t.Fatal("all keys are in the \"\" namespace without a namespace func")
+		}
+	}
+}
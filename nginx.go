@@ -0,0 +1,134 @@
+package limitron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// NginxConfig mirrors the parameters of nginx's limit_req module:
+//
+//	limit_req_zone ... rate=10r/s;
+//	limit_req zone=one burst=20 delay=8;   // or: burst=20 nodelay;
+//
+// so that tuned nginx values can be reused as is.
+type NginxConfig struct {
+	// Rate is the number of requests allowed per Per (nginx: rate=Nr/s or Nr/m).
+	Rate uint32
+	// Per is the period of Rate, typically time.Second or time.Minute.
+	Per time.Duration
+	// Burst is the maximum number of excess requests (nginx: burst=N).
+	Burst uint32
+	// Delay is the number of excess requests served without delay (nginx: delay=N).
+	// Excess requests beyond it are delayed to conform to the rate.
+	Delay uint32
+	// NoDelay serves all requests within the burst without delay (nginx: nodelay).
+	// It is equivalent to Delay == Burst.
+	NoDelay bool
+}
+
+// NginxLimiter implements nginx limit_req semantics (leaky bucket with burst,
+// delay and nodelay).
+//
+// Like RateLimiter it is a stateless configuration, and the per-key state is
+// a single uint64 created with New(). The state holds the time at which
+// the bucket's excess drains to zero, in Unix microseconds, which is equivalent
+// to nginx's "excess" counter and "last" timestamp, but updatable with a single CAS.
+type NginxLimiter struct {
+	// emission is the time between two requests at the configured rate, in microseconds.
+	emission uint64
+	// burst and delay are the excess thresholds in microseconds (requests * emission).
+	burst uint64
+	delay uint64
+	// retries controls the number of atomic CAS attempts made by Take.
+	retries int
+}
+
+// BuildNginxLimiter returns a NginxLimiter for the given nginx-style configuration.
+//
+// Example (limit_req zone=one burst=5 nodelay, with rate=1r/s):
+//
+//	limiter := BuildNginxLimiter(NginxConfig{Rate: 1, Per: time.Second, Burst: 5, NoDelay: true})
+func BuildNginxLimiter(cfg NginxConfig) NginxLimiter {
+	emission := uint64(cfg.Per.Microseconds()) / uint64(max(cfg.Rate, 1))
+	delay := min(cfg.Delay, cfg.Burst)
+	if cfg.NoDelay {
+		delay = cfg.Burst
+	}
+	return NginxLimiter{
+		emission: emission,
+		burst:    uint64(cfg.Burst) * emission,
+		delay:    uint64(delay) * emission,
+		retries:  UpdateRetries,
+	}
+}
+
+// New creates a brand-new, empty (no excess) state.
+func (l NginxLimiter) New() *uint64 {
+	st := uint64(0)
+	return &st
+}
+
+// Take evaluates one request against the state `st`, the same way nginx does.
+//
+// Returns:
+//   - delayMillis, true if the request is accepted. delayMillis is the time the request
+//     should be delayed before being served (nginx holds such requests); it is 0 for
+//     requests within the delay threshold, and always 0 in nodelay mode.
+//   - retryMillis, false if the request is rejected (nginx answers with limit_req_status),
+//     where retryMillis is the time after which a request would be accepted again.
+//
+// Rejected requests do not modify the state, like in nginx.
+func (l NginxLimiter) Take(st *uint64) (int64, bool) {
+	for i := 0; i < l.retries; i++ {
+		tat := atomic.LoadUint64(st)
+		now := uint64(time.Now().UnixMicro())
+
+		// new excess: previous excess drained by the elapsed time, plus this request,
+		// clamped at zero (so the first request of a fresh or drained bucket is not excess)
+		newTat := max(tat+l.emission, now)
+		excess := newTat - now
+
+		if excess > l.burst {
+			return ceilMillis(excess - l.burst), false
+		}
+		if atomic.CompareAndSwapUint64(st, tat, newTat) {
+			if excess <= l.delay {
+				return 0, true
+			}
+			return ceilMillis(excess - l.delay), true
+		}
+	}
+
+	// contended: treat as a minimal wait, like RateLimiter.TakeN
+	return 1, false
+}
+
+// ceilMillis converts microseconds into milliseconds, rounding up.
+func ceilMillis(micros uint64) int64 {
+	return int64((micros + 999) / 1000)
+}
+
+// ParseNginxRate parses an nginx rate such as "10r/s" or "30r/m"
+// into the Rate and Per fields of NginxConfig.
+func ParseNginxRate(s string) (rate uint32, per time.Duration, err error) {
+	num, unit, ok := strings.Cut(s, "r/")
+	if !ok {
+		return 0, 0, fmt.Errorf("limitron: invalid nginx rate %q", s)
+	}
+	switch unit {
+	case "s":
+		per = time.Second
+	case "m":
+		per = time.Minute
+	default:
+		return 0, 0, fmt.Errorf("limitron: invalid nginx rate unit in %q", s)
+	}
+	n, err := strconv.ParseUint(num, 10, 32)
+	if err != nil || n == 0 {
+		return 0, 0, fmt.Errorf("limitron: invalid nginx rate %q", s)
+	}
+	return uint32(n), per, nil
+}
@@ -0,0 +1,95 @@
+package limitron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNginxLimiter_NoBurst(t *testing.T) {
+	l := BuildNginxLimiter(NginxConfig{Rate: 10, Per: time.Second})
+	st := l.New()
+
+	if d, ok := l.Take(st); !ok || d != 0 {
+		t.Fatalf("first request => delay=%d ok=%v, want 0,true", d, ok)
+	}
+	retry, ok := l.Take(st)
+	if ok {
+		t.Fatal("immediate second request should be rejected with burst=0")
+	}
+	if retry <= 0 || retry > 100 {
+		t.Fatalf("retry = %dms, want within one emission interval (100ms)", retry)
+	}
+}
+
+func TestNginxLimiter_BurstDelayed(t *testing.T) {
+	// rate=10r/s burst=3 (no nodelay): excess requests are delayed
+	l := BuildNginxLimiter(NginxConfig{Rate: 10, Per: time.Second, Burst: 3})
+	st := l.New()
+
+	wantDelays := []int64{0, 100, 200, 300}
+	for i, want := range wantDelays {
+		d, ok := l.Take(st)
+		if !ok {
+			t.Fatalf("request %d should be accepted within burst", i)
+		}
+		if d < want-5 || d > want {
+			t.Fatalf("request %d delay = %dms, want about %dms", i, d, want)
+		}
+	}
+	if _, ok := l.Take(st); ok {
+		t.Fatal("request beyond burst should be rejected")
+	}
+}
+
+func TestNginxLimiter_NoDelay(t *testing.T) {
+	l := BuildNginxLimiter(NginxConfig{Rate: 10, Per: time.Second, Burst: 3, NoDelay: true})
+	st := l.New()
+
+	for i := 0; i < 4; i++ {
+		if d, ok := l.Take(st); !ok || d != 0 {
+			t.Fatalf("request %d => delay=%d ok=%v, want 0,true", i, d, ok)
+		}
+	}
+	if _, ok := l.Take(st); ok {
+		t.Fatal("request beyond burst should be rejected")
+	}
+}
+
+func TestNginxLimiter_TwoStageDelay(t *testing.T) {
+	// burst=4 delay=2: two excess requests served immediately, the rest delayed
+	l := BuildNginxLimiter(NginxConfig{Rate: 10, Per: time.Second, Burst: 4, Delay: 2})
+	st := l.New()
+
+	wantDelays := []int64{0, 0, 0, 100, 200}
+	for i, want := range wantDelays {
+		d, ok := l.Take(st)
+		if !ok {
+			t.Fatalf("request %d should be accepted", i)
+		}
+		if d < want-5 || d > want {
+			t.Fatalf("request %d delay = %dms, want about %dms", i, d, want)
+		}
+	}
+}
+
+func TestNginxLimiter_Drains(t *testing.T) {
+	l := BuildNginxLimiter(NginxConfig{Rate: 20, Per: time.Second})
+	st := l.New()
+	l.Take(st)
+	time.Sleep(60 * time.Millisecond)
+	if _, ok := l.Take(st); !ok {
+		t.Fatal("request after one emission interval should be accepted")
+	}
+}
+
+func TestParseNginxRate(t *testing.T) {
+	rate, per, err := ParseNginxRate("30r/m")
+	if err != nil || rate != 30 || per != time.Minute {
+		t.Fatalf("ParseNginxRate(30r/m) = %d,%s,%v", rate, per, err)
+	}
+	for _, bad := range []string{"", "10", "10r/h", "0r/s", "xr/s"} {
+		if _, _, err := ParseNginxRate(bad); err == nil {
+			t.Fatalf("ParseNginxRate(%q) should fail", bad)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package limitron
+
+import "time"
+
+// Option configures optional behavior of a RateLimiter at construction time.
+//
+// Options are accepted by NewRateLimiter, BuildRateLimiterRps, BuildRateLimiter and BuildRateLimiterFull:
+//
+//	limiter := BuildRateLimiterRps(10, WithPunitive(100*time.Millisecond))
+type Option func(*RateLimiter)
+
+// WithRetries sets the number of CAS retries attempted by TakeN under contention
+// (UpdateRetries by default), like BuildRateLimiterFull.
+func WithRetries(retries int) Option {
+	return func(s *RateLimiter) {
+		s.retries = retries
+	}
+}
+
+// WithBurst sets the bucket capacity to `burst` tokens instead of the number of requests
+// per interval, keeping the refill rate.
+//
+// Example:
+//
+//	limiter := BuildRateLimiterRps(10, WithBurst(50)) // 10 per second on average, bursts of 50
+func WithBurst(burst uint16) Option {
+	return func(s *RateLimiter) {
+		s.maxreq = burst
+	}
+}
+
+// WithStartEmpty makes new states start with no tokens instead of a full burst: tokens
+// accrue at the refill rate from the moment the state is created, so the first request
+// of a fresh key waits interval/req. This applies to New and to the states created by
+// KeyedLimiter and StoreLimiter.
+//
+// Example:
+//
+//	// no burst of password resets for a key seen for the first time
+//	limiter := BuildRateLimiter(3, time.Hour, WithStartEmpty())
+func WithStartEmpty() Option {
+	return func(s *RateLimiter) {
+		s.startEmpty = true
+	}
+}
+
+// WithPunitive enables punitive mode: every denied attempt forfeits `penalty`
+// of accumulated refill time, so clients that hammer an endpoint while throttled
+// recover more slowly than patient ones.
+//
+// For example, with 1 token per second and a penalty of 250ms, a client retrying
+// every 500ms gets a token every 2 seconds instead of every second, while a client
+// honoring the suggested wait is not affected. Retrying more often than the penalty
+// keeps a client throttled until it backs off.
+//
+// The wait returned by a denied TakeN already accounts for the penalty.
+// A non-positive penalty disables punitive mode.
+func WithPunitive(penalty time.Duration) Option {
+	return func(s *RateLimiter) {
+		s.penalty = uint64(max(penalty.Milliseconds(), 0))
+	}
+}
+
+// WithDebt lets TakeN overdraw the bucket by up to `limit` tokens: a request for more
+// tokens than are available is allowed if some tokens are available, no earlier
+// debt is outstanding and the missing tokens do not exceed `limit`. The debt is then
+// repaid by the refill, and requests are denied until it is, so that one oversized
+// request is admitted but suppresses the traffic after it.
+//
+// With debt allowed, TakeN accepts requests of up to the burst plus `limit` tokens.
+// A zero limit disables overdrafts (the default).
+//
+// Example:
+//
+//	limiter := BuildRateLimiterRps(100, WithDebt(1000)) // a 1000-token export passes, then ~10s of silence
+func WithDebt(limit uint16) Option {
+	return func(s *RateLimiter) {
+		s.debt = limit
+	}
+}
+
+// WithMinSpacing enables strict pacing (spike arrest): admissions are spaced at least
+// interval/req apart, instead of allowing a burst of `req` requests back to back.
+// A TakeN of n tokens counts as n admissions, pushing the next one n spacings away.
+// Spacings are rounded up to whole ticks of the refill clock: milliseconds, or
+// microseconds with WithMicroseconds.
+//
+// Example:
+//
+//	limiter := BuildRateLimiter(600, time.Minute, WithMinSpacing()) // one request per 100ms, no bursts
+func WithMinSpacing() Option {
+	return func(s *RateLimiter) {
+		s.spaced = true
+	}
+}
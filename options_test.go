@@ -0,0 +1,181 @@
+package limitron
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestWithPunitive_HammeringRecoversSlower(t *testing.T) {
+	patient := BuildRateLimiterRps(5) // 1 token per 200ms
+	punitive := BuildRateLimiterRps(5, WithPunitive(150*time.Millisecond))
+	if punitive.penalty != 150 {
+		t.Fatalf("penalty = %d, want 150", punitive.penalty)
+	}
+
+	rlPatient, rlPunitive := patient.New(), punitive.New()
+	patient.TakeN(rlPatient, 5)
+	punitive.TakeN(rlPunitive, 5)
+
+	time.Sleep(120 * time.Millisecond)
+	if _, ok := patient.Take1(rlPatient); ok {
+		t.Fatal("patient: take before refill should be denied")
+	}
+	if _, ok := punitive.Take1(rlPunitive); ok {
+		t.Fatal("punitive: take before refill should be denied")
+	}
+
+	time.Sleep(120 * time.Millisecond)
+	if _, ok := patient.Take1(rlPatient); !ok {
+		t.Fatal("patient: take after refill should succeed")
+	}
+	if _, ok := punitive.Take1(rlPunitive); ok {
+		t.Fatal("punitive: denied attempt should have delayed the refill")
+	}
+}
+
+func TestWithPunitive_WaitIncludesPenalty(t *testing.T) {
+	s := BuildRateLimiter(1, time.Second, WithPunitive(300*time.Millisecond))
+	rl := s.New()
+	s.Take1(rl)
+	time.Sleep(400 * time.Millisecond)
+
+	// 400ms accrued, 300ms forfeited: about 900ms left to wait
+	wait, ok := s.Take1(rl)
+	if ok {
+		t.Fatal("take should be denied")
+	}
+	if wait < 800 || wait > 1000 {
+		t.Fatalf("wait = %dms, want about 900ms", wait)
+	}
+}
+
+func TestWithPunitive_TakeNAt(t *testing.T) {
+	s := BuildRateLimiter(1, time.Second, WithPunitive(300*time.Millisecond))
+	rl := s.New()
+	at := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	s.TakeNAt(rl, 1, at)
+
+	// the penalty is evaluated at the given time, not the current one
+	wait, ok := s.TakeNAt(rl, 1, at.Add(400*time.Millisecond))
+	if ok || wait < 800 || wait > 1000 {
+		t.Fatalf("TakeNAt = %dms, %v; want denied with about 900ms", wait, ok)
+	}
+}
+
+func TestWithPunitive_NonPositiveDisables(t *testing.T) {
+	if s := BuildRateLimiterRps(5, WithPunitive(-time.Second)); s.penalty != 0 {
+		t.Fatalf("penalty = %d, want 0", s.penalty)
+	}
+}
+
+func TestWithDebt_OversizedRequestThenRepayment(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	s := BuildRateLimiter(10, 100*time.Millisecond, WithDebt(20), WithClock(clock)) // 1 token per 10ms
+	rl := s.New()
+
+	// 25 tokens with 10 available: 15 on debt
+	if wait, ok := s.TakeN(rl, 25); !ok {
+		t.Fatalf("oversized request denied, wait %d", wait)
+	}
+	wait, ok := s.Take1(rl)
+	if ok {
+		t.Fatal("request allowed while in debt")
+	}
+	if wait < 150 || wait > 161 {
+		t.Fatalf("wait = %dms, want the 150ms debt repayment plus one token", wait)
+	}
+
+	// the debt is repaid, but no tokens refilled yet
+	clock.t = clock.t.Add(150 * time.Millisecond)
+	if _, ok := s.Take1(rl); ok {
+		t.Fatal("request allowed with the debt just repaid and no tokens")
+	}
+	clock.t = clock.t.Add(10 * time.Millisecond)
+	if _, ok := s.Take1(rl); !ok {
+		t.Fatal("request denied after the debt was repaid and a token refilled")
+	}
+}
+
+func TestWithDebt_Caps(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	s := BuildRateLimiter(10, time.Second, WithDebt(5), WithClock(clock))
+	rl := s.New()
+
+	if wait, ok := s.TakeN(rl, 16); ok || wait != math.MaxInt64 {
+		t.Fatalf("TakeN over burst plus debt = %d, %v", wait, ok)
+	}
+	s.TakeN(rl, 4)
+	// 6 left: 12 would overdraw by 6 > 5
+	if _, ok := s.TakeN(rl, 12); ok {
+		t.Fatal("overdraft over the debt cap allowed")
+	}
+	if _, ok := s.TakeN(rl, 11); !ok {
+		t.Fatal("overdraft within the debt cap denied")
+	}
+}
+
+func TestWithMinSpacing_NoBurst(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	s := BuildRateLimiter(10, time.Second, WithMinSpacing(), WithClock(clock)) // one per 100ms
+	rl := s.New()
+
+	if _, ok := s.Take1(rl); !ok {
+		t.Fatal("first request denied")
+	}
+	wait, ok := s.Take1(rl)
+	if ok || wait != 100 {
+		t.Fatalf("back-to-back request = %d, %v, want 100, false", wait, ok)
+	}
+
+	clock.t = clock.t.Add(99 * time.Millisecond)
+	if _, ok := s.Take1(rl); ok {
+		t.Fatal("request allowed before the spacing elapsed")
+	}
+	clock.t = clock.t.Add(time.Millisecond)
+	if _, ok := s.TakeN(rl, 3); !ok {
+		t.Fatal("request denied after the spacing elapsed")
+	}
+	// 3 tokens push the next admission 3 spacings away
+	if wait, ok := s.Take1(rl); ok || wait != 300 {
+		t.Fatalf("request after TakeN(3) = %d, %v, want 300, false", wait, ok)
+	}
+
+	// idle time does not accumulate into a burst
+	clock.t = clock.t.Add(10 * time.Second)
+	s.Take1(rl)
+	if _, ok := s.Take1(rl); ok {
+		t.Fatal("burst allowed after idle time")
+	}
+}
+
+func TestWithStartEmpty(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	limiter := BuildRateLimiter(3, time.Hour, WithClock(clock), WithStartEmpty()) // 1 per 20min
+	state := limiter.New()
+
+	waitMillis, ok := limiter.Take1(state)
+	if ok || waitMillis < (20*time.Minute).Milliseconds() {
+		t.Fatalf("first Take1 = %d, %v, want a 20min wait, false", waitMillis, ok)
+	}
+	clock.t = clock.t.Add(20 * time.Minute)
+	if _, ok := limiter.Take1(state); !ok {
+		t.Fatal("token denied after 20min")
+	}
+	if _, ok := limiter.Take1(state); ok {
+		t.Fatal("more than one token accrued in 20min")
+	}
+}
+
+func TestWithStartEmpty_KeyedLimiter(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	kl := NewKeyedLimiter[string](BuildRateLimiter(3, time.Hour, WithClock(clock), WithStartEmpty()))
+
+	if _, ok := kl.Take1("fresh"); ok {
+		t.Fatal("fresh key admitted right away")
+	}
+	clock.t = clock.t.Add(time.Hour)
+	if _, ok := kl.TakeN("fresh", 3); !ok {
+		t.Fatal("full burst denied after an hour")
+	}
+}
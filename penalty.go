@@ -0,0 +1,110 @@
+package limitron
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// PenaltyPolicy describes progressive penalties for repeat offenders of a KeyedLimiter.
+//
+// Every denied request of a key counts as an offense. Each OffensesPerLevel offenses
+// escalate the key by one level, and every Decay period without offenses
+// de-escalates it by one level.
+//
+// At level L (1-based) the key's limit (burst and rate) is scaled by Factors[L-1];
+// a factor of 0 bans the key until it decays below that level. Levels beyond
+// len(Factors) stay at the last factor.
+//
+// Example: half, then quarter, then ban; one level per 10 denials, forgiven one level per minute:
+//
+//	PenaltyPolicy{Factors: []float64{0.5, 0.25, 0}, OffensesPerLevel: 10, Decay: time.Minute}
+type PenaltyPolicy struct {
+	Factors          []float64
+	OffensesPerLevel uint16
+	Decay            time.Duration
+}
+
+// WithPenaltyPolicy enables progressive penalties for repeat offenders. See PenaltyPolicy.
+// A policy without factors, offenses per level or decay disables penalties.
+func WithPenaltyPolicy[K comparable](p PenaltyPolicy) KeyedOption[K] {
+	return func(kl *KeyedLimiter[K]) {
+		if len(p.Factors) == 0 || p.OffensesPerLevel == 0 || p.Decay.Milliseconds() <= 0 {
+			kl.penalty = nil
+			return
+		}
+		kl.penalty = &penaltyLevels{
+			factors:  append([]float64(nil), p.Factors...),
+			perLevel: uint64(p.OffensesPerLevel),
+			decay:    uint64(p.Decay.Milliseconds()),
+			regular:  kl.limiter,
+		}
+	}
+}
+
+// penaltyLevels is the compiled form of a PenaltyPolicy.
+type penaltyLevels struct {
+	factors  []float64
+	perLevel uint64
+	decay    uint64
+	// regular is the regular limiter of the KeyedLimiter. It provides the clock
+	// timestamping offenses, and the base of penalized limits for keys without a limit.
+	regular RateLimiter
+}
+
+// level returns the current offense level encoded in `offenses`, and the effective
+// (decayed) offense count, at time `now`.
+func (p *penaltyLevels) level(offenses uint64, now uint64) (level int, count uint64, last uint64) {
+	c, last := unpackUint16Uint48(offenses)
+	count = uint64(c)
+	if now > last {
+		decayed := (now - last) / p.decay * p.perLevel
+		if decayed >= count {
+			count = 0
+		} else {
+			count -= decayed
+		}
+	}
+	return int(min(count/p.perLevel, uint64(len(p.factors)))), count, last
+}
+
+// limiterFor returns the limiter applying to entry `e` given its offense level,
+// derived from `base`. If the key is banned, it returns banned=true and the wait
+// in millis until the ban decays.
+func (p *penaltyLevels) limiterFor(e *keyedEntry, base RateLimiter) (limiter RateLimiter, waitMillis int64, banned bool) {
+	now := p.regular.nowMillis()
+	level, count, last := p.level(atomic.LoadUint64(&e.offenses), now)
+	if level == 0 {
+		return base, 0, false
+	}
+
+	factor := p.factors[level-1]
+	if factor <= 0 {
+		// decays needed to get below the first banning level
+		banLevel := uint64(level)
+		for banLevel > 1 && p.factors[banLevel-2] <= 0 {
+			banLevel--
+		}
+		decays := (count-banLevel*p.perLevel)/p.perLevel + 1
+		return base, int64(decays*p.decay - (now-last)%p.decay), true
+	}
+	if base.maxreq == 0 {
+		// no limit (e.g., grace period): penalties start from the regular limiter
+		base = p.regular
+	}
+	return base.scaled(factor), 0, false
+}
+
+// recordOffense adds an offense to entry `e`.
+func (p *penaltyLevels) recordOffense(e *keyedEntry) {
+	// cap so that a key never needs more than one decay per level to recover
+	maxCount := uint64(len(p.factors)+1)*p.perLevel - 1
+	for {
+		offenses := atomic.LoadUint64(&e.offenses)
+		now := p.regular.nowMillis()
+		_, count, _ := p.level(offenses, now)
+		count = min(count+1, maxCount, 0xFFFF)
+		if atomic.CompareAndSwapUint64(&e.offenses, offenses, packUint16AndUint48(uint16(count), now)) {
+			return
+		}
+	}
+}
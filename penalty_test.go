@@ -0,0 +1,72 @@
+package limitron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyedLimiter_PenaltyEscalatesAndDecays(t *testing.T) {
+	kl := NewKeyedLimiter(BuildRateLimiter(4, time.Hour),
+		WithPenaltyPolicy[string](PenaltyPolicy{
+			Factors:          []float64{0.5, 0},
+			OffensesPerLevel: 2,
+			Decay:            100 * time.Millisecond,
+		}))
+
+	kl.TakeN("k", 4)
+	e := kl.entry("k")
+	level := func() int {
+		l, _, _ := kl.penalty.level(e.offenses, kl.limiter.nowMillis())
+		return l
+	}
+
+	kl.Take1("k")
+	kl.Take1("k")
+	if got := level(); got != 1 {
+		t.Fatalf("level after 2 offenses = %d, want 1", got)
+	}
+	limiter, _, banned := kl.penalty.limiterFor(e, kl.limiter)
+	if banned || limiter.maxreq != 2 {
+		t.Fatalf("level 1 limiter => maxreq=%d banned=%v, want 2,false", limiter.maxreq, banned)
+	}
+
+	kl.Take1("k")
+	kl.Take1("k")
+	wait, ok := kl.Take1("k")
+	if ok {
+		t.Fatal("banned key should be denied")
+	}
+	if wait <= 0 || wait > 100 {
+		t.Fatalf("ban wait = %dms, want within one decay period", wait)
+	}
+	if got := level(); got != 2 {
+		t.Fatalf("level while banned = %d, want 2 (bans do not escalate further)", got)
+	}
+
+	time.Sleep(110 * time.Millisecond)
+	if got := level(); got != 1 {
+		t.Fatalf("level after one decay = %d, want 1", got)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if got := level(); got != 0 {
+		t.Fatalf("level after two decays = %d, want 0", got)
+	}
+}
+
+func TestKeyedLimiter_PenaltyIsPerKey(t *testing.T) {
+	kl := NewKeyedLimiter(BuildRateLimiter(1, time.Hour),
+		WithPenaltyPolicy[string](PenaltyPolicy{Factors: []float64{0}, OffensesPerLevel: 1, Decay: time.Hour}))
+
+	kl.Take1("bad")
+	kl.Take1("bad") // offense: banned
+	if _, ok := kl.Take1("good"); !ok {
+		t.Fatal("other keys should not be penalized")
+	}
+}
+
+func TestWithPenaltyPolicy_InvalidDisables(t *testing.T) {
+	kl := NewKeyedLimiter(BuildRateLimiterRps(1), WithPenaltyPolicy[string](PenaltyPolicy{Factors: []float64{0.5}}))
+	if kl.penalty != nil {
+		t.Fatal("policy without offenses per level and decay should be disabled")
+	}
+}
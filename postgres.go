@@ -0,0 +1,181 @@
+package limitron
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// PostgresLimiter keeps token-bucket states in a PostgreSQL table, for applications
+// that want durable, transactional quotas co-located with their business data.
+//
+// Every TakeN is a single INSERT ... ON CONFLICT DO UPDATE ... RETURNING statement
+// that refills and consumes tokens in SQL under the row lock, so concurrent
+// application instances share the same quotas. Tokens are stored as floating point
+// numbers, so no fractional refill is lost between calls.
+//
+// The table has the following layout (see CreateTable):
+//
+//	key        text PRIMARY KEY
+//	tokens     double precision NOT NULL
+//	updated_ms bigint NOT NULL  -- last update in Unix milliseconds (limiter clock)
+//
+// Timestamps come from the limiter's clock (see WithClock), not from the database,
+// so application hosts sharing a table should have synchronized clocks. A timestamp
+// behind the recorded one refills nothing.
+//
+// Refill and consumption run in SQL, so limiters with options changing the algorithm
+// are not supported: NewPostgresLimiter panics for limiters with WithDebt,
+// WithMinSpacing, WithPunitive or WithShedding. Metrics (see WithMetrics) are
+// reported as with RateLimiter.TakeN.
+//
+// PostgresLimiter only uses database/sql; the application registers the driver
+// (e.g., github.com/jackc/pgx/v5/stdlib or github.com/lib/pq).
+// All methods are safe for concurrent use.
+type PostgresLimiter struct {
+	db      *sql.DB
+	limiter RateLimiter
+	table   string
+
+	takeQuery string
+	peekQuery string
+}
+
+// Queryer is the subset of *sql.DB, *sql.Tx and *sql.Conn used by PostgresLimiter.
+type Queryer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// NewPostgresLimiter returns a PostgresLimiter applying `limiter` to the keys stored
+// in `table` of `db`. The table name may be schema-qualified ("quotas.api_limits");
+// its parts are quoted as identifiers.
+//
+// Example:
+//
+//	db, _ := sql.Open("pgx", dsn)
+//	quotas := NewPostgresLimiter(db, "api_quotas", BuildRateLimiter(1000, time.Hour))
+//	_ = quotas.CreateTable(ctx)
+//	if _, ok, err := quotas.TakeN(ctx, customerID, 1); err == nil && !ok {
+//	    // quota exceeded
+//	}
+func NewPostgresLimiter(db *sql.DB, table string, limiter RateLimiter) *PostgresLimiter {
+	switch {
+	case limiter.debt > 0:
+		panic("limitron: NewPostgresLimiter with a limiter using WithDebt, which it does not support")
+	case limiter.spaced:
+		panic("limitron: NewPostgresLimiter with a limiter using WithMinSpacing, which it does not support")
+	case limiter.penalty > 0:
+		panic("limitron: NewPostgresLimiter with a limiter using WithPunitive, which it does not support")
+	case limiter.shedding > 0:
+		panic("limitron: NewPostgresLimiter with a limiter using WithShedding, which it does not support")
+	}
+	table = quotePgIdent(table)
+	refilled := "LEAST($2::float8, b.tokens + GREATEST($4::bigint - b.updated_ms, 0) * $5::float8)"
+	return &PostgresLimiter{
+		db:      db,
+		limiter: limiter,
+		table:   table,
+		takeQuery: fmt.Sprintf(`INSERT INTO %[1]s AS b (key, tokens, updated_ms) VALUES ($1, $2::float8 - $3::float8, $4::bigint)
+ON CONFLICT (key) DO UPDATE SET tokens = %[2]s - $3::float8, updated_ms = GREATEST(b.updated_ms, $4::bigint)
+WHERE %[2]s >= $3::float8
+RETURNING tokens`, table, refilled),
+		peekQuery: fmt.Sprintf("SELECT tokens, updated_ms FROM %s WHERE key = $1", table),
+	}
+}
+
+// CreateTable creates the limiter table if it does not exist yet.
+func (p *PostgresLimiter) CreateTable(ctx context.Context) error {
+	_, err := p.db.ExecContext(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	key text PRIMARY KEY,
+	tokens double precision NOT NULL,
+	updated_ms bigint NOT NULL
+)`, p.table))
+	return err
+}
+
+// TakeN attempts to consume `requests` tokens of `key`. See RateLimiter.TakeN.
+//
+// Returns a non-nil error only if the database could not be queried;
+// the limiter decision is undefined in that case.
+func (p *PostgresLimiter) TakeN(ctx context.Context, key string, requests uint16) (int64, bool, error) {
+	return p.TakeNTx(ctx, p.db, key, requests)
+}
+
+// TakeNTx is TakeN executed with `q`, typically a *sql.Tx, so that the consumed tokens
+// are committed or rolled back together with the business data of the transaction.
+//
+// Note that the row of `key` stays locked until the transaction ends.
+func (p *PostgresLimiter) TakeNTx(ctx context.Context, q Queryer, key string, requests uint16) (int64, bool, error) {
+	waitMillis, ok, err := p.takeNTx(ctx, q, key, requests)
+	if err != nil {
+		return waitMillis, ok, err
+	}
+	waitMillis, ok = p.limiter.observe(waitMillis, ok)
+	return waitMillis, ok, nil
+}
+
+func (p *PostgresLimiter) takeNTx(ctx context.Context, q Queryer, key string, requests uint16) (int64, bool, error) {
+	if requests == 0 {
+		return 0, true, nil
+	} else if requests > p.limiter.maxreq {
+		return math.MaxInt64, false, nil
+	}
+
+	now := p.limiter.nowMillis()
+	var tokens float64
+	err := q.QueryRowContext(ctx, p.takeQuery,
+		key, float64(p.limiter.maxreq), float64(requests), int64(now), p.limiter.rrpm).Scan(&tokens)
+	if err == nil {
+		return 0, true, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return 0, false, err
+	}
+
+	// The row exists but holds too few tokens; read it to suggest a wait.
+	var updated int64
+	if err := q.QueryRowContext(ctx, p.peekQuery, key).Scan(&tokens, &updated); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// deleted concurrently, retrying is worthwhile right away
+			return 1, false, nil
+		}
+		return 0, false, err
+	}
+	return p.waitMillis(tokens, updated, now, requests), false, nil
+}
+
+// Take1 attempts to consume 1 token of `key`. See TakeN.
+func (p *PostgresLimiter) Take1(ctx context.Context, key string) (int64, bool, error) {
+	return p.TakeN(ctx, key, 1)
+}
+
+// Delete removes the state of `key`, restoring its full burst.
+func (p *PostgresLimiter) Delete(ctx context.Context, key string) error {
+	_, err := p.db.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE key = $1", p.table), key)
+	return err
+}
+
+// waitMillis returns the wait for `requests` tokens of a row holding `tokens` at `updated`.
+func (p *PostgresLimiter) waitMillis(tokens float64, updated int64, now uint64, requests uint16) int64 {
+	if elapsed := int64(now) - updated; elapsed > 0 {
+		tokens = min(tokens+float64(elapsed)*p.limiter.rrpm, float64(p.limiter.maxreq))
+	}
+	missing := float64(requests) - tokens
+	if missing <= 0 {
+		return 1
+	}
+	return 1 + int64(missing/p.limiter.rrpm)
+}
+
+// quotePgIdent quotes the dot-separated parts of a possibly schema-qualified identifier.
+func quotePgIdent(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = `"` + strings.ReplaceAll(part, `"`, `""`) + `"`
+	}
+	return strings.Join(parts, ".")
+}
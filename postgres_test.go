@@ -0,0 +1,164 @@
+package limitron
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePg is a database/sql driver emulating the statements of PostgresLimiter
+// against an in-memory table, so the Go side can be tested without a server.
+type fakePg struct {
+	mu   sync.Mutex
+	rows map[string][2]float64 // key -> tokens, updated_ms
+}
+
+func (d *fakePg) Open(string) (driver.Conn, error) { return fakePgConn{d}, nil }
+
+type fakePgConn struct{ d *fakePg }
+
+func (c fakePgConn) Prepare(query string) (driver.Stmt, error) { return fakePgStmt{c.d, query}, nil }
+func (c fakePgConn) Close() error                              { return nil }
+func (c fakePgConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+type fakePgStmt struct {
+	d     *fakePg
+	query string
+}
+
+func (s fakePgStmt) Close() error  { return nil }
+func (s fakePgStmt) NumInput() int { return -1 }
+
+func (s fakePgStmt) Exec([]driver.Value) (driver.Result, error) { return driver.RowsAffected(0), nil }
+
+func (s fakePgStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+
+	key := args[0].(string)
+	row, exists := s.d.rows[key]
+	switch {
+	case strings.HasPrefix(s.query, "INSERT"):
+		maxreq, n, now, rate := args[1].(float64), args[2].(float64), float64(args[3].(int64)), args[4].(float64)
+		if !exists {
+			s.d.rows[key] = [2]float64{maxreq - n, now}
+			return &fakePgRows{vals: []driver.Value{maxreq - n}}, nil
+		}
+		refilled := math.Min(maxreq, row[0]+math.Max(now-row[1], 0)*rate)
+		if refilled < n {
+			return &fakePgRows{}, nil
+		}
+		s.d.rows[key] = [2]float64{refilled - n, math.Max(row[1], now)}
+		return &fakePgRows{vals: []driver.Value{refilled - n}}, nil
+	case strings.HasPrefix(s.query, "SELECT"):
+		if !exists {
+			return &fakePgRows{}, nil
+		}
+		return &fakePgRows{vals: []driver.Value{row[0], int64(row[1])}}, nil
+	}
+	return nil, errors.New("unexpected query: " + s.query)
+}
+
+type fakePgRows struct {
+	vals []driver.Value
+	done bool
+}
+
+func (r *fakePgRows) Columns() []string { return make([]string, len(r.vals)) }
+func (r *fakePgRows) Close() error      { return nil }
+
+func (r *fakePgRows) Next(dest []driver.Value) error {
+	if r.done || r.vals == nil {
+		return io.EOF
+	}
+	r.done = true
+	copy(dest, r.vals)
+	return nil
+}
+
+func init() {
+	sql.Register("limitron-fakepg", &fakePg{rows: map[string][2]float64{}})
+}
+
+func TestPostgresLimiter_TakeN(t *testing.T) {
+	db, err := sql.Open("limitron-fakepg", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	p := NewPostgresLimiter(db, "limits", BuildRateLimiter(3, 300*time.Millisecond))
+	for i := 0; i < 3; i++ {
+		if _, ok, err := p.Take1(ctx, "tenant-1"); err != nil || !ok {
+			t.Fatalf("Take1 %d => ok=%v err=%v, want allowed", i, ok, err)
+		}
+	}
+	wait, ok, err := p.Take1(ctx, "tenant-1")
+	if err != nil || ok {
+		t.Fatalf("Take1 over burst => ok=%v err=%v, want denied", ok, err)
+	}
+	if wait < 1 || wait > 101 {
+		t.Fatalf("wait = %d, want about 100ms", wait)
+	}
+	if _, ok, _ := p.Take1(ctx, "tenant-2"); !ok {
+		t.Fatal("other keys should be independent")
+	}
+	if wait, ok, err := p.TakeN(ctx, "tenant-2", 4); ok || err != nil || wait != math.MaxInt64 {
+		t.Fatalf("TakeN over maxreq => wait=%d ok=%v err=%v", wait, ok, err)
+	}
+
+	time.Sleep(110 * time.Millisecond)
+	if _, ok, err := p.Take1(ctx, "tenant-1"); err != nil || !ok {
+		t.Fatalf("Take1 after refill => ok=%v err=%v, want allowed", ok, err)
+	}
+}
+
+func TestNewPostgresLimiter_RejectsOptions(t *testing.T) {
+	for name, opt := range map[string]Option{
+		"WithDebt":       WithDebt(5),
+		"WithMinSpacing": WithMinSpacing(),
+		"WithPunitive":   WithPunitive(time.Second),
+		"WithShedding":   WithShedding(0.5),
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewPostgresLimiter accepted a limiter with %s", name)
+				}
+			}()
+			NewPostgresLimiter(nil, "limits", BuildRateLimiter(3, time.Second, opt))
+		}()
+	}
+}
+
+func TestPostgresLimiter_Metrics(t *testing.T) {
+	db, err := sql.Open("limitron-fakepg", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	hist := NewWaitHistogram(10*time.Millisecond, time.Second)
+	p := NewPostgresLimiter(db, "limits", BuildRateLimiter(1, time.Hour, WithMetrics(hist)))
+	p.Take1(ctx, "metrics-1")
+	p.Take1(ctx, "metrics-1")
+	p.TakeN(ctx, "metrics-1", 2)
+	if snap := hist.Snapshot(); snap.Allowed != 1 || snap.Denied != 2 {
+		t.Fatalf("allowed=%d denied=%d, want 1 and 2", snap.Allowed, snap.Denied)
+	}
+}
+
+func TestQuotePgIdent(t *testing.T) {
+	if got, want := quotePgIdent(`quotas.api"limits`), `"quotas"."api""limits"`; got != want {
+		t.Fatalf("quotePgIdent = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,25 @@
+package limitron
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// WouldAllowAt reports whether a request of `requests` tokens would be allowed
+// at time `t`, assuming no other consumption happens meanwhile.
+// The limiter state `*rl` is not modified.
+//
+// It is meant for schedulers choosing execution slots, e.g., the earliest
+// of several candidate times at which a job would pass the limit.
+// `t` must come from the limiter's clock (see WithClock); times before the last
+// access recorded in the state see no refill.
+func (s RateLimiter) WouldAllowAt(rl *uint64, requests uint16, t time.Time) bool {
+	if requests == 0 {
+		return true
+	} else if requests > s.maxreq {
+		return false
+	}
+
+	newreq, _ := s.calcNewRequestsAt(atomic.LoadUint64(rl), s.ticksOf(t))
+	return requests <= newreq
+}
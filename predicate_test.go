@@ -0,0 +1,46 @@
+package limitron
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWouldAllowAt(t *testing.T) {
+	s := BuildRateLimiterRps(10) // 1 token per 100ms
+	rl := s.New()
+	s.TakeN(rl, 10)
+	before := atomic.LoadUint64(rl)
+
+	now := time.Now()
+	if s.WouldAllowAt(rl, 1, now) {
+		t.Fatal("depleted state should not allow now")
+	}
+	if !s.WouldAllowAt(rl, 1, now.Add(150*time.Millisecond)) {
+		t.Fatal("1 token should be available in 150ms")
+	}
+	if s.WouldAllowAt(rl, 5, now.Add(150*time.Millisecond)) {
+		t.Fatal("5 tokens should not be available in 150ms")
+	}
+	if !s.WouldAllowAt(rl, 5, now.Add(600*time.Millisecond)) {
+		t.Fatal("5 tokens should be available in 600ms")
+	}
+	if s.WouldAllowAt(rl, 1, now.Add(-time.Hour)) {
+		t.Fatal("times before the last access should see no refill")
+	}
+
+	if after := atomic.LoadUint64(rl); after != before {
+		t.Fatalf("WouldAllowAt modified state: before=%#x after=%#x", before, after)
+	}
+}
+
+func TestWouldAllowAt_EdgeCases(t *testing.T) {
+	s := BuildRateLimiterRps(3)
+	rl := s.New()
+	if !s.WouldAllowAt(rl, 0, time.Now()) {
+		t.Fatal("zero requests should always be allowed")
+	}
+	if s.WouldAllowAt(rl, 4, time.Now().Add(time.Hour)) {
+		t.Fatal("requests above burst should never be allowed")
+	}
+}
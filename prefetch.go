@@ -0,0 +1,110 @@
+package limitron
+
+import (
+	"math/rand"
+	"runtime"
+	"sync/atomic"
+)
+
+// Prefetcher serves tokens for a single hot limiter state from small local batches,
+// amortizing atomic operations on the shared state when one key is hit
+// millions of times per second.
+//
+// Tokens are reserved from the shared state `batch` at a time and parked
+// in per-shard counters (one shard per GOMAXPROCS, padded to a cache line).
+// Take1 first tries to serve a token from a shard, touching the shared state only
+// when the shard is empty.
+//
+// Trade-off: tokens parked in shards are already consumed from the shared state,
+// so up to shards*(batch-1) tokens may be held locally and not be visible
+// to other users of the shared state. Keep batches small (e.g., 8).
+type Prefetcher struct {
+	limiter RateLimiter
+	rl      *uint64
+	batch   uint16
+	shards  []prefetchShard
+}
+
+// prefetchShard holds locally reserved tokens; padded to avoid false sharing.
+type prefetchShard struct {
+	tokens atomic.Int64
+	_      [56]byte
+}
+
+// NewPrefetcher returns a Prefetcher that reserves `batch` tokens at a time from `rl`,
+// which must be a state created by `limiter`.
+// The batch is capped at the limiter's burst size; a batch of 0 or 1 disables prefetching.
+//
+// Example:
+//
+//	limiter := BuildRateLimiterRps(50000)
+//	p := NewPrefetcher(limiter, limiter.New(), 8)
+//	if _, ok := p.Take1(); ok {
+//	    // allowed
+//	}
+func NewPrefetcher(limiter RateLimiter, rl *uint64, batch uint16) *Prefetcher {
+	if batch > limiter.maxreq {
+		batch = limiter.maxreq
+	}
+	if batch == 0 {
+		batch = 1
+	}
+	return &Prefetcher{
+		limiter: limiter,
+		rl:      rl,
+		batch:   batch,
+		shards:  make([]prefetchShard, runtime.GOMAXPROCS(0)),
+	}
+}
+
+// Take1 attempts to consume 1 token, serving it from a local batch when possible.
+// Returns the same (waitMillis, ok) pair as RateLimiter.Take1.
+func (p *Prefetcher) Take1() (int64, bool) {
+	shard := &p.shards[rand.Intn(len(p.shards))]
+
+	for {
+		tokens := shard.tokens.Load()
+		if tokens <= 0 {
+			break
+		}
+		if shard.tokens.CompareAndSwap(tokens, tokens-1) {
+			return 0, true
+		}
+	}
+
+	if p.batch > 1 {
+		if _, ok := p.limiter.TakeN(p.rl, p.batch); ok {
+			// one token is served right away, the rest is parked in the shard
+			shard.tokens.Add(int64(p.batch - 1))
+			return 0, true
+		}
+	}
+
+	// not enough allowance for a whole batch: fall back to a single token
+	return p.limiter.Take1(p.rl)
+}
+
+// Flush returns all locally parked tokens to the shared state (capped at burst).
+// Call it when the Prefetcher is about to be discarded, so that parked tokens
+// are not lost.
+func (p *Prefetcher) Flush() {
+	var parked int64
+	for i := range p.shards {
+		parked += p.shards[i].tokens.Swap(0)
+	}
+	if parked == 0 {
+		return
+	}
+
+	for {
+		rlval := atomic.LoadUint64(p.rl)
+		req, ts := unpackUint16Uint48(rlval)
+		newreq := uint64(req) + uint64(parked)
+		if newreq > uint64(p.limiter.maxreq) {
+			newreq = uint64(p.limiter.maxreq)
+		}
+		if atomic.CompareAndSwapUint64(p.rl, rlval, packUint16AndUint48(uint16(newreq), ts)) {
+			return
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package limitron
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPrefetcher_NeverExceedsBurst(t *testing.T) {
+	s := BuildRateLimiter(100, time.Hour) // practically no refill during the test
+	p := NewPrefetcher(s, s.New(), 8)
+
+	var success int64
+	var wg sync.WaitGroup
+	workers := 50
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if _, ok := p.Take1(); ok {
+					atomic.AddInt64(&success, 1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	got := atomic.LoadInt64(&success)
+	if got > int64(s.maxreq) {
+		t.Fatalf("successes=%d exceed burst=%d", got, s.maxreq)
+	}
+}
+
+func TestPrefetcher_ServesFromLocalBatch(t *testing.T) {
+	s := BuildRateLimiter(16, time.Hour)
+	rl := s.New()
+	p := NewPrefetcher(s, rl, 8)
+	p.shards = p.shards[:1] // deterministic: single shard
+
+	if _, ok := p.Take1(); !ok {
+		t.Fatal("first Take1 should succeed")
+	}
+	req, _ := unpackUint16Uint48(atomic.LoadUint64(rl))
+	if req != 8 {
+		t.Fatalf("shared tokens after first take = %d, want 8 (one batch reserved)", req)
+	}
+
+	for i := 0; i < 7; i++ {
+		if _, ok := p.Take1(); !ok {
+			t.Fatalf("local take %d should succeed", i)
+		}
+	}
+	req, _ = unpackUint16Uint48(atomic.LoadUint64(rl))
+	if req != 8 {
+		t.Fatalf("shared tokens after local takes = %d, want 8 (untouched)", req)
+	}
+}
+
+func TestPrefetcher_FallsBackToSingleTokens(t *testing.T) {
+	s := BuildRateLimiter(3, time.Hour)
+	p := NewPrefetcher(s, s.New(), 2)
+	p.shards = p.shards[:1]
+
+	for i := 0; i < 3; i++ {
+		if _, ok := p.Take1(); !ok {
+			t.Fatalf("take %d should succeed", i)
+		}
+	}
+	if wait, ok := p.Take1(); ok || wait <= 0 {
+		t.Fatalf("take after depletion => wait=%d ok=%v, want positive,false", wait, ok)
+	}
+}
+
+func TestPrefetcher_Flush(t *testing.T) {
+	s := BuildRateLimiter(10, time.Hour)
+	rl := s.New()
+	p := NewPrefetcher(s, rl, 4)
+	p.shards = p.shards[:1]
+
+	p.Take1() // reserves 4, parks 3
+	p.Flush()
+
+	req, _ := unpackUint16Uint48(atomic.LoadUint64(rl))
+	if req != 9 {
+		t.Fatalf("shared tokens after flush = %d, want 9", req)
+	}
+}
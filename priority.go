@@ -0,0 +1,101 @@
+package limitron
+
+import (
+	"math"
+	"sync/atomic"
+)
+
+// Priority is the priority class of a PriorityLimiter take.
+type Priority uint8
+
+const (
+	// PriorityLow takes may only use the unreserved tokens.
+	PriorityLow Priority = iota
+	// PriorityHigh takes may use all tokens, including the reserved ones.
+	PriorityHigh
+)
+
+// String returns the name of the priority.
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityHigh:
+		return "high"
+	default:
+		return "unknown"
+	}
+}
+
+// PriorityLimiter is a RateLimiter reserving a share of the tokens for high-priority
+// takes, so that low-priority traffic sharing a key cannot starve, e.g., health checks
+// and admin calls. Low-priority takes are denied once only the reserved tokens are left;
+// high-priority takes may use all tokens.
+//
+// It uses the packed states of the wrapped RateLimiter.
+type PriorityLimiter struct {
+	limiter  RateLimiter
+	reserved uint16
+}
+
+// NewPriorityLimiter returns a PriorityLimiter reserving the `reserve` share (in [0, 1])
+// of the burst of `limiter` for high-priority takes, rounded up to whole tokens.
+//
+// Example:
+//
+//	p := NewPriorityLimiter(BuildRateLimiterRps(100), 0.2) // 20 tokens reserved
+//	state := p.New()
+//	p.TakeN(state, 1, PriorityLow)  // regular traffic: up to 80 tokens
+//	p.TakeN(state, 1, PriorityHigh) // health checks: all 100
+func NewPriorityLimiter(limiter RateLimiter, reserve float64) PriorityLimiter {
+	reserve = min(max(reserve, 0), 1)
+	return PriorityLimiter{
+		limiter:  limiter,
+		reserved: uint16(math.Ceil(float64(limiter.maxreq) * reserve)),
+	}
+}
+
+// Reserved returns the number of tokens reserved for high-priority takes.
+func (p PriorityLimiter) Reserved() uint16 {
+	return p.reserved
+}
+
+// New creates a brand-new, zero-use limiter state.
+func (p PriorityLimiter) New() *uint64 {
+	return p.limiter.New()
+}
+
+// TakeN attempts to atomically consume `requests` tokens at priority `priority`
+// from the limiter state `*rl`.
+//
+// It has the same contract as RateLimiter.TakeN, except that low-priority takes must
+// leave the reserved tokens: they are denied with the wait until they would not touch
+// the reserve, and with math.MaxInt64 if `requests` exceeds the unreserved tokens.
+func (p PriorityLimiter) TakeN(rl *uint64, requests uint16, priority Priority) (int64, bool) {
+	if priority == PriorityHigh {
+		return p.limiter.TakeN(rl, requests)
+	}
+	return p.limiter.observe(p.takeLowAt(rl, requests, p.limiter.nowTicks()))
+}
+
+// takeLowAt is a low-priority TakeN without metrics, evaluated at time `now` in state clock ticks.
+func (p PriorityLimiter) takeLowAt(rl *uint64, requests uint16, now uint64) (int64, bool) {
+	s := p.limiter
+	if requests == 0 {
+		return 0, true
+	} else if uint32(requests)+uint32(p.reserved) > uint32(s.maxreq) {
+		return math.MaxInt64, false
+	}
+
+	for i := 0; i < s.retries; i++ {
+		rlval := atomic.LoadUint64(rl)
+		newreq, ts := s.calcNewRequestsAt(rlval, now)
+		if uint32(requests)+uint32(p.reserved) > uint32(newreq) {
+			return s.tickMillis(s.refillWait(newreq, ts, requests+p.reserved, now)), false
+		}
+		if atomic.CompareAndSwapUint64(rl, rlval, packUint16AndUint48(newreq-requests, ts)) {
+			return 0, true
+		}
+	}
+	return 1, false
+}
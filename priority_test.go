@@ -0,0 +1,51 @@
+package limitron
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestPriorityLimiter_ReservesForHighPriority(t *testing.T) {
+	p := NewPriorityLimiter(BuildRateLimiter(10, time.Hour), 0.2)
+	if p.Reserved() != 2 {
+		t.Fatalf("Reserved() = %d, want 2", p.Reserved())
+	}
+	state := p.New()
+
+	if _, ok := p.TakeN(state, 8, PriorityLow); !ok {
+		t.Fatal("8 low-priority tokens denied")
+	}
+	if _, ok := p.TakeN(state, 1, PriorityLow); ok {
+		t.Fatal("low-priority take allowed into the reserve")
+	}
+	for i := 0; i < 2; i++ {
+		if _, ok := p.TakeN(state, 1, PriorityHigh); !ok {
+			t.Fatalf("high-priority take %d denied with the reserve left", i)
+		}
+	}
+	if _, ok := p.TakeN(state, 1, PriorityHigh); ok {
+		t.Fatal("high-priority take allowed with no tokens left")
+	}
+}
+
+func TestPriorityLimiter_LowPriorityWait(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	p := NewPriorityLimiter(BuildRateLimiter(10, 10*time.Second, WithClock(clock)), 0.5) // 1 token per second
+	state := p.New()
+
+	p.TakeN(state, 5, PriorityLow)
+	wait, ok := p.TakeN(state, 2, PriorityLow)
+	if ok || wait != 2001 {
+		t.Fatalf("low-priority take = %d, %v, want 2001, false", wait, ok)
+	}
+	if wait, ok := p.TakeN(state, 6, PriorityLow); ok || wait != math.MaxInt64 {
+		t.Fatalf("low-priority take over the unreserved tokens = %d, %v", wait, ok)
+	}
+}
+
+func TestPriority_String(t *testing.T) {
+	if PriorityLow.String() != "low" || PriorityHigh.String() != "high" || Priority(9).String() != "unknown" {
+		t.Fatal("unexpected priority names")
+	}
+}
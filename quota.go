@@ -0,0 +1,234 @@
+package limitron
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// QuotaPeriod is the calendar period of a QuotaLimiter.
+type QuotaPeriod uint8
+
+const (
+	// Daily quotas reset at midnight.
+	Daily QuotaPeriod = iota
+	// Monthly quotas reset at midnight of the first day of every month.
+	Monthly
+)
+
+// String returns the name of the period.
+func (p QuotaPeriod) String() string {
+	switch p {
+	case Daily:
+		return "daily"
+	case Monthly:
+		return "monthly"
+	default:
+		return "unknown"
+	}
+}
+
+// QuotaLimiter enforces an absolute cap per calendar period, such as
+// "10,000 calls per day", with no continuous refill: the quota resets at
+// period boundaries, in UTC or the location set with WithLocation.
+//
+// Like RateLimiter, it keeps the entire per-key state in a single uint64,
+// packed as [ 32-bit remaining ][ 32-bit period number + 1 ], and updates it
+// with a lock-free CAS loop. Call New once per key.
+type QuotaLimiter struct {
+	limit  uint32
+	period QuotaPeriod
+
+	// rolloverFraction of the unused quota carries over to the next period,
+	// up to rolloverCap (see WithRollover).
+	rolloverFraction float64
+	rolloverCap      uint32
+
+	// loc is the location of the period boundaries; nil means UTC.
+	loc *time.Location
+
+	// clock is the time source; nil means the system clock.
+	clock Clock
+}
+
+// QuotaOption configures optional behavior of a QuotaLimiter at construction time.
+type QuotaOption func(*QuotaLimiter)
+
+// WithRollover carries `fraction` (in [0, 1]) of the quota left unused at the end
+// of a period over to the next one, up to `cap` extra requests, as some API
+// products offer by contract.
+//
+// Only the quota of the immediately preceding period carries over: unused
+// carried-over quota does not roll over again beyond what `fraction` keeps of it,
+// and a key idle for a whole period carries over `fraction` of the plain limit.
+//
+// Example:
+//
+//	// 10,000 calls per month; half of the unused calls roll over, at most 5,000
+//	quota := BuildQuotaLimiter(10000, Monthly, WithRollover(0.5, 5000))
+func WithRollover(fraction float64, cap uint32) QuotaOption {
+	return func(q *QuotaLimiter) {
+		q.rolloverFraction = min(max(fraction, 0), 1)
+		q.rolloverCap = cap
+	}
+}
+
+// WithLocation makes quota periods follow the calendar of `loc`, e.g., to reset
+// daily quotas at midnight in the customer's time zone rather than in UTC.
+// Days spanning a daylight saving transition are 23 or 25 hours long.
+//
+// Example:
+//
+//	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+//	daily := BuildQuotaLimiter(10000, Daily, WithLocation(tokyo))
+func WithLocation(loc *time.Location) QuotaOption {
+	return func(q *QuotaLimiter) {
+		q.loc = loc
+	}
+}
+
+// WithQuotaClock makes the quota limiter read the current time from `c`
+// instead of the system clock.
+func WithQuotaClock(c Clock) QuotaOption {
+	return func(q *QuotaLimiter) {
+		q.clock = c
+	}
+}
+
+// BuildQuotaLimiter returns a QuotaLimiter allowing `limit` requests per calendar `period`.
+//
+// Example:
+//
+//	daily := BuildQuotaLimiter(10000, Daily)
+//	state := daily.New()
+//	if waitMillis, ok := daily.TakeN(state, 1); !ok {
+//	    // quota exhausted until tomorrow, in waitMillis
+//	}
+func BuildQuotaLimiter(limit uint32, period QuotaPeriod, opts ...QuotaOption) QuotaLimiter {
+	q := QuotaLimiter{limit: limit, period: period}
+	for _, opt := range opts {
+		opt(&q)
+	}
+	return q
+}
+
+// New creates a brand-new quota state with the full limit available.
+func (q QuotaLimiter) New() *uint64 {
+	var state uint64
+	return &state
+}
+
+// Take1 attempts to consume 1 request of the quota. See TakeN.
+func (q QuotaLimiter) Take1(state *uint64) (int64, bool) {
+	return q.TakeN(state, 1)
+}
+
+// TakeN attempts to atomically consume `requests` from the quota state `*state`.
+//
+// It returns (0, true) if the requests were consumed. Otherwise it returns
+// false and the number of milliseconds until the quota resets, or math.MaxInt64
+// if `requests` exceeds what any period can allow.
+func (q QuotaLimiter) TakeN(state *uint64, requests uint32) (int64, bool) {
+	if requests == 0 {
+		return 0, true
+	} else if uint64(requests) > uint64(q.limit)+uint64(q.rolloverCap) {
+		return math.MaxInt64, false
+	}
+
+	now := q.now()
+	period := q.periodOf(now)
+	for i := 0; i < UpdateRetries; i++ {
+		old := atomic.LoadUint64(state)
+		remaining := q.remainingAt(old, period)
+		if requests > remaining {
+			return max(q.periodEnd(now).Sub(now).Milliseconds(), 1), false
+		}
+		if atomic.CompareAndSwapUint64(state, old, packQuota(remaining-requests, period)) {
+			return 0, true
+		}
+	}
+	return 1, false
+}
+
+// Remaining returns the requests left in the current period of `*state`.
+func (q QuotaLimiter) Remaining(state *uint64) uint32 {
+	return q.remainingAt(atomic.LoadUint64(state), q.periodOf(q.now()))
+}
+
+// ResetAt returns the time the current period ends and the quota resets.
+func (q QuotaLimiter) ResetAt() time.Time {
+	return q.periodEnd(q.now())
+}
+
+// remainingAt returns the requests left in period `period` given the packed state `v`,
+// starting a new period with the limit plus the rollover of the last one.
+func (q QuotaLimiter) remainingAt(v uint64, period uint32) uint32 {
+	remaining, stored := unpackQuota(v)
+	switch {
+	case v == 0:
+		// a new state has no previous period
+		return q.limit
+	case stored == period:
+		return remaining
+	case stored+1 == period:
+		return q.limit + q.rollover(remaining)
+	default:
+		// idle for a whole period: its plain limit went unused
+		return q.limit + q.rollover(q.limit)
+	}
+}
+
+// rollover returns the part of `unused` requests carried over to the next period.
+func (q QuotaLimiter) rollover(unused uint32) uint32 {
+	return min(uint32(float64(unused)*q.rolloverFraction), q.rolloverCap, math.MaxUint32-q.limit)
+}
+
+// periodOf returns the number of the period containing `t`, by its calendar date
+// in the limiter's location. Day 0 is 1970-01-01; month 0 is January 1970.
+func (q QuotaLimiter) periodOf(t time.Time) uint32 {
+	y, m, d := t.In(q.location()).Date()
+	if q.period == Monthly {
+		return uint32((y-1970)*12 + int(m) - 1)
+	}
+	return uint32(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / (24 * 60 * 60))
+}
+
+// periodEnd returns the start of the period following the one containing `t`.
+func (q QuotaLimiter) periodEnd(t time.Time) time.Time {
+	loc := q.location()
+	y, m, d := t.In(loc).Date()
+	if q.period == Monthly {
+		return time.Date(y, m+1, 1, 0, 0, 0, 0, loc)
+	}
+	return time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+}
+
+// location returns the location of the period boundaries.
+func (q QuotaLimiter) location() *time.Location {
+	if q.loc != nil {
+		return q.loc
+	}
+	return time.UTC
+}
+
+func (q QuotaLimiter) now() time.Time {
+	if q.clock != nil {
+		return q.clock.Now()
+	}
+	return time.Now()
+}
+
+// packQuota packs a quota state. The period is stored plus one,
+// so that the zero state means "never used".
+func packQuota(remaining, period uint32) uint64 {
+	return uint64(remaining)<<32 | uint64(period+1)
+}
+
+// unpackQuota is the inverse of packQuota. For the zero state it returns period 0.
+func unpackQuota(v uint64) (remaining, period uint32) {
+	stored := uint32(v)
+	if stored == 0 {
+		return 0, 0
+	}
+	return uint32(v >> 32), stored - 1
+}
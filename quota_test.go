@@ -0,0 +1,96 @@
+package limitron
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+// testClock is a Clock reporting a settable time.
+type testClock struct{ t time.Time }
+
+func (c *testClock) Now() time.Time { return c.t }
+
+func TestQuotaLimiter_DailyReset(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 3, 10, 23, 59, 0, 0, time.UTC)}
+	q := BuildQuotaLimiter(3, Daily)
+	q.clock = clock
+	state := q.New()
+
+	if _, ok := q.TakeN(state, 3); !ok {
+		t.Fatalf("first 3 requests denied")
+	}
+	waitMillis, ok := q.Take1(state)
+	if ok || waitMillis != time.Minute.Milliseconds() {
+		t.Fatalf("Take1 = %d, %v; want denied until midnight (60000ms)", waitMillis, ok)
+	}
+	if _, ok := q.TakeN(state, 4); ok {
+		t.Fatalf("more than the limit allowed")
+	}
+	if w, _ := q.TakeN(q.New(), 4); w != math.MaxInt64 {
+		t.Fatalf("wait for requests over the limit = %d, want MaxInt64", w)
+	}
+
+	clock.t = clock.t.Add(time.Minute)
+	if got := q.Remaining(state); got != 3 {
+		t.Fatalf("Remaining after midnight = %d, want 3", got)
+	}
+	if !q.ResetAt().Equal(time.Date(2026, 3, 12, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("ResetAt = %v", q.ResetAt())
+	}
+}
+
+func TestQuotaLimiter_MonthlyRollover(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 1, 15, 12, 0, 0, 0, time.UTC)}
+	q := BuildQuotaLimiter(100, Monthly, WithRollover(0.5, 30))
+	q.clock = clock
+	state := q.New()
+
+	// January: 60 unused, half of which would be 30 = the cap
+	q.TakeN(state, 40)
+	clock.t = time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	if got := q.Remaining(state); got != 130 {
+		t.Fatalf("February quota = %d, want 130", got)
+	}
+
+	// February: 20 unused, 10 carry over
+	q.TakeN(state, 110)
+	clock.t = time.Date(2026, 3, 31, 23, 0, 0, 0, time.UTC)
+	if got := q.Remaining(state); got != 110 {
+		t.Fatalf("March quota = %d, want 110", got)
+	}
+
+	// March and April are idle: May carries over half of the plain limit, capped
+	clock.t = time.Date(2026, 5, 2, 0, 0, 0, 0, time.UTC)
+	if got := q.Remaining(state); got != 130 {
+		t.Fatalf("May quota after an idle April = %d, want 130", got)
+	}
+}
+
+func TestQuotaLimiter_Location(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*60*60)
+	// 14:59 UTC is 23:59 in Tokyo
+	clock := &testClock{t: time.Date(2026, 3, 10, 14, 59, 0, 0, time.UTC)}
+	q := BuildQuotaLimiter(2, Daily, WithLocation(tokyo), WithQuotaClock(clock))
+	state := q.New()
+
+	q.TakeN(state, 2)
+	wait, ok := q.Take1(state)
+	if ok || wait != 60_000 {
+		t.Fatalf("Take1 = %d, %v, want 60000 (until midnight in Tokyo), false", wait, ok)
+	}
+	if want := time.Date(2026, 3, 11, 0, 0, 0, 0, tokyo); !q.ResetAt().Equal(want) {
+		t.Fatalf("ResetAt() = %v, want %v", q.ResetAt(), want)
+	}
+
+	clock.t = time.Date(2026, 3, 10, 15, 0, 0, 0, time.UTC)
+	if got := q.Remaining(state); got != 2 {
+		t.Fatalf("quota after midnight in Tokyo = %d, want 2", got)
+	}
+}
+
+func TestQuotaPeriod_String(t *testing.T) {
+	if Daily.String() != "daily" || Monthly.String() != "monthly" || QuotaPeriod(9).String() != "unknown" {
+		t.Fatalf("unexpected period names")
+	}
+}
@@ -0,0 +1,66 @@
+package limitron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rateUnits maps the unit names accepted by ParseRate to their durations.
+var rateUnits = map[string]time.Duration{
+	"ms": time.Millisecond, "msec": time.Millisecond, "millisecond": time.Millisecond,
+	"s": time.Second, "sec": time.Second, "second": time.Second,
+	"m": time.Minute, "min": time.Minute, "minute": time.Minute,
+	"h": time.Hour, "hr": time.Hour, "hour": time.Hour,
+	"d": 24 * time.Hour, "day": 24 * time.Hour,
+}
+
+// ParseRate returns the RateLimiter of a rate specification "<requests>/<interval>", where
+// the interval is a unit (ms, s, min, h, d, or their longer names, singular or plural),
+// a multiple of a unit such as "30s" or "5min", or any Go duration string such as "1m30s".
+// Spaces around the parts are ignored. The limiter is validated as by NewRateLimiter.
+//
+// Example:
+//
+//	limiter, err := ParseRate("100/min")   // also "10/s", "5000/h", "20/30s"
+//	limiter, err := ParseRate("20/30s", WithBurst(5))
+func ParseRate(spec string, opts ...Option) (RateLimiter, error) {
+	reqPart, intervalPart, ok := strings.Cut(spec, "/")
+	if !ok {
+		return RateLimiter{}, fmt.Errorf("limitron: rate %q: want <requests>/<interval>", spec)
+	}
+	req, err := strconv.ParseUint(strings.TrimSpace(reqPart), 10, 16)
+	if err != nil {
+		return RateLimiter{}, fmt.Errorf("limitron: rate %q: requests: %w", spec, err)
+	}
+	interval, err := parseRateInterval(strings.TrimSpace(intervalPart))
+	if err != nil {
+		return RateLimiter{}, fmt.Errorf("limitron: rate %q: %w", spec, err)
+	}
+	return NewRateLimiter(uint16(req), interval, opts...)
+}
+
+// parseRateInterval parses the interval of a rate specification. See ParseRate.
+func parseRateInterval(s string) (time.Duration, error) {
+	digits := len(s) - len(strings.TrimLeft(s, "0123456789"))
+	unit := strings.ToLower(strings.TrimSpace(s[digits:]))
+	d, ok := rateUnits[unit]
+	if !ok {
+		d, ok = rateUnits[strings.TrimSuffix(unit, "s")]
+	}
+	if !ok {
+		if d, err := time.ParseDuration(s); err == nil {
+			return d, nil
+		}
+		return 0, fmt.Errorf("unknown interval %q", s)
+	}
+	if digits == 0 {
+		return d, nil
+	}
+	n, err := strconv.ParseInt(s[:digits], 10, 64)
+	if err != nil || n > int64(1<<63-1)/int64(d) {
+		return 0, fmt.Errorf("interval %q out of range", s)
+	}
+	return time.Duration(n) * d, nil
+}
@@ -0,0 +1,52 @@
+package limitron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRate(t *testing.T) {
+	for _, tc := range []struct {
+		spec     string
+		req      uint16
+		interval time.Duration
+	}{
+		{"100/min", 100, time.Minute},
+		{"10/s", 10, time.Second},
+		{"5000/h", 5000, time.Hour},
+		{"20/30s", 20, 30 * time.Second},
+		{"3 / 5 minutes", 3, 5 * time.Minute},
+		{"1/day", 1, 24 * time.Hour},
+		{"7/1m30s", 7, 90 * time.Second},
+		{"50/250ms", 50, 250 * time.Millisecond},
+	} {
+		limiter, err := ParseRate(tc.spec)
+		if err != nil {
+			t.Errorf("ParseRate(%q): %v", tc.spec, err)
+			continue
+		}
+		want := BuildRateLimiter(tc.req, tc.interval)
+		if limiter.maxreq != want.maxreq || limiter.rrpm != want.rrpm {
+			t.Errorf("ParseRate(%q) = %d at %v/ms, want %d at %v/ms",
+				tc.spec, limiter.maxreq, limiter.rrpm, want.maxreq, want.rrpm)
+		}
+	}
+}
+
+func TestParseRate_Errors(t *testing.T) {
+	for _, spec := range []string{"", "100", "abc/s", "70000/s", "10/fortnight", "0/s", "10/0s", "10/-1s", "-5/s"} {
+		if _, err := ParseRate(spec); err == nil {
+			t.Errorf("ParseRate(%q): no error", spec)
+		}
+	}
+}
+
+func TestParseRate_Options(t *testing.T) {
+	limiter, err := ParseRate("10/s", WithBurst(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limiter.maxreq != 3 {
+		t.Fatalf("burst = %d, want 3", limiter.maxreq)
+	}
+}
@@ -0,0 +1,264 @@
+package limitron
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RegionWeight is the configured weight of a region in a QuotaPartition.
+type RegionWeight struct {
+	Region string
+	Weight float64
+}
+
+// QuotaPartition divides a single customer-facing per-key quota across regions,
+// for geo-distributed APIs: every region enforces its share of the quota locally,
+// so that the regions together admit at most the global quota per key.
+//
+// Shares start proportional to the configured weights and are periodically
+// rebalanced towards the observed regional demand (see Rebalance), blending weights
+// and demand by the adaptivity factor: share = total * ((1-a)*weight + a*demand),
+// with weights and demand normalized to fractions. Every region keeps at least 1 token.
+//
+// Demand is counted by TakeN in the region where it runs. When regions run in
+// separate processes, exchange the counters of Demand with ReportDemand so that
+// all regions rebalance on the same numbers.
+//
+// Key states are kept until evicted by EvictIdle, which Run calls on every rebalance.
+//
+// The zero value is not usable; create instances with NewQuotaPartition.
+// All methods are safe for concurrent use.
+type QuotaPartition struct {
+	total      uint16
+	interval   time.Duration
+	adaptivity float64
+	opts       []Option
+
+	mu      sync.Mutex
+	regions map[string]*regionPartition
+	order   []string
+}
+
+// regionPartition is the share and the per-key states of a single region.
+type regionPartition struct {
+	weight  float64
+	limiter atomic.Pointer[RateLimiter]
+	demand  atomic.Uint64
+	states  sync.Map // string -> *uint64
+}
+
+// NewQuotaPartition returns a QuotaPartition dividing `total` requests per `interval`
+// per key between the given regions. `adaptivity` in [0, 1] controls how far shares
+// follow observed demand; 0 keeps them proportional to weights.
+// Options are applied to the limiter of every region.
+//
+// Example:
+//
+//	q, err := NewQuotaPartition(1000, time.Minute, 0.5,
+//	    []RegionWeight{{"us-east", 2}, {"eu-west", 1}, {"ap-south", 1}})
+//	go q.Run(ctx, time.Minute)
+//	if _, ok := q.TakeN("eu-west", customerID, 1); !ok {
+//	    // global quota share of this region exhausted
+//	}
+func NewQuotaPartition(total uint16, interval time.Duration, adaptivity float64, regions []RegionWeight, opts ...Option) (*QuotaPartition, error) {
+	if len(regions) == 0 {
+		return nil, fmt.Errorf("limitron: quota partition needs at least one region")
+	}
+	if int(total) < len(regions) {
+		return nil, fmt.Errorf("limitron: quota of %d cannot give each of %d regions a token", total, len(regions))
+	}
+	q := &QuotaPartition{
+		total:      total,
+		interval:   interval,
+		adaptivity: min(max(adaptivity, 0), 1),
+		opts:       opts,
+		regions:    make(map[string]*regionPartition, len(regions)),
+	}
+	for _, rw := range regions {
+		if rw.Weight <= 0 {
+			return nil, fmt.Errorf("limitron: region %q has non-positive weight %v", rw.Region, rw.Weight)
+		}
+		if _, dup := q.regions[rw.Region]; dup {
+			return nil, fmt.Errorf("limitron: duplicate region %q", rw.Region)
+		}
+		q.regions[rw.Region] = &regionPartition{weight: rw.Weight}
+		q.order = append(q.order, rw.Region)
+	}
+	q.apply(q.shares(false))
+	return q, nil
+}
+
+// TakeN attempts to consume `requests` tokens of `key` from the share of `region`,
+// counting them as demand of the region. See RateLimiter.TakeN.
+// Unknown regions are denied with math.MaxInt64.
+func (q *QuotaPartition) TakeN(region, key string, requests uint16) (int64, bool) {
+	r, ok := q.region(region)
+	if !ok {
+		return math.MaxInt64, false
+	}
+	r.demand.Add(uint64(requests))
+
+	limiter := r.limiter.Load()
+	v, ok := r.states.Load(key)
+	if !ok {
+		v, _ = r.states.LoadOrStore(key, limiter.New())
+	}
+	return limiter.TakeN(v.(*uint64), requests)
+}
+
+// Take1 attempts to consume 1 token of `key` from the share of `region`. See TakeN.
+func (q *QuotaPartition) Take1(region, key string) (int64, bool) {
+	return q.TakeN(region, key, 1)
+}
+
+// Shares returns the current per-key share of every region, in requests per interval.
+func (q *QuotaPartition) Shares() map[string]uint16 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	out := make(map[string]uint16, len(q.regions))
+	for name, r := range q.regions {
+		out[name] = r.limiter.Load().maxreq
+	}
+	return out
+}
+
+// Demand returns the demand counted per region since the last rebalance.
+func (q *QuotaPartition) Demand() map[string]uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	out := make(map[string]uint64, len(q.regions))
+	for name, r := range q.regions {
+		out[name] = r.demand.Load()
+	}
+	return out
+}
+
+// ReportDemand adds demand observed elsewhere (e.g., by the process of another
+// region) to the counter of `region`. Unknown regions are ignored.
+func (q *QuotaPartition) ReportDemand(region string, requests uint64) {
+	if r, ok := q.region(region); ok {
+		r.demand.Add(requests)
+	}
+}
+
+// Rebalance recomputes the shares from the weights and the demand counted since
+// the previous rebalance, and resets the demand counters. Without any demand,
+// shares return to the weights. Returns the new shares.
+//
+// The states of keys are kept: a region whose share shrank caps its keys' tokens
+// at the new share, and one whose share grew refills up to it at the new rate.
+func (q *QuotaPartition) Rebalance() map[string]uint16 {
+	q.mu.Lock()
+	shares := q.shares(true)
+	q.apply(shares)
+	q.mu.Unlock()
+	return q.Shares()
+}
+
+// EvictIdle removes the states of the keys of all regions that have not been used for
+// at least `idle` and returns how many were removed. An evicted key starts over with
+// a full share, so `idle` should be at least the interval of the quota.
+func (q *QuotaPartition) EvictIdle(idle time.Duration) int {
+	q.mu.Lock()
+	regions := make([]*regionPartition, 0, len(q.regions))
+	for _, r := range q.regions {
+		regions = append(regions, r)
+	}
+	q.mu.Unlock()
+
+	n := 0
+	for _, r := range regions {
+		limiter := r.limiter.Load()
+		cutoff := time.UnixMilli(int64(limiter.nowMillis())).Add(-idle)
+		r.states.Range(func(k, v any) bool {
+			if !stateLastAccess(*limiter, atomic.LoadUint64(v.(*uint64))).After(cutoff) {
+				r.states.Delete(k)
+				n++
+			}
+			return true
+		})
+	}
+	return n
+}
+
+// Run rebalances every `every` until ctx is done, evicting the keys
+// idle for longer than the interval of the quota (see EvictIdle).
+func (q *QuotaPartition) Run(ctx context.Context, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			q.Rebalance()
+			q.EvictIdle(q.interval)
+		}
+	}
+}
+
+func (q *QuotaPartition) region(name string) (*regionPartition, bool) {
+	q.mu.Lock()
+	r, ok := q.regions[name]
+	q.mu.Unlock()
+	return r, ok
+}
+
+// shares computes the shares of all regions, consuming the demand counters if `useDemand`.
+// Must be called with q.mu held.
+func (q *QuotaPartition) shares(useDemand bool) map[string]uint16 {
+	var weightSum, demandSum float64
+	demand := make(map[string]float64, len(q.regions))
+	for name, r := range q.regions {
+		weightSum += r.weight
+		if useDemand {
+			demand[name] = float64(r.demand.Swap(0))
+			demandSum += demand[name]
+		}
+	}
+
+	// reserve 1 token per region, and divide the rest by the blended fractions
+	spare := float64(int(q.total) - len(q.regions))
+	type frac struct {
+		name string
+		f    float64
+	}
+	fracs := make([]frac, 0, len(q.regions))
+	for _, name := range q.order {
+		f := q.regions[name].weight / weightSum
+		if demandSum > 0 {
+			f = (1-q.adaptivity)*f + q.adaptivity*demand[name]/demandSum
+		}
+		fracs = append(fracs, frac{name, f})
+	}
+
+	// largest remainder method, so that the shares add up to the total
+	out := make(map[string]uint16, len(fracs))
+	assigned := 0
+	for _, fr := range fracs {
+		n := int(fr.f * spare)
+		out[fr.name] = uint16(1 + n)
+		assigned += n
+	}
+	sort.SliceStable(fracs, func(i, j int) bool {
+		ri, rj := fracs[i].f*spare, fracs[j].f*spare
+		return ri-float64(int(ri)) > rj-float64(int(rj))
+	})
+	for i := 0; i < int(spare)-assigned; i++ {
+		out[fracs[i%len(fracs)].name]++
+	}
+	return out
+}
+
+// apply installs the limiters of `shares`. Must be called with q.mu held.
+func (q *QuotaPartition) apply(shares map[string]uint16) {
+	for name, share := range shares {
+		limiter := BuildRateLimiter(share, q.interval, q.opts...)
+		q.regions[name].limiter.Store(&limiter)
+	}
+}
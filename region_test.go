@@ -0,0 +1,80 @@
+package limitron
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestQuotaPartition_SharesFollowWeights(t *testing.T) {
+	q, err := NewQuotaPartition(100, time.Hour, 0.5, []RegionWeight{{"us", 3}, {"eu", 1}})
+	if err != nil {
+		t.Fatalf("NewQuotaPartition: %v", err)
+	}
+	shares := q.Shares()
+	if shares["us"]+shares["eu"] != 100 || shares["us"] < 74 || shares["us"] > 76 {
+		t.Fatalf("shares = %v, want about 75/25 adding up to 100", shares)
+	}
+
+	admitted := 0
+	for i := 0; i < 50; i++ {
+		if _, ok := q.Take1("eu", "customer"); ok {
+			admitted++
+		}
+	}
+	if admitted != int(shares["eu"]) {
+		t.Fatalf("eu admitted %d, want its share of %d", admitted, shares["eu"])
+	}
+	if _, ok := q.Take1("us", "customer"); !ok {
+		t.Fatal("us share should be independent of eu")
+	}
+	if w, ok := q.Take1("mars", "customer"); ok || w != math.MaxInt64 {
+		t.Fatalf("unknown region => %d,%v, want MaxInt64,false", w, ok)
+	}
+}
+
+func TestQuotaPartition_RebalanceTowardsDemand(t *testing.T) {
+	q, _ := NewQuotaPartition(100, time.Hour, 1, []RegionWeight{{"us", 1}, {"eu", 1}})
+	q.ReportDemand("eu", 900)
+	q.ReportDemand("us", 100)
+
+	shares := q.Rebalance()
+	if shares["eu"] < 88 || shares["eu"] > 91 || shares["us"]+shares["eu"] != 100 {
+		t.Fatalf("shares after rebalance = %v, want about 90/10 of demand", shares)
+	}
+	if d := q.Demand(); d["eu"] != 0 || d["us"] != 0 {
+		t.Fatalf("demand after rebalance = %v, want reset", d)
+	}
+	if shares = q.Rebalance(); shares["eu"] != 50 {
+		t.Fatalf("shares without demand = %v, want back to weights", shares)
+	}
+}
+
+func TestNewQuotaPartition_Invalid(t *testing.T) {
+	if _, err := NewQuotaPartition(1, time.Hour, 0, []RegionWeight{{"a", 1}, {"b", 1}}); err == nil {
+		t.Fatal("a total below the number of regions should be rejected")
+	}
+	if _, err := NewQuotaPartition(10, time.Hour, 0, []RegionWeight{{"a", 0}}); err == nil {
+		t.Fatal("zero weights should be rejected")
+	}
+}
+
+func TestQuotaPartition_EvictIdle(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	q, _ := NewQuotaPartition(10, time.Minute, 0, []RegionWeight{{"us", 1}, {"eu", 1}}, WithClock(clock))
+	q.Take1("us", "idle")
+	q.Take1("eu", "idle")
+	clock.t = clock.t.Add(30 * time.Second)
+	q.Take1("us", "busy")
+
+	if n := q.EvictIdle(time.Minute); n != 0 {
+		t.Fatalf("evicted %d keys before they were idle", n)
+	}
+	clock.t = clock.t.Add(40 * time.Second)
+	if n := q.EvictIdle(time.Minute); n != 2 {
+		t.Fatalf("evicted %d keys, want the 2 idle ones", n)
+	}
+	if _, ok := q.regions["us"].states.Load("busy"); !ok {
+		t.Fatal("busy key was evicted")
+	}
+}
@@ -0,0 +1,102 @@
+package limitron
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// ReputationProvider supplies a reputation score for a key, e.g., from an external
+// threat-intelligence feed for IP addresses.
+//
+// The score scales the key's limits (burst and rate): 1 is neutral, 0.5 halves
+// the limits, 2 doubles them, and 0 denies the key entirely. Scores are clamped
+// to [0, 65.535] and kept with a precision of 0.001.
+//
+// Implementations must be safe for concurrent use. They are called outside
+// of any KeyedLimiter lock, but on the request path when a key is first seen.
+type ReputationProvider[K comparable] interface {
+	Reputation(key K) float64
+}
+
+// ReputationFunc adapts an ordinary function to the ReputationProvider interface.
+type ReputationFunc[K comparable] func(key K) float64
+
+// Reputation calls f(key).
+func (f ReputationFunc[K]) Reputation(key K) float64 {
+	return f(key)
+}
+
+// WithReputation makes a KeyedLimiter scale each key's limits by the score of `provider`.
+//
+// The provider is consulted when a key's state is created, and again when the key is
+// accessed after its score is older than `refresh`. Refreshes run asynchronously,
+// so the request triggering one still uses the previous score.
+// A non-positive `refresh` consults the provider only at state creation.
+func WithReputation[K comparable](provider ReputationProvider[K], refresh time.Duration) KeyedOption[K] {
+	return func(kl *KeyedLimiter[K]) {
+		kl.reputation = &reputationScaler[K]{
+			provider: provider,
+			refresh:  uint64(max(refresh.Milliseconds(), 0)),
+			regular:  kl.limiter,
+		}
+	}
+}
+
+// reputationScaler applies reputation scores to the limiters of keyed entries.
+type reputationScaler[K comparable] struct {
+	provider ReputationProvider[K]
+	refresh  uint64
+	// regular is the regular limiter of the KeyedLimiter, providing the clock.
+	regular RateLimiter
+}
+
+// initial returns the packed reputation state for a new entry of `key`.
+func (r *reputationScaler[K]) initial(key K) uint64 {
+	return packUint16AndUint48(scoreToMillis(r.provider.Reputation(key)), r.regular.nowMillis())
+}
+
+// apply scales `limiter` by the reputation of entry `e`, scheduling a refresh
+// of the score if it is stale. For a zero score it returns banned=true and
+// the wait in millis until the next refresh (math.MaxInt64 without refreshes).
+func (r *reputationScaler[K]) apply(key K, e *keyedEntry, limiter RateLimiter) (_ RateLimiter, waitMillis int64, banned bool) {
+	rep := atomic.LoadUint64(&e.reputation)
+	score, refreshed := unpackUint16Uint48(rep)
+	now := r.regular.nowMillis()
+
+	if r.refresh > 0 {
+		// claim the refresh with a CAS, so that only one goroutine performs it
+		if now >= refreshed+r.refresh && atomic.CompareAndSwapUint64(&e.reputation, rep, packUint16AndUint48(score, now)) {
+			go func() {
+				fresh := scoreToMillis(r.provider.Reputation(key))
+				for {
+					cur := atomic.LoadUint64(&e.reputation)
+					_, ts := unpackUint16Uint48(cur)
+					if atomic.CompareAndSwapUint64(&e.reputation, cur, packUint16AndUint48(fresh, ts)) {
+						return
+					}
+				}
+			}()
+		}
+	}
+
+	switch {
+	case score == 0:
+		if r.refresh == 0 {
+			return limiter, math.MaxInt64, true
+		}
+		return limiter, 1 + max(int64(refreshed+r.refresh)-int64(now), 0), true
+	case score == 1000 || limiter.maxreq == 0:
+		return limiter, 0, false
+	default:
+		return limiter.scaled(float64(score) / 1000), 0, false
+	}
+}
+
+// scoreToMillis converts a reputation score into thousandths, clamped to the 16-bit range.
+func scoreToMillis(score float64) uint16 {
+	if math.IsNaN(score) || score <= 0 {
+		return 0
+	}
+	return uint16(min(math.Round(score*1000), math.MaxUint16))
+}
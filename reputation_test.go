@@ -0,0 +1,80 @@
+package limitron
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestKeyedLimiter_ReputationScalesLimits(t *testing.T) {
+	scores := map[string]float64{"shady": 0.5, "bad": 0}
+	kl := NewKeyedLimiter(BuildRateLimiter(4, time.Hour),
+		WithReputation[string](ReputationFunc[string](func(key string) float64 {
+			if s, ok := scores[key]; ok {
+				return s
+			}
+			return 1
+		}), 0))
+
+	granted := func(key string) int {
+		n := 0
+		for i := 0; i < 20; i++ {
+			if _, ok := kl.Take1(key); ok {
+				n++
+			}
+		}
+		return n
+	}
+
+	// the initial state holds the regular burst, the scaled limiter caps or refills it
+	if got := granted("shady"); got != 2 {
+		t.Fatalf("shady granted = %d, want 2", got)
+	}
+	if got := granted("neutral"); got != 4 {
+		t.Fatalf("neutral granted = %d, want 4", got)
+	}
+	if got := granted("bad"); got != 0 {
+		t.Fatalf("bad granted = %d, want 0", got)
+	}
+}
+
+func TestKeyedLimiter_ReputationRefresh(t *testing.T) {
+	var score atomic.Value
+	score.Store(0.0)
+	kl := NewKeyedLimiter(BuildRateLimiter(4, time.Hour),
+		WithReputation[string](ReputationFunc[string](func(string) float64 {
+			return score.Load().(float64)
+		}), 30*time.Millisecond))
+
+	wait, ok := kl.Take1("k")
+	if ok {
+		t.Fatal("zero reputation should deny")
+	}
+	if wait <= 0 || wait > 31 {
+		t.Fatalf("wait = %dms, want until the next refresh", wait)
+	}
+
+	score.Store(1.0)
+	time.Sleep(40 * time.Millisecond)
+	kl.Take1("k") // triggers the asynchronous refresh
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, ok := kl.Take1("k"); ok {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("refreshed reputation was never applied")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestScoreToMillis(t *testing.T) {
+	cases := map[float64]uint16{-1: 0, 0: 0, 0.5: 500, 1: 1000, 1e9: 0xFFFF}
+	for in, want := range cases {
+		if got := scoreToMillis(in); got != want {
+			t.Fatalf("scoreToMillis(%v) = %d, want %d", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package limitron
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// Reservation holds tokens reserved by ReserveN for an action at a later time.
+type Reservation struct {
+	limiter  RateLimiter
+	rl       *uint64
+	tokens   uint16
+	ok       bool
+	at       time.Time
+	canceled atomic.Bool
+}
+
+// ReserveN reserves `requests` tokens from the limiter state `*rl` for an action that
+// may take place after Delay, consuming them right away. Unlike TakeN, it always succeeds
+// for up to the burst: when fewer tokens are available, the missing ones are booked
+// from the future refill, and later reservations and takes queue behind it.
+//
+// If the action does not take place, Cancel returns the tokens. Reservations of more
+// than the burst are not OK and reserve nothing.
+//
+// Example:
+//
+//	r := limiter.ReserveN(state, 10) // book the capacity of the export step
+//	if err := prepare(); err != nil {
+//	    r.Cancel()
+//	    return err
+//	}
+//	time.Sleep(r.Delay())
+//	export()
+func (s RateLimiter) ReserveN(rl *uint64, requests uint16) *Reservation {
+	r := &Reservation{limiter: s, rl: rl, tokens: requests}
+	if requests > s.maxreq {
+		return r
+	}
+
+	now := s.nowTicks()
+	for {
+		rlval := atomic.LoadUint64(rl)
+		newreq, ts := s.calcNewRequestsAt(rlval, now)
+		var next uint64
+		if requests <= newreq {
+			next = packUint16AndUint48(newreq-requests, ts)
+			r.at = s.timeOf(now)
+		} else {
+			// book the missing tokens: nothing refills until their refill time
+			ts += uint64(math.Ceil(float64(requests-newreq) / s.rrpt()))
+			next = packUint16AndUint48(0, ts)
+			r.at = s.timeOf(ts)
+		}
+		if atomic.CompareAndSwapUint64(rl, rlval, next) {
+			r.ok = true
+			return r
+		}
+	}
+}
+
+// TakeNWithin is TakeN that also admits requests whose tokens will be available within
+// `maxWait`, booking the missing tokens from the future refill as ReserveN does. It reports
+// whether the tokens were taken; the caller does not wait, the early admission is repaid
+// by the following requests.
+//
+// Example:
+//
+//	// tolerate a few milliseconds of shortfall instead of answering 429
+//	if !limiter.TakeNWithin(state, 1, 5*time.Millisecond) {
+//	    w.WriteHeader(http.StatusTooManyRequests)
+//	}
+func (s RateLimiter) TakeNWithin(rl *uint64, requests uint16, maxWait time.Duration) bool {
+	if requests == 0 {
+		return true
+	} else if requests > s.maxreq {
+		s.observe(math.MaxInt64, false)
+		return false
+	}
+
+	now := s.nowTicks()
+	limit := now + uint64(max(maxWait, 0)/s.tickDuration(1))
+	for {
+		rlval := atomic.LoadUint64(rl)
+		newreq, ts := s.calcNewRequestsAt(rlval, now)
+		var next uint64
+		if requests <= newreq {
+			next = packUint16AndUint48(newreq-requests, ts)
+		} else {
+			ts += uint64(math.Ceil(float64(requests-newreq) / s.rrpt()))
+			if ts > limit {
+				s.observe(s.tickMillis(int64(ts-now)), false)
+				return false
+			}
+			next = packUint16AndUint48(0, ts)
+		}
+		if atomic.CompareAndSwapUint64(rl, rlval, next) {
+			s.observe(0, true)
+			return true
+		}
+	}
+}
+
+// Take1Within is TakeNWithin for 1 token.
+func (s RateLimiter) Take1Within(rl *uint64, maxWait time.Duration) bool {
+	return s.TakeNWithin(rl, 1, maxWait)
+}
+
+// OK reports whether the tokens were reserved.
+func (r *Reservation) OK() bool {
+	return r.ok
+}
+
+// Delay returns the time left until the reserved action may take place:
+// 0 if it may take place now, and math.MaxInt64 nanoseconds if the reservation is not OK.
+func (r *Reservation) Delay() time.Duration {
+	if !r.ok {
+		return time.Duration(math.MaxInt64)
+	}
+	return max(r.at.Sub(r.limiter.now()), 0)
+}
+
+// Cancel returns the reserved tokens to the limiter state, for an action that will not
+// take place: booked future refill is released first, then tokens are returned up to
+// the burst. Canceling more than once, or a reservation that is not OK, has no effect.
+func (r *Reservation) Cancel() {
+	if !r.ok || r.canceled.Swap(true) {
+		return
+	}
+	r.limiter.ReturnN(r.rl, r.tokens)
+}
@@ -0,0 +1,84 @@
+package limitron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReserveN_AvailableAndBooked(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	s := BuildRateLimiter(10, time.Second, WithClock(clock)) // 1 token per 100ms
+	rl := s.New()
+
+	r1 := s.ReserveN(rl, 6)
+	if !r1.OK() || r1.Delay() != 0 {
+		t.Fatalf("first reservation: OK %v, delay %s, want immediate", r1.OK(), r1.Delay())
+	}
+	// 4 left: 3 more are booked from the refill
+	r2 := s.ReserveN(rl, 7)
+	if !r2.OK() || r2.Delay() != 300*time.Millisecond {
+		t.Fatalf("second reservation: OK %v, delay %s, want 300ms", r2.OK(), r2.Delay())
+	}
+	// the next take queues behind the booking
+	if _, ok := s.Take1(rl); ok {
+		t.Fatal("take allowed ahead of a booked reservation")
+	}
+	clock.t = clock.t.Add(200 * time.Millisecond)
+	if r2.Delay() != 100*time.Millisecond {
+		t.Fatalf("Delay() = %s after 200ms, want 100ms", r2.Delay())
+	}
+
+	if r := s.ReserveN(rl, 11); r.OK() {
+		t.Fatal("reservation over the burst is OK")
+	}
+}
+
+func TestReservation_CancelReturnsTokens(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	s := BuildRateLimiter(10, time.Second, WithClock(clock))
+	rl := s.New()
+
+	r1 := s.ReserveN(rl, 8)
+	r2 := s.ReserveN(rl, 5) // 3 booked, 300ms
+
+	r2.Cancel()
+	r2.Cancel() // no effect
+	if _, ok := s.TakeN(rl, 2); !ok {
+		t.Fatal("tokens of a canceled reservation not returned")
+	}
+	if _, ok := s.Take1(rl); ok {
+		t.Fatal("more tokens returned than reserved")
+	}
+
+	r1.Cancel()
+	if _, ok := s.TakeN(rl, 8); !ok {
+		t.Fatal("tokens of the first canceled reservation not returned")
+	}
+}
+
+func TestRateLimiter_TakeNWithin(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	s := BuildRateLimiter(10, time.Second, WithClock(clock)) // 1 token per 100ms
+	rl := s.New()
+
+	if !s.TakeNWithin(rl, 9, 0) {
+		t.Fatal("available tokens denied")
+	}
+	if s.TakeNWithin(rl, 2, 50*time.Millisecond) {
+		t.Fatal("tokens 100ms away admitted within 50ms")
+	}
+	if !s.TakeNWithin(rl, 2, 100*time.Millisecond) {
+		t.Fatal("tokens 100ms away denied within 100ms")
+	}
+	// the early admission is repaid: nothing is left until 100ms from now
+	if s.Take1Within(rl, 150*time.Millisecond) {
+		t.Fatal("token 200ms away admitted within 150ms")
+	}
+	clock.t = clock.t.Add(100 * time.Millisecond)
+	if _, ok := s.Take1(rl); ok {
+		t.Fatal("booked refill taken again")
+	}
+	if s.TakeNWithin(rl, 11, time.Hour) {
+		t.Fatal("tokens over the burst admitted")
+	}
+}
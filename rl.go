@@ -1,11 +1,13 @@
 package limitron
 
 import (
+	"fmt"
 	"math"
 	"sync/atomic"
 	"time"
 )
 
+// UpdateRetries is the default number of CAS retries attempted by TakeN under contention.
 const UpdateRetries = 5
 
 // RateLimiter defines a minimal non-blocking, zero-allocation,
@@ -23,7 +25,7 @@ const UpdateRetries = 5
 //
 // Fields:
 //   - maxreq:  Maximum number of requests allowed per configured interval (defines the burst size).
-//   - rrpm:    Refill rate per millisecond. Calculated as maxreq / interval.Milliseconds().
+//   - rrpm:    Refill rate per millisecond. Calculated as maxreq / interval in milliseconds.
 //     Used internally to replenish tokens based on elapsed time.
 //   - retries: Number of CAS (Compare-And-Swap) retries attempted during concurrent updates of *rl state.
 //     A small integer (e.g., 4–8) balances correctness under contention with performance.
@@ -41,6 +43,34 @@ type RateLimiter struct {
 	// when updating the shared limiter state concurrently. It helps ensure
 	// correctness under contention without indefinite spinning.
 	retries int
+
+	// penalty is the refill time in milliseconds forfeited by every denied attempt
+	// (punitive mode, see WithPunitive). Zero disables punitive mode.
+	penalty uint64
+
+	// clock is the time source (see WithClock); nil means the system clock.
+	clock Clock
+
+	// metrics receives decisions and waits (see WithMetrics); nil disables reporting.
+	metrics Metrics
+
+	// debt is the number of tokens TakeN may overdraw (see WithDebt); 0 disables overdrafts.
+	debt uint16
+
+	// spaced enforces the minimum spacing between admissions instead of bursts (see WithMinSpacing).
+	spaced bool
+
+	// shedding is the share of the burst below which takes are shed probabilistically
+	// (see WithShedding); 0 disables shedding. It is a share rather than a token count,
+	// so that it follows the burst of scaled and reconfigured limiters.
+	shedding float64
+
+	// startEmpty creates new states without tokens (see WithStartEmpty).
+	startEmpty bool
+
+	// micros stores the refill clock of states in microseconds since microEpoch
+	// instead of Unix milliseconds (see WithMicroseconds).
+	micros bool
 }
 
 // BuildRateLimiterRps returns a RateLimiter that allows up to `rps` requests per second,
@@ -52,8 +82,8 @@ type RateLimiter struct {
 //	limiter := BuildRateLimiterRps(10) // 10 requests per second
 //
 // See: BuildRateLimiter for general-purpose rate limiting over any interval.
-func BuildRateLimiterRps(rps uint16) RateLimiter {
-	return BuildRateLimiter(rps, time.Second)
+func BuildRateLimiterRps(rps uint16, opts ...Option) RateLimiter {
+	return BuildRateLimiter(rps, time.Second, opts...)
 }
 
 // BuildRateLimiter returns a RateLimiter that allows up to `req` requests per given `interval`.
@@ -72,26 +102,76 @@ func BuildRateLimiterRps(rps uint16) RateLimiter {
 // Concurrency-safe for shared use of rl pointers.
 //
 // Note: If you're rate limiting per second, use BuildRateLimiterRps for simplicity.
-func BuildRateLimiter(req uint16, interval time.Duration) RateLimiter {
-	return BuildRateLimiterFull(req, interval, UpdateRetries)
+func BuildRateLimiter(req uint16, interval time.Duration, opts ...Option) RateLimiter {
+	return BuildRateLimiterFull(req, interval, UpdateRetries, opts...)
 }
 
-func BuildRateLimiterFull(req uint16, interval time.Duration, retries int) RateLimiter {
-	return RateLimiter{
+// BuildRateLimiterFull is like BuildRateLimiter, but also sets the number of CAS retries
+// attempted by TakeN under contention.
+//
+// Options (see Option) are applied in order after the base configuration is set.
+func BuildRateLimiterFull(req uint16, interval time.Duration, retries int, opts ...Option) RateLimiter {
+	s := RateLimiter{
 		maxreq:  req,
-		rrpm:    float64(req) / float64(interval.Milliseconds()),
+		rrpm:    ratePerMilli(req, interval),
 		retries: retries,
 	}
+	for _, opt := range opts {
+		opt(&s)
+	}
+	return s
+}
+
+// NewRateLimiter is like BuildRateLimiter, but validates the configuration and returns
+// an error for configurations the Build constructors accept silently: no requests,
+// intervals shorter than a millisecond (a microsecond with WithMicroseconds, including
+// non-positive ones), a zero burst or fewer than one retry.
+//
+// Example:
+//
+//	limiter, err := NewRateLimiter(100, time.Minute, WithBurst(20), WithRetries(8))
+//	if err != nil {
+//	    return err
+//	}
+func NewRateLimiter(req uint16, interval time.Duration, opts ...Option) (RateLimiter, error) {
+	if req == 0 {
+		return RateLimiter{}, fmt.Errorf("limitron: requests must be positive")
+	}
+	s := BuildRateLimiter(req, interval, opts...)
+	if resolution := s.tickDuration(1); interval < resolution {
+		return RateLimiter{}, fmt.Errorf("limitron: interval %s is shorter than %s", interval, resolution)
+	}
+	if s.maxreq == 0 {
+		return RateLimiter{}, fmt.Errorf("limitron: burst must be positive")
+	}
+	if s.retries < 1 {
+		return RateLimiter{}, fmt.Errorf("limitron: retries must be positive, got %d", s.retries)
+	}
+	return s, nil
+}
+
+// ratePerMilli returns the refill rate per millisecond of `req` requests per `interval`.
+func ratePerMilli(req uint16, interval time.Duration) float64 {
+	return float64(req) / (float64(interval) / float64(time.Millisecond))
 }
 
 // New creates a brand-new, zero-use limiter state.
 // Call this once per identity (user/IP/apiKey/etc) and store it;
 // pass a pointer to this uint64 into Take* calls.
 func (s RateLimiter) New() *uint64 {
-	rl := packUint16AndUint48(s.maxreq, 0)
+	rl := s.initialState()
 	return &rl
 }
 
+// initialState returns the packed value of a brand-new state: a full bucket,
+// or an empty one refilling from now with WithStartEmpty.
+func (s RateLimiter) initialState() uint64 {
+	if s.startEmpty {
+		return packUint16AndUint48(0, s.nowTicks())
+	}
+	return packUint16AndUint48(s.maxreq, 0)
+}
+
 // Take1 attempts to consume 1 unit (request/token).
 // Returns true and atomically updates *rl on success;
 // returns false if not allowed now (*rl is not updated in this case).
@@ -118,39 +198,75 @@ func (s RateLimiter) Take1(rl *uint64) (int64, bool) {
 //
 // Edge cases:
 //   - If `requests == 0`: always returns (0, true) - noop
-//   - If `requests > maxreq`: returns (math.MaxInt64, false) immediately
+//   - If `requests > maxreq` (plus the debt limit, see WithDebt): returns (math.MaxInt64, false) immediately;
+//     see TimeToAvailable for the time to drain such requests over several intervals
 //
 // Internally uses atomic CAS to safely update the state under contention.
 func (s RateLimiter) TakeN(rl *uint64, requests uint16) (int64, bool) {
+	return s.observe(s.takeN(rl, requests))
+}
+
+// TakeNAt is TakeN evaluated at time `t` instead of the limiter's clock, for replaying
+// events at their original timestamps or simulating time.
+//
+// Times must not go backwards for a state: tokens do not refill until `t` passes the
+// latest time the state was updated at. Times before the Unix epoch are treated as the epoch.
+//
+// Example:
+//
+//	for _, ev := range events {
+//		if _, ok := limiter.TakeNAt(state, 1, ev.Timestamp); !ok {
+//			flagged = append(flagged, ev)
+//		}
+//	}
+func (s RateLimiter) TakeNAt(rl *uint64, requests uint16, t time.Time) (int64, bool) {
+	return s.observe(s.takeNAt(rl, requests, s.ticksOf(t)))
+}
+
+// Take1At is TakeNAt for 1 token.
+func (s RateLimiter) Take1At(rl *uint64, t time.Time) (int64, bool) {
+	return s.TakeNAt(rl, 1, t)
+}
+
+// observe reports the result of a take to the metrics, if any, and returns it.
+func (s RateLimiter) observe(waitMillis int64, ok bool) (int64, bool) {
+	if s.metrics != nil {
+		s.metrics.ObserveTake(ok, millisToDuration(waitMillis))
+	}
+	return waitMillis, ok
+}
+
+// takeN implements TakeN without reporting metrics.
+func (s RateLimiter) takeN(rl *uint64, requests uint16) (int64, bool) {
+	return s.takeNAt(rl, requests, s.nowTicks())
+}
+
+// takeNAt is takeN with the refill evaluated at time `now` in state clock ticks (see ticksOf).
+func (s RateLimiter) takeNAt(rl *uint64, requests uint16, now uint64) (int64, bool) {
+	waitMillis, ok, _ := s.tryTakeNAt(rl, requests, now)
+	return waitMillis, ok
+}
+
+// tryTakeNAt implements takeNAt, also reporting whether the take failed only because
+// all CAS retries lost to concurrent updates of the state.
+func (s RateLimiter) tryTakeNAt(rl *uint64, requests uint16, now uint64) (waitMillis int64, ok bool, contended bool) {
 	if requests == 0 {
-		return 0, true
-	} else if requests > s.maxreq {
-		return math.MaxInt64, false
+		return 0, true, false
+	} else if uint32(requests) > uint32(s.maxreq)+uint32(s.debt) {
+		return math.MaxInt64, false, false
 	}
 
 	for i := 0; i < s.retries; i++ {
 		// Atomically get current value of rl
 		// (remember: the other clients might use this rl at the same time, hence we need atomic call)
 		rlval := atomic.LoadUint64(rl)
-		// calculate new values for requests and timestamp
-		// with respect to time that passes since the last access timestamp
-		// (last access timestamp is encoded in rlval - in its lower 48 bits)
-		newreq, ts := s.calcNewRequests(rlval)
-
-		// requested tokens are greater than currently available number of tokens
-		if requests > newreq {
-			waitMillis := 1 + int64(float64(requests-newreq)/s.rrpm)
-			return waitMillis, false
-		}
+		next, write, waitMillis, ok := s.step(rlval, requests, now)
 
-		newreq -= requests
-		newrlval := packUint16AndUint48(newreq, ts)
-
-		// if the value hasn't changed since we read it in line 134
-		// then we are good to go.
-		// Otherwise, let's repeat the entire loop again
-		if atomic.CompareAndSwapUint64(rl, rlval, newrlval) {
-			return 0, true
+		// if the value hasn't changed since we read it, we are good to go;
+		// otherwise, let's repeat the entire loop again. Denials that update
+		// the state (see WithPunitive) make a single attempt.
+		if !write || atomic.CompareAndSwapUint64(rl, rlval, next) || !ok {
+			return waitMillis, ok, false
 		}
 	}
 
@@ -158,7 +274,87 @@ func (s RateLimiter) TakeN(rl *uint64, requests uint16) (int64, bool) {
 	// returned false. So, we hadn't to wait, and failed to update rl
 	// only because concurrent modifications occurred.
 	// So it is safe to assume that waitMillis could be 1 millisecond to have minimal wait
-	return 1, false
+	return 1, false, true
+}
+
+// step decides a take of `requests` tokens (at most the burst plus the debt limit) from
+// the state `rlval` at time `now` in state clock ticks, without touching any state, so
+// that the same algorithm runs against atomic states and Stores alike. It returns the
+// state to write if `write`, and the wait in millis and the decision of the take.
+// A denial may also write a state (see WithPunitive).
+func (s RateLimiter) step(rlval uint64, requests uint16, now uint64) (next uint64, write bool, waitMillis int64, ok bool) {
+	if s.spaced {
+		// minimum spacing mode (see WithMinSpacing): the refill clock of the state holds
+		// the earliest time of the next admission, and every admission moves it
+		// `requests` spacings past now
+		_, at := unpackUint16Uint48(rlval)
+		if now < at {
+			return 0, false, s.tickMillis(int64(at - now)), false
+		}
+		return packUint16AndUint48(0, now+uint64(math.Ceil(float64(requests)/s.rrpt()))), true, 0, true
+	}
+
+	// calculate new values for requests and timestamp
+	// with respect to time that passes since the last access timestamp
+	// (last access timestamp is encoded in rlval - in its lower 48 bits)
+	newreq, ts := s.calcNewRequestsAt(rlval, now)
+
+	// requested tokens are greater than currently available number of tokens
+	if requests > newreq {
+		if s.canOverdraw(newreq, ts, requests, now) {
+			// go into debt: the refill clock moves into the future by the time
+			// it takes to refill the missing tokens, so nothing refills until then
+			owed := math.Ceil(float64(requests-newreq) / s.rrpt())
+			return packUint16AndUint48(0, ts+uint64(owed)), true, 0, true
+		}
+		if s.penalty > 0 && ts <= now {
+			next, waitMillis = s.punish(rlval, requests, now)
+			return next, true, waitMillis, false
+		}
+		return 0, false, s.deniedWait(newreq, ts, requests, now), false
+	}
+	if s.shedding > 0 && s.shed(newreq) {
+		return 0, false, s.tickMillis(1 + int64(1/s.rrpt())), false
+	}
+	return packUint16AndUint48(newreq-requests, ts), true, 0, true
+}
+
+// canOverdraw reports whether `requests` tokens may be taken on debt (see WithDebt) from
+// a state holding `newreq` tokens with refill clock `ts`: tokens must be available,
+// no earlier debt may be outstanding, and the missing tokens must fit the debt cap.
+func (s RateLimiter) canOverdraw(newreq uint16, ts uint64, requests uint16, now uint64) bool {
+	return s.debt > 0 && newreq > 0 && ts <= now && requests-newreq <= s.debt
+}
+
+// deniedWait returns the wait in millis until `requests` tokens can be taken from
+// a state holding `newreq` tokens with refill clock `ts`, including the repayment
+// of any outstanding debt.
+func (s RateLimiter) deniedWait(newreq uint16, ts uint64, requests uint16, now uint64) int64 {
+	target := requests
+	if s.debt > 0 {
+		// with debt allowed, the tokens beyond the debt cap suffice, and at least one
+		target = 1
+		if requests > s.debt {
+			target = requests - s.debt
+		}
+	}
+	if s.debt > 0 && ts > now {
+		return s.tickMillis(s.refillWait(newreq, ts, target, now) + int64(ts-now))
+	}
+	return s.tickMillis(s.refillWait(newreq, ts, target, now))
+}
+
+// refillWait returns the wait in ticks, plus one, until a state holding `newreq` tokens with
+// refill clock `ts` holds `requests` tokens, taking the refill accrued since `ts` into account.
+func (s RateLimiter) refillWait(newreq uint16, ts uint64, requests uint16, now uint64) int64 {
+	if requests <= newreq {
+		return 1
+	}
+	wait := int64(float64(requests-newreq) / s.rrpt())
+	if ts < now {
+		wait -= int64(now - ts)
+	}
+	return 1 + max(wait, 0)
 }
 
 // calcNewReq computes the updated number of available requests (tokens) based on
@@ -169,25 +365,189 @@ func (s RateLimiter) TakeN(rl *uint64, requests uint16) (int64, bool) {
 //
 // Returns:
 //   - newreq: the refilled token count (capped at maxreq)
-//   - ts:     the current timestamp in Unix milliseconds (used for the next state update)
+//   - ts:     the refill clock in state clock ticks (used for the next state update): the current
+//     timestamp, less the time already refilled towards the next token when the bucket is not full
 //
 // This function performs refill logic using a token bucket approximation:
 //   - Tokens are replenished over time at a fixed rate (rrpm).
 //   - The number of tokens is capped at maxreq (burst size).
 func (s RateLimiter) calcNewRequests(rl uint64) (newreq uint16, ts uint64) {
+	return s.calcNewRequestsAt(rl, s.nowTicks())
+}
+
+// calcNewRequestsAt is calcNewRequests evaluated at the given time `now` in state clock ticks.
+//
+// If `now` is before the last recorded timestamp (e.g., the clock went backwards),
+// no refill happens and the recorded timestamp is kept.
+func (s RateLimiter) calcNewRequestsAt(rl uint64, now uint64) (newreq uint16, ts uint64) {
 	// req - current requests
-	// lastTs - last access timestamp in unix millis
+	// lastTs - last access timestamp in state clock ticks
 	req, lastTs := unpackUint16Uint48(rl)
-	ts = uint64(time.Now().UnixMilli())
-	// refillReq - refilled requests since last access timestamp
-	refillReq := uint64(s.rrpm * float64(ts-lastTs))
+	if now < lastTs {
+		return min(req, s.maxreq), lastTs
+	}
+	// refill - requests refilled since last access timestamp, including the fraction of the next one
+	refill := s.rrpt() * float64(now-lastTs)
+	refillReq := math.Floor(refill)
 	// new requests (uncapped)
-	uncappedReq := uint64(req) + refillReq
+	uncappedReq := uint64(req) + uint64(refillReq)
+
+	if uncappedReq >= uint64(s.maxreq) {
+		return s.maxreq, now
+	}
+	// keep the fraction of the next token as refill accrued before now, so that
+	// updating the state does not lose it; rounded down, never to over-deliver
+	return uint16(uncappedReq), now - uint64((refill-refillReq)/s.rrpt())
+}
+
+// ReturnN atomically adds `n` tokens back to the limiter state `*rl`, capped at the burst,
+// e.g., to refund the tokens of an operation that failed fast before doing real work,
+// so that legitimate retries are not penalized. It is also used to roll back tokens
+// consumed by a TakeN whose overall operation was denied.
+//
+// Refill booked ahead by a debt or reservation (see WithDebt and ReserveN) is released
+// first, and only the rest of `n` is added as tokens.
+//
+// Example:
+//
+//	if _, ok := limiter.Take1(state); ok {
+//	    if err := validate(req); err != nil {
+//	        limiter.ReturnN(state, 1) // rejected before any work was done
+//	        return err
+//	    }
+//	}
+func (s RateLimiter) ReturnN(rl *uint64, n uint16) {
+	for {
+		rlval := atomic.LoadUint64(rl)
+		now := s.nowTicks()
+		req, ts := unpackUint16Uint48(rlval)
+		tokens := float64(n)
+		if ts > now {
+			// release booked refill time first
+			released := min(float64(ts-now)*s.rrpt(), tokens)
+			ts -= uint64(released / s.rrpt())
+			tokens -= released
+		} else {
+			req, ts = s.calcNewRequestsAt(rlval, now)
+		}
+		newreq := min(float64(req)+math.Floor(tokens), float64(s.maxreq))
+		if atomic.CompareAndSwapUint64(rl, rlval, packUint16AndUint48(uint16(newreq), ts)) {
+			return
+		}
+	}
+}
+
+// Merge atomically combines the limiter state `*src` into `*dst` conservatively, e.g., to
+// coalesce the states of per-IP keys into one per-subnet key: both are refilled to now,
+// then `*dst` keeps the fewer tokens and the later refill clock, so that the merged state
+// never allows more than either of them would. `*src` is not modified.
+//
+// Example:
+//
+//	limiter.Merge(subnetState, ipState)
+func (s RateLimiter) Merge(dst, src *uint64) {
+	for {
+		dstval := atomic.LoadUint64(dst)
+		now := s.nowTicks()
+		dstReq, dstTs := s.calcNewRequestsAt(dstval, now)
+		srcReq, srcTs := s.calcNewRequestsAt(atomic.LoadUint64(src), now)
+		merged := packUint16AndUint48(min(dstReq, srcReq), max(dstTs, srcTs))
+		if atomic.CompareAndSwapUint64(dst, dstval, merged) {
+			return
+		}
+	}
+}
+
+// Penalize atomically removes `n` tokens from the limiter state `*rl` as a punishment,
+// e.g., when abuse is detected, without representing a real request. It never fails and
+// does not report to the metrics.
+//
+// Tokens are removed down to zero; with WithDebt, the rest is charged as debt, up to the
+// debt limit, so that the key stays denied until the refill repays it.
+//
+// Example:
+//
+//	if looksLikeCredentialStuffing(req) {
+//	    limiter.Penalize(state, 50)
+//	}
+func (s RateLimiter) Penalize(rl *uint64, n uint16) {
+	if s.debt == 0 {
+		s.drainN(rl, n)
+		return
+	}
+	for {
+		rlval := atomic.LoadUint64(rl)
+		now := s.nowTicks()
+		req, ts := s.calcNewRequestsAt(rlval, now)
+		if n <= req {
+			req -= n
+		} else {
+			// charge the rest as debt: nothing refills until it is repaid
+			limit := max(now+uint64(math.Ceil(float64(s.debt)/s.rrpt())), ts)
+			ts = min(ts+uint64(math.Ceil(float64(n-req)/s.rrpt())), limit)
+			req = 0
+		}
+		if atomic.CompareAndSwapUint64(rl, rlval, packUint16AndUint48(req, ts)) {
+			return
+		}
+	}
+}
+
+// drainN atomically removes up to `n` tokens from the limiter state `*rl`, saturating at zero.
+// Unlike TakeN it never fails, which makes it suitable for charging usage
+// that has already been admitted elsewhere.
+func (s RateLimiter) drainN(rl *uint64, n uint16) {
+	for {
+		rlval := atomic.LoadUint64(rl)
+		req, ts := s.calcNewRequests(rlval)
+		if n < req {
+			req -= n
+		} else {
+			req = 0
+		}
+		if atomic.CompareAndSwapUint64(rl, rlval, packUint16AndUint48(req, ts)) {
+			return
+		}
+	}
+}
 
-	newreq = s.maxreq
-	if uncappedReq < uint64(newreq) {
-		newreq = uint16(uncappedReq)
+// punish applies the punitive mode penalty to a denied attempt: the refill clock of the state
+// `rlval` is moved forward by the penalty (never past `now`, in state clock ticks),
+// forfeiting that much accumulated refill. It returns the penalized state and the wait
+// in millis for `requests` tokens after the penalty.
+//
+// Callers make a single attempt to write the state: if it fails, the state was
+// concurrently updated, and the penalty is skipped for this attempt.
+func (s RateLimiter) punish(rlval uint64, requests uint16, now uint64) (uint64, int64) {
+	req, lastTs := unpackUint16Uint48(rlval)
+	newTs := min(lastTs+s.penalty*s.ticksPerMilli(), now)
+	if lastTs > now {
+		newTs = lastTs
 	}
 
-	return
+	// wait for the missing tokens, less the refill accrued since newTs
+	missing := float64(requests-req)/s.rrpt() - float64(now-newTs)
+	return packUint16AndUint48(req, newTs), s.tickMillis(1 + max(int64(missing), 0))
+}
+
+// scaled returns a copy of the limiter with burst and refill rate multiplied by `factor`.
+// The burst is kept at least 1 and at most the 16-bit token range.
+func (s RateLimiter) scaled(factor float64) RateLimiter {
+	s.maxreq = uint16(min(max(float64(s.maxreq)*factor, 1), math.MaxUint16))
+	s.rrpm *= factor
+	return s
+}
+
+// waitFor returns the wait in millis until `requests` tokens are available in the state
+// `rlval` at time `now`, without consuming them: 0 if they are available,
+// math.MaxInt64 if `requests > maxreq`.
+func (s RateLimiter) waitFor(rlval uint64, requests uint16, now uint64) int64 {
+	if requests > s.maxreq {
+		return math.MaxInt64
+	}
+	newreq, ts := s.calcNewRequestsAt(rlval, now)
+	if requests <= newreq {
+		return 0
+	}
+	return s.tickMillis(s.refillWait(newreq, ts, requests, now))
 }
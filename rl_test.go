@@ -206,3 +206,177 @@ func TestWaitMillisReasonableWhenInsufficientTokens(t *testing.T) {
 		t.Fatalf("wait=%dms, expected roughly ~200ms (±20%%)", wait)
 	}
 }
+
+func TestReturnN_RefundsUpToBurst(t *testing.T) {
+	s := BuildRateLimiter(5, time.Hour)
+	rl := s.New()
+	s.TakeN(rl, 5)
+
+	s.ReturnN(rl, 2)
+	if _, ok := s.TakeN(rl, 2); !ok {
+		t.Fatal("refunded tokens not available")
+	}
+	s.ReturnN(rl, 100)
+	if _, ok := s.TakeN(rl, 5); !ok {
+		t.Fatal("refund did not restore the burst")
+	}
+	if _, ok := s.Take1(rl); ok {
+		t.Fatal("refund exceeded the burst")
+	}
+}
+
+func TestReturnN_ReleasesDebtFirst(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	s := BuildRateLimiter(10, time.Second, WithDebt(10), WithClock(clock)) // 1 token per 100ms
+	rl := s.New()
+	s.TakeN(rl, 15) // 5 on debt: 500ms
+
+	s.ReturnN(rl, 3)
+	if got := s.TimeToFull(rl); got != 1200*time.Millisecond {
+		t.Fatalf("TimeToFull() = %s after refunding 3 of the debt, want 1.2s", got)
+	}
+	s.ReturnN(rl, 4)
+	if got := s.Available(rl); got != 2 {
+		t.Fatalf("Available() = %d after refunding more than the debt, want 2", got)
+	}
+}
+
+func TestRateLimiter_TakeNAt(t *testing.T) {
+	limiter := BuildRateLimiter(2, time.Second)
+	state := limiter.New()
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i, offset := range []time.Duration{0, 100 * time.Millisecond} {
+		if _, ok := limiter.Take1At(state, start.Add(offset)); !ok {
+			t.Fatalf("event %d denied", i)
+		}
+	}
+	waitMillis, ok := limiter.Take1At(state, start.Add(200*time.Millisecond))
+	if ok || waitMillis < 300 || waitMillis > 510 {
+		t.Fatalf("third event = %d, %v, want 300-500ms, false", waitMillis, ok)
+	}
+	if _, ok := limiter.TakeNAt(state, 2, start.Add(2*time.Second)); !ok {
+		t.Fatal("2 tokens denied 2 seconds later")
+	}
+}
+
+func TestNewRateLimiter_Validation(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		req      uint16
+		interval time.Duration
+		opts     []Option
+	}{
+		{"zero requests", 0, time.Second, nil},
+		{"zero interval", 10, 0, nil},
+		{"negative interval", 10, -time.Second, nil},
+		{"sub-millisecond interval", 10, 500 * time.Microsecond, nil},
+		{"zero burst", 10, time.Second, []Option{WithBurst(0)}},
+		{"zero retries", 10, time.Second, []Option{WithRetries(0)}},
+	} {
+		if _, err := NewRateLimiter(tc.req, tc.interval, tc.opts...); err == nil {
+			t.Errorf("%s: no error", tc.name)
+		}
+	}
+}
+
+func TestNewRateLimiter_Options(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	limiter, err := NewRateLimiter(10, time.Second, WithBurst(20), WithRetries(8), WithClock(clock))
+	if err != nil {
+		t.Fatal(err)
+	}
+	state := limiter.New()
+	if _, ok := limiter.TakeN(state, 20); !ok {
+		t.Fatal("burst of 20 denied")
+	}
+	clock.t = clock.t.Add(time.Second)
+	if _, ok := limiter.TakeN(state, 11); ok {
+		t.Fatal("refill faster than 10 per second")
+	}
+	if _, ok := limiter.TakeN(state, 10); !ok {
+		t.Fatal("refill of 10 per second denied")
+	}
+}
+
+func TestRateLimiter_SlowRefillKeepsFractions(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	limiter := BuildRateLimiter(3, time.Hour, WithClock(clock)) // 1 per 20min
+	state := limiter.New()
+
+	// a take every 10 minutes gets the burst plus the full refill of the hour
+	admitted := 0
+	for i := 0; i <= 6; i++ {
+		if _, ok := limiter.Take1(state); ok {
+			admitted++
+		}
+		clock.t = clock.t.Add(10 * time.Minute)
+	}
+	if admitted != 6 {
+		t.Fatalf("admitted %d requests in an hour, want 6", admitted)
+	}
+}
+
+func TestRateLimiter_Penalize(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	limiter := BuildRateLimiter(10, time.Second, WithClock(clock))
+	state := limiter.New()
+
+	limiter.Penalize(state, 4)
+	if got := limiter.Available(state); got != 6 {
+		t.Fatalf("Available after Penalize(4) = %d, want 6", got)
+	}
+	limiter.Penalize(state, 100)
+	if got := limiter.Available(state); got != 0 {
+		t.Fatalf("Available after Penalize(100) = %d, want 0", got)
+	}
+	clock.t = clock.t.Add(100 * time.Millisecond)
+	if _, ok := limiter.Take1(state); !ok {
+		t.Fatal("penalty without debt went below zero")
+	}
+}
+
+func TestRateLimiter_PenalizeWithDebt(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	limiter := BuildRateLimiter(10, time.Second, WithClock(clock), WithDebt(20))
+	state := limiter.New()
+
+	limiter.Penalize(state, 15) // 5 tokens below zero: 500ms of refill
+	clock.t = clock.t.Add(400 * time.Millisecond)
+	if got := limiter.Available(state); got != 0 {
+		t.Fatalf("Available during the debt = %d, want 0", got)
+	}
+	clock.t = clock.t.Add(200 * time.Millisecond)
+	if got := limiter.Available(state); got != 1 {
+		t.Fatalf("Available after the debt = %d, want 1", got)
+	}
+
+	// the debt is capped at the debt limit of 20 tokens: 2s
+	limiter.Penalize(state, 1000)
+	if got := limiter.TimeToFull(state); got != 3*time.Second {
+		t.Fatalf("TimeToFull after a capped penalty = %s, want 3s", got)
+	}
+}
+
+func TestRateLimiter_Merge(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	limiter := BuildRateLimiter(10, time.Second, WithClock(clock))
+	a, b := limiter.New(), limiter.New()
+	limiter.TakeN(a, 3)
+	limiter.TakeN(b, 7)
+	clock.t = clock.t.Add(100 * time.Millisecond)
+	limiter.TakeN(a, 1)
+
+	srcBefore := *b
+	limiter.Merge(a, b)
+	if got := limiter.Available(a); got != 4 {
+		t.Fatalf("Available after Merge = %d, want the fewer tokens of 4", got)
+	}
+	if *b != srcBefore {
+		t.Fatal("Merge modified the source state")
+	}
+	_, dstTs := unpackUint16Uint48(*a)
+	if dstTs != uint64(clock.t.UnixMilli()) {
+		t.Fatalf("merged refill clock = %d, want the later one %d", dstTs, clock.t.UnixMilli())
+	}
+}
@@ -0,0 +1,123 @@
+package rpclimit
+
+import (
+	"bufio"
+	"encoding/gob"
+	"io"
+	"net/rpc"
+	"sync"
+)
+
+// deniedMethod replaces the method of denied calls. It names no registered service,
+// so the server answers the call with an error without invoking anything.
+const deniedMethod = "rpclimit.denied"
+
+// ServerCodec wraps `codec` so that calls of `client` are limited by `l`.
+// Denied calls are answered with the *limitron.LimitedError message as the
+// call's error, and the method is not invoked.
+//
+// Use it to limit servers with custom codecs:
+//
+//	srv.ServeCodec(lim.ServerCodec(myCodec(conn), clientID))
+func (l *Limiter) ServerCodec(codec rpc.ServerCodec, client string) rpc.ServerCodec {
+	return &limitedCodec{
+		ServerCodec: codec,
+		limiter:     l,
+		client:      client,
+		denied:      make(map[uint64]deniedCall),
+	}
+}
+
+// limitedCodec is a rpc.ServerCodec enforcing the limits of a Limiter.
+type limitedCodec struct {
+	rpc.ServerCodec
+	limiter *Limiter
+	client  string
+
+	// denied maps the sequence numbers of denied calls to their details;
+	// responses are written concurrently with reading further requests.
+	mu     sync.Mutex
+	denied map[uint64]deniedCall
+}
+
+type deniedCall struct {
+	method string
+	err    string
+}
+
+func (c *limitedCodec) ReadRequestHeader(r *rpc.Request) error {
+	if err := c.ServerCodec.ReadRequestHeader(r); err != nil {
+		return err
+	}
+	if err := c.limiter.Allow(c.client, r.ServiceMethod); err != nil {
+		c.mu.Lock()
+		c.denied[r.Seq] = deniedCall{method: r.ServiceMethod, err: err.Error()}
+		c.mu.Unlock()
+		r.ServiceMethod = deniedMethod
+	}
+	return nil
+}
+
+func (c *limitedCodec) WriteResponse(r *rpc.Response, body any) error {
+	c.mu.Lock()
+	call, ok := c.denied[r.Seq]
+	delete(c.denied, r.Seq)
+	c.mu.Unlock()
+	if ok {
+		r.ServiceMethod = call.method
+		r.Error = call.err
+	}
+	return c.ServerCodec.WriteResponse(r, body)
+}
+
+// gobServerCodec is the gob codec of net/rpc, which the package does not export.
+type gobServerCodec struct {
+	rwc    io.ReadWriteCloser
+	dec    *gob.Decoder
+	enc    *gob.Encoder
+	encBuf *bufio.Writer
+	closed bool
+}
+
+func newGobServerCodec(conn io.ReadWriteCloser) *gobServerCodec {
+	buf := bufio.NewWriter(conn)
+	return &gobServerCodec{
+		rwc:    conn,
+		dec:    gob.NewDecoder(conn),
+		enc:    gob.NewEncoder(buf),
+		encBuf: buf,
+	}
+}
+
+func (c *gobServerCodec) ReadRequestHeader(r *rpc.Request) error {
+	return c.dec.Decode(r)
+}
+
+func (c *gobServerCodec) ReadRequestBody(body any) error {
+	return c.dec.Decode(body)
+}
+
+func (c *gobServerCodec) WriteResponse(r *rpc.Response, body any) error {
+	if err := c.enc.Encode(r); err != nil {
+		if c.encBuf.Flush() == nil {
+			// gob couldn't encode the header; shut down the connection to signal that it is broken
+			c.Close()
+		}
+		return err
+	}
+	if err := c.enc.Encode(body); err != nil {
+		if c.encBuf.Flush() == nil {
+			c.Close()
+		}
+		return err
+	}
+	return c.encBuf.Flush()
+}
+
+func (c *gobServerCodec) Close() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	return c.rwc.Close()
+}
@@ -0,0 +1,125 @@
+// Package rpclimit provides rate limiting for net/rpc and JSON-RPC servers,
+// enforcing per-client and per-method limits, and a helper limiting plain functions.
+package rpclimit
+
+import (
+	"math"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"sync"
+	"time"
+
+	"github.com/iryndin/limitron"
+)
+
+// Limiter holds the limits of an RPC server: a per-client limit applying to all
+// calls, and optional per-method limits applying per client to calls of a method.
+// A call is admitted only if all limits applying to it admit it.
+//
+// The zero value is not usable; create instances with New.
+// All methods are safe for concurrent use.
+type Limiter struct {
+	perClient *limitron.KeyedLimiter[string]
+
+	mu        sync.RWMutex
+	perMethod map[string]*limitron.KeyedLimiter[string]
+}
+
+// New returns a Limiter applying `perClient` to all calls of every client.
+// `perClient` may be nil to only enforce per-method limits.
+func New(perClient *limitron.KeyedLimiter[string]) *Limiter {
+	return &Limiter{
+		perClient: perClient,
+		perMethod: make(map[string]*limitron.KeyedLimiter[string]),
+	}
+}
+
+// SetMethodLimit limits the calls of `method` ("Service.Method") per client with `kl`.
+// A nil `kl` removes the method limit.
+func (l *Limiter) SetMethodLimit(method string, kl *limitron.KeyedLimiter[string]) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if kl == nil {
+		delete(l.perMethod, method)
+		return
+	}
+	l.perMethod[method] = kl
+}
+
+// Allow takes a token for a call of `method` by `client`. If the call is denied,
+// it returns a *limitron.LimitedError with the suggested wait.
+//
+// The method limit is checked before the client limit, so that calls denied
+// by a method limit do not count against the client's overall allowance; the
+// method token of a call denied by the client limit is returned.
+func (l *Limiter) Allow(client, method string) error {
+	l.mu.RLock()
+	kl := l.perMethod[method]
+	l.mu.RUnlock()
+
+	if kl != nil {
+		if waitMillis, ok := kl.Take1(client); !ok {
+			return limited(waitMillis)
+		}
+	}
+	if l.perClient != nil {
+		if waitMillis, ok := l.perClient.Take1(client); !ok {
+			if kl != nil {
+				kl.ReturnN(client, 1)
+			}
+			return limited(waitMillis)
+		}
+	}
+	return nil
+}
+
+// ServeConn serves a single net/rpc (gob) connection with `srv`, limiting its calls.
+// Clients are identified by the host of the connection's remote address.
+func (l *Limiter) ServeConn(srv *rpc.Server, conn net.Conn) {
+	srv.ServeCodec(l.ServerCodec(newGobServerCodec(conn), clientOf(conn)))
+}
+
+// ServeJSONConn serves a single JSON-RPC 1.0 connection with `srv`, limiting its calls.
+// Clients are identified by the host of the connection's remote address.
+func (l *Limiter) ServeJSONConn(srv *rpc.Server, conn net.Conn) {
+	srv.ServeCodec(l.ServerCodec(jsonrpc.NewServerCodec(conn), clientOf(conn)))
+}
+
+// Wrap returns `fn` limited by `l` as calls of `method`, with the client derived
+// from the arguments by `client`, e.g., an API key field. Denied calls return
+// a *limitron.LimitedError without calling `fn`.
+//
+// It suits methods of services registered with net/rpc, whose arguments carry
+// the caller's identity, as well as any other function of that shape:
+//
+//	func (s *Orders) Create(args CreateArgs, reply *CreateReply) error {
+//	    return s.create(args, reply)
+//	}
+//	s.create = rpclimit.Wrap(lim, "Orders.Create",
+//	    func(a CreateArgs) string { return a.APIKey }, s.doCreate)
+func Wrap[A, R any](l *Limiter, method string, client func(A) string, fn func(A, R) error) func(A, R) error {
+	return func(args A, reply R) error {
+		if err := l.Allow(client(args), method); err != nil {
+			return err
+		}
+		return fn(args, reply)
+	}
+}
+
+// clientOf returns the client identity of a connection.
+func clientOf(conn net.Conn) string {
+	addr := conn.RemoteAddr().String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// limited converts the wait millis reported by TakeN into a *limitron.LimitedError.
+func limited(waitMillis int64) *limitron.LimitedError {
+	if waitMillis > math.MaxInt64/int64(time.Millisecond) {
+		return &limitron.LimitedError{RetryAfter: math.MaxInt64}
+	}
+	return &limitron.LimitedError{RetryAfter: time.Duration(waitMillis) * time.Millisecond}
+}
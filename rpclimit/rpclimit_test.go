@@ -0,0 +1,122 @@
+package rpclimit
+
+import (
+	"errors"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iryndin/limitron"
+)
+
+type Arith struct{}
+
+type Args struct{ A, B int }
+
+func (Arith) Add(args Args, reply *int) error {
+	*reply = args.A + args.B
+	return nil
+}
+
+func (Arith) Mul(args Args, reply *int) error {
+	*reply = args.A * args.B
+	return nil
+}
+
+func newServer(t *testing.T) *rpc.Server {
+	t.Helper()
+	srv := rpc.NewServer()
+	if err := srv.Register(Arith{}); err != nil {
+		t.Fatal(err)
+	}
+	return srv
+}
+
+func TestLimiter_ServeConn(t *testing.T) {
+	lim := New(limitron.NewKeyedLimiter[string](limitron.BuildRateLimiter(3, time.Hour)))
+	lim.SetMethodLimit("Arith.Mul", limitron.NewKeyedLimiter[string](limitron.BuildRateLimiter(1, time.Hour)))
+
+	serverConn, clientConn := net.Pipe()
+	go lim.ServeConn(newServer(t), serverConn)
+	client := rpc.NewClient(clientConn)
+	defer client.Close()
+
+	var reply int
+	if err := client.Call("Arith.Mul", Args{2, 3}, &reply); err != nil || reply != 6 {
+		t.Fatalf("Mul => %d, %v", reply, err)
+	}
+	if err := client.Call("Arith.Mul", Args{2, 3}, &reply); err == nil || !strings.Contains(err.Error(), "rate limit exceeded") {
+		t.Fatalf("second Mul err = %v, want the method limit", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := client.Call("Arith.Add", Args{1, i}, &reply); err != nil || reply != 1+i {
+			t.Fatalf("Add %d => %d, %v", i, reply, err)
+		}
+	}
+	if err := client.Call("Arith.Add", Args{1, 1}, &reply); err == nil {
+		t.Fatal("Add over the client limit should fail")
+	}
+}
+
+func TestLimiter_ServeJSONConn(t *testing.T) {
+	lim := New(limitron.NewKeyedLimiter[string](limitron.BuildRateLimiter(1, time.Hour)))
+
+	serverConn, clientConn := net.Pipe()
+	go lim.ServeJSONConn(newServer(t), serverConn)
+	client := jsonrpc.NewClient(clientConn)
+	defer client.Close()
+
+	var reply int
+	if err := client.Call("Arith.Add", Args{1, 2}, &reply); err != nil || reply != 3 {
+		t.Fatalf("Add => %d, %v", reply, err)
+	}
+	if err := client.Call("Arith.Add", Args{1, 2}, &reply); err == nil || !strings.Contains(err.Error(), "retry after") {
+		t.Fatalf("second Add err = %v, want a rate limit error", err)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	lim := New(nil)
+	lim.SetMethodLimit("Orders.Create", limitron.NewKeyedLimiter[string](limitron.BuildRateLimiter(1, time.Hour)))
+
+	type createArgs struct{ APIKey string }
+	calls := 0
+	create := Wrap(lim, "Orders.Create", func(a createArgs) string { return a.APIKey },
+		func(createArgs, *int) error { calls++; return nil })
+
+	var reply int
+	if err := create(createArgs{"k1"}, &reply); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	var limited *limitron.LimitedError
+	if err := create(createArgs{"k1"}, &reply); !errors.As(err, &limited) || limited.RetryAfter <= 0 {
+		t.Fatalf("second call err = %v, want *LimitedError", err)
+	}
+	if err := create(createArgs{"k2"}, &reply); err != nil {
+		t.Fatalf("other client: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("fn called %d times, want 2", calls)
+	}
+}
+
+func TestLimiter_AllowRefundsMethodToken(t *testing.T) {
+	perClient := limitron.NewKeyedLimiter[string](limitron.BuildRateLimiter(1, time.Hour))
+	perMethod := limitron.NewKeyedLimiter[string](limitron.BuildRateLimiter(2, time.Hour))
+	lim := New(perClient)
+	lim.SetMethodLimit("Arith.Add", perMethod)
+
+	if err := lim.Allow("c1", "Arith.Add"); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	// denied by the client limit: the method token must be returned
+	if err := lim.Allow("c1", "Arith.Add"); err == nil {
+		t.Fatal("second call should be denied by the client limit")
+	}
+	if _, ok := perMethod.Peek("c1", 1); !ok {
+		t.Fatal("method token of the denied call was not returned")
+	}
+}
@@ -0,0 +1,87 @@
+package limitron
+
+import (
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// samplerCountMax is the saturation value of the 24-bit event counters of a Sampler.
+const samplerCountMax = 1<<24 - 1
+
+// Sampler admits approximately a fixed number of events per window regardless
+// of the input volume, for sampling traces, metrics or audit events.
+//
+// Unlike a RateLimiter, which admits the first events of every interval and drops
+// the rest, a Sampler spreads admissions over the window: it estimates the input
+// rate from the event counts of the current and the previous window and admits
+// each event with probability rate/estimate. As long as the input stays below
+// the rate, every event is admitted.
+//
+// The whole state is a single uint64 updated with a lock-free CAS loop, packed as follows:
+//
+//	64 bits: [ 24-bit previous window count ][ 24-bit current window count ][ 16-bit window number ]
+//
+// Counts saturate at 2^24-1 events per window.
+//
+// The zero value is not usable; create instances with NewSampler.
+// All methods are safe for concurrent use.
+type Sampler struct {
+	// rate is the target number of admitted events per window.
+	rate float64
+	// window is the window length in milliseconds.
+	window uint64
+	// state is the packed sampler state.
+	state uint64
+}
+
+// NewSampler returns a Sampler admitting approximately `rate` events per `per`.
+// A non-positive `per` defaults to one second.
+//
+// Example:
+//
+//	traces := NewSampler(100, time.Second)
+//	if traces.Sample() {
+//	    span.SetSampled(true)
+//	}
+func NewSampler(rate uint32, per time.Duration) *Sampler {
+	window := uint64(per.Milliseconds())
+	if window == 0 {
+		window = 1000
+	}
+	return &Sampler{rate: float64(rate), window: window}
+}
+
+// Sample records an event and reports whether it is admitted.
+//
+// If the state cannot be updated within UpdateRetries attempts due to contention,
+// the event is not admitted.
+func (s *Sampler) Sample() bool {
+	seq := uint64(time.Now().UnixMilli()) / s.window & 0xFFFF
+
+	for i := 0; i < UpdateRetries; i++ {
+		old := atomic.LoadUint64(&s.state)
+		prev, cur, oldSeq := old>>40, old>>16&samplerCountMax, old&0xFFFF
+
+		switch seq {
+		case oldSeq:
+		case (oldSeq + 1) & 0xFFFF:
+			prev, cur = cur, 0
+		default:
+			// at least one whole window without events
+			prev, cur = 0, 0
+		}
+		if cur < samplerCountMax {
+			cur++
+		}
+
+		if atomic.CompareAndSwapUint64(&s.state, old, prev<<40|cur<<16|seq) {
+			estimate := float64(max(prev, cur))
+			if estimate <= s.rate {
+				return true
+			}
+			return rand.Float64()*estimate < s.rate
+		}
+	}
+	return false
+}
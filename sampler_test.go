@@ -0,0 +1,54 @@
+package limitron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSampler_AdmitsAllBelowRate(t *testing.T) {
+	s := NewSampler(100, time.Hour)
+	for i := 0; i < 100; i++ {
+		if !s.Sample() {
+			t.Fatalf("event %d below the rate should be admitted", i)
+		}
+	}
+}
+
+func TestSampler_ApproximateRateUnderLoad(t *testing.T) {
+	s := NewSampler(100, 50*time.Millisecond)
+
+	// warm up one window so the previous-window estimate is known
+	deadline := time.Now().Add(50 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		s.Sample()
+	}
+
+	admitted, total := 0, 0
+	deadline = time.Now().Add(200 * time.Millisecond)
+	for time.Now().Before(deadline) {
+		total++
+		if s.Sample() {
+			admitted++
+		}
+	}
+	// 4 windows worth of budget; allow generous slack for window boundaries and randomness
+	if admitted < 200 || admitted > 1200 {
+		t.Fatalf("admitted %d of %d events in 200ms, want about 400", admitted, total)
+	}
+	if total < 10*admitted {
+		t.Skipf("input volume %d too low to exercise sampling", total)
+	}
+}
+
+func TestSampler_ResetsAfterIdle(t *testing.T) {
+	s := NewSampler(10, 20*time.Millisecond)
+	for i := 0; i < 1000; i++ {
+		s.Sample()
+	}
+	time.Sleep(50 * time.Millisecond)
+	for i := 0; i < 10; i++ {
+		if !s.Sample() {
+			t.Fatalf("event %d after idle windows should be admitted", i)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package limitron
+
+import (
+	"context"
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ShardedLimiter is a single (global) limit whose state is split into one shard
+// per processor, so that goroutines on different cores rarely update the same
+// cache line. Every shard is a bucket with 1/n-th of the burst and refill rate;
+// the remainder of the burst goes to the first shards, one token each.
+//
+// Goroutines pick shards with processor affinity: shard indices are handed out
+// through a sync.Pool, whose per-processor caches make a goroutine mostly reuse
+// the index last used on its processor, without runtime internals.
+//
+// A request its shard cannot serve borrows tokens from the other shards,
+// so the limiter as a whole admits up to the full burst. Balance (or Run)
+// periodically evens out the tokens of all shards, so that processors
+// with more traffic do not keep running dry first.
+//
+// The zero value is not usable; create instances with NewShardedLimiter.
+// All methods are safe for concurrent use.
+type ShardedLimiter struct {
+	// limiter is the limiter of every shard, except for the first `extra` shards,
+	// which use `larger` with one more token of burst.
+	limiter RateLimiter
+	larger  RateLimiter
+	extra   int
+	// burst is the burst of the limiter as a whole.
+	burst  uint16
+	shards []shardedState
+	// indices hands out shard indices with processor affinity.
+	indices sync.Pool
+	next    atomic.Uint32
+}
+
+// shardedState is the state of a shard; padded to a cache line.
+type shardedState struct {
+	state uint64
+	_     [56]byte
+}
+
+// NewShardedLimiter returns a ShardedLimiter enforcing `limiter` with one shard per
+// GOMAXPROCS, but no more shards than the burst size.
+//
+// Example:
+//
+//	limiter := NewShardedLimiter(BuildRateLimiterRps(50000))
+//	go limiter.Run(ctx, 100*time.Millisecond)
+//	if _, ok := limiter.Take1(); !ok {
+//	    // rate limited
+//	}
+func NewShardedLimiter(limiter RateLimiter) *ShardedLimiter {
+	return newShardedLimiter(limiter, runtime.GOMAXPROCS(0))
+}
+
+// newShardedLimiter returns a ShardedLimiter with `n` shards, but no more than the burst size.
+func newShardedLimiter(limiter RateLimiter, n int) *ShardedLimiter {
+	n = max(min(n, int(limiter.maxreq)), 1)
+	shard := limiter.scaled(1 / float64(n))
+	shard.maxreq = limiter.maxreq / uint16(n)
+	larger := shard
+	larger.maxreq++
+	sl := &ShardedLimiter{
+		limiter: shard,
+		larger:  larger,
+		extra:   int(limiter.maxreq) % n,
+		burst:   limiter.maxreq,
+		shards:  make([]shardedState, n),
+	}
+	for i := range sl.shards {
+		sl.shards[i].state = packUint16AndUint48(sl.limiterOf(i).maxreq, 0)
+	}
+	sl.indices.New = func() any {
+		i := int(sl.next.Add(1)-1) % n
+		return &i
+	}
+	return sl
+}
+
+// limiterOf returns the limiter of shard `i`.
+func (sl *ShardedLimiter) limiterOf(i int) RateLimiter {
+	if i < sl.extra {
+		return sl.larger
+	}
+	return sl.limiter
+}
+
+// Shards returns the number of shards.
+func (sl *ShardedLimiter) Shards() int {
+	return len(sl.shards)
+}
+
+// Take1 attempts to consume 1 token. See TakeN.
+func (sl *ShardedLimiter) Take1() (int64, bool) {
+	return sl.TakeN(1)
+}
+
+// TakeN attempts to consume `requests` tokens, first from the shard of the calling
+// processor, then borrowing from the others. Returns the same (waitMillis, ok) pair
+// as RateLimiter.TakeN for the limiter as a whole.
+func (sl *ShardedLimiter) TakeN(requests uint16) (int64, bool) {
+	if requests == 0 {
+		return 0, true
+	} else if requests > sl.burst {
+		return math.MaxInt64, false
+	}
+
+	idx := sl.indices.Get().(*int)
+	home := *idx
+	sl.indices.Put(idx)
+
+	if limiter := sl.limiterOf(home); requests <= limiter.maxreq {
+		if _, ok := limiter.takeN(&sl.shards[home].state, requests); ok {
+			return 0, true
+		}
+	}
+
+	// borrow from all shards, starting at home; roll back if they cannot cover the request
+	taken := make([]uint16, len(sl.shards))
+	var got uint16
+	for i := 0; i < len(sl.shards) && got < requests; i++ {
+		taken[i] = sl.takeUpTo((home+i)%len(sl.shards), requests-got)
+		got += taken[i]
+	}
+	if got == requests {
+		return 0, true
+	}
+	for i, n := range taken {
+		if n > 0 {
+			j := (home + i) % len(sl.shards)
+			sl.limiterOf(j).ReturnN(&sl.shards[j].state, n)
+		}
+	}
+	rate := sl.limiter.rrpm * float64(len(sl.shards))
+	return 1 + int64(float64(requests-got)/rate), false
+}
+
+// takeUpTo atomically consumes up to `n` tokens from shard `i` and returns how many it took.
+func (sl *ShardedLimiter) takeUpTo(i int, n uint16) uint16 {
+	rl := &sl.shards[i].state
+	limiter := sl.limiterOf(i)
+	for {
+		rlval := atomic.LoadUint64(rl)
+		req, ts := limiter.calcNewRequests(rlval)
+		took := min(req, n)
+		if took == 0 {
+			return 0
+		}
+		if atomic.CompareAndSwapUint64(rl, rlval, packUint16AndUint48(req-took, ts)) {
+			return took
+		}
+	}
+}
+
+// Available returns the tokens currently available in all shards together.
+func (sl *ShardedLimiter) Available() int {
+	total := 0
+	for i := range sl.shards {
+		req, _ := sl.limiterOf(i).calcNewRequests(atomic.LoadUint64(&sl.shards[i].state))
+		total += int(req)
+	}
+	return total
+}
+
+// Balance evens out the available tokens of all shards, moving tokens from shards
+// holding more than their fair share to the others. Concurrent takes may make
+// the result approximate, but tokens are never created.
+func (sl *ShardedLimiter) Balance() {
+	fair := uint16(sl.Available() / len(sl.shards))
+
+	// collect the surplus of shards above their fair share ...
+	var surplus int
+	for i := range sl.shards {
+		req, _ := sl.limiterOf(i).calcNewRequests(atomic.LoadUint64(&sl.shards[i].state))
+		if req > fair {
+			surplus += int(sl.takeUpTo(i, req-fair))
+		}
+	}
+	// ... and hand it out to those below it, then the rounding leftovers to any shard with room
+	for _, target := range []uint16{fair, sl.larger.maxreq} {
+		for i := range sl.shards {
+			if surplus == 0 {
+				return
+			}
+			limiter := sl.limiterOf(i)
+			rl := &sl.shards[i].state
+			req, _ := limiter.calcNewRequests(atomic.LoadUint64(rl))
+			if limit := min(target, limiter.maxreq); req < limit {
+				n := min(int(limit-req), surplus)
+				limiter.ReturnN(rl, uint16(n))
+				surplus -= n
+			}
+		}
+	}
+}
+
+// Run calls Balance every `every` until `ctx` is done.
+func (sl *ShardedLimiter) Run(ctx context.Context, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sl.Balance()
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package limitron
+
+import (
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestShardedLimiter_FullBurst(t *testing.T) {
+	limiter := NewShardedLimiter(BuildRateLimiter(100, time.Hour))
+	n := min(runtime.GOMAXPROCS(0), 100)
+	if limiter.Shards() != n {
+		t.Fatalf("Shards() = %d, want %d", limiter.Shards(), n)
+	}
+	burst := 100
+
+	var allowed atomic.Int64
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				if _, ok := limiter.Take1(); ok {
+					allowed.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if int(allowed.Load()) != burst {
+		t.Fatalf("allowed %d requests, want the burst of %d", allowed.Load(), burst)
+	}
+	if waitMillis, ok := limiter.Take1(); ok || waitMillis <= 0 {
+		t.Fatalf("Take1 = %d, %v after the burst", waitMillis, ok)
+	}
+}
+
+func TestShardedLimiter_UnevenBurst(t *testing.T) {
+	limiter := newShardedLimiter(BuildRateLimiter(100, time.Hour), 64)
+	if got := limiter.Available(); got != 100 {
+		t.Fatalf("Available() = %d, want the burst of 100", got)
+	}
+	if _, ok := limiter.TakeN(80); !ok {
+		t.Fatalf("TakeN(80) denied within the burst")
+	}
+	if waitMillis, ok := limiter.TakeN(21); ok || waitMillis == math.MaxInt64 {
+		t.Fatalf("TakeN(21) = %d, %v; want a finite wait", waitMillis, ok)
+	}
+	if waitMillis, _ := limiter.TakeN(101); waitMillis != math.MaxInt64 {
+		t.Fatalf("TakeN(101) = %d, want MaxInt64", waitMillis)
+	}
+	limiter.Balance()
+	if got := limiter.Available(); got != 20 {
+		t.Fatalf("Available() = %d after Balance, want 20", got)
+	}
+}
+
+func TestShardedLimiter_BorrowAndRollback(t *testing.T) {
+	limiter := newShardedLimiter(BuildRateLimiter(40, time.Hour), 4)
+
+	if _, ok := limiter.TakeN(25); !ok {
+		t.Fatalf("request spanning shards denied")
+	}
+	if got := limiter.Available(); got != 15 {
+		t.Fatalf("Available() = %d, want 15", got)
+	}
+	if _, ok := limiter.TakeN(16); ok {
+		t.Fatalf("request over the available tokens allowed")
+	}
+	if got := limiter.Available(); got != 15 {
+		t.Fatalf("Available() = %d after a denied request, want 15 (rolled back)", got)
+	}
+	if w, _ := limiter.TakeN(41); w != math.MaxInt64 {
+		t.Fatalf("wait for more than the total burst = %d, want MaxInt64", w)
+	}
+}
+
+func TestShardedLimiter_Balance(t *testing.T) {
+	limiter := newShardedLimiter(BuildRateLimiter(30, time.Hour), 3)
+	for i, req := range []uint16{10, 1, 0} {
+		limiter.shards[i].state = packUint16AndUint48(req, limiter.limiter.nowMillis())
+	}
+	limiter.Balance()
+
+	var got []uint16
+	for i := range limiter.shards {
+		req, _ := limiter.limiter.calcNewRequests(limiter.shards[i].state)
+		got = append(got, req)
+	}
+	if limiter.Available() != 11 || got[0] < 3 || got[0] > 5 || got[1] < 3 || got[2] < 3 {
+		t.Fatalf("shards after Balance = %v, want 11 tokens spread evenly", got)
+	}
+}
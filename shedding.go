@@ -0,0 +1,35 @@
+package limitron
+
+import (
+	"math"
+	"math/rand"
+)
+
+// WithShedding enables probabilistic load shedding near the limit: once fewer than
+// the `threshold` share (in (0, 1]) of the burst is left, takes are allowed with
+// a probability proportional to the tokens left, instead of all being allowed until
+// the bucket is empty and all denied after. This softens the synchronized retry
+// storms of clients that all hit the empty bucket at the same moment.
+//
+// Shed takes are denied with the wait of one token's refill, and consume nothing.
+// A non-positive threshold disables shedding (the default).
+//
+// Example:
+//
+//	// below 20 of 100 tokens, admit with probability tokens/20
+//	limiter := BuildRateLimiterRps(100, WithShedding(0.2))
+func WithShedding(threshold float64) Option {
+	return func(s *RateLimiter) {
+		s.shedding = 0
+		if threshold > 0 {
+			s.shedding = min(threshold, 1)
+		}
+	}
+}
+
+// shed reports whether a take allowed with `available` tokens is shed (see WithShedding).
+// The threshold is computed against the current burst.
+func (s RateLimiter) shed(available uint16) bool {
+	below := math.Ceil(float64(s.maxreq) * s.shedding)
+	return float64(available) < below && rand.Float64()*below >= float64(available)
+}
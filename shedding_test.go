@@ -0,0 +1,56 @@
+package limitron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithShedding_ProportionalAdmission(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	s := BuildRateLimiter(100, time.Hour, WithShedding(0.5), WithClock(clock))
+	now := uint64(clock.t.UnixMilli())
+
+	allowed := 0
+	const trials = 4000
+	for i := 0; i < trials; i++ {
+		// 25 of the 50 tokens of the threshold left: admitted half of the time
+		rl := packUint16AndUint48(25, now)
+		if _, ok := s.Take1(&rl); ok {
+			allowed++
+		} else if rl != packUint16AndUint48(25, now) {
+			t.Fatal("shed take consumed tokens")
+		}
+	}
+	if allowed < trials*4/10 || allowed > trials*6/10 {
+		t.Fatalf("admitted %d of %d takes at half the threshold, want about half", allowed, trials)
+	}
+}
+
+func TestWithShedding_AboveThresholdAlwaysAllowed(t *testing.T) {
+	s := BuildRateLimiter(100, time.Hour, WithShedding(0.2))
+	rl := s.New()
+	for i := 0; i < 80; i++ {
+		if _, ok := s.Take1(rl); !ok {
+			t.Fatalf("take %d denied above the threshold", i)
+		}
+	}
+}
+
+func TestWithShedding_FollowsTheBurst(t *testing.T) {
+	// the threshold is a share of the current burst, whatever the order of options
+	s := BuildRateLimiter(100, time.Hour, WithShedding(0.2), WithBurst(1000))
+	rl := s.New()
+	for i := 0; i < 800; i++ {
+		if _, ok := s.Take1(rl); !ok {
+			t.Fatalf("take %d denied above the threshold of 200", i)
+		}
+	}
+
+	scaled := BuildRateLimiter(100, time.Hour, WithShedding(0.2)).scaled(0.1)
+	rl = scaled.New()
+	for i := 0; i < 8; i++ {
+		if _, ok := scaled.Take1(rl); !ok {
+			t.Fatalf("scaled take %d denied above the threshold of 2", i)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package limitron
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// FlightGroup combines request coalescing (singleflight) with per-key rate limiting.
+//
+// Concurrent Do calls sharing the same key are collapsed into a single execution
+// of fn, and that execution itself consumes one token from the key's limiter state.
+// Callers that join an in-flight execution share its result and consume no tokens.
+//
+// Per-key states are kept in a KeyedLimiter (see States), so that they can be bounded
+// with WithMaxKeys or swept with EvictIdle and RunEviction like those of any other key.
+//
+// The zero value is not usable; create instances with NewFlightGroup.
+type FlightGroup[K comparable] struct {
+	states *KeyedLimiter[K]
+
+	mu    sync.Mutex
+	calls map[K]*flightCall
+}
+
+// flightCall is an in-flight or completed Do call.
+type flightCall struct {
+	wg   sync.WaitGroup
+	val  any
+	err  error
+	dups int
+	// panicked holds the value fn panicked with, re-panicked in every caller.
+	panicked *flightPanic
+}
+
+// flightPanic is the panic of the fn of a Do call, with the stack it was raised on.
+type flightPanic struct {
+	value any
+	stack []byte
+}
+
+func (p *flightPanic) Error() string {
+	return fmt.Sprintf("%v\n\n%s", p.value, p.stack)
+}
+
+// NewFlightGroup returns a FlightGroup that limits executions per key with `limiter`.
+// Options configure the KeyedLimiter holding the per-key states.
+//
+// Example:
+//
+//	group := NewFlightGroup[string](BuildRateLimiterRps(5), WithMaxKeys[string](100_000, nil))
+//	v, err, shared := group.Do("user:42", loadProfile)
+func NewFlightGroup[K comparable](limiter RateLimiter, opts ...KeyedOption[K]) *FlightGroup[K] {
+	return &FlightGroup[K]{
+		states: NewKeyedLimiter[K](limiter, opts...),
+		calls:  make(map[K]*flightCall),
+	}
+}
+
+// States returns the KeyedLimiter holding the per-key states, e.g., to evict idle keys.
+func (g *FlightGroup[K]) States() *KeyedLimiter[K] {
+	return g.states
+}
+
+// Do executes and returns the results of fn, making sure that only one execution
+// is in-flight for a given key at a time. If a duplicate call comes in while
+// an execution is in-flight, the duplicate caller waits for the original one
+// to complete and receives the same results.
+//
+// A new execution consumes one token from the key's limiter state. If no token
+// is available, fn is not called and Do returns a *LimitedError holding the
+// suggested wait. If fn panics, the panic is propagated to the original caller
+// and to every duplicate caller.
+//
+// The return value shared reports whether the result was given to multiple callers.
+func (g *FlightGroup[K]) Do(key K, fn func() (any, error)) (v any, err error, shared bool) {
+	g.mu.Lock()
+	if c, ok := g.calls[key]; ok {
+		c.dups++
+		g.mu.Unlock()
+		c.wg.Wait()
+		if c.panicked != nil {
+			panic(c.panicked)
+		}
+		return c.val, c.err, true
+	}
+
+	c := new(flightCall)
+	c.wg.Add(1)
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	g.doCall(c, key, fn)
+	if c.panicked != nil {
+		panic(c.panicked)
+	}
+	return c.val, c.err, c.dups > 0
+}
+
+// doCall consumes a token for the key and runs fn if allowed. A panic of fn is
+// recovered and recorded, so that duplicate callers are released and re-panic
+// instead of taking the crashed call for a success.
+func (g *FlightGroup[K]) doCall(c *flightCall, key K, fn func() (any, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.panicked = &flightPanic{value: r, stack: debug.Stack()}
+		}
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		c.wg.Done()
+	}()
+
+	if waitMillis, taken := g.states.Take1(key); !taken {
+		c.err = limitedError(waitMillis)
+		return
+	}
+	c.val, c.err = fn()
+}
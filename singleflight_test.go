@@ -0,0 +1,136 @@
+package limitron
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFlightGroup_DoCoalescesConcurrentCalls(t *testing.T) {
+	g := NewFlightGroup[string](BuildRateLimiter(1, time.Hour))
+
+	var calls int32
+	release := make(chan struct{})
+	fn := func() (any, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "value", nil
+	}
+
+	const workers = 10
+	var wg sync.WaitGroup
+	var sharedCount int32
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			v, err, shared := g.Do("k", fn)
+			if err != nil || v != "value" {
+				t.Errorf("Do => v=%v err=%v, want value,nil", v, err)
+			}
+			if shared {
+				atomic.AddInt32(&sharedCount, 1)
+			}
+		}()
+	}
+
+	// Let the duplicates join the in-flight call before releasing it.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Fatalf("fn executed %d times, want 1", got)
+	}
+	if got := atomic.LoadInt32(&sharedCount); got != workers {
+		t.Fatalf("shared results = %d, want %d", got, workers)
+	}
+}
+
+func TestFlightGroup_DoRespectsPerKeyLimit(t *testing.T) {
+	g := NewFlightGroup[string](BuildRateLimiter(2, time.Hour))
+	fn := func() (any, error) { return 1, nil }
+
+	for i := 0; i < 2; i++ {
+		if _, err, _ := g.Do("a", fn); err != nil {
+			t.Fatalf("call %d: unexpected error %v", i, err)
+		}
+	}
+
+	_, err, _ := g.Do("a", fn)
+	var limited *LimitedError
+	if !errors.As(err, &limited) {
+		t.Fatalf("err = %v, want *LimitedError", err)
+	}
+	if limited.RetryAfter <= 0 {
+		t.Fatalf("RetryAfter = %s, want positive", limited.RetryAfter)
+	}
+
+	// Other keys have their own budget.
+	if _, err, _ := g.Do("b", fn); err != nil {
+		t.Fatalf("key b: unexpected error %v", err)
+	}
+}
+
+func TestFlightGroup_DoRecoversAfterPanic(t *testing.T) {
+	g := NewFlightGroup[string](BuildRateLimiterRps(10))
+
+	func() {
+		defer func() { _ = recover() }()
+		_, _, _ = g.Do("k", func() (any, error) { panic("boom") })
+	}()
+
+	v, err, _ := g.Do("k", func() (any, error) { return "ok", nil })
+	if err != nil || v != "ok" {
+		t.Fatalf("Do after panic => v=%v err=%v, want ok,nil", v, err)
+	}
+}
+
+func TestFlightGroup_DoPanicsInDuplicates(t *testing.T) {
+	g := NewFlightGroup[string](BuildRateLimiterRps(10))
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	do := func(fn func() (any, error)) (recovered any) {
+		defer func() { recovered = recover() }()
+		g.Do("k", fn)
+		return nil
+	}
+	original := make(chan any, 1)
+	go func() {
+		original <- do(func() (any, error) {
+			close(started)
+			<-release
+			panic("boom")
+		})
+	}()
+	<-started
+
+	duplicate := make(chan any, 1)
+	go func() {
+		duplicate <- do(func() (any, error) { return "ok", nil })
+	}()
+	// Let the duplicate join the in-flight call before it panics.
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	for name, ch := range map[string]chan any{"original": original, "duplicate": duplicate} {
+		p, ok := (<-ch).(*flightPanic)
+		if !ok || p.value != "boom" {
+			t.Fatalf("%s caller recovered %v, want the panic of fn", name, p)
+		}
+	}
+}
+
+func TestFlightGroup_StatesAreBounded(t *testing.T) {
+	g := NewFlightGroup[int](BuildRateLimiterRps(10), WithMaxKeys[int](64, nil))
+	fn := func() (any, error) { return nil, nil }
+	for key := 0; key < 1000; key++ {
+		g.Do(key, fn)
+	}
+	if n := g.States().Len(); n > 64 {
+		t.Fatalf("group keeps %d key states, want at most 64", n)
+	}
+}
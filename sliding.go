@@ -0,0 +1,133 @@
+package limitron
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// SlidingWindow is the name of the SlidingWindowLimiter algorithm in the registry (see NewLimiter).
+const SlidingWindow = "sliding-window"
+
+func init() {
+	RegisterAlgorithm(SlidingWindow, func(req uint16, interval time.Duration, opts ...Option) Limiter {
+		return BuildSlidingWindowLimiter(req, interval, opts...)
+	})
+}
+
+// SlidingWindowLimiter is a sliding window counter rate limiter: it allows `req`
+// requests in any window of `interval`, estimating the requests of the sliding window
+// from the counts of the current and the previous fixed window, the previous count
+// weighted by the part of it still inside the sliding window.
+//
+// Unlike a token bucket, which refills continuously and lets a full burst through
+// right after a window's requests, it does not over-admit at window boundaries.
+//
+// It stores the entire per-key state in a single uint64 updated lock-free:
+// the current window's count in the upper 16 bits, the previous window's count
+// in the next 16 bits, and the index of the current window (Unix time divided
+// by the interval, modulo 2^32) in the lower 32 bits.
+//
+// Options WithClock and WithMetrics apply as for RateLimiter; WithPunitive has no effect.
+type SlidingWindowLimiter struct {
+	// base holds the limit (maxreq), CAS retries, clock and metrics.
+	base RateLimiter
+	// window is the window length in milliseconds.
+	window uint64
+}
+
+// BuildSlidingWindowLimiter returns a SlidingWindowLimiter allowing up to `req` requests
+// in any window of `interval`. The interval is truncated to whole milliseconds.
+//
+// Example:
+//
+//	limiter := BuildSlidingWindowLimiter(1000, time.Hour) // billed quota: 1000 calls per hour
+//	state := limiter.New()
+//	if wait, ok := limiter.Take1(state); !ok {
+//	    // retry after `wait` millis
+//	}
+func BuildSlidingWindowLimiter(req uint16, interval time.Duration, opts ...Option) SlidingWindowLimiter {
+	return SlidingWindowLimiter{
+		base:   BuildRateLimiter(req, interval, opts...),
+		window: uint64(max(interval.Milliseconds(), 1)),
+	}
+}
+
+// New creates a brand-new limiter state with no requests counted.
+func (l SlidingWindowLimiter) New() *uint64 {
+	var rl uint64
+	return &rl
+}
+
+// Take1 attempts to count 1 request. See TakeN.
+func (l SlidingWindowLimiter) Take1(rl *uint64) (int64, bool) {
+	return l.TakeN(rl, 1)
+}
+
+// TakeN attempts to atomically count `requests` requests in the limiter state `*rl`.
+//
+// It has the same contract as RateLimiter.TakeN: it returns 0, true if the requests
+// are allowed, or the number of millis to wait before they would be, and false.
+// If `requests > req`, it returns (math.MaxInt64, false) immediately.
+func (l SlidingWindowLimiter) TakeN(rl *uint64, requests uint16) (int64, bool) {
+	return l.base.observe(l.takeNAt(rl, requests, l.base.nowMillis()))
+}
+
+// takeNAt is TakeN without metrics, evaluated at time `now` in Unix milliseconds.
+func (l SlidingWindowLimiter) takeNAt(rl *uint64, requests uint16, now uint64) (int64, bool) {
+	if requests == 0 {
+		return 0, true
+	} else if requests > l.base.maxreq {
+		return math.MaxInt64, false
+	}
+
+	index := uint32(now / l.window)
+	elapsed := now % l.window
+	for i := 0; i < l.base.retries; i++ {
+		rlval := atomic.LoadUint64(rl)
+		cur, prev := l.countsAt(rlval, index)
+
+		// the previous window's requests still inside the sliding window
+		weight := float64(l.window-elapsed) / float64(l.window)
+		if float64(prev)*weight+float64(cur)+float64(requests) > float64(l.base.maxreq) {
+			return l.wait(cur, prev, requests, elapsed), false
+		}
+		if atomic.CompareAndSwapUint64(rl, rlval, packSliding(cur+requests, prev, index)) {
+			return 0, true
+		}
+	}
+	return 1, false
+}
+
+// countsAt returns the counts of the window `index` and of the one before it, from state `rl`.
+func (l SlidingWindowLimiter) countsAt(rl uint64, index uint32) (cur, prev uint16) {
+	cur, prev, stored := uint16(rl>>48), uint16(rl>>32), uint32(rl)
+	switch index - stored {
+	case 0:
+		return cur, prev
+	case 1:
+		return 0, cur
+	default:
+		return 0, 0
+	}
+}
+
+// wait returns the millis until `requests` more requests fit the sliding window,
+// given the counts of the current and previous windows at `elapsed` millis into
+// the current window.
+func (l SlidingWindowLimiter) wait(cur, prev, requests uint16, elapsed uint64) int64 {
+	limit := float64(l.base.maxreq)
+	// within the current window, the previous window's weight must drop enough
+	if room := limit - float64(cur) - float64(requests); room >= 0 && prev > 0 {
+		at := (1 - room/float64(prev)) * float64(l.window)
+		return max(1, int64(math.Ceil(at))-int64(elapsed))
+	}
+	// otherwise, in the next window, the current window's weight must
+	at := (1 - (limit-float64(requests))/float64(cur)) * float64(l.window)
+	return int64(l.window-elapsed) + max(int64(math.Ceil(at)), 0)
+}
+
+// packSliding packs the counts of the window `index` and of the one before it.
+func packSliding(cur, prev uint16, index uint32) uint64 {
+	return uint64(cur)<<48 | uint64(prev)<<32 | uint64(index)
+}
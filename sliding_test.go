@@ -0,0 +1,80 @@
+package limitron
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+var _ Limiter = SlidingWindowLimiter{}
+
+func TestSlidingWindowLimiter_NoBoundaryBurst(t *testing.T) {
+	// 10:00:00.000 is the start of a window
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	l := BuildSlidingWindowLimiter(10, time.Second, WithClock(clock))
+	state := l.New()
+
+	// the whole limit at the end of a window
+	clock.t = clock.t.Add(900 * time.Millisecond)
+	if _, ok := l.TakeN(state, 10); !ok {
+		t.Fatal("10 requests denied")
+	}
+
+	// right after the boundary, the previous window still weighs 90%
+	clock.t = clock.t.Add(200 * time.Millisecond) // 10:00:01.100
+	if _, ok := l.TakeN(state, 2); ok {
+		t.Fatal("2 requests allowed with 9 still in the sliding window")
+	}
+	wait, ok := l.Take1(state)
+	if !ok {
+		t.Fatalf("1 request denied with 9 in the sliding window, wait %d", wait)
+	}
+
+	// 10 * 0.9 + 1 = 10: the next request fits once the weight drops below 0.8
+	wait, ok = l.Take1(state)
+	if ok || wait != 100 {
+		t.Fatalf("Take1 = %d, %v, want 100, false", wait, ok)
+	}
+	clock.t = clock.t.Add(time.Duration(wait) * time.Millisecond)
+	if _, ok := l.Take1(state); !ok {
+		t.Fatal("request denied after the suggested wait")
+	}
+}
+
+func TestSlidingWindowLimiter_WaitIntoNextWindow(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	l := BuildSlidingWindowLimiter(4, time.Second, WithClock(clock))
+	state := l.New()
+
+	clock.t = clock.t.Add(250 * time.Millisecond)
+	l.TakeN(state, 4)
+	// the next window starts in 750ms, and then 1 request fits once 3/4 of
+	// the 4 requests left the sliding window
+	wait, ok := l.Take1(state)
+	if ok || wait != 1000 {
+		t.Fatalf("Take1 = %d, %v, want 1000, false", wait, ok)
+	}
+	clock.t = clock.t.Add(999 * time.Millisecond)
+	if _, ok := l.Take1(state); ok {
+		t.Fatal("request allowed before the suggested wait")
+	}
+	clock.t = clock.t.Add(time.Millisecond)
+	if _, ok := l.Take1(state); !ok {
+		t.Fatal("request denied after the suggested wait")
+	}
+}
+
+func TestSlidingWindowLimiter_ForgetsOldWindows(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	l := BuildSlidingWindowLimiter(3, time.Second, WithClock(clock))
+	state := l.New()
+	l.TakeN(state, 3)
+
+	clock.t = clock.t.Add(2 * time.Second)
+	if _, ok := l.TakeN(state, 3); !ok {
+		t.Fatal("full limit denied two windows later")
+	}
+	if wait, ok := l.TakeN(state, 4); ok || wait != math.MaxInt64 {
+		t.Fatalf("TakeN over the limit = %d, %v", wait, ok)
+	}
+}
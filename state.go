@@ -0,0 +1,99 @@
+package limitron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// State is a typed holder of a packed limiter state, for code that passes states around
+// instead of naked *uint64 values. Pass Raw() to the limiter methods:
+//
+//	st := limiter.NewState()
+//	if _, ok := limiter.Take1(st.Raw()); !ok { ... }
+//
+// The zero value is an empty state with no tokens; use RateLimiter.NewState for a full one.
+// A State must not be copied after first use. All methods are safe for concurrent use.
+type State struct {
+	// v is the packed state; the first field, so that it is 64-bit aligned on 32-bit platforms.
+	v uint64
+}
+
+// NewState creates a brand-new, zero-use limiter state, like New.
+func (s RateLimiter) NewState() *State {
+	return &State{v: s.initialState()}
+}
+
+// Raw returns the packed state, for the methods of RateLimiter and the other limiters.
+func (st *State) Raw() *uint64 {
+	return &st.v
+}
+
+// Load returns the packed value of the state.
+func (st *State) Load() uint64 {
+	return atomic.LoadUint64(&st.v)
+}
+
+// Store sets the packed value of the state, e.g., to restore a saved one.
+func (st *State) Store(rlval uint64) {
+	atomic.StoreUint64(&st.v, rlval)
+}
+
+// Tokens returns the tokens recorded in the state at its last update, not including the
+// refill since. Use RateLimiter.Available for the tokens available now.
+func (st *State) Tokens() uint16 {
+	tokens, _ := unpackUint16Uint48(st.Load())
+	return tokens
+}
+
+// LastAccess returns the time recorded in the state at its last update, or the zero time
+// for a state never updated. The refill clock keeps the progress towards the next token,
+// so it may precede the actual update by up to the refill time of one token.
+//
+// It assumes Unix milliseconds: states of limiters using WithMicroseconds are decoded
+// by KeyedLimiter.LastAccess instead.
+func (st *State) LastAccess() time.Time {
+	_, ts := unpackUint16Uint48(st.Load())
+	if ts == 0 {
+		return time.Time{}
+	}
+	return time.UnixMilli(int64(ts))
+}
+
+// String returns the tokens and last access of the state, for logs and debugging.
+func (st *State) String() string {
+	rlval := st.Load()
+	tokens, ts := unpackUint16Uint48(rlval)
+	if ts == 0 {
+		return fmt.Sprintf("State{tokens: %d, never accessed}", tokens)
+	}
+	return fmt.Sprintf("State{tokens: %d, last access: %s}", tokens,
+		time.UnixMilli(int64(ts)).UTC().Format(time.RFC3339Nano))
+}
+
+// MarshalText encodes the state as "<tokens>@<timestamp>", the timestamp being the raw
+// refill clock of the state (Unix milliseconds unless the limiter uses WithMicroseconds).
+func (st *State) MarshalText() ([]byte, error) {
+	tokens, ts := unpackUint16Uint48(st.Load())
+	return []byte(strconv.FormatUint(uint64(tokens), 10) + "@" + strconv.FormatUint(ts, 10)), nil
+}
+
+// UnmarshalText decodes a state encoded by MarshalText.
+func (st *State) UnmarshalText(text []byte) error {
+	tokensPart, tsPart, ok := strings.Cut(string(text), "@")
+	if !ok {
+		return fmt.Errorf("limitron: state %q: want <tokens>@<timestamp>", text)
+	}
+	tokens, err := strconv.ParseUint(tokensPart, 10, 16)
+	if err != nil {
+		return fmt.Errorf("limitron: state %q: tokens: %w", text, err)
+	}
+	ts, err := strconv.ParseUint(tsPart, 10, 48)
+	if err != nil {
+		return fmt.Errorf("limitron: state %q: timestamp: %w", text, err)
+	}
+	st.Store(packUint16AndUint48(uint16(tokens), ts))
+	return nil
+}
@@ -0,0 +1,50 @@
+package limitron
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestState_WithLimiter(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	limiter := BuildRateLimiter(10, time.Second, WithClock(clock))
+	st := limiter.NewState()
+
+	if st.Tokens() != 10 || !st.LastAccess().IsZero() {
+		t.Fatalf("new state = %s, want 10 tokens, never accessed", st)
+	}
+	if _, ok := limiter.TakeN(st.Raw(), 4); !ok {
+		t.Fatal("take from a State denied")
+	}
+	if st.Tokens() != 6 || !st.LastAccess().Equal(clock.t) {
+		t.Fatalf("state = %s, want 6 tokens accessed at %v", st, clock.t)
+	}
+	if got, want := st.String(), "State{tokens: 6, last access: 2026-05-01T10:00:00Z}"; got != want {
+		t.Fatalf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestState_TextRoundTrip(t *testing.T) {
+	limiter := BuildRateLimiterRps(10)
+	st := limiter.NewState()
+	limiter.TakeN(st.Raw(), 3)
+
+	data, err := json.Marshal(map[string]*State{"k": st})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored map[string]*State
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatal(err)
+	}
+	if restored["k"].Load() != st.Load() {
+		t.Fatalf("restored %s from %s, want %s", restored["k"], data, st)
+	}
+
+	for _, bad := range []string{"", "5", "x@1", "70000@1", "1@-1"} {
+		if err := new(State).UnmarshalText([]byte(bad)); err == nil {
+			t.Errorf("UnmarshalText(%q): no error", bad)
+		}
+	}
+}
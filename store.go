@@ -0,0 +1,248 @@
+package limitron
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Store holds packed limiter states (see RateLimiter) by key, so that the limiting
+// algorithms can run against pluggable backends: process memory, Redis, DynamoDB,
+// memory-mapped files, and so on. Third parties can add backends by implementing
+// Store, without touching the algorithms.
+//
+// A missing (or expired) key has state 0. Implementations must be safe
+// for concurrent use, and CompareAndSet must be atomic with respect to
+// other CompareAndSet calls on the same key, possibly from other processes.
+type Store interface {
+	// Get returns the state of `key`, or 0 if it is missing.
+	Get(ctx context.Context, key string) (uint64, error)
+
+	// CompareAndSet sets the state of `key` to `new` if it currently is `old`
+	// (0 matching a missing key) and reports whether it did.
+	CompareAndSet(ctx context.Context, key string, old, new uint64) (bool, error)
+
+	// Expire makes `key` expire after `ttl` unless it is set again meanwhile.
+	Expire(ctx context.Context, key string, ttl time.Duration) error
+}
+
+// StoreLimiter applies a RateLimiter to states kept in a Store.
+//
+// The algorithm is the same as RateLimiter.TakeN, with the CAS loop running
+// against the store, so that the options of the limiter (debt, minimum spacing,
+// punitive mode, shedding and metrics) apply alike. After every update the key is
+// set to expire once its bucket would be full again, since a full bucket is
+// equivalent to a missing key. With WithStartEmpty, keys are stored on first use
+// and never expire.
+//
+// By default every call checks the store synchronously (Strict consistency);
+// see WithConsistency for the Eventual mode, where the options apply to the
+// local decisions and reconciliation merges the consumed tokens.
+type StoreLimiter struct {
+	store   Store
+	limiter RateLimiter
+
+	// eventual holds the local states in Eventual mode; nil in Strict mode.
+	eventual *eventualStates
+}
+
+// StoreOption configures optional behavior of a StoreLimiter.
+type StoreOption func(*StoreLimiter)
+
+// NewStoreLimiter returns a StoreLimiter applying `limiter` to the states in `store`.
+//
+// Example:
+//
+//	sl := NewStoreLimiter(NewMemoryStore(), BuildRateLimiterRps(10))
+//	if _, ok, err := sl.Take1(ctx, "user:42"); err == nil && !ok {
+//	    // rate limited
+//	}
+func NewStoreLimiter(store Store, limiter RateLimiter, opts ...StoreOption) *StoreLimiter {
+	sl := &StoreLimiter{store: store, limiter: limiter}
+	for _, opt := range opts {
+		opt(sl)
+	}
+	return sl
+}
+
+// TakeN attempts to consume `requests` tokens of `key`. See RateLimiter.TakeN.
+//
+// Returns a non-nil error only if the store failed; the limiter decision
+// is undefined in that case.
+func (sl *StoreLimiter) TakeN(ctx context.Context, key string, requests uint16) (int64, bool, error) {
+	s := sl.limiter
+	if requests == 0 {
+		return 0, true, nil
+	} else if uint32(requests) > uint32(s.maxreq)+uint32(s.debt) {
+		waitMillis, ok := s.observe(math.MaxInt64, false)
+		return waitMillis, ok, nil
+	}
+	if sl.eventual != nil {
+		waitMillis, ok := sl.eventual.take(key, requests)
+		return waitMillis, ok, nil
+	}
+
+	now := s.nowTicks()
+	for i := 0; i < s.retries; i++ {
+		old, err := sl.store.Get(ctx, key)
+		if err != nil {
+			return 0, false, err
+		}
+		rlval := old
+		if rlval == 0 {
+			rlval = s.initialState()
+		}
+
+		next, write, waitMillis, ok := s.step(rlval, requests, now)
+		if !write {
+			if old == 0 && s.startEmpty {
+				// persist the empty bucket, so that it refills instead of starting over empty
+				if _, err := sl.store.CompareAndSet(ctx, key, 0, rlval); err != nil {
+					return 0, false, err
+				}
+			}
+			waitMillis, ok = s.observe(waitMillis, ok)
+			return waitMillis, ok, nil
+		}
+
+		swapped, err := sl.store.CompareAndSet(ctx, key, old, next)
+		if err != nil {
+			return 0, false, err
+		}
+		if swapped {
+			err = sl.expire(ctx, key, next, now)
+		}
+		if swapped || !ok {
+			// denials that update the state (see WithPunitive) make a single attempt
+			waitMillis, ok = s.observe(waitMillis, ok)
+			return waitMillis, ok, err
+		}
+	}
+	waitMillis, ok := s.observe(1, false)
+	return waitMillis, ok, nil
+}
+
+// Take1 attempts to consume 1 token of `key`. See TakeN.
+func (sl *StoreLimiter) Take1(ctx context.Context, key string) (int64, bool, error) {
+	return sl.TakeN(ctx, key, 1)
+}
+
+// Close writes the consumption pending in write-behind batches to the store
+// and stops batching (see WithWriteBehind). Later calls are written through
+// right away. Close is a no-op for other limiters.
+func (sl *StoreLimiter) Close() error {
+	if sl.eventual != nil {
+		sl.eventual.close()
+	}
+	return nil
+}
+
+// expire sets `key` to expire once its bucket in state `state` would be full again.
+// With WithStartEmpty a missing key is an empty bucket rather than a full one, so keys never expire.
+func (sl *StoreLimiter) expire(ctx context.Context, key string, state uint64, now uint64) error {
+	if sl.limiter.startEmpty {
+		return nil
+	}
+	return sl.store.Expire(ctx, key, sl.refillTime(state, now))
+}
+
+// refillTime returns the time the bucket in state `state` needs to become full at time
+// `now` in state clock ticks, plus a millisecond. A refill clock in the future (owed
+// debt, or the next admission with WithMinSpacing) delays the refill until then.
+func (sl *StoreLimiter) refillTime(state uint64, now uint64) time.Duration {
+	s := sl.limiter
+	req, ts := unpackUint16Uint48(state)
+	missing := math.Ceil(float64(s.maxreq-min(req, s.maxreq)) / s.rrpt())
+	if ts > now {
+		missing += float64(ts - now)
+	}
+	return time.Millisecond + s.tickDuration(uint64(missing))
+}
+
+// MemoryStore is a Store kept in process memory. Expired keys are removed
+// lazily on access, or explicitly by Purge.
+//
+// The zero value is not usable; create instances with NewMemoryStore.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]memoryStoreEntry
+}
+
+type memoryStoreEntry struct {
+	state uint64
+	// expires is the expiry time in Unix milliseconds; 0 means never.
+	expires int64
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]memoryStoreEntry)}
+}
+
+// Get implements Store.
+func (m *MemoryStore) Get(_ context.Context, key string) (uint64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.getLocked(key, time.Now().UnixMilli()).state, nil
+}
+
+// CompareAndSet implements Store.
+func (m *MemoryStore) CompareAndSet(_ context.Context, key string, old, new uint64) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e := m.getLocked(key, time.Now().UnixMilli())
+	if e.state != old {
+		return false, nil
+	}
+	e.state = new
+	m.entries[key] = e
+	return true, nil
+}
+
+// Expire implements Store.
+func (m *MemoryStore) Expire(_ context.Context, key string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UnixMilli()
+	if e, ok := m.entries[key]; ok && !e.expired(now) {
+		e.expires = now + ttl.Milliseconds()
+		m.entries[key] = e
+	}
+	return nil
+}
+
+// Len returns the number of stored keys, including expired ones not yet purged.
+func (m *MemoryStore) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.entries)
+}
+
+// Purge removes all expired keys.
+func (m *MemoryStore) Purge() {
+	now := time.Now().UnixMilli()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for k, e := range m.entries {
+		if e.expired(now) {
+			delete(m.entries, k)
+		}
+	}
+}
+
+// getLocked returns the live entry of `key`, removing it if expired. Must be called with m.mu held.
+func (m *MemoryStore) getLocked(key string, now int64) memoryStoreEntry {
+	e, ok := m.entries[key]
+	if ok && e.expired(now) {
+		delete(m.entries, key)
+		return memoryStoreEntry{}
+	}
+	return e
+}
+
+func (e memoryStoreEntry) expired(now int64) bool {
+	return e.expires != 0 && now >= e.expires
+}
@@ -0,0 +1,160 @@
+package limitron
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestStoreLimiter_TakeN(t *testing.T) {
+	ctx := context.Background()
+	sl := NewStoreLimiter(NewMemoryStore(), BuildRateLimiter(3, 300*time.Millisecond))
+
+	for i := 0; i < 3; i++ {
+		if _, ok, err := sl.Take1(ctx, "a"); err != nil || !ok {
+			t.Fatalf("Take1 %d => ok=%v err=%v, want allowed", i, ok, err)
+		}
+	}
+	wait, ok, err := sl.Take1(ctx, "a")
+	if err != nil || ok || wait < 1 || wait > 101 {
+		t.Fatalf("Take1 over burst => wait=%d ok=%v err=%v, want about 100ms,false", wait, ok, err)
+	}
+	if wait, ok, _ := sl.TakeN(ctx, "b", 4); ok || wait != math.MaxInt64 {
+		t.Fatalf("TakeN over maxreq => wait=%d ok=%v", wait, ok)
+	}
+
+	time.Sleep(110 * time.Millisecond)
+	if _, ok, _ := sl.Take1(ctx, "a"); !ok {
+		t.Fatal("Take1 after refill should be allowed")
+	}
+}
+
+func TestStoreLimiter_StartEmpty(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	sl := NewStoreLimiter(store, BuildRateLimiter(2, 100*time.Millisecond, WithStartEmpty()))
+
+	// the denied first take stores the empty bucket, which then refills
+	if _, ok, _ := sl.Take1(ctx, "a"); ok {
+		t.Fatal("first take of an empty bucket allowed")
+	}
+	if store.Len() != 1 {
+		t.Fatalf("empty bucket not stored")
+	}
+	time.Sleep(120 * time.Millisecond)
+	if _, ok, err := sl.TakeN(ctx, "a", 2); err != nil || !ok {
+		t.Fatalf("refilled bucket denied: ok=%v err=%v", ok, err)
+	}
+
+	// the key does not expire back to an empty bucket once full
+	time.Sleep(120 * time.Millisecond)
+	if _, ok, _ := sl.Take1(ctx, "a"); !ok {
+		t.Fatal("idle key came back empty")
+	}
+}
+
+func TestStoreLimiter_Debt(t *testing.T) {
+	ctx := context.Background()
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	sl := NewStoreLimiter(NewMemoryStore(), BuildRateLimiter(10, 100*time.Millisecond, WithDebt(20), WithClock(clock)))
+
+	// 25 tokens with 10 available: 15 on debt, as with RateLimiter.TakeN
+	if wait, ok, err := sl.TakeN(ctx, "a", 25); err != nil || !ok {
+		t.Fatalf("oversized request => wait=%d ok=%v err=%v, want allowed", wait, ok, err)
+	}
+	wait, ok, err := sl.Take1(ctx, "a")
+	if err != nil || ok || wait < 150 || wait > 161 {
+		t.Fatalf("Take1 in debt => wait=%d ok=%v err=%v, want about 160ms,false", wait, ok, err)
+	}
+	if wait, ok, _ := sl.TakeN(ctx, "b", 31); ok || wait != math.MaxInt64 {
+		t.Fatalf("TakeN over maxreq and debt => wait=%d ok=%v", wait, ok)
+	}
+
+	clock.t = clock.t.Add(160 * time.Millisecond)
+	if _, ok, err := sl.Take1(ctx, "a"); err != nil || !ok {
+		t.Fatalf("Take1 after the repayment => ok=%v err=%v, want allowed", ok, err)
+	}
+}
+
+func TestStoreLimiter_Options(t *testing.T) {
+	ctx := context.Background()
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+
+	// WithMinSpacing admits one request per 100ms even with a full bucket
+	spaced := NewStoreLimiter(NewMemoryStore(), BuildRateLimiter(10, time.Second, WithMinSpacing(), WithClock(clock)))
+	if _, ok, _ := spaced.Take1(ctx, "a"); !ok {
+		t.Fatal("first spaced take denied")
+	}
+	if wait, ok, _ := spaced.Take1(ctx, "a"); ok || wait < 99 || wait > 101 {
+		t.Fatalf("second spaced take => wait=%d ok=%v, want about 100ms,false", wait, ok)
+	}
+
+	// WithPunitive pushes the refill back on every denied attempt
+	punitive := NewStoreLimiter(NewMemoryStore(), BuildRateLimiter(1, time.Second, WithPunitive(300*time.Millisecond), WithClock(clock)))
+	punitive.Take1(ctx, "a")
+	clock.t = clock.t.Add(400 * time.Millisecond)
+	if wait, ok, _ := punitive.Take1(ctx, "a"); ok || wait < 899 || wait > 901 {
+		t.Fatalf("punished take => wait=%d ok=%v, want about 900ms,false", wait, ok)
+	}
+	clock.t = clock.t.Add(700 * time.Millisecond)
+	if _, ok, _ := punitive.Take1(ctx, "a"); ok {
+		t.Fatal("punished key allowed before the penalty elapsed")
+	}
+
+	// metrics see every decision
+	hist := NewWaitHistogram(10*time.Millisecond, time.Second)
+	observed := NewStoreLimiter(NewMemoryStore(), BuildRateLimiter(1, time.Second, WithMetrics(hist), WithClock(clock)))
+	observed.Take1(ctx, "a")
+	observed.Take1(ctx, "a")
+	observed.TakeN(ctx, "a", 2)
+	if snap := hist.Snapshot(); snap.Allowed != 1 || snap.Denied != 2 {
+		t.Fatalf("allowed=%d denied=%d, want 1 and 2", snap.Allowed, snap.Denied)
+	}
+}
+
+func TestStoreLimiter_ConcurrentAdmitsBurst(t *testing.T) {
+	ctx := context.Background()
+	sl := NewStoreLimiter(NewMemoryStore(), BuildRateLimiterFull(50, time.Hour, 1000))
+
+	var admitted atomic.Int64
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				if _, ok, _ := sl.Take1(ctx, "k"); ok {
+					admitted.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if got := admitted.Load(); got != 50 {
+		t.Fatalf("admitted %d, want exactly the burst of 50", got)
+	}
+}
+
+func TestMemoryStore_ExpiresToMissing(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStore()
+	if ok, _ := m.CompareAndSet(ctx, "k", 0, 42); !ok {
+		t.Fatal("CompareAndSet from 0 should match a missing key")
+	}
+	if ok, _ := m.CompareAndSet(ctx, "k", 0, 43); ok {
+		t.Fatal("CompareAndSet with a stale old value should fail")
+	}
+	_ = m.Expire(ctx, "k", 20*time.Millisecond)
+
+	time.Sleep(30 * time.Millisecond)
+	if st, _ := m.Get(ctx, "k"); st != 0 {
+		t.Fatalf("expired key has state %d, want 0", st)
+	}
+	m.Purge()
+	if m.Len() != 0 {
+		t.Fatalf("Len after Purge = %d, want 0", m.Len())
+	}
+}
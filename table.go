@@ -0,0 +1,176 @@
+package limitron
+
+import (
+	"hash/maphash"
+	"math/bits"
+	"sync/atomic"
+)
+
+// StateTable maps uint64 keys to the packed states of a RateLimiter in a fixed-capacity,
+// open-addressing hash table that takes no lock at all, for key spaces where the map
+// and shard locks of a KeyedLimiter become the bottleneck (millions of keys at hundreds
+// of thousands of lookups per second).
+//
+// Slots are (key, state) pairs in a single array probed linearly. A key claims an empty
+// slot with a CAS and keeps it for the lifetime of the table, so lookups are plain atomic
+// loads and the state is updated with the usual CAS loop of the limiter. Keys are never
+// removed: size the table for the number of distinct keys it will see, and replace it
+// with a new one to start over. Once `capacity` keys are stored, further keys share a
+// single overflow state, so that the table never grows and new keys are limited together
+// rather than let through.
+//
+// Wider keys such as IPv6 addresses or strings can be hashed to 64 bits first; at a few
+// million keys, collisions of a good 64-bit hash are negligible.
+//
+// The zero value is not usable; create instances with NewStateTable.
+// All methods are safe for concurrent use.
+type StateTable struct {
+	limiter RateLimiter
+	seed    maphash.Seed
+	slots   []tableSlot
+	mask    uint64
+	// capacity is the maximum number of keys; count is the number of keys stored.
+	capacity int64
+	count    atomic.Int64
+	// zero holds the state of key 0, which marks empty slots; its key is 1 once used.
+	zero tableSlot
+	// overflow is the state shared by the keys that did not fit.
+	overflow uint64
+}
+
+// tableSlot is a slot of a StateTable. A zero key marks an empty slot;
+// a zero state marks a claimed slot whose state is not initialized yet.
+type tableSlot struct {
+	key   uint64
+	state uint64
+}
+
+// NewStateTable returns an empty StateTable applying `limiter` to up to `capacity` keys.
+// The table preallocates 16 bytes per slot, with at least 4 slots for every 3 keys.
+//
+// Example:
+//
+//	perIP := NewStateTable(BuildRateLimiterRps(10), 5_000_000)
+//	ip4 := clientIP.As4()
+//	if _, ok := perIP.Take1(uint64(binary.BigEndian.Uint32(ip4[:]))); !ok {
+//	    // rate limited
+//	}
+func NewStateTable(limiter RateLimiter, capacity int) *StateTable {
+	capacity = max(capacity, 1)
+	n := 1 << bits.Len(uint(capacity+capacity/3))
+	return &StateTable{
+		limiter:  limiter,
+		seed:     maphash.MakeSeed(),
+		slots:    make([]tableSlot, n),
+		mask:     uint64(n - 1),
+		capacity: int64(capacity),
+		overflow: limiter.initialState(),
+	}
+}
+
+// Limiter returns the RateLimiter applied to every key.
+func (t *StateTable) Limiter() RateLimiter {
+	return t.limiter
+}
+
+// Len returns the number of keys with a state.
+func (t *StateTable) Len() int {
+	return int(t.count.Load())
+}
+
+// Cap returns the maximum number of keys with their own state.
+func (t *StateTable) Cap() int {
+	return int(t.capacity)
+}
+
+// TakeN attempts to consume `requests` tokens from the state of `key`,
+// creating the state if needed. See RateLimiter.TakeN.
+func (t *StateTable) TakeN(key uint64, requests uint16) (int64, bool) {
+	return t.limiter.TakeN(t.State(key), requests)
+}
+
+// Take1 is TakeN for 1 token.
+func (t *StateTable) Take1(key uint64) (int64, bool) {
+	return t.TakeN(key, 1)
+}
+
+// State returns the packed state of `key`, creating it if needed, for use with
+// the methods of Limiter() that take a state. When the table is full, it returns
+// the overflow state shared by all keys that did not fit.
+func (t *StateTable) State(key uint64) *uint64 {
+	if key == 0 {
+		if atomic.CompareAndSwapUint64(&t.zero.key, 0, 1) {
+			t.count.Add(1)
+		}
+		return t.ready(&t.zero)
+	}
+	i := mixUint64(t.seed, key) & t.mask
+	for n := uint64(0); n <= t.mask; n++ {
+		s := &t.slots[i]
+		k := atomic.LoadUint64(&s.key)
+		if k == key {
+			return t.ready(s)
+		}
+		if k == 0 {
+			// slots are never emptied, so the key is not stored further along the probe
+			if t.count.Add(1) > t.capacity {
+				t.count.Add(-1)
+				return &t.overflow
+			}
+			if atomic.CompareAndSwapUint64(&s.key, 0, key) {
+				return t.ready(s)
+			}
+			t.count.Add(-1)
+			if atomic.LoadUint64(&s.key) == key {
+				return t.ready(s)
+			}
+		}
+		i = (i + 1) & t.mask
+	}
+	return &t.overflow
+}
+
+// Lookup returns the packed state of `key`, or nil if it has none.
+func (t *StateTable) Lookup(key uint64) *uint64 {
+	if key == 0 {
+		if atomic.LoadUint64(&t.zero.key) == 0 {
+			return nil
+		}
+		return t.ready(&t.zero)
+	}
+	i := mixUint64(t.seed, key) & t.mask
+	for n := uint64(0); n <= t.mask; n++ {
+		s := &t.slots[i]
+		switch atomic.LoadUint64(&s.key) {
+		case key:
+			return t.ready(s)
+		case 0:
+			return nil
+		}
+		i = (i + 1) & t.mask
+	}
+	return nil
+}
+
+// Range calls `f` with every key and its current packed state, until `f` returns false.
+// Keys added concurrently may or may not be visited.
+func (t *StateTable) Range(f func(key, state uint64) bool) {
+	if atomic.LoadUint64(&t.zero.key) != 0 && !f(0, atomic.LoadUint64(t.ready(&t.zero))) {
+		return
+	}
+	for i := range t.slots {
+		s := &t.slots[i]
+		if k := atomic.LoadUint64(&s.key); k != 0 && !f(k, atomic.LoadUint64(t.ready(s))) {
+			return
+		}
+	}
+}
+
+// ready returns the state of claimed slot `s`, initializing it if the claiming goroutine
+// has not done so yet. Initial states are never zero, so whoever comes first wins.
+func (t *StateTable) ready(s *tableSlot) *uint64 {
+	if atomic.LoadUint64(&s.state) == 0 {
+		atomic.CompareAndSwapUint64(&s.state, 0, t.limiter.initialState())
+	}
+	return &s.state
+}
@@ -0,0 +1,93 @@
+package limitron
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStateTable(t *testing.T) {
+	tab := NewStateTable(BuildRateLimiter(2, time.Hour), 1000)
+	if tab.Cap() != 1000 || len(tab.slots) < 1334 {
+		t.Fatalf("Cap() = %d with %d slots", tab.Cap(), len(tab.slots))
+	}
+	for _, key := range []uint64{0, 1, 42, 1 << 63} {
+		for i := 0; i < 2; i++ {
+			if _, ok := tab.Take1(key); !ok {
+				t.Fatalf("key %d: take %d denied", key, i)
+			}
+		}
+		if _, ok := tab.Take1(key); ok {
+			t.Fatalf("key %d: allowed more than the burst", key)
+		}
+	}
+	if tab.Len() != 4 {
+		t.Fatalf("Len() = %d, want 4", tab.Len())
+	}
+	if tab.Lookup(7) != nil {
+		t.Fatalf("Lookup of a missing key returned a state")
+	}
+	if s := tab.Lookup(42); s == nil || tab.Limiter().Available(s) != 0 {
+		t.Fatalf("Lookup(42) = %v", s)
+	}
+
+	seen := map[uint64]bool{}
+	tab.Range(func(key, state uint64) bool {
+		seen[key] = true
+		return true
+	})
+	if len(seen) != 4 || !seen[0] || !seen[1<<63] {
+		t.Fatalf("Range visited %v", seen)
+	}
+}
+
+func TestStateTable_Overflow(t *testing.T) {
+	tab := NewStateTable(BuildRateLimiter(3, time.Hour), 10)
+	for key := uint64(1); key <= 10; key++ {
+		tab.Take1(key)
+	}
+	if tab.Len() != 10 {
+		t.Fatalf("Len() = %d, want 10", tab.Len())
+	}
+
+	// keys beyond the capacity share one state instead of being let through
+	for key := uint64(11); key <= 13; key++ {
+		if _, ok := tab.Take1(key); !ok {
+			t.Fatalf("overflow key %d denied", key)
+		}
+	}
+	if _, ok := tab.Take1(100); ok {
+		t.Fatalf("overflow state allowed more than the burst")
+	}
+	if tab.Len() != 10 || tab.Lookup(11) != nil {
+		t.Fatalf("overflow keys were stored")
+	}
+	// stored keys keep their own state
+	if _, ok := tab.TakeN(1, 2); !ok {
+		t.Fatalf("stored key denied")
+	}
+}
+
+func TestStateTable_Concurrent(t *testing.T) {
+	tab := NewStateTable(BuildRateLimiter(100, time.Hour), 10_000)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for key := uint64(0); key < 5000; key++ {
+				tab.Take1(key)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if tab.Len() != 5000 {
+		t.Fatalf("Len() = %d, want 5000", tab.Len())
+	}
+	for key := uint64(0); key < 5000; key++ {
+		if got := tab.Limiter().Available(tab.Lookup(key)); got != 92 {
+			t.Fatalf("key %d has %d tokens, want 92", key, got)
+		}
+	}
+}
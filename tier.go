@@ -0,0 +1,105 @@
+package limitron
+
+import (
+	"fmt"
+	"time"
+)
+
+// WithTierFunc assigns every key of a KeyedLimiter to the tier (e.g., free, paid or
+// enterprise plan) returned by fn. Keys of a tier with a limiter set by SetTierLimiter
+// are limited by it instead of the default limiter; all other keys use the default.
+// fn is called on every take and should be fast; returning a per-key tier makes
+// overrides for individual keys possible.
+//
+// Example:
+//
+//	kl := NewKeyedLimiter(BuildRateLimiter(100, time.Hour), WithTierFunc(planOf))
+//	kl.SetTierLimiter("paid", BuildRateLimiter(1000, time.Hour))
+//	kl.SetTierLimiter("enterprise", BuildRateLimiter(10000, time.Hour))
+func WithTierFunc[K comparable](fn func(key K) string) KeyedOption[K] {
+	return func(kl *KeyedLimiter[K]) {
+		kl.tier = fn
+	}
+}
+
+// SetTierLimiter sets the limiter of the keys of tier `tier` at runtime (see WithTierFunc).
+// The limiter is resolved on every take, so keys switch to it on their next request and
+// continue with the tokens they have left, capped at the new burst.
+//
+// States of all tiers share the clock of the default limiter, which replaces the clock of
+// `limiter`. An error is returned if `limiter` stores states with another tick resolution
+// than the default one (see WithMicroseconds).
+func (kl *KeyedLimiter[K]) SetTierLimiter(tier string, limiter RateLimiter) error {
+	if limiter.micros != kl.limiter.micros {
+		return fmt.Errorf("limitron: tier %q limiter has another tick resolution than the default limiter", tier)
+	}
+	limiter.clock = kl.limiter.clock
+	kl.updateTiers(func(tiers map[string]RateLimiter) {
+		tiers[tier] = limiter
+	})
+	return nil
+}
+
+// DeleteTierLimiter removes the limiter of tier `tier`; its keys fall back to the default limiter.
+func (kl *KeyedLimiter[K]) DeleteTierLimiter(tier string) {
+	kl.updateTiers(func(tiers map[string]RateLimiter) {
+		delete(tiers, tier)
+	})
+}
+
+// TierLimiter returns the limiter of tier `tier`, reporting false if it has none.
+func (kl *KeyedLimiter[K]) TierLimiter(tier string) (RateLimiter, bool) {
+	tiers := kl.tiers.Load()
+	if tiers == nil {
+		return RateLimiter{}, false
+	}
+	limiter, ok := (*tiers)[tier]
+	return limiter, ok
+}
+
+// updateTiers applies `fn` to a copy of the tier limiters and publishes it.
+func (kl *KeyedLimiter[K]) updateTiers(fn func(map[string]RateLimiter)) {
+	kl.tiersMu.Lock()
+	defer kl.tiersMu.Unlock()
+
+	tiers := make(map[string]RateLimiter)
+	if old := kl.tiers.Load(); old != nil {
+		for k, v := range *old {
+			tiers[k] = v
+		}
+	}
+	fn(tiers)
+	kl.tiers.Store(&tiers)
+}
+
+// limiterOf returns the limiter configured for `key`: the limiter of its tier, or the default.
+func (kl *KeyedLimiter[K]) limiterOf(key K) RateLimiter {
+	if kl.tier == nil {
+		return kl.limiter
+	}
+	tiers := kl.tiers.Load()
+	if tiers == nil {
+		return kl.limiter
+	}
+	if limiter, ok := (*tiers)[kl.tier(key)]; ok {
+		return limiter
+	}
+	return kl.limiter
+}
+
+// refillTime returns the longest time the bucket of any key takes to refill completely,
+// across the default limiter and the tier limiters.
+func (kl *KeyedLimiter[K]) refillTime() time.Duration {
+	refill := func(l RateLimiter) time.Duration {
+		return time.Duration(float64(l.maxreq) / l.rrpm * float64(time.Millisecond))
+	}
+	longest := refill(kl.limiter)
+	if tiers := kl.tiers.Load(); tiers != nil {
+		for _, l := range *tiers {
+			if l.rrpm > 0 {
+				longest = max(longest, refill(l))
+			}
+		}
+	}
+	return longest
+}
@@ -0,0 +1,51 @@
+package limitron
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeyedLimiter_Tiers(t *testing.T) {
+	planOf := func(key string) string {
+		plan, _, _ := strings.Cut(key, ":")
+		return plan
+	}
+	kl := NewKeyedLimiter(BuildRateLimiter(2, time.Hour), WithTierFunc(planOf))
+	if err := kl.SetTierLimiter("paid", BuildRateLimiter(5, time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := kl.TakeN("free:a", 3); ok {
+		t.Fatalf("free key allowed more than the default burst")
+	}
+	// a new paid key starts with the burst of its tier
+	if _, ok := kl.TakeN("paid:b", 5); !ok {
+		t.Fatalf("paid key denied its tier burst")
+	}
+	if d := kl.Take1Result("paid:c"); !d.Allowed || d.Limit != 5 || d.Remaining != 4 {
+		t.Fatalf("paid decision = %+v", d)
+	}
+	if l, ok := kl.TierLimiter("paid"); !ok || l.maxreq != 5 {
+		t.Fatalf("TierLimiter(paid) = %v, %v", l.maxreq, ok)
+	}
+
+	// keys switch limiters on their next request, keeping the tokens they have left up to the new burst
+	kl.DeleteTierLimiter("paid")
+	if _, ok := kl.TakeN("paid:c", 2); !ok {
+		t.Fatalf("paid key lost its tokens when its tier was removed")
+	}
+	if _, ok := kl.Take1("paid:c"); ok {
+		t.Fatalf("removed tier still applies")
+	}
+	if _, ok := kl.TierLimiter("paid"); ok {
+		t.Fatalf("removed tier still reported")
+	}
+}
+
+func TestKeyedLimiter_TierResolution(t *testing.T) {
+	kl := NewKeyedLimiter(BuildRateLimiter(2, time.Hour), WithTierFunc(func(string) string { return "x" }))
+	if err := kl.SetTierLimiter("x", BuildRateLimiter(5, time.Hour, WithMicroseconds())); err == nil {
+		t.Fatalf("tier with another tick resolution accepted")
+	}
+}
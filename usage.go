@@ -0,0 +1,156 @@
+package limitron
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// mapEntryOverhead approximates the per-entry bookkeeping of a Go map
+// (hash byte, bucket slack and overflow pointers), in bytes.
+const mapEntryOverhead = 16
+
+// NamespaceUsage is the approximate memory usage of a namespace of a KeyedLimiter.
+type NamespaceUsage struct {
+	// Keys is the number of keys with a state.
+	Keys int
+	// Bytes approximates the memory held by the keys and their states,
+	// including map overhead and the contents of string keys.
+	Bytes int64
+	// Evictions is the number of keys removed since the limiter was created.
+	Evictions uint64
+}
+
+// keyedUsage holds the eviction counters of a KeyedLimiter by namespace.
+type keyedUsage struct {
+	evictions sync.Map // namespace -> *atomic.Uint64
+}
+
+// MemoryUsage returns the approximate memory usage of every namespace
+// (see WithNamespaceFunc), for capacity planning of high-cardinality deployments.
+// Namespaces whose keys were all evicted are reported with zero keys.
+//
+// It walks all keys, one shard at a time, so call it periodically rather than per request.
+//
+// Example:
+//
+//	for ns, u := range kl.MemoryUsage() {
+//	    log.Printf("namespace=%q keys=%d bytes=%d evictions=%d", ns, u.Keys, u.Bytes, u.Evictions)
+//	}
+func (kl *KeyedLimiter[K]) MemoryUsage() map[string]NamespaceUsage {
+	usage := make(map[string]NamespaceUsage)
+	for i := range kl.shards {
+		sh := &kl.shards[i]
+		sh.mu.RLock()
+		for k := range sh.entries {
+			ns := kl.namespaceOf(k)
+			u := usage[ns]
+			u.Keys++
+			u.Bytes += kl.entryBytes(k)
+			usage[ns] = u
+		}
+		sh.mu.RUnlock()
+	}
+	kl.usage.evictions.Range(func(ns, n any) bool {
+		u := usage[ns.(string)]
+		u.Evictions = n.(*atomic.Uint64).Load()
+		usage[ns.(string)] = u
+		return true
+	})
+	return usage
+}
+
+// EvictIdle removes the states of all keys that have not been accessed for
+// at least `idle` and returns how many were removed.
+//
+// An evicted key starts over with a full bucket. Unlike RunEviction, EvictIdle uses
+// `idle` as given: if it is shorter than the time the bucket takes to refill, a client
+// pausing for `idle` gets its full burst back early, above the configured rate.
+//
+// Example:
+//
+//	go func() {
+//	    for range time.Tick(time.Minute) {
+//	        kl.EvictIdle(10 * time.Minute)
+//	    }
+//	}()
+func (kl *KeyedLimiter[K]) EvictIdle(idle time.Duration) int {
+	cutoff := time.UnixMilli(int64(kl.limiter.nowMillis())).Add(-idle)
+	n := 0
+	for i := range kl.shards {
+		sh := &kl.shards[i]
+		sh.mu.Lock()
+		for k, e := range sh.entries {
+			// a new key counts as accessed at creation, before its first update
+			last := max(stateLastAccess(kl.limiter, atomic.LoadUint64(&e.state)).UnixMilli(), int64(e.created))
+			if !time.UnixMilli(last).After(cutoff) {
+				delete(sh.entries, k)
+				kl.unlinkLRU(sh, e)
+				if kl.hot != nil {
+					kl.hot.remove(hashKey(kl.seed, k), e)
+				}
+				kl.evicted(k)
+				n++
+			}
+		}
+		sh.mu.Unlock()
+	}
+	return n
+}
+
+// RunEviction calls EvictIdle every `every` until `ctx` is done, as a background sweeper
+// of idle keys. `idle` is raised to the time the bucket takes to refill if shorter (the longest
+// of all tiers, see WithTierFunc), so that an evicted key never comes back with more tokens
+// than it would have had. A non-positive `every` defaults to the raised `idle`.
+//
+// Example:
+//
+//	go perIP.RunEviction(ctx, 10*time.Minute, time.Minute)
+func (kl *KeyedLimiter[K]) RunEviction(ctx context.Context, idle, every time.Duration) {
+	if every <= 0 {
+		every = max(idle, kl.refillTime(), time.Millisecond)
+	}
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			kl.EvictIdle(max(idle, kl.refillTime()))
+		}
+	}
+}
+
+// evicted counts the eviction of `key` in its namespace.
+func (kl *KeyedLimiter[K]) evicted(key K) {
+	ns := kl.namespaceOf(key)
+	n, ok := kl.usage.evictions.Load(ns)
+	if !ok {
+		n, _ = kl.usage.evictions.LoadOrStore(ns, new(atomic.Uint64))
+	}
+	n.(*atomic.Uint64).Add(1)
+}
+
+// namespaceOf returns the namespace of `key`.
+func (kl *KeyedLimiter[K]) namespaceOf(key K) string {
+	if kl.namespace == nil {
+		return ""
+	}
+	return kl.namespace(key)
+}
+
+// entryBytes approximates the memory held by the map entry of `key` and its state.
+func (kl *KeyedLimiter[K]) entryBytes(key K) int64 {
+	n := int64(unsafe.Sizeof(key)) + int64(unsafe.Sizeof(&keyedEntry{})) +
+		int64(unsafe.Sizeof(keyedEntry{})) + mapEntryOverhead
+	if kl.lru != nil {
+		n += int64(unsafe.Sizeof(lruNode[K]{}))
+	}
+	if s, ok := any(key).(string); ok {
+		n += int64(len(s))
+	}
+	return n
+}
@@ -0,0 +1,91 @@
+package limitron
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeyedLimiter_MemoryUsage(t *testing.T) {
+	kl := NewKeyedLimiter[string](BuildRateLimiterRps(10),
+		WithNamespaceFunc(func(key string) string {
+			ns, _, _ := strings.Cut(key, "/")
+			return ns
+		}))
+	kl.Take1("a/1")
+	kl.Take1("a/2")
+	kl.Take1("b/a-much-longer-key")
+
+	usage := kl.MemoryUsage()
+	if usage["a"].Keys != 2 || usage["b"].Keys != 1 {
+		t.Fatalf("usage = %+v, want 2 keys in a and 1 in b", usage)
+	}
+	if usage["a"].Bytes <= 0 || usage["b"].Bytes <= usage["a"].Bytes/2 {
+		t.Errorf("usage bytes = %+v, want longer keys to count more", usage)
+	}
+}
+
+func TestKeyedLimiter_EvictIdle(t *testing.T) {
+	kl := NewKeyedLimiter[string](BuildRateLimiterRps(10),
+		WithNamespaceFunc(func(key string) string { return key[:1] }))
+	kl.Take1("a1")
+	kl.Take1("a2")
+	time.Sleep(60 * time.Millisecond)
+	kl.Take1("b1")
+
+	if n := kl.EvictIdle(50 * time.Millisecond); n != 2 {
+		t.Fatalf("evicted %d keys, want 2", n)
+	}
+	if kl.Len() != 1 {
+		t.Fatalf("Len() = %d after eviction, want 1", kl.Len())
+	}
+
+	usage := kl.MemoryUsage()
+	if u := usage["a"]; u.Keys != 0 || u.Bytes != 0 || u.Evictions != 2 {
+		t.Errorf(`usage["a"] = %+v, want 0 keys and 2 evictions`, u)
+	}
+	if u := usage["b"]; u.Keys != 1 || u.Evictions != 0 {
+		t.Errorf(`usage["b"] = %+v, want 1 key and no evictions`, u)
+	}
+}
+
+func TestKeyedLimiter_RunEviction(t *testing.T) {
+	kl := NewKeyedLimiter[string](BuildRateLimiter(10, 20*time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		kl.RunEviction(ctx, time.Millisecond, 5*time.Millisecond) // idle raised to the 20ms refill
+		close(done)
+	}()
+
+	kl.TakeN("a", 10)
+	time.Sleep(10 * time.Millisecond)
+	if kl.Len() != 1 {
+		t.Fatal("key evicted before its bucket refilled")
+	}
+	time.Sleep(40 * time.Millisecond)
+	if kl.Len() != 0 {
+		t.Fatal("idle key not evicted")
+	}
+	cancel()
+	<-done
+}
+
+func TestKeyedLimiter_RunEvictionDefaultInterval(t *testing.T) {
+	kl := NewKeyedLimiter[string](BuildRateLimiter(10, 20*time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		kl.RunEviction(ctx, time.Millisecond, 0) // sweeps every 20ms
+		close(done)
+	}()
+
+	kl.TakeN("a", 10)
+	time.Sleep(80 * time.Millisecond)
+	if kl.Len() != 0 {
+		t.Fatal("idle key not evicted")
+	}
+	cancel()
+	<-done
+}
@@ -0,0 +1,62 @@
+package limitron
+
+// VectorLimiter limits several linked budgets (dimensions) at once, e.g., requests,
+// bytes and compute units of a multi-metered API.
+//
+// Each dimension has its own RateLimiter; the per-identity state is a small slice
+// holding one packed uint64 state per dimension, created with New().
+// TakeN consumes from all dimensions or from none: if any dimension denies,
+// the dimensions already consumed are rolled back, so a denial never leaks
+// partial consumption.
+type VectorLimiter struct {
+	dims []RateLimiter
+}
+
+// NewVectorLimiter returns a VectorLimiter with one dimension per limiter, in order.
+//
+// Example:
+//
+//	v := NewVectorLimiter(
+//	    BuildRateLimiterRps(100),              // requests
+//	    BuildRateLimiter(50000, time.Second),  // kilobytes
+//	)
+//	st := v.New()
+//	_, ok := v.TakeN(st, 1, 256) // 1 request of 256 KB
+func NewVectorLimiter(dims ...RateLimiter) VectorLimiter {
+	return VectorLimiter{dims: append([]RateLimiter(nil), dims...)}
+}
+
+// Dims returns the number of dimensions.
+func (v VectorLimiter) Dims() int {
+	return len(v.dims)
+}
+
+// New creates brand-new, zero-use states, one per dimension.
+func (v VectorLimiter) New() []uint64 {
+	states := make([]uint64, len(v.dims))
+	for i, d := range v.dims {
+		states[i] = *d.New()
+	}
+	return states
+}
+
+// TakeN attempts to consume costs[i] tokens from dimension i, for every dimension, atomically
+// with respect to denials: either all dimensions are consumed, or none is.
+//
+// Returns (0, true) on success, or the wait in millis reported by the first denying dimension
+// and false. Panics if the number of states or costs differs from the number of dimensions.
+func (v VectorLimiter) TakeN(states []uint64, costs ...uint16) (int64, bool) {
+	if len(states) != len(v.dims) || len(costs) != len(v.dims) {
+		panic("limitron: VectorLimiter.TakeN: states and costs must match the number of dimensions")
+	}
+
+	for i, d := range v.dims {
+		if waitMillis, ok := d.TakeN(&states[i], costs[i]); !ok {
+			for j := 0; j < i; j++ {
+				v.dims[j].ReturnN(&states[j], costs[j])
+			}
+			return waitMillis, false
+		}
+	}
+	return 0, true
+}
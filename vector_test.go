@@ -0,0 +1,42 @@
+package limitron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVectorLimiter_AllOrNothing(t *testing.T) {
+	v := NewVectorLimiter(
+		BuildRateLimiter(10, time.Hour),  // requests
+		BuildRateLimiter(100, time.Hour), // bytes
+	)
+	st := v.New()
+
+	if _, ok := v.TakeN(st, 1, 60); !ok {
+		t.Fatal("first take should succeed")
+	}
+	// requests budget is fine, bytes budget is not: nothing must be consumed
+	if wait, ok := v.TakeN(st, 1, 60); ok || wait <= 0 {
+		t.Fatalf("take over bytes budget => wait=%d ok=%v, want positive,false", wait, ok)
+	}
+	if req, _ := unpackUint16Uint48(st[0]); req != 9 {
+		t.Fatalf("requests tokens = %d, want 9 (denied take rolled back)", req)
+	}
+
+	if _, ok := v.TakeN(st, 1, 40); !ok {
+		t.Fatal("take within both budgets should succeed")
+	}
+	if req, _ := unpackUint16Uint48(st[1]); req != 0 {
+		t.Fatalf("bytes tokens = %d, want 0", req)
+	}
+}
+
+func TestVectorLimiter_PanicsOnDimensionMismatch(t *testing.T) {
+	v := NewVectorLimiter(BuildRateLimiterRps(1), BuildRateLimiterRps(1))
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic on cost/dimension mismatch")
+		}
+	}()
+	v.TakeN(v.New(), 1)
+}
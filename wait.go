@@ -0,0 +1,140 @@
+package limitron
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// waitConfig holds the settings of a single WaitN call.
+type waitConfig struct {
+	maxWait time.Duration
+}
+
+// WaitOption configures a blocking WaitN call.
+type WaitOption func(*waitConfig)
+
+// WithMaxWait caps the total time WaitN may block at `d`.
+// If the tokens cannot be obtained within `d`, WaitN returns a *LimitedError
+// immediately, without sleeping, so that callers can translate it into
+// an immediate denial with an appropriate Retry-After.
+// A non-positive `d` means no cap (the default).
+func WithMaxWait(d time.Duration) WaitOption {
+	return func(c *waitConfig) {
+		c.maxWait = d
+	}
+}
+
+// WaitN blocks until `requests` tokens are consumed from the limiter state `*rl`,
+// or until ctx is done.
+//
+// It sleeps the wait suggested by TakeN and re-checks the state on wake, since
+// other users of the same state may have consumed the refilled tokens meanwhile.
+//
+// Returns:
+//   - nil once the tokens were consumed
+//   - ctx.Err() if ctx is done while waiting
+//   - *LimitedError if the tokens cannot be obtained before ctx's deadline
+//     or the WithMaxWait cap; in this case WaitN returns without sleeping
+//   - *LimitedError with the maximum RetryAfter if `requests > maxreq`,
+//     since such a request can never succeed
+func (s RateLimiter) WaitN(ctx context.Context, rl *uint64, requests uint16, opts ...WaitOption) error {
+	return wait(ctx, func() (int64, bool) { return s.TakeN(rl, requests) }, s.metrics, opts)
+}
+
+// Wait is WaitN for any Limiter, e.g., one built by NewLimiter: it blocks until
+// `requests` are admitted by `l` for the state `*rl`, or until ctx is done.
+// It returns the same errors as RateLimiter.WaitN.
+//
+// Limiters admitting requests with a pacing delay, such as LeakyBucketLimiter,
+// are waited for until the delay elapses too; if ctx is done during that delay,
+// Wait returns ctx.Err() with the requests admitted.
+//
+// Example:
+//
+//	limiter, _ := NewLimiter(GCRA, 100, time.Second)
+//	state := limiter.New()
+//	if err := Wait(ctx, limiter, state, 1); err != nil {
+//	    return err
+//	}
+func Wait(ctx context.Context, l Limiter, rl *uint64, requests uint16, opts ...WaitOption) error {
+	var metrics Metrics
+	if s, ok := l.(RateLimiter); ok {
+		metrics = s.metrics
+	}
+	return wait(ctx, func() (int64, bool) { return l.TakeN(rl, requests) }, metrics, opts)
+}
+
+// WaitN blocks until `requests` tokens are consumed from the state of `key`,
+// or until ctx is done. It returns the same errors as RateLimiter.WaitN.
+func (kl *KeyedLimiter[K]) WaitN(ctx context.Context, key K, requests uint16, opts ...WaitOption) error {
+	return wait(ctx, func() (int64, bool) { return kl.TakeN(key, requests) }, kl.limiter.metrics, opts)
+}
+
+// wait implements the WaitN loop around `take`, reporting the time blocked to `metrics`, if any.
+func wait(ctx context.Context, take func() (int64, bool), metrics Metrics, opts []WaitOption) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var cfg waitConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var deadline time.Time
+	if cfg.maxWait > 0 {
+		deadline = time.Now().Add(cfg.maxWait)
+	}
+	if d, ok := ctx.Deadline(); ok && (deadline.IsZero() || d.Before(deadline)) {
+		deadline = d
+	}
+
+	start := time.Now()
+	var timer *time.Timer
+	defer func() {
+		if timer != nil {
+			timer.Stop()
+		}
+	}()
+	sleep := func(d time.Duration) error {
+		if timer == nil {
+			timer = time.NewTimer(d)
+		} else {
+			timer.Reset(d)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return nil
+		}
+	}
+
+	for {
+		waitMillis, ok := take()
+		if ok {
+			// a pacing delay of an admitted request
+			if waitMillis > 0 {
+				if err := sleep(millisToDuration(waitMillis)); err != nil {
+					return err
+				}
+			}
+			if metrics != nil {
+				metrics.ObserveWait(time.Since(start))
+			}
+			return nil
+		}
+		if waitMillis == math.MaxInt64 {
+			return limitedError(waitMillis)
+		}
+
+		d := millisToDuration(waitMillis)
+		if !deadline.IsZero() && time.Now().Add(d).After(deadline) {
+			return &LimitedError{RetryAfter: d}
+		}
+		if err := sleep(d); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package limitron
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWaitN_BlocksUntilRefill(t *testing.T) {
+	s := BuildRateLimiterRps(20) // 1 token per 50ms
+	rl := s.New()
+	if _, ok := s.TakeN(rl, 20); !ok {
+		t.Fatal("depleting take should succeed")
+	}
+
+	start := time.Now()
+	if err := s.WaitN(context.Background(), rl, 1); err != nil {
+		t.Fatalf("WaitN: unexpected error %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Fatalf("WaitN returned after %s, expected to block for a refill", elapsed)
+	}
+}
+
+func TestWaitN_MaxWaitDeniesImmediately(t *testing.T) {
+	s := BuildRateLimiter(1, time.Minute)
+	rl := s.New()
+	s.Take1(rl)
+
+	start := time.Now()
+	err := s.WaitN(context.Background(), rl, 1, WithMaxWait(100*time.Millisecond))
+	var limited *LimitedError
+	if !errors.As(err, &limited) {
+		t.Fatalf("err = %v, want *LimitedError", err)
+	}
+	if limited.RetryAfter < 50*time.Second {
+		t.Fatalf("RetryAfter = %s, want about a minute", limited.RetryAfter)
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Fatalf("WaitN blocked for %s, want immediate denial", elapsed)
+	}
+}
+
+func TestWaitN_MaxWaitAllowsShortWaits(t *testing.T) {
+	s := BuildRateLimiterRps(20)
+	rl := s.New()
+	s.TakeN(rl, 20)
+
+	if err := s.WaitN(context.Background(), rl, 1, WithMaxWait(time.Second)); err != nil {
+		t.Fatalf("WaitN within max wait: unexpected error %v", err)
+	}
+}
+
+func TestWaitN_ContextCancelled(t *testing.T) {
+	s := BuildRateLimiter(1, time.Minute)
+	rl := s.New()
+	s.Take1(rl)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+	if err := s.WaitN(ctx, rl, 1); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+}
+
+func TestWaitN_ExceedsBurst(t *testing.T) {
+	s := BuildRateLimiterRps(5)
+	var limited *LimitedError
+	if err := s.WaitN(context.Background(), s.New(), 6); !errors.As(err, &limited) {
+		t.Fatalf("err = %v, want *LimitedError", err)
+	}
+}
+
+func TestWait_AnyLimiter(t *testing.T) {
+	limiter, err := NewLimiter(GCRA, 20, time.Second) // one per 50ms
+	if err != nil {
+		t.Fatal(err)
+	}
+	rl := limiter.New()
+	limiter.TakeN(rl, 20)
+
+	start := time.Now()
+	if err := Wait(context.Background(), limiter, rl, 1); err != nil {
+		t.Fatalf("Wait: unexpected error %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Fatalf("Wait returned after %s, expected to block for the emission interval", elapsed)
+	}
+}
+
+func TestWait_SleepsPacingDelay(t *testing.T) {
+	l := BuildLeakyBucketLimiter(10, 500*time.Millisecond) // drains one per 50ms
+	rl := l.New()
+	l.Take1(rl)
+
+	start := time.Now()
+	if err := Wait(context.Background(), l, rl, 1); err != nil {
+		t.Fatalf("Wait: unexpected error %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Fatalf("Wait returned after %s, expected to sleep the pacing delay", elapsed)
+	}
+}
+
+func TestKeyedLimiter_WaitN(t *testing.T) {
+	kl := NewKeyedLimiter[string](BuildRateLimiterRps(20))
+	kl.TakeN("a", 20)
+
+	start := time.Now()
+	if err := kl.WaitN(context.Background(), "a", 1); err != nil {
+		t.Fatalf("WaitN: unexpected error %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Fatalf("WaitN returned after %s, expected to block for a refill", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	kl.TakeN("b", 20)
+	var limited *LimitedError
+	if err := kl.WaitN(ctx, "b", 20); !errors.As(err, &limited) {
+		t.Fatalf("WaitN past the deadline: err = %v, want *LimitedError", err)
+	}
+}
@@ -0,0 +1,347 @@
+package limitron
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// File names of a WALStore directory.
+const (
+	walFileName      = "limitron.wal"
+	snapshotFileName = "limitron.snapshot"
+)
+
+// errWALStoreClosed is returned by updates of a closed WALStore.
+var errWALStoreClosed = errors.New("limitron: WALStore is closed")
+
+// WALOptions configures a WALStore.
+type WALOptions struct {
+	// FlushInterval is how often the log is written and synced to disk; updates
+	// made within the last interval may be lost in a crash. Defaults to 1 second.
+	FlushInterval time.Duration
+	// SnapshotSize is the log size in bytes above which a snapshot of all states
+	// is written and the log is truncated. Defaults to 64 MiB.
+	SnapshotSize int64
+}
+
+// WALStore is a Store kept in memory and persisted to a directory with
+// a write-ahead log and periodic snapshots, so that consumed quotas of long
+// windows (e.g., daily or monthly limits) survive crashes and restarts.
+//
+// Every update appends the key's full state to the log, which is written and
+// synced every FlushInterval: a crash loses at most that much accuracy.
+// Once the log grows beyond SnapshotSize, the live states are written to a
+// snapshot file and the log starts over. On open, the snapshot is loaded
+// and the log replayed on top of it; a torn record at the end of the log
+// (from a crash during a write) is ignored. Log records are numbered and the
+// snapshot records the number of the last one it includes, so that a log left
+// over by a crash right after a snapshot is not replayed over it.
+//
+// Use it with a StoreLimiter. A directory must be used by a single WALStore at a time.
+type WALStore struct {
+	mem  *MemoryStore
+	dir  string
+	opts WALOptions
+
+	// mu serializes updates, so that the log holds them in the order they were made.
+	mu      sync.Mutex
+	wal     *os.File
+	buf     *bufio.Writer
+	walSize int64
+	// seq is the sequence number of the last record appended to the log.
+	seq uint64
+	err error
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// OpenWALStore opens the WALStore persisted in `dir`, creating the directory
+// if needed, and starts flushing in the background. Call Close when done.
+//
+// Example:
+//
+//	store, err := OpenWALStore("/var/lib/myapp/quotas", WALOptions{FlushInterval: 100 * time.Millisecond})
+//	if err != nil { ... }
+//	defer store.Close()
+//	monthly := NewStoreLimiter(store, BuildRateLimiter(10000, 30*24*time.Hour))
+func OpenWALStore(dir string, opts WALOptions) (*WALStore, error) {
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = time.Second
+	}
+	if opts.SnapshotSize <= 0 {
+		opts.SnapshotSize = 64 << 20
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	w := &WALStore{mem: NewMemoryStore(), dir: dir, opts: opts, done: make(chan struct{})}
+	now := time.Now().UnixMilli()
+	if err := w.load(filepath.Join(dir, snapshotFileName), now, true); err != nil {
+		return nil, err
+	}
+	if err := w.load(filepath.Join(dir, walFileName), now, false); err != nil {
+		return nil, err
+	}
+
+	// start a fresh log from the recovered states
+	if err := w.snapshotLocked(); err != nil {
+		return nil, err
+	}
+
+	w.wg.Add(1)
+	go w.flusher()
+	return w, nil
+}
+
+// Get implements Store.
+func (w *WALStore) Get(ctx context.Context, key string) (uint64, error) {
+	return w.mem.Get(ctx, key)
+}
+
+// CompareAndSet implements Store.
+func (w *WALStore) CompareAndSet(ctx context.Context, key string, old, new uint64) (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return false, w.err
+	}
+	swapped, err := w.mem.CompareAndSet(ctx, key, old, new)
+	if swapped {
+		w.appendLocked(key)
+	}
+	return swapped, err
+}
+
+// Expire implements Store.
+func (w *WALStore) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+	if err := w.mem.Expire(ctx, key, ttl); err != nil {
+		return err
+	}
+	w.appendLocked(key)
+	return nil
+}
+
+// Flush writes and syncs the log to disk, taking a snapshot if it grew too large.
+// It returns the first persistence error, after which updates fail.
+func (w *WALStore) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.err != nil {
+		return w.err
+	}
+	if err := w.buf.Flush(); err != nil {
+		w.err = err
+		return err
+	}
+	if err := w.wal.Sync(); err != nil {
+		w.err = err
+		return err
+	}
+	if w.walSize > w.opts.SnapshotSize {
+		if err := w.snapshotLocked(); err != nil {
+			w.err = err
+			return err
+		}
+	}
+	return nil
+}
+
+// Close stops background flushing, writes a final snapshot and closes the log.
+func (w *WALStore) Close() error {
+	close(w.done)
+	w.wg.Wait()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	err := w.err
+	if err == nil {
+		err = w.snapshotLocked()
+	}
+	if cerr := w.wal.Close(); err == nil {
+		err = cerr
+	}
+	w.err = errWALStoreClosed
+	return err
+}
+
+func (w *WALStore) flusher() {
+	defer w.wg.Done()
+	ticker := time.NewTicker(w.opts.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			_ = w.Flush()
+		}
+	}
+}
+
+// appendLocked appends the current entry of `key` to the log buffer, as the next
+// record in sequence. Must be called with w.mu held.
+func (w *WALStore) appendLocked(key string) {
+	w.mem.mu.Lock()
+	e := w.mem.entries[key]
+	w.mem.mu.Unlock()
+
+	w.seq++
+	rec := binary.LittleEndian.AppendUint64(nil, w.seq)
+	n, err := w.buf.Write(append(rec, encodeWALRecord(key, e)...))
+	w.walSize += int64(n)
+	if err != nil {
+		w.err = err
+	}
+}
+
+// snapshotLocked writes all live entries to a new snapshot file, headed by the sequence
+// number of the last log record, then starts an empty log.
+// Must be called with w.mu held (or before the store is shared).
+func (w *WALStore) snapshotLocked() error {
+	w.mem.Purge()
+
+	tmp := filepath.Join(w.dir, snapshotFileName+".tmp")
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(f)
+	_, err = bw.Write(binary.LittleEndian.AppendUint64(nil, w.seq))
+	w.mem.mu.Lock()
+	for key, e := range w.mem.entries {
+		if err != nil {
+			break
+		}
+		if _, err = bw.Write(encodeWALRecord(key, e)); err != nil {
+			break
+		}
+	}
+	w.mem.mu.Unlock()
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, filepath.Join(w.dir, snapshotFileName)); err != nil {
+		return err
+	}
+
+	// the snapshot holds everything the log did, so the log can start over
+	if w.wal != nil {
+		w.wal.Close()
+	}
+	w.wal, err = os.Create(filepath.Join(w.dir, walFileName))
+	if err != nil {
+		return err
+	}
+	w.buf = bufio.NewWriter(w.wal)
+	w.walSize = 0
+	return nil
+}
+
+// load applies the records of the file at `path`, if it exists, to the memory store.
+// A snapshot sets the sequence number of the log; log records at or below it are
+// already included in the snapshot and skipped.
+func (w *WALStore) load(path string, now int64, snapshot bool) error {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var seq [8]byte
+	if snapshot {
+		if _, err := io.ReadFull(r, seq[:]); err != nil {
+			return fmt.Errorf("limitron: read %s: %w", path, err)
+		}
+		w.seq = binary.LittleEndian.Uint64(seq[:])
+	}
+	for {
+		// log records are headed by their sequence number
+		var n uint64
+		if !snapshot {
+			_, err = io.ReadFull(r, seq[:])
+			n = binary.LittleEndian.Uint64(seq[:])
+		}
+		var key string
+		var e memoryStoreEntry
+		if err == nil {
+			key, e, err = decodeWALRecord(r)
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			// end of file, possibly with a torn last record
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("limitron: read %s: %w", path, err)
+		}
+		if !snapshot {
+			if n <= w.seq {
+				continue
+			}
+			w.seq = n
+		}
+		if e.expired(now) {
+			delete(w.mem.entries, key)
+			continue
+		}
+		w.mem.entries[key] = e
+	}
+}
+
+// encodeWALRecord encodes an entry as [uvarint key length][key][8-byte state][8-byte expiry].
+func encodeWALRecord(key string, e memoryStoreEntry) []byte {
+	b := make([]byte, 0, binary.MaxVarintLen64+len(key)+16)
+	b = binary.AppendUvarint(b, uint64(len(key)))
+	b = append(b, key...)
+	b = binary.LittleEndian.AppendUint64(b, e.state)
+	return binary.LittleEndian.AppendUint64(b, uint64(e.expires))
+}
+
+// maxWALKeyLen bounds the key length accepted when decoding, to detect corruption.
+const maxWALKeyLen = 1 << 20
+
+func decodeWALRecord(r *bufio.Reader) (string, memoryStoreEntry, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", memoryStoreEntry{}, err
+	}
+	if n > maxWALKeyLen {
+		return "", memoryStoreEntry{}, fmt.Errorf("corrupt record: key length %d", n)
+	}
+	buf := make([]byte, n+16)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return "", memoryStoreEntry{}, err
+	}
+	return string(buf[:n]), memoryStoreEntry{
+		state:   binary.LittleEndian.Uint64(buf[n:]),
+		expires: int64(binary.LittleEndian.Uint64(buf[n+8:])),
+	}, nil
+}
@@ -0,0 +1,187 @@
+package limitron
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWALStoreRecoversAfterClose(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	store, err := OpenWALStore(dir, WALOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sl := NewStoreLimiter(store, BuildRateLimiter(10, time.Hour))
+	for i := 0; i < 7; i++ {
+		if _, ok, err := sl.Take1(ctx, "k"); err != nil || !ok {
+			t.Fatalf("take %d: ok=%v err=%v", i, ok, err)
+		}
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.CompareAndSet(ctx, "k", 0, 1); err == nil {
+		t.Errorf("update after Close must fail")
+	}
+
+	store, err = OpenWALStore(dir, WALOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	sl = NewStoreLimiter(store, BuildRateLimiter(10, time.Hour))
+	if _, ok, _ := sl.TakeN(ctx, "k", 3); !ok {
+		t.Errorf("expected 3 tokens left after reopening")
+	}
+	if _, ok, _ := sl.Take1(ctx, "k"); ok {
+		t.Errorf("expected the quota to be exhausted after reopening")
+	}
+}
+
+func TestWALStoreRecoversFromLogAfterCrash(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	store, err := OpenWALStore(dir, WALOptions{FlushInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sl := NewStoreLimiter(store, BuildRateLimiter(10, time.Hour))
+	sl.TakeN(ctx, "a", 4)
+	sl.TakeN(ctx, "b", 10)
+	time.Sleep(50 * time.Millisecond)
+
+	// simulate a crash: stop flushing without a final snapshot, copy the files as they are
+	crashed := t.TempDir()
+	for _, name := range []string{walFileName, snapshotFileName} {
+		b, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// a torn record at the end of the log must be ignored
+		if name == walFileName {
+			b = append(b, 5, 'x')
+		}
+		if err := os.WriteFile(filepath.Join(crashed, name), b, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	store.Close()
+
+	recovered, err := OpenWALStore(crashed, WALOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer recovered.Close()
+	sl = NewStoreLimiter(recovered, BuildRateLimiter(10, time.Hour))
+	if _, ok, _ := sl.TakeN(ctx, "a", 6); !ok {
+		t.Errorf("expected 6 tokens of a left")
+	}
+	if _, ok, _ := sl.Take1(ctx, "a"); ok {
+		t.Errorf("expected a to be exhausted")
+	}
+	if _, ok, _ := sl.Take1(ctx, "b"); ok {
+		t.Errorf("expected b to be exhausted")
+	}
+}
+
+func TestWALStoreSnapshotTruncatesLog(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	store, err := OpenWALStore(dir, WALOptions{FlushInterval: time.Hour, SnapshotSize: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	sl := NewStoreLimiter(store, BuildRateLimiter(100, time.Hour))
+	for i := 0; i < 20; i++ {
+		sl.Take1(ctx, "key")
+	}
+	if err := store.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(filepath.Join(dir, walFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != 0 {
+		t.Errorf("log size after snapshot = %d, want 0", fi.Size())
+	}
+	if state, _ := store.Get(ctx, "key"); state == 0 {
+		t.Errorf("state lost by snapshot")
+	}
+}
+
+func TestWALStoreDropsExpiredKeys(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	store, err := OpenWALStore(dir, WALOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.CompareAndSet(ctx, "short", 0, 42)
+	store.Expire(ctx, "short", 10*time.Millisecond)
+	store.CompareAndSet(ctx, "long", 0, 43)
+	store.Expire(ctx, "long", time.Hour)
+	store.Close()
+	time.Sleep(20 * time.Millisecond)
+
+	store, err = OpenWALStore(dir, WALOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if got, _ := store.Get(ctx, "short"); got != 0 {
+		t.Errorf("expired key recovered with state %d", got)
+	}
+	if got, _ := store.Get(ctx, "long"); got != 43 {
+		t.Errorf("long = %d, want 43", got)
+	}
+}
+
+func TestWALStoreSkipsLogIncludedInSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	store, err := OpenWALStore(dir, WALOptions{FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sl := NewStoreLimiter(store, BuildRateLimiter(10, time.Hour))
+	sl.TakeN(ctx, "k", 4)
+	if err := store.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	stale, err := os.ReadFile(filepath.Join(dir, walFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sl.TakeN(ctx, "k", 4)
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// simulate a crash after the snapshot was renamed, before the log was truncated
+	if err := os.WriteFile(filepath.Join(dir, walFileName), stale, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	store, err = OpenWALStore(dir, WALOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	sl = NewStoreLimiter(store, BuildRateLimiter(10, time.Hour))
+	if _, ok, _ := sl.TakeN(ctx, "k", 3); ok {
+		t.Errorf("the log replayed over the snapshot restored consumed tokens")
+	}
+	if _, ok, _ := sl.TakeN(ctx, "k", 2); !ok {
+		t.Errorf("expected 2 tokens left")
+	}
+}
@@ -0,0 +1,31 @@
+package limitron
+
+import "time"
+
+// WithWarmUp makes newly created keys start at `initial` (in (0, 1]) times the
+// configured burst and rate, ramping up linearly to the full limits over `d`,
+// so that new tenants cannot hit cold caches downstream with a full burst
+// in their first second.
+//
+// Warm-up applies to the limiter in effect for the key, including the relaxed
+// limiter of a grace period (see WithGracePeriod).
+//
+// Example:
+//
+//	kl := NewKeyedLimiter[string](BuildRateLimiterRps(1000),
+//	    WithWarmUp[string](5*time.Minute, 0.1)) // 100 rps at first, 1000 rps after 5 minutes
+func WithWarmUp[K comparable](d time.Duration, initial float64) KeyedOption[K] {
+	return func(kl *KeyedLimiter[K]) {
+		kl.warmUp = uint64(max(d.Milliseconds(), 0))
+		kl.warmUpFrom = min(max(initial, 0.001), 1)
+	}
+}
+
+// warmedUp scales `limiter` by the warm-up factor of entry `e` at time `now`, if it is still warming up.
+func (kl *KeyedLimiter[K]) warmedUp(e *keyedEntry, limiter RateLimiter, now uint64) RateLimiter {
+	if kl.warmUp == 0 || limiter.maxreq == 0 || now >= e.created+kl.warmUp {
+		return limiter
+	}
+	progress := float64(now-min(e.created, now)) / float64(kl.warmUp)
+	return limiter.scaled(kl.warmUpFrom + (1-kl.warmUpFrom)*progress)
+}
@@ -0,0 +1,41 @@
+package limitron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyedLimiter_WarmUpRamps(t *testing.T) {
+	// 1 token per ms, starting at a tenth: a burst of 10
+	kl := NewKeyedLimiter(BuildRateLimiter(100, 100*time.Millisecond),
+		WithWarmUp[string](100*time.Millisecond, 0.1))
+
+	if _, ok := kl.TakeN("new", 20); ok {
+		t.Fatal("burst of 20 allowed at the start of the warm-up")
+	}
+	if _, ok := kl.TakeN("new", 10); !ok {
+		t.Fatal("burst of 10 denied at the start of the warm-up")
+	}
+
+	// warmed up, and refilled at the full rate
+	time.Sleep(250 * time.Millisecond)
+	if _, ok := kl.TakeN("new", 80); !ok {
+		t.Fatal("burst of 80 denied after the warm-up")
+	}
+}
+
+func TestKeyedLimiter_WarmUpOnlyNewKeys(t *testing.T) {
+	kl := NewKeyedLimiter(BuildRateLimiter(10, 10*time.Millisecond),
+		WithWarmUp[string](50*time.Millisecond, 0.5))
+
+	kl.Take1("old")
+	time.Sleep(70 * time.Millisecond)
+
+	// "old" has warmed up and refilled; "new" starts at half the burst
+	if _, ok := kl.TakeN("old", 10); !ok {
+		t.Fatal("warmed-up key denied its full burst")
+	}
+	if _, ok := kl.TakeN("new", 6); ok {
+		t.Fatal("new key allowed more than half the burst")
+	}
+}
@@ -0,0 +1,169 @@
+package limitron
+
+import (
+	"container/heap"
+	"math"
+	"sync"
+)
+
+// WFQDispatcher is a weighted fair queueing (WFQ) dispatcher in front of a single
+// global limiter.
+//
+// When the global limiter is saturated, tenants receive bandwidth proportional
+// to their weights, so that premium tenants are not starved by noisy free-tier traffic.
+// Scheduling uses self-clocked fair queueing: every item is tagged with a virtual
+// finish time max(V, lastFinish(tenant)) + cost/weight at enqueue time, and the pending
+// item with the smallest tag is dispatched next, advancing the virtual time V to its tag.
+//
+// The zero value is not usable; create instances with NewWFQDispatcher.
+// All methods are safe for concurrent use.
+type WFQDispatcher[K comparable, T any] struct {
+	limiter RateLimiter
+	state   *uint64
+
+	mu      sync.Mutex
+	tenants map[K]*wfqTenant[K, T]
+	ready   wfqHeap[K, T]
+	vtime   float64
+}
+
+// wfqTenant is the per-tenant FIFO of tagged items.
+type wfqTenant[K comparable, T any] struct {
+	key        K
+	weight     float64
+	lastFinish float64
+	items      []wfqItem[T]
+	// index in the ready heap, -1 when the tenant has no pending items
+	index int
+}
+
+type wfqItem[T any] struct {
+	item   T
+	cost   uint16
+	finish float64
+}
+
+// NewWFQDispatcher returns a WFQDispatcher whose dispatch rate is capped by `limiter`.
+// Tenants have weight 1 unless changed with SetWeight.
+func NewWFQDispatcher[K comparable, T any](limiter RateLimiter) *WFQDispatcher[K, T] {
+	return &WFQDispatcher[K, T]{
+		limiter: limiter,
+		state:   limiter.New(),
+		tenants: make(map[K]*wfqTenant[K, T]),
+	}
+}
+
+// SetWeight sets the weight of `key`. The weight applies to items enqueued afterwards.
+// Non-positive weights are ignored.
+func (d *WFQDispatcher[K, T]) SetWeight(key K, weight float64) {
+	if weight <= 0 {
+		return
+	}
+	d.mu.Lock()
+	d.tenant(key).weight = weight
+	d.mu.Unlock()
+}
+
+// Enqueue appends `item` with the given `cost` (tokens charged to the global limiter)
+// to the queue of tenant `key`. Items costing more than the burst of the limiter could
+// never be dispatched and would block all tenants behind them, so they are rejected
+// with ErrExceedsBurst.
+func (d *WFQDispatcher[K, T]) Enqueue(key K, item T, cost uint16) error {
+	if cost > d.limiter.maxreq {
+		return ErrExceedsBurst
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	t := d.tenant(key)
+	finish := max(d.vtime, t.lastFinish) + float64(cost)/t.weight
+	t.lastFinish = finish
+	t.items = append(t.items, wfqItem[T]{item: item, cost: cost, finish: finish})
+	if t.index < 0 {
+		heap.Push(&d.ready, t)
+	}
+	return nil
+}
+
+// Len returns the total number of pending items.
+func (d *WFQDispatcher[K, T]) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	n := 0
+	for _, t := range d.ready {
+		n += len(t.items)
+	}
+	return n
+}
+
+// Dequeue returns the pending item with the smallest virtual finish time,
+// if the global limiter admits it.
+//
+// Otherwise it returns ok=false and the number of milliseconds to wait before
+// calling it again (math.MaxInt64 when nothing is pending).
+func (d *WFQDispatcher[K, T]) Dequeue() (key K, item T, waitMillis int64, ok bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if len(d.ready) == 0 {
+		return key, item, math.MaxInt64, false
+	}
+
+	t := d.ready[0]
+	head := t.items[0]
+	if wait, taken := d.limiter.TakeN(d.state, head.cost); !taken {
+		return key, item, wait, false
+	}
+
+	d.vtime = head.finish
+	var zero wfqItem[T]
+	t.items[0] = zero
+	t.items = t.items[1:]
+	if len(t.items) == 0 {
+		t.items = nil
+		heap.Pop(&d.ready)
+	} else {
+		heap.Fix(&d.ready, 0)
+	}
+	return t.key, head.item, 0, true
+}
+
+// tenant returns the tenant of `key`, creating it if needed. Must be called with d.mu held.
+func (d *WFQDispatcher[K, T]) tenant(key K) *wfqTenant[K, T] {
+	t, ok := d.tenants[key]
+	if !ok {
+		t = &wfqTenant[K, T]{key: key, weight: 1, index: -1}
+		d.tenants[key] = t
+	}
+	return t
+}
+
+// wfqHeap orders tenants with pending items by the finish time of their head item.
+type wfqHeap[K comparable, T any] []*wfqTenant[K, T]
+
+func (h wfqHeap[K, T]) Len() int { return len(h) }
+
+func (h wfqHeap[K, T]) Less(i, j int) bool { return h[i].items[0].finish < h[j].items[0].finish }
+
+func (h wfqHeap[K, T]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *wfqHeap[K, T]) Push(x any) {
+	t := x.(*wfqTenant[K, T])
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+
+func (h *wfqHeap[K, T]) Pop() any {
+	old := *h
+	n := len(old)
+	t := old[n-1]
+	old[n-1] = nil
+	t.index = -1
+	*h = old[:n-1]
+	return t
+}
@@ -0,0 +1,99 @@
+package limitron
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestWFQDispatcher_ProportionalToWeight(t *testing.T) {
+	d := NewWFQDispatcher[string, int](BuildRateLimiter(1000, time.Hour))
+	d.SetWeight("premium", 3)
+	for i := 0; i < 30; i++ {
+		d.Enqueue("premium", i, 1)
+		d.Enqueue("free", i, 1)
+	}
+
+	counts := map[string]int{}
+	for i := 0; i < 20; i++ {
+		k, _, _, ok := d.Dequeue()
+		if !ok {
+			t.Fatalf("Dequeue %d failed", i)
+		}
+		counts[k]++
+	}
+	if counts["premium"] != 15 || counts["free"] != 5 {
+		t.Fatalf("first 20 dispatches = %v, want premium:15 free:5", counts)
+	}
+}
+
+func TestWFQDispatcher_FIFOWithinTenant(t *testing.T) {
+	d := NewWFQDispatcher[string, int](BuildRateLimiter(1000, time.Hour))
+	for i := 0; i < 5; i++ {
+		d.Enqueue("a", i, 1)
+	}
+	for i := 0; i < 5; i++ {
+		_, item, _, ok := d.Dequeue()
+		if !ok || item != i {
+			t.Fatalf("Dequeue %d => item=%d ok=%v", i, item, ok)
+		}
+	}
+	if _, _, wait, ok := d.Dequeue(); ok || wait != math.MaxInt64 {
+		t.Fatalf("empty Dequeue => wait=%d ok=%v, want MaxInt64,false", wait, ok)
+	}
+}
+
+func TestWFQDispatcher_GlobalLimit(t *testing.T) {
+	d := NewWFQDispatcher[string, int](BuildRateLimiter(3, time.Hour))
+	for i := 0; i < 5; i++ {
+		d.Enqueue("a", i, 1)
+	}
+	for i := 0; i < 3; i++ {
+		if _, _, _, ok := d.Dequeue(); !ok {
+			t.Fatalf("Dequeue %d should be admitted", i)
+		}
+	}
+	if _, _, wait, ok := d.Dequeue(); ok || wait <= 0 {
+		t.Fatalf("Dequeue over global limit => wait=%d ok=%v, want positive,false", wait, ok)
+	}
+	if got := d.Len(); got != 2 {
+		t.Fatalf("Len = %d, want 2", got)
+	}
+}
+
+func TestWFQDispatcher_NewTenantNotPenalized(t *testing.T) {
+	d := NewWFQDispatcher[string, int](BuildRateLimiter(1000, time.Hour))
+	for i := 0; i < 10; i++ {
+		d.Enqueue("busy", i, 1)
+	}
+	for i := 0; i < 5; i++ {
+		d.Dequeue()
+	}
+
+	// A tenant arriving late starts at the current virtual time,
+	// so it is served right away instead of after the busy backlog.
+	d.Enqueue("late", 0, 1)
+	seen := false
+	for i := 0; i < 2; i++ {
+		if k, _, _, _ := d.Dequeue(); k == "late" {
+			seen = true
+		}
+	}
+	if !seen {
+		t.Fatal("late tenant should be dispatched within the next two items")
+	}
+}
+
+func TestWFQDispatcher_RejectsCostOverBurst(t *testing.T) {
+	d := NewWFQDispatcher[string, int](BuildRateLimiter(5, time.Hour))
+	if err := d.Enqueue("a", 0, 6); !errors.Is(err, ErrExceedsBurst) {
+		t.Fatalf("Enqueue over the burst = %v, want ErrExceedsBurst", err)
+	}
+	if err := d.Enqueue("b", 1, 5); err != nil {
+		t.Fatal(err)
+	}
+	if k, _, _, ok := d.Dequeue(); !ok || k != "b" {
+		t.Fatalf("Dequeue = %q, %v; want b", k, ok)
+	}
+}
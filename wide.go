@@ -0,0 +1,137 @@
+package limitron
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// WideRateLimiter is a token bucket like RateLimiter for limits above the 65,535 tokens
+// of the 16-bit layout, such as 250,000 requests per minute for a whole datacenter.
+// The per-key state is a single uint64 updated lock-free, packed as follows:
+//
+//	[32-bit tokens][32-bit refill clock in Unix seconds]
+//
+// The refill clock is coarse: tokens refill once per second, and the clock only moves
+// when at least one whole token was refilled, so that slow refill rates accumulate
+// instead of losing their progress on every update. Waits are still reported in millis,
+// up to the next second at which the missing tokens are refilled. The clock covers
+// dates until 2106.
+//
+// Options WithClock, WithMetrics and WithRetries apply as for RateLimiter; other options
+// have no effect. WideRateLimiter does not implement Limiter, since it takes 32-bit requests.
+type WideRateLimiter struct {
+	// base holds the CAS retries, clock and metrics.
+	base RateLimiter
+	// maxreq is the burst size in tokens.
+	maxreq uint32
+	// rps is the refill rate in tokens per second.
+	rps float64
+}
+
+// BuildWideRateLimiter returns a WideRateLimiter that allows up to `req` requests per `interval`,
+// with a burst capacity of `req`.
+//
+// Example:
+//
+//	limiter := BuildWideRateLimiter(250_000, time.Minute)
+//	state := limiter.New()
+//	if wait, ok := limiter.TakeN(state, 1200); !ok {
+//	    // retry after `wait` millis
+//	}
+func BuildWideRateLimiter(req uint32, interval time.Duration, opts ...Option) WideRateLimiter {
+	return WideRateLimiter{
+		base:   BuildRateLimiter(1, interval, opts...),
+		maxreq: req,
+		rps:    float64(req) / interval.Seconds(),
+	}
+}
+
+// packWide packs a WideRateLimiter state.
+func packWide(tokens uint32, ts uint32) uint64 {
+	return uint64(tokens)<<32 | uint64(ts)
+}
+
+// unpackWide unpacks a WideRateLimiter state.
+func unpackWide(rlval uint64) (tokens uint32, ts uint32) {
+	return uint32(rlval >> 32), uint32(rlval)
+}
+
+// New creates a brand-new, zero-use limiter state with a full burst.
+func (w WideRateLimiter) New() *uint64 {
+	rl := packWide(w.maxreq, 0)
+	return &rl
+}
+
+// Take1 attempts to consume 1 token. See TakeN.
+func (w WideRateLimiter) Take1(rl *uint64) (int64, bool) {
+	return w.TakeN(rl, 1)
+}
+
+// TakeN attempts to atomically consume `requests` tokens from the limiter state `*rl`.
+//
+// It has the same contract as RateLimiter.TakeN: it returns 0, true if the tokens
+// were consumed, or the number of millis to wait before they would be, and false.
+// If `requests > req`, it returns (math.MaxInt64, false) immediately.
+func (w WideRateLimiter) TakeN(rl *uint64, requests uint32) (int64, bool) {
+	return w.base.observe(w.takeNAt(rl, requests, w.base.now()))
+}
+
+// takeNAt is TakeN without metrics, evaluated at time `now`.
+func (w WideRateLimiter) takeNAt(rl *uint64, requests uint32, now time.Time) (int64, bool) {
+	if requests == 0 {
+		return 0, true
+	} else if requests > w.maxreq {
+		return math.MaxInt64, false
+	}
+
+	for i := 0; i < w.base.retries; i++ {
+		rlval := atomic.LoadUint64(rl)
+		tokens, ts := w.refillAt(rlval, now)
+		if requests > tokens {
+			// the missing tokens are refilled a whole number of seconds after ts
+			secs := math.Ceil(float64(requests-tokens) / w.rps)
+			at := time.Unix(int64(ts)+int64(secs), 0)
+			return 1 + max(at.Sub(now).Milliseconds(), 0), false
+		}
+		if atomic.CompareAndSwapUint64(rl, rlval, packWide(tokens-requests, ts)) {
+			return 0, true
+		}
+	}
+	return 1, false
+}
+
+// refillAt returns the tokens of the state `rlval` at time `now`, and its new refill clock.
+// The clock moves to now only if whole tokens were refilled.
+func (w WideRateLimiter) refillAt(rlval uint64, now time.Time) (tokens uint32, ts uint32) {
+	tokens, ts = unpackWide(rlval)
+	sec := uint32(max(now.Unix(), 0))
+	if sec <= ts {
+		return min(tokens, w.maxreq), ts
+	}
+	refill := math.Floor(w.rps * float64(sec-ts))
+	if refill < 1 {
+		return min(tokens, w.maxreq), ts
+	}
+	return uint32(min(float64(tokens)+refill, float64(w.maxreq))), sec
+}
+
+// Available returns the number of tokens available in the limiter state `*rl` right now,
+// without modifying the state.
+func (w WideRateLimiter) Available(rl *uint64) uint32 {
+	tokens, _ := w.refillAt(atomic.LoadUint64(rl), w.base.now())
+	return tokens
+}
+
+// ReturnN atomically adds `n` tokens back to the limiter state `*rl`, capped at the burst.
+// See RateLimiter.ReturnN.
+func (w WideRateLimiter) ReturnN(rl *uint64, n uint32) {
+	for {
+		rlval := atomic.LoadUint64(rl)
+		tokens, ts := w.refillAt(rlval, w.base.now())
+		tokens = uint32(min(uint64(tokens)+uint64(n), uint64(w.maxreq)))
+		if atomic.CompareAndSwapUint64(rl, rlval, packWide(tokens, ts)) {
+			return
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package limitron
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestWideRateLimiter_LargeBurst(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)}
+	w := BuildWideRateLimiter(250_000, time.Minute, WithClock(clock))
+	state := w.New()
+
+	if _, ok := w.TakeN(state, 200_000); !ok {
+		t.Fatal("200k tokens denied")
+	}
+	if _, ok := w.TakeN(state, 50_000); !ok {
+		t.Fatal("remaining 50k tokens denied")
+	}
+	waitMillis, ok := w.TakeN(state, 10_000)
+	if ok || waitMillis < 2000 || waitMillis > 3001 {
+		t.Fatalf("TakeN(10k) = %d, %v, want about 3s, false", waitMillis, ok)
+	}
+
+	clock.t = clock.t.Add(3 * time.Second)
+	if got := w.Available(state); got != 12_500 {
+		t.Fatalf("Available after 3s = %d, want 12500", got)
+	}
+	if _, ok := w.TakeN(state, 300_000); ok {
+		t.Fatal("request over the burst allowed")
+	}
+	if waitMillis, _ := w.TakeN(state, 300_000); waitMillis != math.MaxInt64 {
+		t.Fatalf("wait over the burst = %d, want MaxInt64", waitMillis)
+	}
+}
+
+func TestWideRateLimiter_SlowRateAccumulates(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)}
+	w := BuildWideRateLimiter(3, time.Hour, WithClock(clock)) // 1 per 20min
+	state := w.New()
+	w.TakeN(state, 3)
+
+	// frequent denied attempts must not reset the refill progress
+	for i := 0; i < 20; i++ {
+		clock.t = clock.t.Add(time.Minute)
+		if i < 19 {
+			if _, ok := w.Take1(state); ok {
+				t.Fatalf("token refilled after %d minutes", i+1)
+			}
+		}
+	}
+	if _, ok := w.Take1(state); !ok {
+		t.Fatal("token denied after 20 minutes")
+	}
+}
+
+func TestWideRateLimiter_ReturnN(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)}
+	w := BuildWideRateLimiter(100_000, time.Hour, WithClock(clock))
+	state := w.New()
+	w.TakeN(state, 100_000)
+	w.ReturnN(state, 70_000)
+	if got := w.Available(state); got != 70_000 {
+		t.Fatalf("Available = %d, want 70000", got)
+	}
+	w.ReturnN(state, 70_000)
+	if got := w.Available(state); got != 100_000 {
+		t.Fatalf("Available = %d, want the burst of 100000", got)
+	}
+}
@@ -0,0 +1,97 @@
+package limitron
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// WindowLogLimiter is an exact sliding window rate limiter: it allows `req` requests
+// in any window of `interval`, keeping a log of the times of the admitted requests.
+//
+// Unlike the other limiters, its per-key state does not fit a uint64: every key
+// gets a WindowLog, a ring buffer of `req` timestamps allocated by New. It is meant
+// for a small set of sensitive keys needing exact accounting, next to the
+// approximate, uint64-packed limiters such as SlidingWindowLimiter used for the rest.
+//
+// Options WithClock and WithMetrics apply as for RateLimiter; WithPunitive has no effect.
+type WindowLogLimiter struct {
+	// base holds the limit (maxreq), clock and metrics.
+	base RateLimiter
+	// window is the window length in milliseconds.
+	window int64
+}
+
+// WindowLog is the per-key state of a WindowLogLimiter, created with WindowLogLimiter.New.
+// It is safe for concurrent use.
+type WindowLog struct {
+	mu sync.Mutex
+	// times is a ring buffer of the admission times in Unix milliseconds, oldest at head.
+	times []int64
+	head  int
+	n     int
+}
+
+// BuildWindowLogLimiter returns a WindowLogLimiter allowing up to `req` requests
+// in any window of `interval`. The interval is truncated to whole milliseconds.
+//
+// Example:
+//
+//	limiter := BuildWindowLogLimiter(5, time.Minute) // e.g., 5 password resets per minute
+//	log := limiter.New()
+//	if wait, ok := limiter.Take1(log); !ok {
+//	    // retry after `wait` millis
+//	}
+func BuildWindowLogLimiter(req uint16, interval time.Duration, opts ...Option) WindowLogLimiter {
+	return WindowLogLimiter{
+		base:   BuildRateLimiter(req, interval, opts...),
+		window: max(interval.Milliseconds(), 1),
+	}
+}
+
+// New creates a brand-new, empty log for a key.
+func (l WindowLogLimiter) New() *WindowLog {
+	return &WindowLog{times: make([]int64, l.base.maxreq)}
+}
+
+// Take1 attempts to admit 1 request. See TakeN.
+func (l WindowLogLimiter) Take1(log *WindowLog) (int64, bool) {
+	return l.TakeN(log, 1)
+}
+
+// TakeN attempts to admit `requests` requests against the log `log`.
+//
+// It has the same contract as RateLimiter.TakeN: it returns 0, true if the requests
+// are allowed, or the exact number of millis until enough logged requests leave
+// the window, and false. If `requests > req`, it returns (math.MaxInt64, false) immediately.
+func (l WindowLogLimiter) TakeN(log *WindowLog, requests uint16) (int64, bool) {
+	return l.base.observe(l.takeNAt(log, requests, l.base.now().UnixMilli()))
+}
+
+// takeNAt is TakeN without metrics, evaluated at time `now` in Unix milliseconds.
+func (l WindowLogLimiter) takeNAt(log *WindowLog, requests uint16, now int64) (int64, bool) {
+	if requests == 0 {
+		return 0, true
+	} else if requests > l.base.maxreq {
+		return math.MaxInt64, false
+	}
+
+	log.mu.Lock()
+	defer log.mu.Unlock()
+
+	size := len(log.times)
+	for log.n > 0 && log.times[log.head] <= now-l.window {
+		log.head = (log.head + 1) % size
+		log.n--
+	}
+	if over := log.n + int(requests) - size; over > 0 {
+		// the over-th oldest request must leave the window first
+		leaves := log.times[(log.head+over-1)%size] + l.window
+		return max(leaves-now, 1), false
+	}
+	for i := 0; i < int(requests); i++ {
+		log.times[(log.head+log.n)%size] = now
+		log.n++
+	}
+	return 0, true
+}
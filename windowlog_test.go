@@ -0,0 +1,52 @@
+package limitron
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestWindowLogLimiter_ExactWindow(t *testing.T) {
+	clock := &testClock{t: time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC)}
+	l := BuildWindowLogLimiter(3, time.Second, WithClock(clock))
+	log := l.New()
+
+	for _, at := range []time.Duration{0, 100 * time.Millisecond, 700 * time.Millisecond} {
+		clock.t = time.Date(2026, 5, 1, 10, 0, 0, 0, time.UTC).Add(at)
+		if _, ok := l.Take1(log); !ok {
+			t.Fatalf("request at +%s denied", at)
+		}
+	}
+
+	clock.t = clock.t.Add(200 * time.Millisecond) // +900ms
+	wait, ok := l.Take1(log)
+	if ok || wait != 100 {
+		t.Fatalf("Take1 at +900ms = %d, %v, want 100, false", wait, ok)
+	}
+	wait, ok = l.TakeN(log, 2)
+	if ok || wait != 200 {
+		t.Fatalf("TakeN(2) at +900ms = %d, %v, want 200, false", wait, ok)
+	}
+
+	clock.t = clock.t.Add(100 * time.Millisecond) // +1000ms: the first request left
+	if _, ok := l.Take1(log); !ok {
+		t.Fatal("request denied after the oldest left the window")
+	}
+	if _, ok := l.Take1(log); ok {
+		t.Fatal("request allowed over the limit")
+	}
+}
+
+func TestWindowLogLimiter_EdgeCases(t *testing.T) {
+	l := BuildWindowLogLimiter(2, time.Second)
+	log := l.New()
+	if wait, ok := l.TakeN(log, 0); !ok || wait != 0 {
+		t.Fatalf("TakeN(0) = %d, %v", wait, ok)
+	}
+	if wait, ok := l.TakeN(log, 3); ok || wait != math.MaxInt64 {
+		t.Fatalf("TakeN over the limit = %d, %v", wait, ok)
+	}
+	if _, ok := l.TakeN(log, 2); !ok {
+		t.Fatal("full limit denied")
+	}
+}